@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
@@ -22,30 +23,102 @@ func main() {
 	// Structured logging (optional JSON) + access logs + trace correlation.
 	observability.Init()
 
-	// サービスの初期化
-	services, err := initServices(ctx)
+	// テナント（世帯）設定の読み込み
+	tenantConfigs, err := config.LoadTenants(config.TenantsConfigPath)
 	if err != nil {
-		log.Fatalf("Failed to initialize services: %v", err)
+		log.Fatalf("Failed to load tenants: %v", err)
+	}
+
+	// 処理台帳などの保存先が再起動で消えないことを確認（Cloud Runでは /tmp がメモリ上にあるため）
+	if config.RequirePersistentStorage {
+		dirs := []string{config.AIUsage.Dir}
+		for _, tc := range tenantConfigs {
+			dirs = append(dirs, tc.LedgerDir(), tc.DeadLetterDir())
+		}
+		for _, dir := range dirs {
+			if err := config.CheckPersistentDir(dir); err != nil {
+				log.Fatalf("Persistent storage required (REQUIRE_PERSISTENT_STORAGE=false to skip): %v", err)
+			}
+		}
+	}
+
+	// AIRouter（全テナント共通）
+	aiRouter, err := service.NewAIRouter(ctx)
+	if err != nil {
+		log.Fatalf("Failed to initialize AIRouter: %v", err)
+	}
+
+	// AI使用量の記録（オプショナル、全テナント共通）
+	aiUsage, err := service.NewUsageTracker(config.AIUsage.Dir, config.AIUsage.MonthlyBudgetUSD, config.AIUsage.Prices)
+	if err != nil {
+		log.Printf("Warning: UsageTracker initialization failed: %v", err)
+		aiUsage = nil
+	} else {
+		aiRouter.SetUsageTracker(aiUsage)
+		log.Printf("UsageTracker initialized: %s (budget: $%.2f/month)", config.AIUsage.Dir, config.AIUsage.MonthlyBudgetUSD)
 	}
 
 	// Webhook URLの設定
 	webhookURL := getWebhookURL()
 	log.Printf("Webhook URL: %s", webhookURL)
 
-	// WatchManagerの初期化
-	var watchManager *service.WatchManager
-	if webhookURL != "" {
-		watchManager = service.NewWatchManager(services.DriveClient, services.FileSorter, webhookURL)
-		log.Printf("WatchManager initialized")
+	// RAG用のGemini APIキー（LINE Botが有効なテナントのみ使用）
+	geminiAPIKey := os.Getenv("GEMINI_API_KEY")
 
-		// 起動時に自動でWatch開始（インスタンス再起動時にも監視を復旧）
-		if err := watchManager.StartWatch(ctx); err != nil {
-			log.Printf("Warning: Auto-start watch failed: %v", err)
+	tenants := service.NewTenantRegistry()
+	lineDispatcher := linebot.NewDispatcher(tenantConfigs[0].ID)
+	for _, tc := range tenantConfigs {
+		resolveTenantSecrets(ctx, tc)
+
+		// サービスの初期化
+		services, err := initServices(ctx, tc, aiRouter, aiUsage)
+		if err != nil {
+			log.Fatalf("Failed to initialize services for tenant %s: %v", tc.ID, err)
+		}
+
+		// WatchManagerの初期化
+		var watchManager *service.WatchManager
+		if webhookURL != "" {
+			watchManager = service.NewWatchManager(services.DriveClient, services.FileSorter, webhookURL)
+			log.Printf("WatchManager initialized (tenant=%s)", tc.ID)
+
+			// 起動時に自動でWatch開始（インスタンス再起動時にも監視を復旧）
+			if err := watchManager.StartWatch(ctx); err != nil {
+				log.Printf("Warning: Auto-start watch failed (tenant=%s): %v", tc.ID, err)
+			} else {
+				log.Printf("Watch auto-started on boot (tenant=%s)", tc.ID)
+			}
 		} else {
-			log.Printf("Watch auto-started on boot")
+			log.Printf("Warning: Webhook URL not configured, WatchManager disabled")
+		}
+
+		// LINE Bot（チャネル設定のあるテナントのみ）
+		if tc.LineChannelSecret != "" && tc.LineChannelAccessToken != "" {
+			if geminiAPIKey == "" {
+				// Secret Managerから取得を試行
+				geminiAPIKey = getSecretValue(ctx, config.SecretGeminiAPIKey)
+			}
+			if lineHandler := initLineBot(ctx, tc, services, geminiAPIKey); lineHandler != nil {
+				lineDispatcher.Register(tc.ID, tc.LineBotUserID, lineHandler)
+			}
+		} else {
+			log.Printf("Info: LINE Bot credentials not set for tenant %s", tc.ID)
+		}
+
+		if err := tenants.Add(&service.Tenant{Config: tc, Services: services, WatchManager: watchManager}); err != nil {
+			log.Fatalf("Failed to register tenant: %v", err)
+		}
+
+		// 設定ファイルの変更を監視（CONFIG_RELOAD_INTERVAL=0 で無効）
+		if config.ConfigReload.PollInterval > 0 {
+			go services.ConfigReloader.Watch(ctx, config.ConfigReload.PollInterval)
 		}
-	} else {
-		log.Printf("Warning: Webhook URL not configured, WatchManager disabled")
+	}
+	log.Printf("%d tenant(s) initialized (default: %s)", len(tenants.List()), tenants.Default().Config.ID)
+
+	// 予算超過は運用者（既定のテナント）のDiscordに通知する
+	if aiUsage != nil {
+		aiUsage.SetBudgetAlert(tenants.Default().Services.DiscordNotifier.NotifyBudgetExceeded)
 	}
 
 	// Ginルーターの設定（デフォルトロガーを構造化ログに置き換え）
@@ -55,9 +128,12 @@ func main() {
 	router.Use(observability.AccessLogMiddleware())
 
 	// ハンドラーの初期化
-	pubsubHandler := handler.NewPubSubHandler(services, watchManager)
+	pubsubHandler := handler.NewPubSubHandler(tenants)
 	adminAuth := handler.AdminAuthMiddleware()
 
+	// ?tenant= / X-Tenant-ID で対象テナントを選択（未指定時は既定のテナント）
+	router.Use(pubsubHandler.TenantMiddleware())
+
 	// ルートの設定
 	router.POST("/", pubsubHandler.HandlePubSub)
 	router.GET("/health", pubsubHandler.HealthCheck)
@@ -66,6 +142,21 @@ func main() {
 	router.GET("/admin/ping", adminAuth, pubsubHandler.AdminPing)
 	router.POST("/admin/cleanup", adminAuth, pubsubHandler.AdminCleanup)
 	router.POST("/trigger/inbox", adminAuth, pubsubHandler.TriggerInbox)
+	router.GET("/admin/ledger", adminAuth, pubsubHandler.AdminLedger)
+	router.POST("/admin/ledger/resume", adminAuth, pubsubHandler.AdminLedgerResume)
+	router.POST("/admin/reclassify", adminAuth, pubsubHandler.AdminReclassify)
+	router.GET("/admin/dlq", adminAuth, pubsubHandler.AdminDLQ)
+	router.POST("/admin/dlq/:fileId/replay", adminAuth, pubsubHandler.AdminDLQReplay)
+	router.POST("/admin/dlq/:fileId/discard", adminAuth, pubsubHandler.AdminDLQDiscard)
+	router.GET("/admin/config", adminAuth, pubsubHandler.AdminConfig)
+	router.POST("/admin/config/reload", adminAuth, pubsubHandler.AdminConfigReload)
+	router.GET("/admin/tenants", adminAuth, pubsubHandler.AdminTenants)
+	router.GET("/admin/usage", adminAuth, pubsubHandler.AdminUsage)
+	router.GET("/admin/medical-expenses", adminAuth, pubsubHandler.AdminMedicalExpenses)
+	router.POST("/admin/medical-expenses/remind", adminAuth, pubsubHandler.AdminMedicalExpensesRemind)
+	router.GET("/admin/finance", adminAuth, pubsubHandler.AdminFinance)
+	router.POST("/admin/retention/run", adminAuth, pubsubHandler.AdminRetentionRun)
+	router.POST("/admin/rollover", adminAuth, pubsubHandler.AdminRollover)
 
 	// Drive Watch関連のエンドポイント
 	router.POST("/webhook/drive", pubsubHandler.HandleDriveWebhook)
@@ -74,41 +165,11 @@ func main() {
 	router.POST("/admin/watch/stop", adminAuth, pubsubHandler.WatchStop)
 	router.GET("/admin/watch/status", adminAuth, pubsubHandler.WatchStatus)
 
-	// LINE Bot Webhook
-	if config.LineChannelSecret != "" && config.LineChannelAccessToken != "" {
-		lineService, err := linebot.NewService(config.LineBotSettingsPath)
-		if err != nil {
-			log.Printf("Warning: LINE Bot Service initialization failed: %v", err)
-		} else {
-			// RAGService初期化（オプショナル）
-			var ragService *linebot.RAGService
-			geminiAPIKey := os.Getenv("GEMINI_API_KEY")
-			if geminiAPIKey == "" {
-				// Secret Managerから取得を試行
-				geminiAPIKey = getSecretValue(ctx, config.SecretGeminiAPIKey)
-			}
-			if geminiAPIKey != "" {
-				ragService, err = linebot.NewRAGService(ctx, geminiAPIKey, config.LineUserSettingsPath, services.DriveClient)
-				if err != nil {
-					log.Printf("Warning: RAG Service initialization failed: %v", err)
-					ragService = nil
-				} else if ragService != nil {
-					log.Printf("RAG Service initialized with model: %s", config.GeminiModelsConfig.LineRAG)
-				} else {
-					log.Printf("Info: RAG Service disabled (no documents or folders configured)")
-				}
-			} else {
-				log.Printf("Info: GEMINI_API_KEY not set, RAG Service disabled")
-			}
-
-			lineHandler, err := linebot.NewHandler(config.LineChannelSecret, config.LineChannelAccessToken, lineService, ragService)
-			if err != nil {
-				log.Printf("Warning: LINE Bot Handler initialization failed: %v", err)
-			} else {
-				router.POST("/callback", lineHandler.HandleWebhook)
-				log.Printf("LINE Bot Webhook registered at /callback")
-			}
-		}
+	// LINE Bot Webhook（/callback は destination で、/callback/:tenant はパスで振り分け）
+	if lineDispatcher.Len() > 0 {
+		router.POST("/callback", lineDispatcher.HandleWebhook)
+		router.POST("/callback/:tenant", lineDispatcher.HandleWebhook)
+		log.Printf("LINE Bot Webhook registered at /callback (%d tenant(s))", lineDispatcher.Len())
 	} else {
 		log.Printf("Info: LINE Bot credentials not set, /callback endpoint disabled")
 	}
@@ -125,6 +186,67 @@ func main() {
 	}
 }
 
+// initLineBot はテナントのLINE Botハンドラーを初期化（失敗時はnil）
+func initLineBot(ctx context.Context, tc *config.Tenant, services *service.Services, geminiAPIKey string) *linebot.Handler {
+	lineService, err := linebot.NewService(tc.LineBotSettingsPath)
+	if err != nil {
+		log.Printf("Warning: LINE Bot Service initialization failed (tenant=%s): %v", tc.ID, err)
+		return nil
+	}
+	services.ConfigReloader.Register(ctx, "linebot", lineService)
+
+	// RAGService初期化（オプショナル）
+	var ragService *linebot.RAGService
+	if geminiAPIKey != "" {
+		ragService, err = linebot.NewRAGService(ctx, geminiAPIKey, tc.LineUserSettingsPath, services.DriveClient, services.Household)
+		if err != nil {
+			log.Printf("Warning: RAG Service initialization failed (tenant=%s): %v", tc.ID, err)
+			ragService = nil
+		} else if ragService != nil {
+			services.ConfigReloader.Register(ctx, "rag", ragService)
+			log.Printf("RAG Service initialized with model: %s (tenant=%s)", config.GeminiModelsConfig.LineRAG, tc.ID)
+		} else {
+			log.Printf("Info: RAG Service disabled (no documents or folders configured)")
+		}
+	} else {
+		log.Printf("Info: GEMINI_API_KEY not set, RAG Service disabled")
+	}
+
+	lineHandler, err := linebot.NewHandler(tc.LineChannelSecret, tc.LineChannelAccessToken, lineService, ragService)
+	if err != nil {
+		log.Printf("Warning: LINE Bot Handler initialization failed (tenant=%s): %v", tc.ID, err)
+		return nil
+	}
+
+	// 低信頼度の分類結果をLINEでレビュー（送信先が設定されている場合のみ）
+	lineHandler.SetReviewResolver(services.FileSorter)
+	lineHandler.SetReviewTarget(tc.LineReviewTargetID, tc.LineFamilyGroupID)
+	if tc.LineReviewTargetID != "" {
+		services.FileSorter.SetReviewNotifier(lineHandler)
+		log.Printf("LINE review enabled (tenant=%s, threshold: %.2f)", tc.ID, config.AIRouter.ReviewThreshold)
+	}
+	return lineHandler
+}
+
+// resolveTenantSecrets はテナントのシークレットを環境変数またはSecret Managerから取得
+func resolveTenantSecrets(ctx context.Context, tc *config.Tenant) {
+	tc.LineChannelSecret = lookupSecret(ctx, tc.Secrets.LineChannelSecret)
+	tc.LineChannelAccessToken = lookupSecret(ctx, tc.Secrets.LineChannelAccessToken)
+	tc.DiscordWebhookURL = lookupSecret(ctx, tc.Secrets.DiscordWebhookURL)
+	tc.DriveWebhookToken = lookupSecret(ctx, tc.Secrets.DriveWebhookToken)
+}
+
+// lookupSecret は同名の環境変数を優先し、なければSecret Managerから取得
+func lookupSecret(ctx context.Context, name string) string {
+	if name == "" {
+		return ""
+	}
+	if value := strings.TrimSpace(os.Getenv(name)); value != "" {
+		return value
+	}
+	return strings.TrimSpace(getSecretValue(ctx, name))
+}
+
 // getWebhookURL はCloud RunのサービスURLを取得してWebhook URLを生成
 func getWebhookURL() string {
 	// 環境変数から明示的に指定されている場合はそれを使用
@@ -161,26 +283,32 @@ func getWebhookURL() string {
 	return ""
 }
 
-// initServices は全サービスを初期化
-func initServices(ctx context.Context) (*service.Services, error) {
-	// AIRouter
-	aiRouter, err := service.NewAIRouter(ctx)
+// initServices はテナントの全サービスを初期化
+func initServices(ctx context.Context, tc *config.Tenant, aiRouter *service.AIRouter, aiUsage *service.UsageTracker) (*service.Services, error) {
+	// DriveClient
+	driveClient, err := service.NewDriveClient(ctx, tc)
 	if err != nil {
 		return nil, err
 	}
 
-	// DriveClient
-	driveClient, err := service.NewDriveClient(ctx)
+	// 世帯設定（フォルダID・家族構成）
+	h, err := config.LoadHousehold(ctx, tc.HouseholdConfig, driveClient.DownloadFile)
 	if err != nil {
 		return nil, err
 	}
+	household := config.NewHouseholdStore(h)
+	log.Printf("Household config loaded: %s (tenant=%s, categories=%d, children=%d)", tc.HouseholdConfig, tc.ID, len(h.Categories), len(h.ChildAliases))
+
+	// 設定のホットリロード（世帯設定はLINE Bot設定より先に適用する）
+	configReloader := service.NewConfigReloader()
+	configReloader.Register(ctx, "household", service.NewHouseholdReloadSource(household, tc.HouseholdConfig, driveClient.DownloadFile))
 
 	// PDFProcessor
 	pdfProcessor := service.NewPDFProcessor()
 
 	// PhotosClient (オプショナル)
 	var photosClient *service.PhotosClient
-	photosClient, err = service.NewPhotosClient(ctx)
+	photosClient, err = service.NewPhotosClient(ctx, tc)
 	if err != nil {
 		log.Printf("Warning: PhotosClient initialization failed: %v", err)
 		photosClient = nil
@@ -188,7 +316,7 @@ func initServices(ctx context.Context) (*service.Services, error) {
 
 	// CalendarClient (オプショナル)
 	var calendarClient *service.CalendarClient
-	calendarClient, err = service.NewCalendarClient(ctx)
+	calendarClient, err = service.NewCalendarClient(ctx, tc, household)
 	if err != nil {
 		log.Printf("Warning: CalendarClient initialization failed: %v", err)
 		calendarClient = nil
@@ -196,7 +324,7 @@ func initServices(ctx context.Context) (*service.Services, error) {
 
 	// TasksClient (オプショナル)
 	var tasksClient *service.TasksClient
-	tasksClient, err = service.NewTasksClient(ctx)
+	tasksClient, err = service.NewTasksClient(ctx, tc)
 	if err != nil {
 		log.Printf("Warning: TasksClient initialization failed: %v", err)
 		tasksClient = nil
@@ -204,25 +332,28 @@ func initServices(ctx context.Context) (*service.Services, error) {
 
 	// NotebookLMSync (オプショナル)
 	var notebooklmSync *service.NotebookLMSync
-	notebooklmSync, err = service.NewNotebookLMSync(ctx, driveClient)
+	notebooklmSync, err = service.NewNotebookLMSync(ctx, driveClient, household)
 	if err != nil {
 		log.Printf("Warning: NotebookLMSync initialization failed: %v", err)
 		notebooklmSync = nil
 	}
 
 	// GradeManager
-	gradeManager := service.NewGradeManager()
+	gradeManager := service.NewGradeManager(household)
 
 	// DiscordNotifier (オプショナル)
-	discordWebhookURL := config.DiscordWebhookURL
-	if discordWebhookURL == "" {
-		discordWebhookURL = getSecretValue(ctx, "DISCORD_WEBHOOK_URL")
-	}
-	discordNotifier := service.NewDiscordNotifier(discordWebhookURL)
+	discordNotifier := service.NewDiscordNotifier(tc.DiscordWebhookURL)
 	if discordNotifier != nil {
 		log.Printf("DiscordNotifier initialized")
 	}
 
+	// 処理台帳
+	ledger, err := service.NewFileLedger(tc.LedgerDir())
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("ProcessingLedger initialized: %s", tc.LedgerDir())
+
 	// FileSorter
 	fileSorter := service.NewFileSorter(
 		aiRouter,
@@ -233,10 +364,71 @@ func initServices(ctx context.Context) (*service.Services, error) {
 		tasksClient,
 		notebooklmSync,
 		gradeManager,
+		ledger,
+		household,
 	)
 
+	// デッドレター（失敗ファイルの退避先）
+	deadLetters, err := service.NewFileDeadLetterStore(tc.DeadLetterDir())
+	if err != nil {
+		log.Printf("Warning: DeadLetterStore initialization failed: %v", err)
+	} else {
+		fileSorter.SetDeadLetterStore(deadLetters)
+		log.Printf("DeadLetterStore initialized: %s", tc.DeadLetterDir())
+	}
+
+	// ローカルOCR（AIが使えない場合の仮分類）
+	if config.LocalOCR.Enabled {
+		localOCR, err := service.NewLocalOCR(config.LocalOCR, pdfProcessor)
+		if err != nil {
+			log.Printf("Warning: LocalOCR initialization failed: %v", err)
+		} else {
+			fileSorter.SetLocalOCR(localOCR)
+			log.Printf("LocalOCR initialized (%s)", config.LocalOCR.Languages)
+		}
+	}
+
+	// 重複判定インデックス（同じ書類の再スキャン・再アップロードを検出）
+	if config.Dedup.Enabled {
+		dedupIndex, err := service.NewFileDedupIndex(tc.DedupDir(), config.Dedup.HammingThreshold)
+		if err != nil {
+			log.Printf("Warning: DedupIndex initialization failed: %v", err)
+		} else {
+			fileSorter.SetDedupIndex(dedupIndex)
+			log.Printf("DedupIndex initialized: %s", tc.DedupDir())
+		}
+	}
+
+	// 医療費控除の集計台帳（医療費の領収書から支払いを記録）
+	var medicalExpenses service.MedicalExpenseLedger
+	if config.MedicalExpenses.Enabled {
+		expenseLedger, err := service.NewFileMedicalExpenseLedger(tc.MedicalExpenseDir())
+		if err != nil {
+			log.Printf("Warning: MedicalExpenseLedger initialization failed: %v", err)
+		} else {
+			medicalExpenses = expenseLedger
+			fileSorter.SetMedicalExpenseLedger(expenseLedger)
+			log.Printf("MedicalExpenseLedger initialized: %s", tc.MedicalExpenseDir())
+		}
+	}
+
+	// 家計の支払いの記録（マネー・税務の書類から支払先・金額・期日を記録）
+	var finance service.FinanceLedger
+	if config.Finance.Enabled {
+		financeLedger, err := service.NewFileFinanceLedger(tc.FinanceDir())
+		if err != nil {
+			log.Printf("Warning: FinanceLedger initialization failed: %v", err)
+		} else {
+			finance = financeLedger
+			fileSorter.SetFinanceLedger(financeLedger)
+			log.Printf("FinanceLedger initialized: %s", tc.FinanceDir())
+		}
+	}
+
 	return &service.Services{
+		Household:       household,
 		AIRouter:        aiRouter,
+		AIUsage:         aiUsage,
 		PDFProcessor:    pdfProcessor,
 		DriveClient:     driveClient,
 		PhotosClient:    photosClient,
@@ -246,6 +438,9 @@ func initServices(ctx context.Context) (*service.Services, error) {
 		GradeManager:    gradeManager,
 		FileSorter:      fileSorter,
 		DiscordNotifier: discordNotifier,
+		ConfigReloader:  configReloader,
+		MedicalExpenses: medicalExpenses,
+		Finance:         finance,
 	}, nil
 }
 
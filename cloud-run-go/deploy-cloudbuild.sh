@@ -22,6 +22,13 @@ ENABLE_COMBINED_GEMINI="${ENABLE_COMBINED_GEMINI:-true}"
 
 ENV_VARS="${ENV_VARS},ADMIN_AUTH_MODE=${ADMIN_AUTH_MODE},LOG_FORMAT=${LOG_FORMAT},LOG_LEVEL=${LOG_LEVEL},ENABLE_COMBINED_GEMINI=${ENABLE_COMBINED_GEMINI}"
 
+# 処理台帳の保存先（GCSバケットを共有ボリュームとしてマウントし、複数インスタンスで運用）
+# 医療費・家計の記録やレビュー待ちも保存するため必須（/tmp はメモリ上にあり再起動で消える。未マウントの場合は起動しない）
+LEDGER_BUCKET="${LEDGER_BUCKET:?ERROR: Set LEDGER_BUCKET (GCS bucket for the processing ledger) before running}"
+MAX_INSTANCES="${MAX_INSTANCES_OVERRIDE:-3}"
+ENV_VARS="${ENV_VARS},PROCESSING_LEDGER_DIR=/mnt/ledger"
+VOLUME_ARGS=(--add-volume "name=ledger,type=cloud-storage,bucket=${LEDGER_BUCKET}" --add-volume-mount "volume=ledger,mount-path=/mnt/ledger")
+
 OAUTH_CLIENT_ID="${OAUTH_CLIENT_ID:?ERROR: Set OAUTH_CLIENT_ID env var before running}"
 OAUTH_CLIENT_SECRET="${OAUTH_CLIENT_SECRET:?ERROR: Set OAUTH_CLIENT_SECRET env var before running}"
 ENV_VARS="${ENV_VARS},OAUTH_CLIENT_ID=${OAUTH_CLIENT_ID},OAUTH_CLIENT_SECRET=${OAUTH_CLIENT_SECRET}"
@@ -52,6 +59,8 @@ echo "Cloud Buildでビルド中..."
 gcloud builds submit --tag ${IMAGE_NAME}:latest --project ${PROJECT_ID}
 
 # Cloud Runにデプロイ
+# --no-cpu-throttling: LINEのレビュー回答はWebhookにすぐ応答してから仕分けるため、応答後もCPUを割り当てる
+# （割り当てがないと応答後の処理が止まり、滞留ファイルの再開まで仕分けが遅れる）
 echo "Cloud Runにデプロイ中..."
 gcloud run deploy ${SERVICE_NAME} \
     --image ${IMAGE_NAME}:latest \
@@ -62,10 +71,12 @@ gcloud run deploy ${SERVICE_NAME} \
     --memory 384Mi \
     --cpu 1 \
     --timeout 540 \
+    --no-cpu-throttling \
     --concurrency 80 \
-    --max-instances 1 \
+    --max-instances ${MAX_INSTANCES} \
     --set-env-vars "${ENV_VARS}" \
     --set-secrets "${SECRETS}" \
+    "${VOLUME_ARGS[@]}" \
     --service-account homedocmanager-sa@${PROJECT_ID}.iam.gserviceaccount.com
 
 echo "デプロイ完了！"
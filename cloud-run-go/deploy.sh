@@ -42,6 +42,10 @@ if [ -n "${OAUTH_CLIENT_SECRET:-}" ]; then
     ENV_VARS="${ENV_VARS},OAUTH_CLIENT_SECRET=${OAUTH_CLIENT_SECRET}"
 fi
 
+# 処理台帳の保存先（GCSバケットを共有ボリュームとしてマウント。/tmp はメモリ上にあり再起動で消えるため必須）
+LEDGER_BUCKET="${LEDGER_BUCKET:?ERROR: Set LEDGER_BUCKET (GCS bucket for the processing ledger) before running}"
+ENV_VARS="${ENV_VARS},PROCESSING_LEDGER_DIR=/mnt/ledger"
+
 SET_SECRETS_ARGS=()
 if [ -n "${USE_SECRET_MANAGER}" ]; then
     SECRETS="ADMIN_TOKEN=ADMIN_TOKEN:latest,DRIVE_WEBHOOK_TOKEN=DRIVE_WEBHOOK_TOKEN:latest,GEMINI_API_KEY=GEMINI_API_KEY:latest"
@@ -92,6 +96,7 @@ echo -e "${GREEN}GCRにプッシュ中...${NC}"
 docker push ${IMAGE_NAME}:latest
 
 # Cloud Runにデプロイ
+# --no-cpu-throttling: LINEのレビュー回答後の仕分けはWebhookへの応答後に行うため（deploy-cloudbuild.sh と同じ）
 echo -e "${GREEN}Cloud Runにデプロイ中...${NC}"
 gcloud run deploy ${SERVICE_NAME} \
     --image ${IMAGE_NAME}:latest \
@@ -102,10 +107,13 @@ gcloud run deploy ${SERVICE_NAME} \
     --memory 384Mi \
     --cpu 1 \
     --timeout 540 \
+    --no-cpu-throttling \
     --concurrency 4 \
     --max-instances 3 \
     --set-env-vars "${ENV_VARS}" \
     "${SET_SECRETS_ARGS[@]}" \
+    --add-volume "name=ledger,type=cloud-storage,bucket=${LEDGER_BUCKET}" \
+    --add-volume-mount "volume=ledger,mount-path=/mnt/ledger" \
     --service-account homedocmanager-sa@${PROJECT_ID}.iam.gserviceaccount.com
 
 echo -e "${GREEN}デプロイ完了！${NC}"
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CalendarRoute は予定の登録先（対象の子供・大人ごとのカレンダー・色・招待する家族）
+// children / adults のどちらも指定しないルートは、どのルートにも一致しない予定の登録先になる
+type CalendarRoute struct {
+	Children []string `json:"children,omitempty"`
+	Adults   []string `json:"adults,omitempty"`
+	// 未指定の場合は calendar_id
+	CalendarID string `json:"calendar_id,omitempty"`
+	// Google Calendar の予定の色（"1"〜"11"。未指定はカレンダーの色）
+	ColorID string `json:"color_id,omitempty"`
+	// 招待する大人の正規名（adult_emails で解決）またはメールアドレス
+	Attendees []string `json:"attendees,omitempty"`
+}
+
+// IsDefault は対象を指定しない既定のルートかを返す
+func (r *CalendarRoute) IsDefault() bool {
+	return len(r.Children) == 0 && len(r.Adults) == 0
+}
+
+// matches は対象の子供・大人のいずれかがルートに含まれるかを返す
+func (r *CalendarRoute) matches(children []string, adult string) bool {
+	for _, child := range children {
+		for _, c := range r.Children {
+			if c == child {
+				return true
+			}
+		}
+	}
+	if adult != "" {
+		for _, a := range r.Adults {
+			if a == adult {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CalendarRoutesFor は予定の対象に一致するルートを設定順に返す（一致しない場合は既定のルート。なければ nil）
+func (h *Household) CalendarRoutesFor(children []string, adult string) []*CalendarRoute {
+	var matched, defaults []*CalendarRoute
+	for _, r := range h.CalendarRoutes {
+		switch {
+		case r.IsDefault():
+			defaults = append(defaults, r)
+		case r.matches(children, adult):
+			matched = append(matched, r)
+		}
+	}
+	if len(matched) > 0 {
+		return matched
+	}
+	return defaults
+}
+
+// AttendeeEmail は招待する家族のメールアドレスを返す（メールアドレスはそのまま。未登録の名前は空文字）
+func (h *Household) AttendeeEmail(attendee string) string {
+	if strings.Contains(attendee, "@") {
+		return attendee
+	}
+	return h.AdultEmails[attendee]
+}
+
+// validate は予定の登録先の設定を検証する
+func (r *CalendarRoute) validate(h *Household) error {
+	var errs []error
+	for _, child := range r.Children {
+		if _, ok := h.ChildAliases[child]; !ok {
+			errs = append(errs, fmt.Errorf("children: %s は child_aliases に存在しません", child))
+		}
+	}
+	for _, adult := range r.Adults {
+		if _, ok := h.AdultAliases[adult]; !ok {
+			errs = append(errs, fmt.Errorf("adults: %s は adult_aliases に存在しません", adult))
+		}
+	}
+	if r.ColorID != "" {
+		if n, err := strconv.Atoi(r.ColorID); err != nil || n < 1 || n > 11 {
+			errs = append(errs, fmt.Errorf("color_id %q は \"1\"〜\"11\" で指定してください", r.ColorID))
+		}
+	}
+	for _, attendee := range r.Attendees {
+		if h.AttendeeEmail(attendee) == "" {
+			errs = append(errs, fmt.Errorf("attendees: %s のメールアドレスが adult_emails にありません", attendee))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseHousehold_InvalidCalendarRoutes(t *testing.T) {
+	base := `{"version":1,"folder_ids":{"SOURCE":"s","CHILDREN_EDU":"c","PHOTO_OTHER":"p"},"categories":{"40_子供・教育":"CHILDREN_EDU"},"child_aliases":{"太郎":["たろう"]},"adult_aliases":{"父":["パパ"],"母":["ママ"]},"adult_emails":{"父":"papa@example.com"},"grades":{"base_fiscal_year":2024},"calendar_routes":[%s]}`
+	tests := []struct {
+		name  string
+		route string
+		want  string
+	}{
+		{"unknown child", `{"children":["次郎"]}`, "次郎"},
+		{"unknown adult", `{"adults":["祖父"]}`, "祖父"},
+		{"color out of range", `{"children":["太郎"],"color_id":"12"}`, "color_id"},
+		{"attendee without email", `{"children":["太郎"],"attendees":["母"]}`, "adult_emails"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseHousehold([]byte(strings.Replace(base, "%s", tt.route, 1)))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+			if !strings.Contains(err.Error(), "calendar_routes[0]") {
+				t.Fatalf("error should point at the route: %v", err)
+			}
+		})
+	}
+
+	ok := strings.Replace(base, "%s", `{"children":["太郎"],"color_id":"9","attendees":["父","mama@example.com"]}`, 1)
+	if _, err := ParseHousehold([]byte(ok)); err != nil {
+		t.Fatalf("valid route rejected: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChildProfile は書類の対象の子供を特定する手がかり（学校・担任・塾や習い事）
+// お便りに学年の記載がなくても、学校名や先生の名前から子供を判定できるようにする
+type ChildProfile struct {
+	Schools []ChildSchool `json:"schools,omitempty"`
+	// 年度 → 担任の先生（例: 山田先生）
+	HomeroomTeachers map[int]string `json:"homeroom_teachers,omitempty"`
+	// 塾・習い事・スポーツクラブなど（年度によらない）
+	Activities []string `json:"activities,omitempty"`
+}
+
+// ChildSchool は子供が通う学校・園と在籍する年度（0 の場合は制限なし）
+type ChildSchool struct {
+	Name           string   `json:"name"`
+	Aliases        []string `json:"aliases,omitempty"` // 略称（例: ○○小）
+	FromFiscalYear int      `json:"from_fiscal_year,omitempty"`
+	ToFiscalYear   int      `json:"to_fiscal_year,omitempty"`
+}
+
+// AttendsIn はその年度に在籍しているかを返す
+func (s ChildSchool) AttendsIn(fiscalYear int) bool {
+	return (s.FromFiscalYear == 0 || fiscalYear >= s.FromFiscalYear) &&
+		(s.ToFiscalYear == 0 || fiscalYear <= s.ToFiscalYear)
+}
+
+// SchoolsIn はその年度に在籍している学校・園を返す
+func (p *ChildProfile) SchoolsIn(fiscalYear int) []ChildSchool {
+	if p == nil {
+		return nil
+	}
+	var schools []ChildSchool
+	for _, s := range p.Schools {
+		if s.AttendsIn(fiscalYear) {
+			schools = append(schools, s)
+		}
+	}
+	return schools
+}
+
+// Clues はその年度の手がかりになる名前（学校名・略称・担任・習い事）を返す
+func (p *ChildProfile) Clues(fiscalYear int) []string {
+	if p == nil {
+		return nil
+	}
+	var clues []string
+	for _, s := range p.SchoolsIn(fiscalYear) {
+		clues = append(clues, s.Name)
+		clues = append(clues, s.Aliases...)
+	}
+	if teacher := p.HomeroomTeachers[fiscalYear]; teacher != "" {
+		clues = append(clues, teacher)
+	}
+	return append(clues, p.Activities...)
+}
+
+// ChildProfileNames はプロフィールを設定した子供の名前を昇順で返す
+func (h *Household) ChildProfileNames() []string {
+	return sortedKeys(h.ChildProfiles)
+}
+
+// validate はプロフィールの設定を検証する
+func (p *ChildProfile) validate() error {
+	var errs []error
+	for i, s := range p.Schools {
+		if strings.TrimSpace(s.Name) == "" {
+			errs = append(errs, fmt.Errorf("schools[%d].name は必須です", i))
+		}
+		if s.FromFiscalYear != 0 && s.ToFiscalYear != 0 && s.FromFiscalYear > s.ToFiscalYear {
+			errs = append(errs, fmt.Errorf("schools[%d] %s: from_fiscal_year（%d）が to_fiscal_year（%d）より後です", i, s.Name, s.FromFiscalYear, s.ToFiscalYear))
+		}
+	}
+	years := make([]int, 0, len(p.HomeroomTeachers))
+	for year := range p.HomeroomTeachers {
+		years = append(years, year)
+	}
+	sort.Ints(years)
+	for _, year := range years {
+		if year < 2000 {
+			errs = append(errs, fmt.Errorf("homeroom_teachers.%d: 年度が不正です", year))
+		}
+		if strings.TrimSpace(p.HomeroomTeachers[year]) == "" {
+			errs = append(errs, fmt.Errorf("homeroom_teachers.%d: 先生の名前が空です", year))
+		}
+	}
+	for i, activity := range p.Activities {
+		if strings.TrimSpace(activity) == "" {
+			errs = append(errs, fmt.Errorf("activities[%d] が空です", i))
+		}
+	}
+	return errors.Join(errs...)
+}
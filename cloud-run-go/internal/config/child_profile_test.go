@@ -0,0 +1,54 @@
+package config
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChildProfileClues(t *testing.T) {
+	h, err := LoadHousehold(context.Background(), "../../resources/household/household.example.yaml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		child      string
+		fiscalYear int
+		want       []string
+	}{
+		{"太郎", 2025, []string{"○○市立○○小学校", "○○小", "山田先生", "△△学習塾", "□□サッカークラブ"}},
+		{"太郎", 2028, []string{"△△学習塾", "□□サッカークラブ"}},
+		{"花子", 2025, []string{"○○保育園", "スイミングスクール◇◇"}},
+		{"花子", 2027, []string{"スイミングスクール◇◇"}},
+	}
+	for _, tt := range tests {
+		if got := h.ChildProfiles[tt.child].Clues(tt.fiscalYear); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Clues(%s, %d) = %v, want %v", tt.child, tt.fiscalYear, got, tt.want)
+		}
+	}
+}
+
+func TestParseHousehold_InvalidChildProfiles(t *testing.T) {
+	base := `{"version":1,"folder_ids":{"SOURCE":"s","CHILDREN_EDU":"c","PHOTO_OTHER":"p"},"categories":{"40_子供・教育":"CHILDREN_EDU"},"child_aliases":{"太郎":["たろう"]},"grades":{"base_fiscal_year":2024},"child_profiles":{%s}}`
+	tests := []struct {
+		name    string
+		profile string
+		want    string
+	}{
+		{"unknown child", `"次郎":{"activities":["塾"]}`, "child_profiles.次郎: child_aliases"},
+		{"school without name", `"太郎":{"schools":[{"from_fiscal_year":2024}]}`, "schools[0].name"},
+		{"reversed years", `"太郎":{"schools":[{"name":"○○小","from_fiscal_year":2027,"to_fiscal_year":2024}]}`, "from_fiscal_year"},
+		{"bad teacher year", `"太郎":{"homeroom_teachers":{"25":"山田先生"}}`, "homeroom_teachers.25"},
+		{"empty activity", `"太郎":{"activities":[" "]}`, "activities[0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseHousehold([]byte(strings.Replace(base, "%s", tt.profile, 1)))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
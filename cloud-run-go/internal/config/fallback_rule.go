@@ -0,0 +1,35 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ClassificationRule はAIが使えない場合の仮分類に使うキーワードルール
+// ローカルOCRの本文に含まれるキーワードが最も多いルールのカテゴリを採用する（sorting_rules と違い、すべてに一致する必要はない）
+type ClassificationRule struct {
+	Category    string   `json:"category"`
+	SubCategory string   `json:"sub_category,omitempty"` // サブカテゴリのあるカテゴリ（CategorySubCategories）の場合のみ
+	Keywords    []string `json:"keywords"`
+}
+
+// validate は仮分類ルールを検証する
+func (r *ClassificationRule) validate(h *Household) error {
+	var errs []error
+	if _, ok := h.Categories[r.Category]; !ok {
+		errs = append(errs, fmt.Errorf("category %q は categories に存在しません", r.Category))
+	}
+	if r.SubCategory != "" && !ValidSubCategory(r.Category, r.SubCategory) {
+		errs = append(errs, fmt.Errorf("sub_category %q は %s では使えません", r.SubCategory, r.Category))
+	}
+	if len(r.Keywords) == 0 {
+		errs = append(errs, errors.New("keywords は必須です"))
+	}
+	for i, kw := range r.Keywords {
+		if strings.TrimSpace(kw) == "" {
+			errs = append(errs, fmt.Errorf("keywords[%d] が空です", i))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseHousehold_InvalidFallbackRules(t *testing.T) {
+	base := `{"version":1,"folder_ids":{"SOURCE":"s","CHILDREN_EDU":"c","PHOTO_OTHER":"p"},"categories":{"40_子供・教育":"CHILDREN_EDU"},"child_aliases":{"太郎":["太郎"]},"grades":{"base_fiscal_year":2024},"fallback_rules":[%s]}`
+	tests := []struct {
+		name string
+		rule string
+		want string
+	}{
+		{"no keywords", `{"category":"40_子供・教育"}`, "keywords は必須"},
+		{"empty keyword", `{"category":"40_子供・教育","keywords":["学校"," "]}`, "keywords[1]"},
+		{"unknown category", `{"category":"10_マネー・税務","keywords":["請求"]}`, "10_マネー・税務"},
+		{"unknown sub category", `{"category":"40_子供・教育","sub_category":"04_その他","keywords":["学校"]}`, "04_その他"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseHousehold([]byte(strings.Replace(base, "%s", tt.rule, 1)))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+			if !strings.Contains(err.Error(), "fallback_rules[0]") {
+				t.Fatalf("error should point at the rule: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,379 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HouseholdSchemaVersion は対応している世帯設定ファイルのバージョン
+const HouseholdSchemaVersion = 1
+
+// 世帯設定ファイルの場所（ローカルパス、または drive://{fileID}）
+var HouseholdConfigSource = GetEnv("HOUSEHOLD_CONFIG", "resources/household/household.json")
+
+// 必須のフォルダキー（仕分けのフォールバック先として参照される）
+var requiredFolderKeys = []string{"SOURCE", "CHILDREN_EDU", "PHOTO_OTHER"}
+
+// Household は家庭ごとの設定（フォルダID・家族構成・連携先）
+// バイナリに埋め込まず、設定ファイルから読み込む
+type Household struct {
+	Version int    `json:"version"`
+	Name    string `json:"name,omitempty"`
+
+	// Google DriveフォルダID（キー: SOURCE, MONEY_TAX, CHILDREN_EDU など）
+	FolderIDs map[string]string `json:"folder_ids"`
+	// 分類カテゴリ → folder_ids のキー
+	Categories map[string]string `json:"categories"`
+
+	// 名寄せルール（正規名 → 別名）
+	ChildAliases map[string][]string `json:"child_aliases"`
+	AdultAliases map[string][]string `json:"adult_aliases"`
+	// 大人の正規名 → メールアドレス（予定に招待する家族）
+	AdultEmails map[string]string `json:"adult_emails,omitempty"`
+
+	Grades GradeConfig `json:"grades"`
+	// 子供 → 学校・担任・習い事（学年の記載がない書類の子供の特定に使う）
+	ChildProfiles map[string]*ChildProfile `json:"child_profiles,omitempty"`
+
+	CalendarID           string `json:"calendar_id,omitempty"`
+	NotebookLMOwnerEmail string `json:"notebooklm_owner_email,omitempty"`
+	// 予定の登録先（対象の子供・大人ごとのカレンダー・色・招待。未指定の場合はすべて calendar_id）
+	CalendarRoutes []*CalendarRoute `json:"calendar_routes,omitempty"`
+
+	// LINE User ID → 大人メンバー名（line_user_settings.json の設定で上書き可能）
+	LineUserMap map[string]string `json:"line_user_map,omitempty"`
+	// RAG対象 Google Docs ID
+	RAGDocumentIDs []string `json:"rag_document_ids,omitempty"`
+
+	// AIを使わずに仕分けるルール（上から順に評価し、最初に一致したものを使う）
+	SortingRules []*SortingRule `json:"sorting_rules,omitempty"`
+	// AIが使えない場合にローカルOCRの本文から仮分類するキーワードルール（未指定の場合はすべて FallbackCategory）
+	FallbackRules []*ClassificationRule `json:"fallback_rules,omitempty"`
+	// カテゴリ → ファイル名テンプレート（未指定のカテゴリは Filename.DefaultTemplate）
+	FilenameTemplates map[string]string `json:"filename_templates,omitempty"`
+	// カテゴリ → 移動先フォルダのパステンプレート（未指定のカテゴリは DefaultFolderTemplates）
+	FolderTemplates map[string]string `json:"folder_templates,omitempty"`
+	// 保存期間（未指定のカテゴリは期限なし）
+	RetentionPolicies []*RetentionPolicy `json:"retention_policies,omitempty"`
+
+	// カテゴリ → フォルダID（読み込み時に導出）
+	CategoryMap map[string]string `json:"-"`
+}
+
+// 学年・クラス設定
+type GradeConfig struct {
+	BaseFiscalYear     int                    `json:"base_fiscal_year"`
+	ChildrenBaseGrades map[string]int         `json:"children_base_grades"`
+	PreschoolClasses   map[int]PreschoolClass `json:"preschool_classes"`
+	SharedGroups       map[string]SharedGroup `json:"shared_groups"`
+}
+
+type PreschoolClass struct {
+	Name  string `json:"name"`
+	Emoji string `json:"emoji"`
+}
+
+type SharedGroup struct {
+	Children   []string `json:"children"`
+	FolderName string   `json:"folder_name"`
+	Label      string   `json:"label"`
+}
+
+// RAGSourceFolderIDs はRAGソース対象のフォルダID（NotebookLM同期フォルダ）
+func (h *Household) RAGSourceFolderIDs() []string {
+	if id := h.FolderIDs["NOTEBOOKLM_SYNC"]; id != "" {
+		return []string{id}
+	}
+	return nil
+}
+
+// CategoryNames はカテゴリ名を昇順で返す
+func (h *Household) CategoryNames() []string {
+	names := make([]string, 0, len(h.CategoryMap))
+	for name := range h.CategoryMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate は設定内容を検証する（問題はまとめて返す）
+func (h *Household) Validate() error {
+	var errs []error
+
+	if h.Version != HouseholdSchemaVersion {
+		errs = append(errs, fmt.Errorf("version: %d は未対応です（対応バージョン: %d）", h.Version, HouseholdSchemaVersion))
+	}
+
+	for _, key := range requiredFolderKeys {
+		if h.FolderIDs[key] == "" {
+			errs = append(errs, fmt.Errorf("folder_ids.%s は必須です", key))
+		}
+	}
+
+	if len(h.Categories) == 0 {
+		errs = append(errs, errors.New("categories が空です"))
+	}
+	for _, category := range sortedKeys(h.Categories) {
+		key := h.Categories[category]
+		if h.FolderIDs[key] == "" {
+			errs = append(errs, fmt.Errorf("categories.%s: folder_ids に %s がありません", category, key))
+		}
+	}
+
+	if h.Grades.BaseFiscalYear < 2000 {
+		errs = append(errs, fmt.Errorf("grades.base_fiscal_year が不正です: %d", h.Grades.BaseFiscalYear))
+	}
+	for _, child := range sortedKeys(h.Grades.ChildrenBaseGrades) {
+		if _, ok := h.ChildAliases[child]; !ok {
+			errs = append(errs, fmt.Errorf("grades.children_base_grades.%s: child_aliases に存在しません", child))
+		}
+	}
+	for _, name := range sortedKeys(h.Grades.SharedGroups) {
+		group := h.Grades.SharedGroups[name]
+		if group.FolderName == "" {
+			errs = append(errs, fmt.Errorf("grades.shared_groups.%s.folder_name は必須です", name))
+		}
+		for _, child := range group.Children {
+			if _, ok := h.ChildAliases[child]; !ok {
+				errs = append(errs, fmt.Errorf("grades.shared_groups.%s: %s は child_aliases に存在しません", name, child))
+			}
+		}
+	}
+
+	for _, child := range sortedKeys(h.ChildProfiles) {
+		if _, ok := h.ChildAliases[child]; !ok {
+			errs = append(errs, fmt.Errorf("child_profiles.%s: child_aliases に存在しません", child))
+		}
+		if profile := h.ChildProfiles[child]; profile != nil {
+			if err := profile.validate(); err != nil {
+				errs = append(errs, fmt.Errorf("child_profiles.%s: %w", child, err))
+			}
+		}
+	}
+
+	for _, category := range sortedKeys(h.FilenameTemplates) {
+		if _, ok := h.Categories[category]; !ok {
+			errs = append(errs, fmt.Errorf("filename_templates.%s: categories に存在しません", category))
+		}
+		if err := ValidateFilenameTemplate(h.FilenameTemplates[category]); err != nil {
+			errs = append(errs, fmt.Errorf("filename_templates.%s: %w", category, err))
+		}
+	}
+
+	for _, category := range sortedKeys(h.FolderTemplates) {
+		if _, ok := h.Categories[category]; !ok {
+			errs = append(errs, fmt.Errorf("folder_templates.%s: categories に存在しません", category))
+		}
+		if err := ValidateFolderTemplate(h.FolderTemplates[category]); err != nil {
+			errs = append(errs, fmt.Errorf("folder_templates.%s: %w", category, err))
+		}
+	}
+
+	for i, rule := range h.SortingRules {
+		if err := rule.validate(h); err != nil {
+			errs = append(errs, fmt.Errorf("sorting_rules[%d] %s: %w", i, rule.Name, err))
+		}
+	}
+
+	for i, rule := range h.FallbackRules {
+		if err := rule.validate(h); err != nil {
+			errs = append(errs, fmt.Errorf("fallback_rules[%d] %s: %w", i, rule.Category, err))
+		}
+	}
+
+	seenPolicies := map[string]bool{}
+	for i, policy := range h.RetentionPolicies {
+		key := policy.Category + "/" + policy.SubCategory
+		if seenPolicies[key] {
+			errs = append(errs, fmt.Errorf("retention_policies[%d] %s: 同じカテゴリ・サブカテゴリの保存期間が重複しています", i, key))
+		}
+		seenPolicies[key] = true
+		if err := policy.validate(h); err != nil {
+			errs = append(errs, fmt.Errorf("retention_policies[%d] %s: %w", i, key, err))
+		}
+	}
+
+	for _, adult := range sortedKeys(h.AdultEmails) {
+		if _, ok := h.AdultAliases[adult]; !ok {
+			errs = append(errs, fmt.Errorf("adult_emails.%s: adult_aliases に存在しません", adult))
+		}
+		if !strings.Contains(h.AdultEmails[adult], "@") {
+			errs = append(errs, fmt.Errorf("adult_emails.%s が不正です: %s", adult, h.AdultEmails[adult]))
+		}
+	}
+	for i, route := range h.CalendarRoutes {
+		if err := route.validate(h); err != nil {
+			errs = append(errs, fmt.Errorf("calendar_routes[%d]: %w", i, err))
+		}
+	}
+
+	if h.NotebookLMOwnerEmail != "" && !strings.Contains(h.NotebookLMOwnerEmail, "@") {
+		errs = append(errs, fmt.Errorf("notebooklm_owner_email が不正です: %s", h.NotebookLMOwnerEmail))
+	}
+
+	return errors.Join(errs...)
+}
+
+// normalize は導出フィールドを設定する
+func (h *Household) normalize() {
+	h.CategoryMap = make(map[string]string, len(h.Categories))
+	for category, key := range h.Categories {
+		h.CategoryMap[category] = h.FolderIDs[key]
+	}
+	// 環境変数での上書き（既存デプロイとの互換）
+	if id := os.Getenv("CALENDAR_ID"); id != "" {
+		h.CalendarID = id
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ParseHousehold はJSONまたはYAMLの世帯設定を読み込み、検証する
+// 未知のキーはタイプミスとみなしてエラーにする
+func ParseHousehold(data []byte) (*Household, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, errors.New("household config is empty")
+	}
+
+	jsonData := trimmed
+	if trimmed[0] != '{' {
+		var doc interface{}
+		if err := yaml.Unmarshal(trimmed, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse household yaml: %w", err)
+		}
+		b, err := json.Marshal(yamlToJSONValue(doc))
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert household yaml: %w", err)
+		}
+		jsonData = b
+	}
+
+	var h Household
+	dec := json.NewDecoder(bytes.NewReader(jsonData))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&h); err != nil {
+		return nil, fmt.Errorf("failed to parse household config: %w", err)
+	}
+	if err := h.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid household config: %w", err)
+	}
+	h.normalize()
+	return &h, nil
+}
+
+// yamlToJSONValue はYAMLのマップキー（数値キーなど）を文字列に変換する
+func yamlToJSONValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			t[k] = yamlToJSONValue(val)
+		}
+		return t
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			m[fmt.Sprint(k)] = yamlToJSONValue(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range t {
+			t[i] = yamlToJSONValue(val)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// HouseholdFetcher はDrive上の設定ファイルを取得する関数
+type HouseholdFetcher func(ctx context.Context, fileID string) ([]byte, error)
+
+// LoadHousehold は設定ファイルを読み込む
+// source が drive://{fileID} の場合は fetch で取得し、それ以外はローカルファイルとして読む
+func LoadHousehold(ctx context.Context, source string, fetch HouseholdFetcher) (*Household, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if fileID, ok := strings.CutPrefix(source, "drive://"); ok {
+		if fetch == nil {
+			return nil, errors.New("drive fetcher is not configured")
+		}
+		data, err = fetch(ctx, fileID)
+	} else {
+		data, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read household config %s: %w", source, err)
+	}
+	return ParseHousehold(data)
+}
+
+// HouseholdStore は現在有効な世帯設定を保持する
+// 再読み込み時は検証済みの設定で丸ごと差し替える
+type HouseholdStore struct {
+	mu      sync.RWMutex
+	current *Household
+}
+
+// NewHouseholdStore は新しいHouseholdStoreを作成
+func NewHouseholdStore(h *Household) *HouseholdStore {
+	return &HouseholdStore{current: h}
+}
+
+// Current は現在の設定を返す（呼び出し側で変更しないこと）
+func (s *HouseholdStore) Current() *Household {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+// Replace は検証に通った設定に差し替える。不正な場合は現在の設定を維持する
+func (s *HouseholdStore) Replace(h *Household) error {
+	if err := h.Validate(); err != nil {
+		return err
+	}
+	if h.CategoryMap == nil {
+		h.normalize()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = h
+	return nil
+}
+
+// FileFingerprint はファイルの更新日時とサイズから変更検知用の値を作る
+// 存在しないファイルも区別できるようにし、作成・削除も変更として扱う
+func FileFingerprint(paths ...string) string {
+	var sb strings.Builder
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(&sb, "%s:missing;", path)
+			continue
+		}
+		fmt.Fprintf(&sb, "%s:%d:%d;", path, info.ModTime().UnixNano(), info.Size())
+	}
+	return sb.String()
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestLoadHousehold_BundledFiles(t *testing.T) {
+	for _, path := range []string{
+		"../../resources/household/household.json",
+		"../../resources/household/household.example.yaml",
+	} {
+		h, err := LoadHousehold(context.Background(), path, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if h.CategoryMap["40_子供・教育"] != h.FolderIDs["CHILDREN_EDU"] {
+			t.Fatalf("%s: category map not derived: %+v", path, h.CategoryMap)
+		}
+	}
+}
+
+func TestParseHousehold_YAMLNumericKeys(t *testing.T) {
+	h, err := ParseHousehold([]byte(`
+version: 1
+folder_ids: {SOURCE: s, CHILDREN_EDU: c, PHOTO_OTHER: p}
+categories: {40_子供・教育: CHILDREN_EDU}
+child_aliases: {太郎: [太郎]}
+adult_aliases: {}
+grades:
+  base_fiscal_year: 2024
+  children_base_grades: {太郎: -1}
+  preschool_classes:
+    -1: {name: きりん組, emoji: "🦒"}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Grades.PreschoolClasses[-1].Name; got != "きりん組" {
+		t.Fatalf("preschool class = %q", got)
+	}
+}
+
+func TestParseHousehold_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"unknown field", `{"version":1,"folderids":{}}`, "unknown field"},
+		{"unsupported version", `{"version":2,"folder_ids":{"SOURCE":"s","CHILDREN_EDU":"c","PHOTO_OTHER":"p"},"categories":{"a":"SOURCE"},"grades":{"base_fiscal_year":2024}}`, "version"},
+		{"missing folder", `{"version":1,"folder_ids":{"SOURCE":"s"},"categories":{"a":"SOURCE"},"grades":{"base_fiscal_year":2024}}`, "folder_ids.CHILDREN_EDU"},
+		{"dangling category", `{"version":1,"folder_ids":{"SOURCE":"s","CHILDREN_EDU":"c","PHOTO_OTHER":"p"},"categories":{"a":"MONEY_TAX"},"grades":{"base_fiscal_year":2024}}`, "categories.a"},
+		{"unknown child", `{"version":1,"folder_ids":{"SOURCE":"s","CHILDREN_EDU":"c","PHOTO_OTHER":"p"},"categories":{"a":"SOURCE"},"grades":{"base_fiscal_year":2024,"children_base_grades":{"次郎":1}}}`, "次郎"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseHousehold([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestHouseholdStore_ReplaceKeepsCurrentOnInvalid(t *testing.T) {
+	h, err := LoadHousehold(context.Background(), "../../resources/household/household.example.yaml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewHouseholdStore(h)
+	if err := store.Replace(&Household{Version: 1}); err == nil {
+		t.Fatal("expected validation error")
+	}
+	if store.Current() != h {
+		t.Fatal("current config should be kept")
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 保存期間を過ぎた書類の扱い
+const (
+	RetentionActionArchive = "archive" // folder_ids.ARCHIVE へ移動
+	RetentionActionTrash   = "trash"   // Driveのゴミ箱へ移動
+)
+
+// RetentionPolicy はカテゴリ（・サブカテゴリ）ごとの保存期間
+// 保存期限は書類の年度末（3月31日）から years 年後の年度末で、0 の場合はその年度末
+// legal_years を指定した場合は、書類の日付の年の確定申告期限（翌年3月15日）から years 年後の3月15日
+type RetentionPolicy struct {
+	Category    string `json:"category"`
+	SubCategory string `json:"sub_category,omitempty"` // 未指定の場合はカテゴリ全体
+	Years       int    `json:"years"`
+	Action      string `json:"action"`
+	// 法定の保存期間（年）。指定した書類は削除前にDiscordで予告し、猶予期間（Retention.DeleteGraceDays）の後に削除する
+	LegalYears int `json:"legal_years,omitempty"`
+}
+
+// RetentionPolicyFor はカテゴリ・サブカテゴリに適用する保存期間を返す（サブカテゴリ指定のものを優先。該当なしは nil）
+func (h *Household) RetentionPolicyFor(category, subCategory string) *RetentionPolicy {
+	var fallback *RetentionPolicy
+	for _, p := range h.RetentionPolicies {
+		if p.Category != category {
+			continue
+		}
+		if p.SubCategory == "" {
+			fallback = p
+		} else if p.SubCategory == subCategory {
+			return p
+		}
+	}
+	return fallback
+}
+
+// validate は保存期間の設定を検証する
+func (p *RetentionPolicy) validate(h *Household) error {
+	var errs []error
+	if _, ok := h.Categories[p.Category]; !ok {
+		errs = append(errs, fmt.Errorf("category %q は categories に存在しません", p.Category))
+	}
+	if p.SubCategory != "" && !ValidSubCategory(p.Category, p.SubCategory) {
+		errs = append(errs, fmt.Errorf("sub_category %q は %s では使えません", p.SubCategory, p.Category))
+	}
+	switch p.Action {
+	case RetentionActionArchive:
+		if h.FolderIDs["ARCHIVE"] == "" {
+			errs = append(errs, errors.New("action: archive には folder_ids.ARCHIVE が必要です"))
+		}
+	case RetentionActionTrash:
+	default:
+		errs = append(errs, fmt.Errorf("action %q は archive / trash のいずれかを指定してください", p.Action))
+	}
+	if p.Years < 0 || p.LegalYears < 0 {
+		errs = append(errs, errors.New("years / legal_years は0以上を指定してください"))
+	}
+	if p.Years < p.LegalYears {
+		errs = append(errs, fmt.Errorf("years（%d年）が法定の保存期間 legal_years（%d年）より短くなっています", p.Years, p.LegalYears))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRetentionPolicyFor(t *testing.T) {
+	h, err := LoadHousehold(context.Background(), "../../resources/household/household.example.yaml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		category    string
+		subCategory string
+		wantAction  string
+	}{
+		{"40_子供・教育", "01_お便り・スケジュール", RetentionActionArchive},
+		{"40_子供・教育", "02_提出・手続き・重要", ""},
+		{"10_マネー・税務", "", RetentionActionTrash},
+		{"30_ライフ・行政", "", ""},
+	}
+	for _, tt := range tests {
+		got := h.RetentionPolicyFor(tt.category, tt.subCategory)
+		action := ""
+		if got != nil {
+			action = got.Action
+		}
+		if action != tt.wantAction {
+			t.Errorf("RetentionPolicyFor(%s, %s) = %+v, want action %q", tt.category, tt.subCategory, got, tt.wantAction)
+		}
+	}
+}
+
+func TestParseHousehold_InvalidRetentionPolicies(t *testing.T) {
+	base := `{"version":1,"folder_ids":{"SOURCE":"s","CHILDREN_EDU":"c","PHOTO_OTHER":"p"},"categories":{"40_子供・教育":"CHILDREN_EDU"},"grades":{"base_fiscal_year":2024},"retention_policies":[%s]}`
+	tests := []struct {
+		name     string
+		policies string
+		want     string
+	}{
+		{"unknown category", `{"category":"10_マネー・税務","years":7,"action":"trash"}`, "10_マネー・税務"},
+		{"unknown action", `{"category":"40_子供・教育","years":1,"action":"delete"}`, "archive / trash"},
+		{"archive without folder", `{"category":"40_子供・教育","years":1,"action":"archive"}`, "folder_ids.ARCHIVE"},
+		{"shorter than legal", `{"category":"40_子供・教育","years":5,"legal_years":7,"action":"trash"}`, "legal_years"},
+		{"duplicate", `{"category":"40_子供・教育","years":1,"action":"trash"},{"category":"40_子供・教育","years":2,"action":"trash"}`, "重複"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseHousehold([]byte(strings.Replace(base, "%s", tt.policies, 1)))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+			if !strings.Contains(err.Error(), "retention_policies[") {
+				t.Fatalf("error should point at the policy: %v", err)
+			}
+		})
+	}
+}
@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // GCP設定
@@ -10,21 +12,13 @@ var (
 	GCPProjectID = GetEnv("GCP_PROJECT_ID", "your-project-id")
 	GCPRegion    = GetEnv("GCP_REGION", "asia-northeast1")
 
-	// LINE Bot設定
-	LineChannelSecret      = os.Getenv("LINE_CHANNEL_SECRET")
-	LineChannelAccessToken = os.Getenv("LINE_CHANNEL_ACCESS_TOKEN")
-	LineBotSettingsPath    = GetEnv("LINE_BOT_SETTINGS_PATH", "resources/linebot/line_settings.json")
+	// LINE Bot設定（チャネルシークレット等はテナントごとに解決する: tenant.go）
+	LineBotSettingsPath = GetEnv("LINE_BOT_SETTINGS_PATH", "resources/linebot/line_settings.json")
 
 	// 管理系エンドポイントの認証設定
 	// required | optional | disabled
 	AdminAuthMode = GetEnv("ADMIN_AUTH_MODE", "required")
 	AdminToken    = os.Getenv("ADMIN_TOKEN")
-
-	// Drive webhook検証用トークン（設定されていれば検証する）
-	DriveWebhookToken = os.Getenv("DRIVE_WEBHOOK_TOKEN")
-
-	// Discord通知設定
-	DiscordWebhookURL = os.Getenv("DISCORD_WEBHOOK_URL")
 )
 
 // Secret Manager設定
@@ -51,69 +45,147 @@ type AIRouterConfig struct {
 	ConfidenceThreshold float64
 	MaxFlashRetries     int
 	EnableProEscalation bool
+	// エスカレーション後もこの信頼度を下回る場合は家族のレビュー待ちにする（LINE通知が有効な場合のみ）
+	ReviewThreshold float64
+	// 応答がスキーマに合わない場合に、違反内容を伝えて再生成させる回数
+	SchemaRetries int
 }
 
 var AIRouter = AIRouterConfig{
 	ConfidenceThreshold: 0.8,
 	MaxFlashRetries:     2,
 	EnableProEscalation: true,
+	ReviewThreshold:     0.6,
+	SchemaRetries:       1,
+}
+
+// AIバックエンド設定
+// gemini: Gemini API / openai: OpenAI互換API（llama.cpp server, Ollama 等のローカルモデル）
+type AIBackendConfig struct {
+	Provider        string
+	OpenAIBaseURL   string
+	OpenAIAPIKey    string // ローカルサーバーでは不要
+	OpenAIModel     string // Flash相当（一次解析）
+	OpenAIHighModel string // Pro相当（エスカレーション先、未指定時は OpenAIModel）
+	RequestTimeout  time.Duration
+}
+
+var AIBackend = AIBackendConfig{
+	Provider:        GetEnv("AI_PROVIDER", "gemini"),
+	OpenAIBaseURL:   GetEnv("OPENAI_BASE_URL", "http://localhost:11434/v1"),
+	OpenAIAPIKey:    os.Getenv("OPENAI_API_KEY"),
+	OpenAIModel:     GetEnv("OPENAI_MODEL", ""),
+	OpenAIHighModel: GetEnv("OPENAI_HIGH_MODEL", ""),
+	RequestTimeout:  GetEnvDuration("AI_REQUEST_TIMEOUT", 3*time.Minute),
+}
+
+// ModelPrice はモデルの料金（USD / 100万トークン）
+type ModelPrice struct {
+	InputPerMTok  float64
+	OutputPerMTok float64
+}
+
+// AI使用量の記録・月次予算の設定
+// 月の費用が予算を超えると、Proへのエスカレーションを停止してDiscordに通知する（0の場合は予算なし）
+type AIUsageConfig struct {
+	Dir              string
+	MonthlyBudgetUSD float64
+	Prices           map[string]ModelPrice // 未登録のモデル（ローカルモデル等）は0円として扱う
+}
+
+var AIUsage = AIUsageConfig{
+	Dir:              GetEnv("AI_USAGE_DIR", ProcessingLedger.Dir+"/usage"),
+	MonthlyBudgetUSD: GetEnvFloat("GEMINI_MONTHLY_BUDGET_USD", 0),
+	Prices: map[string]ModelPrice{
+		"gemini-3-flash-preview": {InputPerMTok: 0.50, OutputPerMTok: 3.00},
+		"gemini-3-pro-preview":   {InputPerMTok: 2.00, OutputPerMTok: 12.00},
+	},
 }
 
 // Gemini統合呼び出しの有効化
 var EnableCombinedGemini = GetEnvBool("ENABLE_COMBINED_GEMINI", true)
 
-// Google Driveフォルダ設定
-var FolderIDs = map[string]string{
-	"SOURCE":          "1T_XJURJbSsSiarr2Y-ofH0lCpSn9Dmak",
-	"MONEY_TAX":       "1rUnmoPoJoD-UwLn0PQW7-FtBfg9FlUTi",
-	"PROJECT_ASSET":   "1xBNSHmmnpuQpz0pvXxg_VlUAy0Zk4SOG",
-	"LIFE_ADMIN":      "1keZdfSSrmpPqPWhC22Fg2A5GmaCfg3Xg",
-	"CHILDREN_EDU":    "14TyZrKoXRSSP6kxpytxvap4poKmDn4qs",
-	"PHOTO_OTHER":     "1euBhhNI0Ny13tXs1JVrcO0KLKHySFnEy",
-	"HEALTH_MEDICAL":  "1IXeTT23a4_sWyLc1H195dtrfaLFJXeDn",
-	"LIBRARY":         "1MxppChMYZOJOyY2s-w6CsVam3P5_vccv",
-	"NOTEBOOKLM_SYNC": "1AVRbK5Zy8IVC3XYtSQ7ZwNGMIB3ToaBu",
-	"ARCHIVE":         "14iqjkHeBVMz47sNzPFkxrp5syr2tIOeO",
-}
-
-// カテゴリマッピング
-var CategoryMap = map[string]string{
-	"10_マネー・税務":    FolderIDs["MONEY_TAX"],
-	"20_プロジェクト・資産": FolderIDs["PROJECT_ASSET"],
-	"30_ライフ・行政":    FolderIDs["LIFE_ADMIN"],
-	"40_子供・教育":     FolderIDs["CHILDREN_EDU"],
-	"50_写真・その他":    FolderIDs["PHOTO_OTHER"],
-	"60_ヘルス・医療":    FolderIDs["HEALTH_MEDICAL"],
-	"90_ライブラリ":     FolderIDs["LIBRARY"],
-	"99_転送済みアーカイブ": FolderIDs["ARCHIVE"],
-}
-
-// 子供の名寄せルール
-var ChildAliases = map[string][]string{
-	"明日香":  {"明日香", "あすか", "アスカ", "Asuka"},
-	"遥香":   {"遥香", "はるか", "ハルカ", "Haruka"},
-	"文香":   {"文香", "ふみか", "フミカ", "Fumika"},
-	"ビクトル": {"ビクトル", "Victor", "Viktor"},
-	"ミハイル": {"ミハイル", "Mikhail", "Mihail"},
-	"アンナ":  {"アンナ", "Anna"},
-}
-
-// 大人の名寄せルール
-var AdultAliases = map[string][]string{
-	"千世己": {"千世己", "Chiseki", "ちせき", "チセキ"},
-	"まどか": {"まどか", "Madoka", "マドカ"},
-	"怜央奈": {"怜央奈", "Leo", "Reona", "れおな", "レオナ"},
-	"今日子": {"今日子", "Kyoko", "きょうこ", "綿谷", "Wataya"},
-	"えりか": {"えりか", "Erika", "エリカ", "Эрика"},
-}
-
-// LINE User ID → 大人メンバー名マッピング
-// 実際のUser IDは環境変数またはJSON設定ファイルから読み込み推奨
-// 子供の情報はどの大人ユーザーからも参照可能
-var LineUserMap = map[string]string{
-	// TODO: 実際のLINE User IDを設定
-	// "Uxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx": "怜央奈",
-	// "Uyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyy": "今日子",
+// 処理台帳設定
+// 複数インスタンスで運用する場合は共有ボリューム（GCS FUSE等）上のディレクトリを指定する
+type ProcessingLedgerConfig struct {
+	Dir           string
+	LeaseDuration time.Duration // 処理権のリース期間（Cloud Runのタイムアウトより長く）
+	StuckAfter    time.Duration // 未完了のまま更新がない場合に滞留とみなす時間
+}
+
+var ProcessingLedger = ProcessingLedgerConfig{
+	Dir:           GetEnv("PROCESSING_LEDGER_DIR", "/tmp/homedocmanager/ledger"),
+	LeaseDuration: 10 * time.Minute,
+	StuckAfter:    15 * time.Minute,
+}
+
+// DeadLetterConfig はデッドレター（処理失敗ファイル）の設定
+type DeadLetterConfig struct {
+	Dir            string
+	ReviewFolder   string // 失敗ファイルの移動先フォルダ名（Inbox直下に作成）
+	ReviewFolderID string // 指定時はこのフォルダへ移動
+	MaxAttempts    int    // この回数失敗したらデッドレターへ（それまでは滞留ファイルとして自動再開）
+}
+
+var DeadLetter = DeadLetterConfig{
+	Dir:            GetEnv("DEAD_LETTER_DIR", ProcessingLedger.Dir+"/dlq"),
+	ReviewFolder:   "要確認",
+	ReviewFolderID: GetEnv("REVIEW_FOLDER_ID", ""),
+	MaxAttempts:    3,
+}
+
+// DedupConfig は重複書類の検出設定
+type DedupConfig struct {
+	Enabled          bool
+	Folder           string // 重複ファイルの移動先フォルダ名（Inbox直下に作成）
+	FolderID         string // 指定時はこのフォルダへ移動
+	HammingThreshold int    // 知覚ハッシュのハミング距離がこの値以下なら同一とみなす（64ビット中）
+	PreviewDPI       int    // PDFページの知覚ハッシュ計算用の解像度
+	MaxPages         int    // PDFの知覚ハッシュを計算するページ数の上限
+}
+
+var Dedup = DedupConfig{
+	Enabled:          GetEnvBool("ENABLE_DEDUP", true),
+	Folder:           "重複",
+	FolderID:         GetEnv("DUPLICATE_FOLDER_ID", ""),
+	HammingThreshold: 4,
+	PreviewDPI:       36,
+	MaxPages:         5,
+}
+
+// PDFSplitConfig は複数の書類をまとめてスキャンしたPDFの分割設定
+type PDFSplitConfig struct {
+	Enabled       bool
+	MaxPages      int    // これより多いページ数のPDFは分割しない（1冊の冊子などを想定）
+	ArchiveFolder string // 分割元PDFの移動先フォルダ名（Inbox直下に作成）
+}
+
+var PDFSplit = PDFSplitConfig{
+	Enabled:       GetEnvBool("ENABLE_PDF_SPLIT", false),
+	MaxPages:      30,
+	ArchiveFolder: "分割済み",
+}
+
+// FilenameConfig は仕分け後のファイル名の設定
+type FilenameConfig struct {
+	DefaultTemplate string // 世帯設定・仕分けルールでテンプレートが未指定の場合
+	MaxRunes        int    // 拡張子を除いたファイル名の最大文字数
+	MaxCollisions   int    // 移動先フォルダの同名ファイルを避ける連番の上限
+}
+
+var Filename = FilenameConfig{
+	DefaultTemplate: "{date}_{summary}",
+	MaxRunes:        80,
+	MaxCollisions:   99,
+}
+
+// ConfigReloadConfig は設定ファイルのホットリロード設定
+type ConfigReloadConfig struct {
+	PollInterval time.Duration // 変更検知の間隔（0の場合はポーリングしない。/admin/config/reload のみ）
+}
+
+var ConfigReload = ConfigReloadConfig{
+	PollInterval: GetEnvDuration("CONFIG_RELOAD_INTERVAL", time.Minute),
 }
 
 // LINE User設定ファイルパス
@@ -122,24 +194,12 @@ var LineUserSettingsPath = GetEnv("LINE_USER_SETTINGS_PATH", "resources/linebot/
 // 家族グループID（自動識別に利用）
 var LineFamilyGroupID = GetEnv("LINE_FAMILY_GROUP_ID", "")
 
-// RAGソース対象 Google Drive フォルダID (自動同期用)
-var LineRAGSourceFolderIDs = []string{
-	FolderIDs["NOTEBOOKLM_SYNC"],
-}
-
-// RAG対象 Google Docs ID
-// 家族全員の情報が含まれるドキュメント
-var RAGDocumentIDs = []string{
-	// TODO: 実際のGoogle Docs IDを設定
-	// "1xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxABC", // 生活情報
-	// "2xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxDEF", // マネー情報
-}
+// 仕分けレビュー依頼の送信先（未指定時は家族グループ）
+var LineReviewTargetID = GetEnv("LINE_REVIEW_TARGET_ID", LineFamilyGroupID)
 
-// 年度サブフォルダを作成するカテゴリ
-var CategoriesWithYearSubfolder = []string{
-	"10_マネー・税務",
-	"30_ライフ・行政",
-	"40_子供・教育",
+// AI分類の対象外カテゴリ（フォルダとしては存在するが、解析結果としては選ばせない）
+var UnclassifiedCategories = map[string]bool{
+	"99_転送済みアーカイブ": true,
 }
 
 // NotebookLM同期除外カテゴリ（画像主体のため転記不可）
@@ -172,9 +232,6 @@ var NotebookLMCategoryMap = map[string]string{
 	"90_ライブラリ":     NotebookLibrary,
 }
 
-// NotebookLMドキュメントのオーナー
-const NotebookLMOwnerEmail = "leo.courageous.lion@gmail.com"
-
 // 子供の卒業設定
 const ChildGraduationGrade = 12
 
@@ -184,77 +241,109 @@ var AdultCategories = []string{
 	"30_ライフ・行政",
 }
 
-// 学年・クラス設定
-type GradeConfig struct {
-	BaseFiscalYear     int
-	ChildrenBaseGrades map[string]int
-	PreschoolClasses   map[int]PreschoolClass
-	SharedGroups       map[string]SharedGroup
+// サブカテゴリ（40_子供・教育）
+var SubCategories = []string{
+	"01_お便り・スケジュール",
+	"02_提出・手続き・重要",
+	"03_記録・作品・成績",
 }
 
-type PreschoolClass struct {
-	Name  string
-	Emoji string
+// サブカテゴリ（60_ヘルス・医療）
+var MedicalSubCategories = []string{
+	"01_領収書・明細",
+	"02_検査結果・診断書",
+	"03_予防接種・健診",
 }
 
-type SharedGroup struct {
-	Children   []string
-	FolderName string
-	Label      string
+// サブカテゴリを持つカテゴリ → サブカテゴリ一覧
+var CategorySubCategories = map[string][]string{
+	"40_子供・教育":  SubCategories,
+	"60_ヘルス・医療": MedicalSubCategories,
 }
 
-var GradeConfigSettings = GradeConfig{
-	BaseFiscalYear: 2024,
-	ChildrenBaseGrades: map[string]int{
-		"ビクトル": 2,
-		"明日香":  -1,
-		"遥香":   -3,
-		"アンナ":  -3,
-		"ミハイル": -3,
-		"文香":   -5,
-	},
-	PreschoolClasses: map[int]PreschoolClass{
-		-1: {Name: "ぽぷら組", Emoji: "🌳"},
-		-2: {Name: "いちょう組", Emoji: "🍂"},
-		-3: {Name: "くるみ組", Emoji: "🐿️"},
-		-4: {Name: "たんぽぽ組", Emoji: "🌼"},
-		-5: {Name: "りんご組", Emoji: "🍎"},
-		-6: {Name: "さくらんぼ組", Emoji: "🍒"},
-	},
-	SharedGroups: map[string]SharedGroup{
-		"くるみ組": {
-			Children:   []string{"遥香", "アンナ", "ミハイル"},
-			FolderName: "Haruka-Anna-Mischa",
-			Label:      "🐿️",
-		},
-		"いちょう組": {
-			Children:   []string{"遥香", "アンナ", "ミハイル"},
-			FolderName: "Haruka-Anna-Mischa",
-			Label:      "🍂",
-		},
-		"ぽぷら組": {
-			Children:   []string{"遥香", "アンナ", "ミハイル"},
-			FolderName: "Haruka-Anna-Mischa",
-			Label:      "🌳",
-		},
-	},
+// ValidSubCategory はカテゴリに対して有効なサブカテゴリかを返す
+func ValidSubCategory(category, subCategory string) bool {
+	return containsString(CategorySubCategories[category], subCategory)
 }
 
-// サブカテゴリ
-var SubCategories = []string{
-	"01_お便り・スケジュール",
-	"02_提出・手続き・重要",
-	"03_記録・作品・成績",
+// MedicalConfig は60_ヘルス・医療の書類の扱い
+type MedicalConfig struct {
+	// 医療書類のOCRテキストをNotebookLM（家族共有のRAGソース）に同期しない
+	ExcludeFromRAG bool
+}
+
+var Medical = MedicalConfig{
+	ExcludeFromRAG: GetEnvBool("EXCLUDE_MEDICAL_FROM_RAG", false),
+}
+
+// MedicalExpenseConfig は医療費控除の集計設定
+type MedicalExpenseConfig struct {
+	Enabled bool
+	// 確定申告の時期（MM-DD）。この期間は前年分の集計を通知する
+	FilingSeasonStart string
+	FilingSeasonEnd   string
+	// 医療費控除の足切り額（総所得金額等が200万円未満の場合は所得の5%だが、所得は扱わないため固定額で目安を出す）
+	DeductionThreshold int
+	DeductionLimit     int
+}
+
+var MedicalExpenses = MedicalExpenseConfig{
+	Enabled:            GetEnvBool("ENABLE_MEDICAL_EXPENSES", true),
+	FilingSeasonStart:  GetEnv("MEDICAL_EXPENSE_REMIND_FROM", "02-01"),
+	FilingSeasonEnd:    GetEnv("MEDICAL_EXPENSE_REMIND_UNTIL", "03-15"),
+	DeductionThreshold: 100000,
+	DeductionLimit:     2000000,
+}
+
+// FinanceConfig は家計の支払い（10_マネー・税務の書類）の集計設定
+type FinanceConfig struct {
+	Enabled bool
+	// 支払期日をGoogle Tasksに登録する（期日が過ぎているものは登録しない）
+	CreateDueTasks bool
 }
 
+var Finance = FinanceConfig{
+	Enabled:        GetEnvBool("ENABLE_FINANCE", true),
+	CreateDueTasks: GetEnvBool("FINANCE_DUE_TASKS", true),
+}
+
+// RetentionConfig は保存期間を過ぎた書類の整理ジョブの設定
+type RetentionConfig struct {
+	// 法定の保存期間がある書類を、削除の予告から実際に削除するまでの日数
+	DeleteGraceDays int
+	// 1回の実行で移動・削除する最大件数（Drive APIの呼び出しを抑える）
+	MaxPerRun int
+}
+
+var Retention = RetentionConfig{
+	DeleteGraceDays: GetEnvInt("RETENTION_DELETE_GRACE_DAYS", 30),
+	MaxPerRun:       GetEnvInt("RETENTION_MAX_PER_RUN", 100),
+}
+
+// LocalOCRConfig はAIが使えない場合のローカルOCR（tesseract）の設定
+type LocalOCRConfig struct {
+	Enabled    bool
+	Command    string  // tesseract の実行ファイル
+	Languages  string  // tesseract の -l に渡す言語
+	Confidence float64 // ローカルOCR・ルールによる仮分類の信頼度
+}
+
+var LocalOCR = LocalOCRConfig{
+	Enabled:    GetEnvBool("ENABLE_LOCAL_OCR_FALLBACK", true),
+	Command:    GetEnv("TESSERACT_PATH", "tesseract"),
+	Languages:  GetEnv("TESSERACT_LANGS", "jpn+eng"),
+	Confidence: 0.3,
+}
+
+// 仮分類ルール（世帯設定の fallback_rules）に一致しない場合の仮分類カテゴリ
+const FallbackCategory = "50_写真・その他"
+
 // CalendarSync設定
 var TargetSubfolderNames = []string{
 	"01_お便り・スケジュール",
 	"02_提出・手続き・重要",
 }
 
-var CalendarID = GetEnv("CALENDAR_ID", "639243bb722810f6fbe8f95b9dc57adf65677a53810d7fcdc76eef0fc4845792@group.calendar.google.com")
-
 // API設定
 type APIConfig struct {
 	TimeoutMS    int
@@ -309,3 +398,42 @@ func GetEnvBool(key string, defaultValue bool) bool {
 		return defaultValue
 	}
 }
+
+// GetEnvDuration は環境変数をtime.Durationとして取得する（例: "30s", "5m"）
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		return defaultValue
+	}
+	return d
+}
+
+// GetEnvFloat は環境変数を数値として取得する（負数・不正な値は既定値）
+func GetEnvFloat(key string, defaultValue float64) float64 {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return defaultValue
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || v < 0 {
+		return defaultValue
+	}
+	return v
+}
+
+// GetEnvInt は環境変数を整数として取得する（負数・不正な値は既定値）
+func GetEnvInt(key string, defaultValue int) int {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return defaultValue
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		return defaultValue
+	}
+	return v
+}
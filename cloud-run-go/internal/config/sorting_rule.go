@@ -0,0 +1,114 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// SortingRule は定期的に届く書類（同じ学校のお便り・銀行の明細など）をAIを使わずに仕分けるルール
+// 指定した条件のすべてに一致した場合、設定した分類結果をそのまま使う
+type SortingRule struct {
+	Name string `json:"name"`
+
+	// 条件
+	FilenamePattern string   `json:"filename_pattern,omitempty"` // 元のファイル名の正規表現
+	Keywords        []string `json:"keywords,omitempty"`         // 本文に含まれる語（差出人名など。すべてを含む場合に一致）
+	Uploader        string   `json:"uploader,omitempty"`         // Driveにアップロードしたユーザーのメールアドレス
+
+	// 分類結果
+	Category         string `json:"category"`
+	SubCategory      string `json:"sub_category,omitempty"`
+	Child            string `json:"child,omitempty"`
+	Adult            string `json:"adult,omitempty"`
+	Summary          string `json:"summary,omitempty"`           // 未指定時は本文・ファイル名から作る
+	FilenameTemplate string `json:"filename_template,omitempty"` // 未指定時は通常のファイル名
+
+	filenameRe *regexp.Regexp
+}
+
+// NeedsText は本文の抽出が必要なルールかを返す
+func (r *SortingRule) NeedsText() bool {
+	return len(r.Keywords) > 0
+}
+
+// Matches はルールの条件にすべて一致するかを返す（text は本文。空白は無視して照合する）
+func (r *SortingRule) Matches(filename, uploader, text string) bool {
+	if r.filenameRe != nil && !r.filenameRe.MatchString(filename) {
+		return false
+	}
+	if r.Uploader != "" && !strings.EqualFold(r.Uploader, uploader) {
+		return false
+	}
+	if len(r.Keywords) > 0 {
+		compact := removeSpaces(text)
+		for _, kw := range r.Keywords {
+			if !strings.Contains(compact, removeSpaces(kw)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// validate はルールを検証し、ファイル名の正規表現をコンパイルする
+func (r *SortingRule) validate(h *Household) error {
+	var errs []error
+	if r.Name == "" {
+		errs = append(errs, errors.New("name は必須です"))
+	}
+	if r.FilenamePattern == "" && len(r.Keywords) == 0 && r.Uploader == "" {
+		errs = append(errs, errors.New("filename_pattern / keywords / uploader のいずれかが必要です"))
+	}
+	if r.FilenamePattern != "" {
+		re, err := regexp.Compile(r.FilenamePattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("filename_pattern が不正です: %w", err))
+		}
+		r.filenameRe = re
+	}
+	if r.FilenameTemplate != "" {
+		if err := ValidateFilenameTemplate(r.FilenameTemplate); err != nil {
+			errs = append(errs, fmt.Errorf("filename_template: %w", err))
+		}
+	}
+	if _, ok := h.Categories[r.Category]; !ok {
+		errs = append(errs, fmt.Errorf("category %q は categories に存在しません", r.Category))
+	}
+	if r.SubCategory != "" {
+		if !ValidSubCategory(r.Category, r.SubCategory) {
+			errs = append(errs, fmt.Errorf("sub_category %q は %s では使えません", r.SubCategory, r.Category))
+		}
+	}
+	if r.Child != "" {
+		if _, ok := h.ChildAliases[r.Child]; !ok {
+			errs = append(errs, fmt.Errorf("child %q は child_aliases に存在しません", r.Child))
+		}
+	}
+	if r.Adult != "" {
+		if _, ok := h.AdultAliases[r.Adult]; !ok {
+			errs = append(errs, fmt.Errorf("adult %q は adult_aliases に存在しません", r.Adult))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func removeSpaces(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
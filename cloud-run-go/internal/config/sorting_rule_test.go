@@ -0,0 +1,71 @@
+package config
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSortingRule_Matches(t *testing.T) {
+	rule := &SortingRule{
+		Name:            "学年だより",
+		FilenamePattern: `^scan_`,
+		Keywords:        []string{"○○小学校", "学年 だより"},
+		Uploader:        "mama@example.com",
+		Category:        "40_子供・教育",
+	}
+	h, err := LoadHousehold(context.Background(), "../../resources/household/household.example.yaml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rule.validate(h); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+		uploader string
+		text     string
+		want     bool
+	}{
+		{"all match", "scan_001.pdf", "Mama@Example.com", "○○小学校\n学年だより 4月号", true},
+		{"spaces ignored", "scan_001.pdf", "mama@example.com", "○ ○ 小 学 校 学 年 だ よ り", true},
+		{"filename mismatch", "IMG_001.jpg", "mama@example.com", "○○小学校 学年だより", false},
+		{"uploader mismatch", "scan_001.pdf", "papa@example.com", "○○小学校 学年だより", false},
+		{"missing keyword", "scan_001.pdf", "mama@example.com", "○○小学校 保健だより", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rule.Matches(tt.filename, tt.uploader, tt.text); got != tt.want {
+				t.Fatalf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHousehold_InvalidSortingRules(t *testing.T) {
+	base := `{"version":1,"folder_ids":{"SOURCE":"s","CHILDREN_EDU":"c","PHOTO_OTHER":"p"},"categories":{"40_子供・教育":"CHILDREN_EDU"},"child_aliases":{"太郎":["太郎"]},"grades":{"base_fiscal_year":2024},"sorting_rules":[%s]}`
+	tests := []struct {
+		name string
+		rule string
+		want string
+	}{
+		{"no condition", `{"name":"r","category":"40_子供・教育"}`, "いずれかが必要"},
+		{"bad pattern", `{"name":"r","filename_pattern":"(","category":"40_子供・教育"}`, "filename_pattern"},
+		{"unknown category", `{"name":"r","keywords":["x"],"category":"10_マネー・税務"}`, "10_マネー・税務"},
+		{"unknown sub category", `{"name":"r","keywords":["x"],"category":"40_子供・教育","sub_category":"04_その他"}`, "04_その他"},
+		{"unknown child", `{"name":"r","keywords":["x"],"category":"40_子供・教育","child":"次郎"}`, "次郎"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseHousehold([]byte(strings.Replace(base, "%s", tt.rule, 1)))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+			if !strings.Contains(err.Error(), "sorting_rules[0]") {
+				t.Fatalf("error should point at the rule: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 処理台帳などの保存先が永続ボリューム上にあることを起動時に確認するか
+// Cloud Run（K_SERVICE が設定される）では既定で確認する。/tmp はメモリ上にあり、再起動で医療費・家計の記録やレビュー待ちが消えるため
+var RequirePersistentStorage = GetEnvBool("REQUIRE_PERSISTENT_STORAGE", os.Getenv("K_SERVICE") != "")
+
+// ephemeralFSTypes はインスタンスの再起動で内容が消えるファイルシステム
+var ephemeralFSTypes = map[string]bool{
+	"tmpfs":   true,
+	"ramfs":   true,
+	"overlay": true,
+}
+
+// CheckPersistentDir は dir がマウントされた永続ボリューム（GCS FUSE・NFSなど）上にあるかを確認する
+func CheckPersistentDir(dir string) error {
+	mountinfo, err := os.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return fmt.Errorf("マウント情報の取得失敗: %w", err)
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("%s: %w", dir, err)
+	}
+	point, fsType := mountOf(mountinfo, abs)
+	if point == "" || point == "/" || ephemeralFSTypes[fsType] {
+		return fmt.Errorf("%s は永続ボリューム上にありません（%s %s）。再起動で記録が消えるため、GCSバケットなどをマウントしたディレクトリを指定してください", dir, point, fsType)
+	}
+	return nil
+}
+
+// mountOf は /proc/self/mountinfo の内容から dir を含む最も深いマウントポイントとファイルシステムの種類を返す
+func mountOf(mountinfo []byte, dir string) (string, string) {
+	var point, fsType string
+	for _, line := range bytes.Split(mountinfo, []byte("\n")) {
+		// 例: 36 35 98:0 / /mnt/ledger rw,relatime shared:1 - fuse gcsfuse rw
+		fields := strings.Fields(string(line))
+		sep := -1
+		for i, f := range fields {
+			if f == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 5 || sep+1 >= len(fields) {
+			continue
+		}
+		mp := fields[4]
+		if mp != "/" && dir != mp && !strings.HasPrefix(dir, mp+"/") {
+			continue
+		}
+		if len(mp) >= len(point) {
+			point, fsType = mp, fields[sep+1]
+		}
+	}
+	return point, fsType
+}
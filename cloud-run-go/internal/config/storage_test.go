@@ -0,0 +1,31 @@
+package config
+
+import "testing"
+
+func TestMountOf(t *testing.T) {
+	mountinfo := []byte(`1 0 0:1 / / rw,relatime - overlay overlay rw
+2 1 0:2 / /tmp rw,nosuid - tmpfs tmpfs rw
+3 1 0:3 / /mnt/ledger rw,relatime shared:1 - fuse gcsfuse rw
+4 1 0:4 / /mnt/ledger-old rw,relatime - nfs server:/export rw
+`)
+	tests := []struct {
+		dir       string
+		wantPoint string
+		wantType  string
+	}{
+		{"/tmp/homedocmanager/ledger", "/tmp", "tmpfs"},
+		{"/mnt/ledger", "/mnt/ledger", "fuse"},
+		{"/mnt/ledger/grandparents/dlq", "/mnt/ledger", "fuse"},
+		{"/mnt/ledger-old/usage", "/mnt/ledger-old", "nfs"},
+		{"/mnt/ledgers", "/", "overlay"},
+		{"/var/lib/homedocmanager", "/", "overlay"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			point, fsType := mountOf(mountinfo, tt.dir)
+			if point != tt.wantPoint || fsType != tt.wantType {
+				t.Fatalf("mountOf(%s) = %s %s, want %s %s", tt.dir, point, fsType, tt.wantPoint, tt.wantType)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TemplatePlaceholderPattern はファイル名・フォルダのテンプレートのプレースホルダー
+// {name}、{name:format}、値が空の場合の既定値を付けた {name|default}（{name:format|default}）の形式
+var TemplatePlaceholderPattern = regexp.MustCompile(`\{([a-z_]+)(?::([^{}|]*))?(?:\|([^{}]*))?\}`)
+
+// filenamePlaceholders はファイル名テンプレートで使えるプレースホルダー（値: 書式を指定できるか）
+var filenamePlaceholders = map[string]bool{
+	"date":         true, // {date:YYYY-MM} のように YYYY / YY / MM / DD で書式を指定
+	"fiscal_year":  false,
+	"child":        false,
+	"grade_label":  false,
+	"adult":        false,
+	"person":       false, // 対象の大人、いなければ子供（医療書類の受診者など）
+	"summary":      false,
+	"category":     false,
+	"sub_category": false,
+	"seq":          true, // 同名ファイルを避ける連番（{seq:3} で桁数を指定。既定は2桁）
+}
+
+// folderPlaceholders はフォルダのパステンプレートで使えるプレースホルダー
+var folderPlaceholders = map[string]bool{
+	"date":         true,
+	"fiscal_year":  false,
+	"child":        false,
+	"grade_label":  false,
+	"adult":        false,
+	"person":       false,
+	"category":     false,
+	"sub_category": false,
+}
+
+// DefaultFolderTemplates は移動先フォルダのパステンプレートの既定値
+// カテゴリのフォルダからの相対パスで、「/」で区切った階層ごとにフォルダを作成する（世帯設定の folder_templates で上書き）
+var DefaultFolderTemplates = map[string]string{
+	"10_マネー・税務": "{fiscal_year}年度",
+	"30_ライフ・行政": "{fiscal_year}年度",
+	"40_子供・教育":  "{child|共通・学校全般}/{fiscal_year}年度/{sub_category|01_お便り・スケジュール}",
+	// 医療費控除は暦年で集計するため年度ではなく年で分ける
+	"60_ヘルス・医療": "{person|家族共通}/{date:YYYY}年/{sub_category|01_領収書・明細}",
+}
+
+var dateFormatTokens = strings.NewReplacer("YYYY", "2006", "YY", "06", "MM", "01", "DD", "02")
+
+// DateLayout は {date:...} の書式をGoのレイアウトに変換する（空の場合は YYYYMMDD）
+func DateLayout(format string) string {
+	if format == "" {
+		return "20060102"
+	}
+	return dateFormatTokens.Replace(format)
+}
+
+// SeqWidth は {seq:...} の桁数を返す（空・不正な場合は2桁）
+func SeqWidth(format string) int {
+	if !validSeqWidth(format) {
+		return 2
+	}
+	n, _ := strconv.Atoi(format)
+	return n
+}
+
+// ValidateFilenameTemplate はファイル名テンプレートを検証する
+func ValidateFilenameTemplate(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return errors.New("テンプレートが空です")
+	}
+	return validateTemplate(template, filenamePlaceholders)
+}
+
+// ValidateFolderTemplate はフォルダのパステンプレートを検証する（空の場合はカテゴリのフォルダ直下）
+func ValidateFolderTemplate(template string) error {
+	return validateTemplate(template, folderPlaceholders)
+}
+
+// validateTemplate はプレースホルダーの名前・書式を検証する
+func validateTemplate(template string, placeholders map[string]bool) error {
+	var errs []error
+	for _, m := range TemplatePlaceholderPattern.FindAllStringSubmatch(template, -1) {
+		name, format := m[1], m[2]
+		formattable, ok := placeholders[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("{%s} は未対応のプレースホルダーです", name))
+			continue
+		}
+		if format == "" {
+			continue
+		}
+		switch {
+		case !formattable:
+			errs = append(errs, fmt.Errorf("{%s} には書式を指定できません", name))
+		case name == "date" && DateLayout(format) == format:
+			errs = append(errs, fmt.Errorf("{date:%s} に YYYY / YY / MM / DD が含まれていません", format))
+		case name == "seq" && !validSeqWidth(format):
+			errs = append(errs, fmt.Errorf("{seq:%s} の桁数は1〜6で指定してください", format))
+		}
+	}
+	if rest := TemplatePlaceholderPattern.ReplaceAllString(template, ""); strings.ContainsAny(rest, "{}") {
+		errs = append(errs, fmt.Errorf("対応していない { } があります: %s", template))
+	}
+	return errors.Join(errs...)
+}
+
+func validSeqWidth(format string) bool {
+	n, err := strconv.Atoi(format)
+	return err == nil && n >= 1 && n <= 6
+}
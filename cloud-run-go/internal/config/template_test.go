@@ -0,0 +1,78 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateFilenameTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     string // 空の場合はエラーなし
+	}{
+		{"default", "{date}_{summary}", ""},
+		{"formats", "{date:YYYY-MM}_{child}_{grade_label}_{seq:3}", ""},
+		{"empty", " ", "空"},
+		{"unknown placeholder", "{date}_{title}", "{title}"},
+		{"format not allowed", "{summary:10}", "{summary}"},
+		{"bad date format", "{date:yyyy}", "{date:yyyy}"},
+		{"bad seq width", "{summary}_{seq:0}", "{seq:0}"},
+		{"unbalanced brace", "{date}_{summary", "{ }"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFilenameTemplate(tt.template)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidateFolderTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     string // 空の場合はエラーなし
+	}{
+		{"empty means category root", "", ""},
+		{"defaults", DefaultFolderTemplates["40_子供・教育"], ""},
+		{"monthly", "{fiscal_year}年度/{date:MM}月", ""},
+		{"fallback value", "{adult|家族共通}", ""},
+		{"filename only placeholder", "{fiscal_year}年度/{seq}", "{seq}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFolderTemplate(tt.template)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestDateLayout(t *testing.T) {
+	tests := map[string]string{
+		"":          "20060102",
+		"YYYY-MM":   "2006-01",
+		"YY年MM月DD日": "06年01月02日",
+	}
+	for format, want := range tests {
+		if got := DateLayout(format); got != want {
+			t.Errorf("DateLayout(%q) = %q, want %q", format, got, want)
+		}
+	}
+}
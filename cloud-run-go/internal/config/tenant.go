@@ -0,0 +1,179 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// テナント（世帯）一覧ファイル。未指定の場合は環境変数から単一テナントを構成する
+var TenantsConfigPath = GetEnv("TENANTS_CONFIG", "")
+
+// DefaultTenantID は単一世帯運用時のテナントID
+const DefaultTenantID = "default"
+
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// TenantSecrets はテナントごとのシークレット名
+// 同名の環境変数があればそれを、なければSecret Managerの値を使う
+type TenantSecrets struct {
+	OAuthRefreshToken      string `json:"oauth_refresh_token"`
+	LineChannelSecret      string `json:"line_channel_secret,omitempty"`
+	LineChannelAccessToken string `json:"line_channel_access_token,omitempty"`
+	DiscordWebhookURL      string `json:"discord_webhook_url,omitempty"`
+	DriveWebhookToken      string `json:"drive_webhook_token,omitempty"`
+}
+
+// Tenant は1世帯分のデプロイ設定（世帯設定ファイル・認証情報・通知先）
+type Tenant struct {
+	ID              string        `json:"id"`
+	HouseholdConfig string        `json:"household_config"`
+	Secrets         TenantSecrets `json:"secrets"`
+
+	LineBotSettingsPath  string `json:"line_bot_settings_path,omitempty"`
+	LineUserSettingsPath string `json:"line_user_settings_path,omitempty"`
+	LineFamilyGroupID    string `json:"line_family_group_id,omitempty"`
+	LineReviewTargetID   string `json:"line_review_target_id,omitempty"`
+	// LINE Webhookの destination（ボットのユーザーID）。/callback で受けた通知の振り分けに使う
+	LineBotUserID string `json:"line_bot_user_id,omitempty"`
+
+	// 処理台帳・デッドレターの保存先（未指定時は PROCESSING_LEDGER_DIR/{id}）
+	DataDir string `json:"data_dir,omitempty"`
+
+	// シークレットの解決結果（起動時に設定）
+	LineChannelSecret      string `json:"-"`
+	LineChannelAccessToken string `json:"-"`
+	DiscordWebhookURL      string `json:"-"`
+	DriveWebhookToken      string `json:"-"`
+}
+
+// LedgerDir は処理台帳の保存先を返す
+func (t *Tenant) LedgerDir() string {
+	return t.DataDir
+}
+
+// DeadLetterDir はデッドレターの保存先を返す
+func (t *Tenant) DeadLetterDir() string {
+	if t.ID == DefaultTenantID && t.DataDir == ProcessingLedger.Dir {
+		return DeadLetter.Dir
+	}
+	return filepath.Join(t.DataDir, "dlq")
+}
+
+// DedupDir は重複判定インデックスの保存先を返す
+func (t *Tenant) DedupDir() string {
+	return filepath.Join(t.DataDir, "dedup")
+}
+
+// MedicalExpenseDir は医療費の集計（年ごとのJSON Lines）の保存先を返す
+func (t *Tenant) MedicalExpenseDir() string {
+	return filepath.Join(t.DataDir, "medical_expenses")
+}
+
+// FinanceDir は家計の支払いの記録の保存先を返す
+func (t *Tenant) FinanceDir() string {
+	return filepath.Join(t.DataDir, "finance")
+}
+
+// DefaultTenant は従来の環境変数・設定値から単一テナントを構成する
+func DefaultTenant() *Tenant {
+	return &Tenant{
+		ID:              DefaultTenantID,
+		HouseholdConfig: HouseholdConfigSource,
+		Secrets: TenantSecrets{
+			OAuthRefreshToken:      "OAUTH_REFRESH_TOKEN",
+			LineChannelSecret:      "LINE_CHANNEL_SECRET",
+			LineChannelAccessToken: "LINE_CHANNEL_ACCESS_TOKEN",
+			DiscordWebhookURL:      "DISCORD_WEBHOOK_URL",
+			DriveWebhookToken:      "DRIVE_WEBHOOK_TOKEN",
+		},
+		LineBotSettingsPath:  LineBotSettingsPath,
+		LineUserSettingsPath: LineUserSettingsPath,
+		LineFamilyGroupID:    LineFamilyGroupID,
+		LineReviewTargetID:   LineReviewTargetID,
+		DataDir:              ProcessingLedger.Dir,
+	}
+}
+
+// Validate はテナント設定を検証する
+func (t *Tenant) Validate() error {
+	var errs []error
+	if !tenantIDPattern.MatchString(t.ID) {
+		errs = append(errs, fmt.Errorf("id %q は英小文字・数字・-_ で32文字以内にしてください", t.ID))
+	}
+	if t.HouseholdConfig == "" {
+		errs = append(errs, fmt.Errorf("%s: household_config は必須です", t.ID))
+	}
+	if t.Secrets.OAuthRefreshToken == "" {
+		errs = append(errs, fmt.Errorf("%s: secrets.oauth_refresh_token は必須です", t.ID))
+	}
+	if (t.Secrets.LineChannelSecret == "") != (t.Secrets.LineChannelAccessToken == "") {
+		errs = append(errs, fmt.Errorf("%s: secrets.line_channel_secret と line_channel_access_token は両方指定してください", t.ID))
+	}
+	return errors.Join(errs...)
+}
+
+// applyDefaults は未指定の項目に既定値を設定する
+func (t *Tenant) applyDefaults() {
+	if t.LineBotSettingsPath == "" {
+		t.LineBotSettingsPath = LineBotSettingsPath
+	}
+	if t.LineUserSettingsPath == "" {
+		t.LineUserSettingsPath = LineUserSettingsPath
+	}
+	if t.LineReviewTargetID == "" {
+		t.LineReviewTargetID = t.LineFamilyGroupID
+	}
+	if t.DataDir == "" {
+		t.DataDir = filepath.Join(ProcessingLedger.Dir, t.ID)
+	}
+}
+
+// ParseTenants はテナント一覧（JSON配列）を読み込み、検証する
+func ParseTenants(data []byte) ([]*Tenant, error) {
+	var tenants []*Tenant
+	if err := json.Unmarshal(data, &tenants); err != nil {
+		return nil, fmt.Errorf("failed to parse tenants config: %w", err)
+	}
+	if len(tenants) == 0 {
+		return nil, errors.New("tenants config is empty")
+	}
+
+	var errs []error
+	seen := make(map[string]bool, len(tenants))
+	secrets := make(map[string]string, len(tenants))
+	for _, t := range tenants {
+		if err := t.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+		if seen[t.ID] {
+			errs = append(errs, fmt.Errorf("id %q が重複しています", t.ID))
+		}
+		seen[t.ID] = true
+		// 別世帯のDriveに書き込まないよう、OAuthトークンの共有は禁止
+		if other, ok := secrets[t.Secrets.OAuthRefreshToken]; ok && t.Secrets.OAuthRefreshToken != "" {
+			errs = append(errs, fmt.Errorf("%s と %s が同じ oauth_refresh_token を参照しています", other, t.ID))
+		}
+		secrets[t.Secrets.OAuthRefreshToken] = t.ID
+		t.applyDefaults()
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid tenants config: %w", err)
+	}
+	return tenants, nil
+}
+
+// LoadTenants はテナント一覧を読み込む。path が空の場合は単一テナントを返す
+func LoadTenants(path string) ([]*Tenant, error) {
+	if path == "" {
+		return []*Tenant{DefaultTenant()}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenants config %s: %w", path, err)
+	}
+	return ParseTenants(data)
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadTenants_Default(t *testing.T) {
+	tenants, err := LoadTenants("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tenants) != 1 || tenants[0].ID != DefaultTenantID {
+		t.Fatalf("unexpected tenants: %+v", tenants)
+	}
+	if tenants[0].LedgerDir() != ProcessingLedger.Dir || tenants[0].DeadLetterDir() != DeadLetter.Dir {
+		t.Fatalf("default tenant should keep existing data dirs: %+v", tenants[0])
+	}
+}
+
+func TestLoadTenants_Example(t *testing.T) {
+	tenants, err := LoadTenants("../../resources/tenants.example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tenants) != 2 {
+		t.Fatalf("got %d tenants", len(tenants))
+	}
+	second := tenants[1]
+	if second.LedgerDir() != filepath.Join(ProcessingLedger.Dir, "grandparents") {
+		t.Fatalf("ledger dir = %s", second.LedgerDir())
+	}
+	if second.DeadLetterDir() != filepath.Join(second.LedgerDir(), "dlq") {
+		t.Fatalf("dead letter dir = %s", second.DeadLetterDir())
+	}
+	if second.LineBotSettingsPath != LineBotSettingsPath {
+		t.Fatalf("line settings path default not applied: %s", second.LineBotSettingsPath)
+	}
+}
+
+func TestParseTenants_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"empty", `[]`, "empty"},
+		{"bad id", `[{"id":"Bad ID","household_config":"h.json","secrets":{"oauth_refresh_token":"T"}}]`, "Bad ID"},
+		{"missing household", `[{"id":"a","secrets":{"oauth_refresh_token":"T"}}]`, "household_config"},
+		{"duplicate id", `[{"id":"a","household_config":"h","secrets":{"oauth_refresh_token":"T1"}},{"id":"a","household_config":"h","secrets":{"oauth_refresh_token":"T2"}}]`, "重複"},
+		{"shared oauth", `[{"id":"a","household_config":"h","secrets":{"oauth_refresh_token":"T"}},{"id":"b","household_config":"h","secrets":{"oauth_refresh_token":"T"}}]`, "oauth_refresh_token"},
+		{"partial line", `[{"id":"a","household_config":"h","secrets":{"oauth_refresh_token":"T","line_channel_secret":"S"}}]`, "line_channel_access_token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTenants([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoadTenants_MissingFile(t *testing.T) {
+	_, err := LoadTenants(filepath.Join(t.TempDir(), "missing.json"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected not-exist error, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminConfig は現在の設定とリロード状況を返す
+func (h *PubSubHandler) AdminConfig(c *gin.Context) {
+	household := h.services(c).Household.Current()
+	c.JSON(http.StatusOK, gin.H{
+		"status": "OK",
+		"household": gin.H{
+			"version":    household.Version,
+			"name":       household.Name,
+			"categories": household.CategoryNames(),
+			"children":   len(household.ChildAliases),
+			"adults":     len(household.AdultAliases),
+		},
+		"reload": h.services(c).ConfigReloader.Status(),
+	})
+}
+
+// AdminConfigReload は設定ファイルを再読み込みする
+// 検証に失敗した場合は 422 を返し、現在の設定を維持する
+func (h *PubSubHandler) AdminConfigReload(c *gin.Context) {
+	status, err := h.services(c).ConfigReloader.Reload(c.Request.Context())
+	if err != nil {
+		log.Printf("Config reload rejected: %v", err)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  err.Error(),
+			"reload": status,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "OK",
+		"reload": status,
+	})
+}
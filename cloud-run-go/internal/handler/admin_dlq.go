@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leo-sagawa/homedocmanager/internal/service"
+)
+
+// AdminDLQ はデッドレター（処理失敗ファイル）の一覧を返す
+func (h *PubSubHandler) AdminDLQ(c *gin.Context) {
+	entries, err := h.services(c).FileSorter.ListDeadLetters(c.Request.Context())
+	if err != nil {
+		log.Printf("Error listing dead letters: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"count":   len(entries),
+		"entries": entries,
+	})
+}
+
+// AdminDLQReplay はデッドレターのファイルを再処理する
+func (h *PubSubHandler) AdminDLQReplay(c *gin.Context) {
+	fileID := c.Param("fileId")
+
+	result, err := h.services(c).FileSorter.ReplayDeadLetter(c.Request.Context(), fileID)
+	if err != nil {
+		writeDLQError(c, fileID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"file_id": fileID,
+		"result":  string(result),
+	})
+}
+
+// AdminDLQDiscard はデッドレターから削除し、以後の処理対象外とする
+func (h *PubSubHandler) AdminDLQDiscard(c *gin.Context) {
+	fileID := c.Param("fileId")
+
+	if err := h.services(c).FileSorter.DiscardDeadLetter(c.Request.Context(), fileID); err != nil {
+		writeDLQError(c, fileID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"file_id": fileID,
+	})
+}
+
+func writeDLQError(c *gin.Context, fileID string, err error) {
+	if errors.Is(err, service.ErrDeadLetterNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "dead letter not found", "file_id": fileID})
+		return
+	}
+	log.Printf("Error handling dead letter %s: %v", fileID, err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leo-sagawa/homedocmanager/internal/service"
+)
+
+// AdminFinance は家計の支払いの集計を返す
+// ?month=YYYY-MM でその月の支払先別の集計（既定: 当月）、?payee= でその支払先の月別の推移を返す
+// いずれの場合も、今日以降に支払期日がある支払いを upcoming として返す
+func (h *PubSubHandler) AdminFinance(c *gin.Context) {
+	ledger := h.services(c).Finance
+	if ledger == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "finance ledger is not enabled"})
+		return
+	}
+
+	month := c.Query("month")
+	if month == "" {
+		month = time.Now().Format("2006-01")
+	}
+	if _, err := time.Parse("2006-01", month); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "month must be YYYY-MM"})
+		return
+	}
+
+	records, err := ledger.List(c.Request.Context())
+	if err != nil {
+		log.Printf("Error reading finance records: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	report := service.FinanceMonthReport(month, records)
+	if payee := c.Query("payee"); payee != "" {
+		report = service.FinancePayeeReport(payee, records)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "OK",
+		"report":   report,
+		"upcoming": service.UpcomingFinanceRecords(records, time.Now()),
+	})
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// AdminLedger は処理台帳の一覧を返す（?stuck=true で滞留ファイルのみ）
+func (h *PubSubHandler) AdminLedger(c *gin.Context) {
+	var (
+		entries []*model.LedgerEntry
+		err     error
+	)
+	if c.Query("stuck") == "true" {
+		entries, err = h.services(c).FileSorter.FindStuckFiles(c.Request.Context())
+	} else {
+		entries, err = h.services(c).FileSorter.ListLedger(c.Request.Context())
+	}
+	if err != nil {
+		log.Printf("Error listing ledger: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"count":   len(entries),
+		"entries": entries,
+	})
+}
+
+// AdminLedgerResume は滞留ファイルの処理を再開する（file_id 指定時はそのファイルのみ）
+func (h *PubSubHandler) AdminLedgerResume(c *gin.Context) {
+	var req struct {
+		FileID string `json:"file_id"`
+	}
+	// ボディは任意
+	_ = c.ShouldBindJSON(&req)
+
+	if req.FileID != "" {
+		result := h.services(c).FileSorter.ProcessFile(c.Request.Context(), req.FileID)
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "OK",
+			"results": map[string]string{req.FileID: string(result)},
+		})
+		return
+	}
+
+	results, err := h.services(c).FileSorter.ResumeStuckFiles(c.Request.Context())
+	if err != nil {
+		log.Printf("Error resuming stuck files: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"results": results,
+	})
+}
+
+// AdminReclassify はAI障害時に仮分類したファイルをAIで解析し直し、仕分けをやり直す（file_id 指定時はそのファイルのみ）
+func (h *PubSubHandler) AdminReclassify(c *gin.Context) {
+	var req struct {
+		FileID string `json:"file_id"`
+	}
+	// ボディは任意
+	_ = c.ShouldBindJSON(&req)
+
+	if req.FileID != "" {
+		result := h.services(c).FileSorter.Reclassify(c.Request.Context(), req.FileID)
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "OK",
+			"results": map[string]string{req.FileID: string(result)},
+		})
+		return
+	}
+
+	results, err := h.services(c).FileSorter.ReclassifyProvisional(c.Request.Context())
+	if err != nil {
+		log.Printf("Error reclassifying provisional files: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"results": results,
+	})
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leo-sagawa/homedocmanager/internal/service"
+)
+
+// AdminMedicalExpenses は医療費控除の年間集計を返す
+// ?year=YYYY で対象年（既定: 前年）、?format=xlsx / ?format=csv で国税庁の医療費集計フォームと同じ列のExcelファイル / CSVを返す
+func (h *PubSubHandler) AdminMedicalExpenses(c *gin.Context) {
+	ledger := h.services(c).MedicalExpenses
+	if ledger == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "medical expense ledger is not enabled"})
+		return
+	}
+
+	year, ok := medicalExpenseYear(c)
+	if !ok {
+		return
+	}
+
+	expenses, err := ledger.List(c.Request.Context(), year)
+	if err != nil {
+		log.Printf("Error reading medical expenses: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "OK",
+			"summary": service.SummarizeMedicalExpenses(year, expenses),
+		})
+	case "csv":
+		c.Header("Content-Disposition", `attachment; filename="medical-expenses-`+strconv.Itoa(year)+`.csv"`)
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		if err := service.WriteMedicalExpenseCSV(c.Writer, expenses); err != nil {
+			log.Printf("Error writing medical expense CSV: %v", err)
+		}
+	case "xlsx":
+		c.Header("Content-Disposition", `attachment; filename="medical-expenses-`+strconv.Itoa(year)+`.xlsx"`)
+		c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		c.Status(http.StatusOK)
+		if err := service.WriteMedicalExpenseXLSX(c.Writer, expenses); err != nil {
+			log.Printf("Error writing medical expense XLSX: %v", err)
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json, csv or xlsx"})
+	}
+}
+
+// AdminMedicalExpensesRemind は確定申告の時期に前年の医療費の集計をDiscordに通知する
+// Cloud Scheduler から毎日呼ばれる想定で、時期外は何もしない（?force=true で時期外でも通知）
+func (h *PubSubHandler) AdminMedicalExpensesRemind(c *gin.Context) {
+	svc := h.services(c)
+	if svc.MedicalExpenses == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "medical expense ledger is not enabled"})
+		return
+	}
+
+	now := time.Now()
+	if c.Query("force") != "true" && !service.InFilingSeason(now) {
+		c.JSON(http.StatusOK, gin.H{"status": "SKIPPED", "reason": "not in filing season"})
+		return
+	}
+
+	year := now.Year() - 1
+	expenses, err := svc.MedicalExpenses.List(c.Request.Context(), year)
+	if err != nil {
+		log.Printf("Error reading medical expenses: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	summary := service.SummarizeMedicalExpenses(year, expenses)
+	svc.DiscordNotifier.NotifyMedicalExpenses(summary)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"summary": summary,
+	})
+}
+
+// medicalExpenseYear は ?year= を検証する（既定: 前年。確定申告は前年分を申告するため）
+func medicalExpenseYear(c *gin.Context) (int, bool) {
+	value := c.Query("year")
+	if value == "" {
+		return time.Now().Year() - 1, true
+	}
+	year, err := strconv.Atoi(value)
+	if err != nil || year < 2000 || year > 9999 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "year must be YYYY"})
+		return 0, false
+	}
+	return year, true
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leo-sagawa/homedocmanager/internal/service"
+)
+
+// AdminRetentionRun は保存期間を過ぎた書類をアーカイブ・削除する（Cloud Scheduler から定期的に呼ぶ想定）
+// ?dry_run=true で対象の一覧のみを返す。法定の保存期間がある書類は削除をDiscordで予告し、猶予期間の後に削除する
+func (h *PubSubHandler) AdminRetentionRun(c *gin.Context) {
+	svc := h.services(c)
+	opts := service.RetentionOptions{
+		Now:    time.Now(),
+		DryRun: c.Query("dry_run") == "true",
+	}
+	// 削除の予告はDiscordに届いた書類だけを記録する（通知先がなければ予告・削除しない）
+	if svc.DiscordNotifier != nil {
+		opts.Warner = svc.DiscordNotifier
+	}
+
+	result, err := svc.FileSorter.ApplyRetention(c.Request.Context(), opts)
+	if err != nil {
+		log.Printf("Error applying retention policies: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	svc.DiscordNotifier.NotifyRetention(result)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "OK",
+		"result": result,
+	})
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminRollover は新年度に向けて子供・教育のフォルダを作成し、子供の学年と共有グループの状況を返す
+// ?fiscal_year=YYYY で対象年度（既定: 次の4月に始まる年度）、?dry_run=true でフォルダを作成せずに一覧のみを返す
+func (h *PubSubHandler) AdminRollover(c *gin.Context) {
+	svc := h.services(c)
+
+	fiscalYear := svc.GradeManager.CalculateFiscalYear("") + 1
+	if value := c.Query("fiscal_year"); value != "" {
+		year, err := strconv.Atoi(value)
+		if err != nil || year < 2000 || year > 9999 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "fiscal_year must be YYYY"})
+			return
+		}
+		fiscalYear = year
+	}
+
+	report, err := svc.FileSorter.Rollover(c.Request.Context(), fiscalYear, c.Query("dry_run") == "true")
+	if err != nil {
+		log.Printf("Error preparing fiscal year rollover: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "OK",
+		"report": report,
+	})
+}
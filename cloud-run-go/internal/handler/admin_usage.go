@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminUsage はAI使用量の月次集計を返す
+// ?month=YYYY-MM で対象月（既定: 当月）、?file_id= でそのファイルの呼び出し履歴を返す
+func (h *PubSubHandler) AdminUsage(c *gin.Context) {
+	usage := h.services(c).AIUsage
+	if usage == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "usage tracking is not enabled"})
+		return
+	}
+
+	month := c.Query("month")
+	if month == "" {
+		month = time.Now().Format("2006-01")
+	}
+	if _, err := time.Parse("2006-01", month); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "month must be YYYY-MM"})
+		return
+	}
+
+	if fileID := c.Query("file_id"); fileID != "" {
+		records, err := usage.Records(month, fileID)
+		if err != nil {
+			log.Printf("Error reading AI usage: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "OK",
+			"month":   month,
+			"file_id": fileID,
+			"records": records,
+		})
+		return
+	}
+
+	summary, err := usage.Summary(month)
+	if err != nil {
+		log.Printf("Error reading AI usage: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "OK",
+		"usage":  summary,
+	})
+}
@@ -7,22 +7,19 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/leo-sagawa/homedocmanager/internal/config"
 	"github.com/leo-sagawa/homedocmanager/internal/model"
 	"github.com/leo-sagawa/homedocmanager/internal/service"
 )
 
 // PubSubHandler はPub/Subメッセージを処理するハンドラー
 type PubSubHandler struct {
-	services     *service.Services
-	watchManager *service.WatchManager
+	tenants *service.TenantRegistry
 }
 
 // NewPubSubHandler は新しいPubSubHandlerを作成
-func NewPubSubHandler(services *service.Services, watchManager *service.WatchManager) *PubSubHandler {
+func NewPubSubHandler(tenants *service.TenantRegistry) *PubSubHandler {
 	return &PubSubHandler{
-		services:     services,
-		watchManager: watchManager,
+		tenants: tenants,
 	}
 }
 
@@ -56,12 +53,23 @@ func (h *PubSubHandler) HandlePubSub(c *gin.Context) {
 		return
 	}
 
-	log.Printf("Processing file: %s", fileData.FileID)
+	// テナント指定（data の tenant_id、または属性 tenant）
+	tenantID := fileData.TenantID
+	if tenantID == "" {
+		tenantID = message.Message.Attributes["tenant"]
+	}
+	if tenantID != "" && !h.setTenant(c, tenantID) {
+		log.Printf("Unknown tenant in Pub/Sub message: %s", tenantID)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown tenant"})
+		return
+	}
+
+	log.Printf("Processing file: %s (tenant=%s)", fileData.FileID, h.tenant(c).Config.ID)
 
 	// ファイル処理を実行
-	result := h.services.FileSorter.ProcessFile(c.Request.Context(), fileData.FileID)
+	result := h.services(c).FileSorter.ProcessFile(c.Request.Context(), fileData.FileID)
 
-	if result == model.ProcessResultProcessed || result == model.ProcessResultSkipped {
+	if result != model.ProcessResultError {
 		log.Printf("File processed successfully (%s): %s", result, fileData.FileID)
 		c.JSON(http.StatusOK, gin.H{"status": "OK"})
 		return
@@ -80,6 +88,7 @@ func (h *PubSubHandler) HealthCheck(c *gin.Context) {
 func (h *PubSubHandler) TestEndpoint(c *gin.Context) {
 	var req struct {
 		FileID string `json:"file_id" binding:"required"`
+		DryRun bool   `json:"dry_run"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -87,10 +96,30 @@ func (h *PubSubHandler) TestEndpoint(c *gin.Context) {
 		return
 	}
 
+	// ドライラン: 解析のみ行い、処理予定を返す
+	if req.DryRun {
+		log.Printf("Test dry-run file: %s", req.FileID)
+		plan, err := h.services(c).FileSorter.PreviewFile(c.Request.Context(), req.FileID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "failed",
+				"file_id": req.FileID,
+				"error":   err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"dry_run": true,
+			"plan":    plan,
+		})
+		return
+	}
+
 	log.Printf("Test processing file: %s", req.FileID)
-	result := h.services.FileSorter.ProcessFile(c.Request.Context(), req.FileID)
+	result := h.services(c).FileSorter.ProcessFile(c.Request.Context(), req.FileID)
 
-	if result == model.ProcessResultProcessed || result == model.ProcessResultSkipped {
+	if result != model.ProcessResultError {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  "success",
 			"result":  string(result),
@@ -107,7 +136,7 @@ func (h *PubSubHandler) TestEndpoint(c *gin.Context) {
 
 // AdminInfo はストレージ情報を取得
 func (h *PubSubHandler) AdminInfo(c *gin.Context) {
-	about, err := h.services.DriveClient.GetAbout(c.Request.Context())
+	about, err := h.services(c).DriveClient.GetAbout(c.Request.Context())
 	if err != nil {
 		log.Printf("Error getting info: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -122,7 +151,7 @@ func (h *PubSubHandler) AdminInfo(c *gin.Context) {
 
 // AdminCleanup はSAのストレージクリーンアップを実行
 func (h *PubSubHandler) AdminCleanup(c *gin.Context) {
-	stats, err := h.services.DriveClient.CleanupServiceAccountStorage(c.Request.Context())
+	stats, err := h.services(c).DriveClient.CleanupServiceAccountStorage(c.Request.Context())
 	if err != nil {
 		log.Printf("Error executing cleanup: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -142,14 +171,19 @@ func (h *PubSubHandler) AdminPing(c *gin.Context) {
 
 // TriggerInbox はInboxフォルダ内の全ファイルを処理
 func (h *PubSubHandler) TriggerInbox(c *gin.Context) {
-	inboxID := config.FolderIDs["SOURCE"]
-	files, err := h.services.DriveClient.ListFilesInFolder(c.Request.Context(), inboxID, 50)
+	inboxID := h.services(c).Household.Current().FolderIDs["SOURCE"]
+	files, err := h.services(c).DriveClient.ListFilesInFolder(c.Request.Context(), inboxID, 50)
 	if err != nil {
 		log.Printf("Error listing files: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	if c.Query("dry_run") == "true" {
+		h.previewInbox(c, files)
+		return
+	}
+
 	results := map[string]interface{}{
 		"processed": 0,
 		"errors":    0,
@@ -166,7 +200,7 @@ func (h *PubSubHandler) TriggerInbox(c *gin.Context) {
 		fileName := file.Name
 		log.Printf("Inbox scan processing: %s (%s)", fileName, fileID)
 
-		result := h.services.FileSorter.ProcessFile(c.Request.Context(), fileID)
+		result := h.services(c).FileSorter.ProcessFile(c.Request.Context(), fileID)
 		detail := map[string]interface{}{
 			"id":     fileID,
 			"name":   fileName,
@@ -174,10 +208,10 @@ func (h *PubSubHandler) TriggerInbox(c *gin.Context) {
 		}
 
 		switch result {
-		case model.ProcessResultProcessed:
+		case model.ProcessResultProcessed, model.ProcessResultSplit:
 			processedCount++
 			results["processed"] = results["processed"].(int) + 1
-		case model.ProcessResultSkipped:
+		case model.ProcessResultSkipped, model.ProcessResultPendingReview, model.ProcessResultDuplicate:
 			skippedCount++
 			results["processed"] = results["processed"].(int) + 1
 		default:
@@ -189,9 +223,24 @@ func (h *PubSubHandler) TriggerInbox(c *gin.Context) {
 		results["details"] = append(results["details"].([]map[string]interface{}), detail)
 	}
 
+	// 途中で中断したファイル（Inbox外へ移動済みのものを含む）を再開
+	resumed, err := h.services(c).FileSorter.ResumeStuckFiles(c.Request.Context())
+	if err != nil {
+		log.Printf("Error resuming stuck files: %v", err)
+	}
+	for fileID, result := range resumed {
+		if result == model.ProcessResultError {
+			errorCount++
+		} else {
+			processedCount++
+		}
+		discordDetails = append(discordDetails, service.FileDetail{Name: fileID, Result: string(result)})
+	}
+	results["resumed"] = resumed
+
 	// Discord通知
-	if h.services.DiscordNotifier != nil {
-		h.services.DiscordNotifier.NotifyInboxScanResult(len(files), processedCount, skippedCount, errorCount, discordDetails)
+	if h.services(c).DiscordNotifier != nil {
+		h.services(c).DiscordNotifier.NotifyInboxScanResult(len(files)+len(resumed), processedCount, skippedCount, errorCount, discordDetails)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -200,6 +249,29 @@ func (h *PubSubHandler) TriggerInbox(c *gin.Context) {
 	})
 }
 
+// previewInbox はInbox内の全ファイルの処理予定を返す（滞留ファイルの再開・Discord通知は行わない）
+func (h *PubSubHandler) previewInbox(c *gin.Context, files []*model.FileInfo) {
+	plans := []*model.ProcessingPlan{}
+	failures := map[string]string{}
+
+	for _, file := range files {
+		log.Printf("Inbox dry-run: %s (%s)", file.Name, file.ID)
+		plan, err := h.services(c).FileSorter.PreviewFile(c.Request.Context(), file.ID)
+		if err != nil {
+			failures[file.ID] = err.Error()
+			continue
+		}
+		plans = append(plans, plan)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"dry_run": true,
+		"plans":   plans,
+		"errors":  failures,
+	})
+}
+
 // HandleDriveWebhook はGoogle Driveからの変更通知を処理
 func (h *PubSubHandler) HandleDriveWebhook(c *gin.Context) {
 	// Drive APIからの通知ヘッダーを確認
@@ -208,24 +280,33 @@ func (h *PubSubHandler) HandleDriveWebhook(c *gin.Context) {
 	channelToken := c.GetHeader("X-Goog-Channel-Token")
 	resourceState := c.GetHeader("X-Goog-Resource-State")
 
+	// チャンネルID・トークンからテナントを特定
+	tenant, ok := h.tenants.ForDriveChannel(channelID, channelToken)
+	if !ok {
+		log.Printf("Drive webhook for unknown tenant: channelID=%s", channelID)
+		c.JSON(http.StatusForbidden, gin.H{"error": "invalid webhook"})
+		return
+	}
+	c.Set(tenantContextKey, tenant)
+
 	// トークン検証（設定されている場合のみ）
-	if config.DriveWebhookToken != "" {
-		if channelToken == "" || channelToken != config.DriveWebhookToken {
+	if expected := tenant.Config.DriveWebhookToken; expected != "" {
+		if channelToken == "" || channelToken != expected {
 			log.Printf("Drive webhook token mismatch: channelID=%s", channelID)
 			c.JSON(http.StatusForbidden, gin.H{"error": "invalid webhook token"})
 			return
 		}
 		// トークン検証に成功 → 正当なWatch通知として処理を続行
-	} else if h.watchManager != nil {
+	} else if h.watchManager(c) != nil {
 		// トークン未設定の場合のみ、Watch情報で照合（フォールバック検証）
-		if ok, reason := h.watchManager.ValidateNotification(channelID, resourceID); !ok {
+		if ok, reason := h.watchManager(c).ValidateNotification(channelID, resourceID); !ok {
 			log.Printf("Drive webhook validation failed: %s (channelID=%s)", reason, channelID)
 			c.JSON(http.StatusForbidden, gin.H{"error": "invalid webhook"})
 			return
 		}
 	}
 
-	log.Printf("Drive webhook received: channelID=%s, resourceID=%s, state=%s", channelID, resourceID, resourceState)
+	log.Printf("Drive webhook received: tenant=%s, channelID=%s, resourceID=%s, state=%s", tenant.Config.ID, channelID, resourceID, resourceState)
 
 	// syncは初期確認なのでスキップ
 	if resourceState == "sync" {
@@ -236,17 +317,17 @@ func (h *PubSubHandler) HandleDriveWebhook(c *gin.Context) {
 
 	// 変更があった場合は処理
 	if resourceState == "change" {
-		if h.watchManager == nil {
+		if h.watchManager(c) == nil {
 			log.Printf("WatchManager not initialized")
 			c.JSON(http.StatusOK, gin.H{"status": "OK", "message": "watchManager not available"})
 			return
 		}
 
-		processed, err := h.watchManager.HandleNotification(c.Request.Context())
+		processed, err := h.watchManager(c).HandleNotification(c.Request.Context())
 		if err != nil {
 			log.Printf("Error processing notification: %v", err)
-			if h.services.DiscordNotifier != nil {
-				h.services.DiscordNotifier.NotifyError("webhook処理", err.Error())
+			if h.services(c).DiscordNotifier != nil {
+				h.services(c).DiscordNotifier.NotifyError("webhook処理", err.Error())
 			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -265,12 +346,12 @@ func (h *PubSubHandler) HandleDriveWebhook(c *gin.Context) {
 
 // WatchStart はWatch監視を開始
 func (h *PubSubHandler) WatchStart(c *gin.Context) {
-	if h.watchManager == nil {
+	if h.watchManager(c) == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "watchManager not initialized"})
 		return
 	}
 
-	if err := h.watchManager.StartWatch(c.Request.Context()); err != nil {
+	if err := h.watchManager(c).StartWatch(c.Request.Context()); err != nil {
 		log.Printf("Failed to start watch: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -279,18 +360,18 @@ func (h *PubSubHandler) WatchStart(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "OK",
 		"message": "Watch started",
-		"watch":   h.watchManager.GetStatus(),
+		"watch":   h.watchManager(c).GetStatus(),
 	})
 }
 
 // WatchRenew はWatchを更新
 func (h *PubSubHandler) WatchRenew(c *gin.Context) {
-	if h.watchManager == nil {
+	if h.watchManager(c) == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "watchManager not initialized"})
 		return
 	}
 
-	if err := h.watchManager.RenewWatch(c.Request.Context()); err != nil {
+	if err := h.watchManager(c).RenewWatch(c.Request.Context()); err != nil {
 		log.Printf("Failed to renew watch: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -299,18 +380,18 @@ func (h *PubSubHandler) WatchRenew(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "OK",
 		"message": "Watch renewed",
-		"watch":   h.watchManager.GetStatus(),
+		"watch":   h.watchManager(c).GetStatus(),
 	})
 }
 
 // WatchStop はWatch監視を停止
 func (h *PubSubHandler) WatchStop(c *gin.Context) {
-	if h.watchManager == nil {
+	if h.watchManager(c) == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "watchManager not initialized"})
 		return
 	}
 
-	if err := h.watchManager.StopWatch(c.Request.Context()); err != nil {
+	if err := h.watchManager(c).StopWatch(c.Request.Context()); err != nil {
 		log.Printf("Failed to stop watch: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -324,7 +405,7 @@ func (h *PubSubHandler) WatchStop(c *gin.Context) {
 
 // WatchStatus はWatch状態を取得
 func (h *PubSubHandler) WatchStatus(c *gin.Context) {
-	if h.watchManager == nil {
+	if h.watchManager(c) == nil {
 		c.JSON(http.StatusOK, gin.H{
 			"status": "OK",
 			"watch":  map[string]interface{}{"active": false, "message": "watchManager not initialized"},
@@ -334,6 +415,6 @@ func (h *PubSubHandler) WatchStatus(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"status": "OK",
-		"watch":  h.watchManager.GetStatus(),
+		"watch":  h.watchManager(c).GetStatus(),
 	})
 }
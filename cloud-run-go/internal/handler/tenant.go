@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leo-sagawa/homedocmanager/internal/service"
+)
+
+const tenantContextKey = "tenant"
+
+// TenantMiddleware は ?tenant= または X-Tenant-ID ヘッダーで指定されたテナントを選択する
+// 指定がない場合は既定のテナントで処理する
+func (h *PubSubHandler) TenantMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Query("tenant")
+		if tenantID == "" {
+			tenantID = c.GetHeader("X-Tenant-ID")
+		}
+		if tenantID != "" && !h.setTenant(c, tenantID) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "unknown tenant: " + tenantID})
+			return
+		}
+		c.Next()
+	}
+}
+
+// setTenant はリクエストの処理対象テナントを設定する（未登録の場合はfalse）
+func (h *PubSubHandler) setTenant(c *gin.Context, tenantID string) bool {
+	t, ok := h.tenants.Get(tenantID)
+	if !ok {
+		return false
+	}
+	c.Set(tenantContextKey, t)
+	return true
+}
+
+// tenant はリクエストの処理対象テナントを返す
+func (h *PubSubHandler) tenant(c *gin.Context) *service.Tenant {
+	if v, ok := c.Get(tenantContextKey); ok {
+		if t, ok := v.(*service.Tenant); ok {
+			return t
+		}
+	}
+	return h.tenants.Default()
+}
+
+func (h *PubSubHandler) services(c *gin.Context) *service.Services {
+	return h.tenant(c).Services
+}
+
+func (h *PubSubHandler) watchManager(c *gin.Context) *service.WatchManager {
+	return h.tenant(c).WatchManager
+}
+
+// AdminTenants は登録済みのテナント一覧を返す
+func (h *PubSubHandler) AdminTenants(c *gin.Context) {
+	tenants := make([]gin.H, 0, len(h.tenants.List()))
+	for _, t := range h.tenants.List() {
+		tenants = append(tenants, gin.H{
+			"id":        t.Config.ID,
+			"household": t.Services.Household.Current().Name,
+			"line":      t.Config.LineChannelSecret != "",
+			"watch":     t.WatchManager != nil && t.WatchManager.ChannelID() != "",
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"default": h.tenants.Default().Config.ID,
+		"tenants": tenants,
+	})
+}
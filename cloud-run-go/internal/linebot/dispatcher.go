@@ -0,0 +1,87 @@
+package linebot
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Dispatcher は複数世帯（テナント）のLINEチャネルへWebhookを振り分ける
+// /callback/:tenant はパスで、/callback は Webhook の destination（ボットのユーザーID）で振り分ける
+type Dispatcher struct {
+	handlers      map[string]*Handler // key: テナントID
+	byDestination map[string]*Handler // key: ボットのユーザーID
+	defaultID     string
+}
+
+// NewDispatcher は新しいDispatcherを作成（defaultID は振り分け先が不明な場合の送信先）
+func NewDispatcher(defaultID string) *Dispatcher {
+	return &Dispatcher{
+		handlers:      make(map[string]*Handler),
+		byDestination: make(map[string]*Handler),
+		defaultID:     defaultID,
+	}
+}
+
+// Register はテナントのハンドラーを登録する
+func (d *Dispatcher) Register(tenantID, botUserID string, h *Handler) {
+	d.handlers[tenantID] = h
+	if botUserID != "" {
+		d.byDestination[botUserID] = h
+	}
+}
+
+// Len は登録済みのハンドラー数を返す
+func (d *Dispatcher) Len() int {
+	return len(d.handlers)
+}
+
+// HandleWebhook はテナントを特定してWebhookを処理する
+// 署名検証は振り分け先のチャネルシークレットで行われる
+func (d *Dispatcher) HandleWebhook(c *gin.Context) {
+	if tenantID := c.Param("tenant"); tenantID != "" {
+		h, ok := d.handlers[tenantID]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown tenant"})
+			return
+		}
+		h.HandleWebhook(c)
+		return
+	}
+
+	if h := d.handlerForDestination(c); h != nil {
+		h.HandleWebhook(c)
+		return
+	}
+	if h, ok := d.handlers[d.defaultID]; ok {
+		h.HandleWebhook(c)
+		return
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "unknown tenant"})
+}
+
+// handlerForDestination はリクエストボディの destination からハンドラーを探す
+// ボディは署名検証のため読み戻せるようにしておく
+func (d *Dispatcher) handlerForDestination(c *gin.Context) *Handler {
+	if len(d.byDestination) == 0 {
+		return nil
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Printf("[LINE] Failed to read webhook body: %v", err)
+		return nil
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var payload struct {
+		Destination string `json:"destination"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil
+	}
+	return d.byDestination[payload.Destination]
+}
@@ -13,9 +13,14 @@ import (
 )
 
 type Handler struct {
-	bot        *linebot.Client
-	service    *Service
-	ragService *RAGService
+	bot            *linebot.Client
+	service        *Service
+	ragService     *RAGService
+	reviewResolver ReviewResolver
+
+	// レビュー依頼の送信先と、回答を受け付ける家族グループ
+	reviewTargetID string
+	familyGroupID  string
 }
 
 // NewHandler は新しいLINE Webhookハンドラーを作成
@@ -60,6 +65,15 @@ func (h *Handler) HandleWebhook(c *gin.Context) {
 					userID, groupID, sourceType, truncateText(message.Text, 50))
 				h.handleTextMessage(event.ReplyToken, userID, groupID, message.Text)
 			}
+		} else if event.Type == linebot.EventTypePostback && event.Postback != nil {
+			userID := ""
+			groupID := ""
+			if event.Source != nil {
+				userID = event.Source.UserID
+				groupID = event.Source.GroupID
+			}
+			log.Printf("[LINE] Postback received - UserID: %s, GroupID: %s, Data: %s", userID, groupID, event.Postback.Data)
+			h.handlePostback(event.ReplyToken, userID, groupID, event.Postback.Data)
 		}
 	}
 
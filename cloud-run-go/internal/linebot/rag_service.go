@@ -3,7 +3,9 @@ package linebot
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
 	"strings"
@@ -29,6 +31,8 @@ type DriveClientInterface interface {
 type RAGService struct {
 	driveClient     DriveClientInterface
 	geminiClient    *genai.Client
+	household       *config.HouseholdStore
+	settingsPath    string
 	userMap         map[string]string
 	mu              sync.RWMutex // ユーザーマップおよびキャッシュ更新用
 	documentIDs     []string     // 個別に指定されたドキュメントID
@@ -36,6 +40,9 @@ type RAGService struct {
 	modelName       string
 	systemPrompt    string
 
+	// 表示名から自動登録したユーザー（リロード時も維持）
+	learnedUsers map[string]string
+
 	// キャッシュ
 	docCache   string
 	cacheValid bool
@@ -57,23 +64,17 @@ type RAGUserSettings struct {
 
 // NewRAGService は新しいRAGサービスを作成
 // geminiAPIKey が空の場合はnilを返す（RAG機能無効）
-func NewRAGService(ctx context.Context, geminiAPIKey string, settingsPath string, driveClient DriveClientInterface) (*RAGService, error) {
+func NewRAGService(ctx context.Context, geminiAPIKey string, settingsPath string, driveClient DriveClientInterface, household *config.HouseholdStore) (*RAGService, error) {
 	if geminiAPIKey == "" {
 		return nil, nil // RAG機能無効
 	}
+	h := household.Current()
 
 	// 設定ファイルを読み込み
 	settings, err := loadRAGUserSettings(settingsPath)
 	if err != nil {
 		// 設定ファイルがなくてもデフォルト値で動作
-		settings = &RAGUserSettings{
-			UserMap:            config.LineUserMap,
-			RAGDocumentIDs:     config.RAGDocumentIDs,
-			RAGSourceFolderIDs: config.LineRAGSourceFolderIDs,
-		}
-		settings.RAGSettings.Model = config.GeminiModelsConfig.LineRAG
-		settings.RAGSettings.Temperature = 0.0
-		settings.RAGSettings.SystemPromptTemplate = defaultSystemPrompt()
+		settings = defaultRAGUserSettings(h)
 	}
 
 	// ドキュメントIDとソースフォルダIDが両方空の場合はRAG機能無効
@@ -93,6 +94,32 @@ func NewRAGService(ctx context.Context, geminiAPIKey string, settingsPath string
 		return nil, fmt.Errorf("failed to create gemini client: %w", err)
 	}
 
+	r := &RAGService{
+		driveClient:  driveClient,
+		geminiClient: geminiClient,
+		household:    household,
+		settingsPath: settingsPath,
+		cacheValid:   false,
+	}
+	r.applySettings(h, settings)
+	return r, nil
+}
+
+func defaultRAGUserSettings(h *config.Household) *RAGUserSettings {
+	settings := &RAGUserSettings{
+		UserMap:            h.LineUserMap,
+		RAGDocumentIDs:     h.RAGDocumentIDs,
+		RAGSourceFolderIDs: h.RAGSourceFolderIDs(),
+	}
+	settings.RAGSettings.Model = config.GeminiModelsConfig.LineRAG
+	settings.RAGSettings.Temperature = 0.0
+	settings.RAGSettings.SystemPromptTemplate = defaultSystemPrompt()
+	return settings
+}
+
+// applySettings は設定を反映する（呼び出し側でロックを取得すること）
+// 表示名から自動登録したユーザーは維持し、設定ファイルの紐付けを優先する
+func (r *RAGService) applySettings(h *config.Household, settings *RAGUserSettings) {
 	modelName := settings.RAGSettings.Model
 	if modelName == "" {
 		modelName = config.GeminiModelsConfig.LineRAG
@@ -103,18 +130,44 @@ func NewRAGService(ctx context.Context, geminiAPIKey string, settingsPath string
 		systemPrompt = defaultSystemPrompt()
 	}
 
-	return &RAGService{
-		driveClient:     driveClient,
-		geminiClient:    geminiClient,
-		userMap:         mergeUserMaps(config.LineUserMap, settings.UserMap),
-		documentIDs:     settings.RAGDocumentIDs,
-		sourceFolderIDs: settings.RAGSourceFolderIDs,
-		modelName:       modelName,
-		systemPrompt:    systemPrompt,
-		cacheValid:      false,
+	r.userMap = mergeUserMaps(r.learnedUsers, mergeUserMaps(h.LineUserMap, settings.UserMap))
+	r.documentIDs = settings.RAGDocumentIDs
+	r.sourceFolderIDs = settings.RAGSourceFolderIDs
+	r.modelName = modelName
+	r.systemPrompt = systemPrompt
+}
+
+// PrepareReload はRAG設定ファイルを読み込んで検証し、適用関数を返す
+// ファイルがない場合は世帯設定から既定値を作り、不正な場合は現在の設定を維持する
+func (r *RAGService) PrepareReload(ctx context.Context) (func(), error) {
+	settings, err := loadRAGUserSettings(r.settingsPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to load RAG settings %s: %w", r.settingsPath, err)
+	}
+
+	return func() {
+		// 世帯設定は同じリロード内で先に差し替わるため、適用時点の設定を参照する
+		h := r.household.Current()
+		if settings == nil {
+			settings = defaultRAGUserSettings(h)
+		}
+		if len(settings.RAGDocumentIDs) == 0 && len(settings.RAGSourceFolderIDs) == 0 {
+			log.Printf("[RAG] Warning: no documents or folders configured after reload")
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.applySettings(h, settings)
+		r.cacheValid = false
+		log.Printf("[RAG] Settings reloaded (model: %s), cache invalidated", r.modelName)
 	}, nil
 }
 
+// Fingerprint はRAG設定ファイルの変更検知用の値を返す
+func (r *RAGService) Fingerprint(ctx context.Context) (string, error) {
+	return config.FileFingerprint(r.settingsPath), nil
+}
+
 func loadRAGUserSettings(path string) (*RAGUserSettings, error) {
 	b, err := os.ReadFile(path)
 	if err != nil {
@@ -338,7 +391,11 @@ func (r *RAGService) UpdateUser(userID, name string) {
 	if r.userMap == nil {
 		r.userMap = make(map[string]string)
 	}
+	if r.learnedUsers == nil {
+		r.learnedUsers = make(map[string]string)
+	}
 	r.userMap[userID] = name
+	r.learnedUsers[userID] = name
 }
 
 // IsUserKnown はUserIDが既にマップにあるか確認
@@ -349,9 +406,16 @@ func (r *RAGService) IsUserKnown(userID string) bool {
 	return ok
 }
 
+// UserName はUserIDに紐付いた名前を返す（未登録時は空文字）
+func (r *RAGService) UserName(userID string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.userMap[userID]
+}
+
 // IdentifyUserByDisplayName は表示名から大人メンバーの名前を特定
 func (r *RAGService) IdentifyUserByDisplayName(displayName string) string {
-	for name, aliases := range config.AdultAliases {
+	for name, aliases := range r.household.Current().AdultAliases {
 		if name == displayName {
 			return name
 		}
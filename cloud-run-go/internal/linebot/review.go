@@ -0,0 +1,282 @@
+package linebot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+	"github.com/line/line-bot-sdk-go/v7/linebot"
+)
+
+// ReviewResolver はレビュー回答を仕分け処理に反映する（循環参照を避けるためのインターフェース）
+type ReviewResolver interface {
+	RecordReview(ctx context.Context, fileID, category, decidedBy string) error
+	RecordDuplicate(ctx context.Context, fileID, decidedBy string) error
+	ProcessFile(ctx context.Context, fileID string) model.ProcessResult
+	ReviewCategories() []string
+}
+
+// レビュー用postbackのアクション
+const (
+	reviewActionApprove = "approve"
+	reviewActionChange  = "change"
+	reviewActionSet     = "set"
+	// 見た目が似ている仕分け済みの書類の重複として移動
+	reviewActionDuplicate = "duplicate"
+)
+
+// reviewResolveTimeout はレビュー回答後の仕分けの制限時間（処理台帳のリースより短く）
+const reviewResolveTimeout = 5 * time.Minute
+
+// SetReviewResolver はレビュー回答の反映先を設定
+func (h *Handler) SetReviewResolver(resolver ReviewResolver) {
+	h.reviewResolver = resolver
+}
+
+// SetReviewTarget はレビュー依頼の送信先と家族グループIDを設定
+func (h *Handler) SetReviewTarget(targetID, familyGroupID string) {
+	h.reviewTargetID = targetID
+	h.familyGroupID = familyGroupID
+}
+
+// NotifyReview は分類結果の確認依頼をFlex Messageで送信
+func (h *Handler) NotifyReview(ctx context.Context, req *model.ReviewRequest) error {
+	if h.reviewTargetID == "" {
+		return fmt.Errorf("LINE_REVIEW_TARGET_ID / LINE_FAMILY_GROUP_ID が設定されていません")
+	}
+
+	b, err := json.Marshal(buildReviewBubble(req))
+	if err != nil {
+		return fmt.Errorf("failed to marshal review message: %w", err)
+	}
+	container, err := linebot.UnmarshalFlexMessageJSON(b)
+	if err != nil {
+		return fmt.Errorf("failed to build review message: %w", err)
+	}
+
+	msg := linebot.NewFlexMessage("📄 仕分け確認: "+req.FileName, container)
+	if _, err := h.bot.PushMessage(h.reviewTargetID, msg).WithContext(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to push review message: %w", err)
+	}
+	log.Printf("[LINE] Review requested: %s (%s)", req.FileName, req.FileID)
+	return nil
+}
+
+// buildReviewBubble はレビュー依頼のbubbleを作成
+func buildReviewBubble(req *model.ReviewRequest) map[string]interface{} {
+	target := req.TargetAdult
+	if len(req.TargetChildren) > 0 {
+		target = strings.Join(req.TargetChildren, "・")
+	}
+	if target == "" {
+		target = "-"
+	}
+	category := req.Category
+	if req.SubCategory != "" {
+		category += " / " + req.SubCategory
+	}
+
+	row := func(label, value string) map[string]interface{} {
+		return map[string]interface{}{
+			"type": "box", "layout": "baseline", "spacing": "sm",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": label, "size": "sm", "color": "#888888", "flex": 2},
+				map[string]interface{}{"type": "text", "text": value, "size": "sm", "wrap": true, "flex": 5},
+			},
+		}
+	}
+
+	body := []interface{}{
+		map[string]interface{}{"type": "text", "text": "📄 仕分けの確認をお願いします", "weight": "bold", "wrap": true},
+		map[string]interface{}{"type": "text", "text": fmt.Sprintf("AIの自信度: %.0f%%", req.ConfidenceScore*100), "size": "xs", "color": "#888888"},
+		row("元ファイル", req.FileName),
+		row("カテゴリ", category),
+		row("対象", target),
+		row("新しい名前", req.NewFileName),
+	}
+	buttons := []interface{}{
+		map[string]interface{}{
+			"type": "button", "style": "primary",
+			"action": map[string]interface{}{
+				"type": "postback", "label": "✅ この内容で承認",
+				"data":        reviewPostbackData(reviewActionApprove, req.FileID, ""),
+				"displayText": "承認",
+			},
+		},
+		map[string]interface{}{
+			"type": "button", "style": "secondary",
+			"action": map[string]interface{}{
+				"type": "postback", "label": "✏️ カテゴリを変更",
+				"data":        reviewPostbackData(reviewActionChange, req.FileID, ""),
+				"displayText": "カテゴリを変更",
+			},
+		},
+	}
+	// 見た目が似ている書類がある場合は、重複かどうかも確認する
+	if req.SimilarTo != nil {
+		body = append(body, row("似た書類", req.SimilarTo.FileName))
+		buttons = append(buttons,
+			map[string]interface{}{
+				"type": "button", "style": "secondary",
+				"action": map[string]interface{}{
+					"type": "postback", "label": "🗂 重複として移動",
+					"data":        reviewPostbackData(reviewActionDuplicate, req.FileID, ""),
+					"displayText": "重複として移動",
+				},
+			},
+			map[string]interface{}{
+				"type": "button", "style": "link",
+				"action": map[string]interface{}{
+					"type": "uri", "label": "似た書類を開く",
+					"uri": fmt.Sprintf("https://drive.google.com/file/d/%s/view", req.SimilarTo.FileID),
+				},
+			},
+		)
+	}
+	buttons = append(buttons, map[string]interface{}{
+		"type": "button", "style": "link",
+		"action": map[string]interface{}{
+			"type": "uri", "label": "ファイルを開く",
+			"uri": fmt.Sprintf("https://drive.google.com/file/d/%s/view", req.FileID),
+		},
+	})
+
+	return map[string]interface{}{
+		"type": "bubble",
+		"body": map[string]interface{}{
+			"type": "box", "layout": "vertical", "spacing": "md",
+			"contents": body,
+		},
+		"footer": map[string]interface{}{
+			"type": "box", "layout": "vertical", "spacing": "sm",
+			"contents": buttons,
+		},
+	}
+}
+
+// reviewPostbackData はレビュー用postbackデータを作成
+func reviewPostbackData(action, fileID, category string) string {
+	v := url.Values{}
+	v.Set("review", action)
+	v.Set("file", fileID)
+	if category != "" {
+		v.Set("category", category)
+	}
+	return v.Encode()
+}
+
+// handlePostback はpostbackイベントを処理
+func (h *Handler) handlePostback(replyToken, userID, groupID, data string) {
+	values, err := url.ParseQuery(data)
+	if err != nil || values.Get("review") == "" {
+		log.Printf("[LINE] Unknown postback: %s", data)
+		return
+	}
+	h.handleReviewPostback(replyToken, userID, groupID, values)
+}
+
+// handleReviewPostback はレビューの回答（承認・カテゴリ変更）を処理
+func (h *Handler) handleReviewPostback(replyToken, userID, groupID string, values url.Values) {
+	// 家族グループ以外からの回答は受け付けない
+	if h.familyGroupID != "" && groupID != h.familyGroupID {
+		log.Printf("[LINE] Review postback from outside family group ignored: user=%s group=%s", userID, groupID)
+		return
+	}
+	if h.reviewResolver == nil {
+		h.replyErrorMessage(replyToken, "仕分けレビュー機能が無効です。")
+		return
+	}
+
+	fileID := values.Get("file")
+	switch values.Get("review") {
+	case reviewActionChange:
+		h.replyCategoryChoices(replyToken, fileID)
+		return
+	case reviewActionApprove, reviewActionSet, reviewActionDuplicate:
+	default:
+		return
+	}
+
+	decidedBy := userID
+	if h.ragService != nil {
+		if name := h.ragService.UserName(userID); name != "" {
+			decidedBy = name
+		}
+	}
+
+	// 回答は台帳に記録してから応答する（仕分けが途中で止まっても、滞留ファイルの再開で記録した回答どおりに続ける）
+	category := values.Get("category")
+	var err error
+	if values.Get("review") == reviewActionDuplicate {
+		err = h.reviewResolver.RecordDuplicate(context.Background(), fileID, decidedBy)
+	} else {
+		err = h.reviewResolver.RecordReview(context.Background(), fileID, category, decidedBy)
+	}
+	if err != nil {
+		log.Printf("[LINE] Review record failed for %s: %v", fileID, err)
+		h.replyErrorMessage(replyToken, "⚠️ 回答を受け付けられませんでした（回答済み、または処理中の可能性があります）。")
+		return
+	}
+	if _, err := h.bot.ReplyMessage(replyToken, linebot.NewTextMessage("受け付けました。仕分けが終わったらお知らせします。")).Do(); err != nil {
+		log.Printf("Error replying review acceptance: %v", err)
+	}
+
+	// 仕分けはAIの再実行やDriveの移動で時間がかかるため、Webhookへの応答後に行う
+	// （Cloud RunはCPUを常に割り当ててデプロイする。deploy-cloudbuild.sh の --no-cpu-throttling を参照）
+	notifyTo := groupID
+	if notifyTo == "" {
+		notifyTo = userID
+	}
+	go h.processReviewed(notifyTo, fileID, category)
+}
+
+// processReviewed は回答を記録したファイルの仕分けを再開し、結果を回答のあったトークへ送る
+func (h *Handler) processReviewed(notifyTo, fileID, category string) {
+	ctx, cancel := context.WithTimeout(context.Background(), reviewResolveTimeout)
+	defer cancel()
+
+	var msg string
+	switch result := h.reviewResolver.ProcessFile(ctx, fileID); {
+	case result == model.ProcessResultDuplicate:
+		msg = "🗂 重複として「重複」フォルダへ移動しました。"
+	case result == model.ProcessResultProcessed && category != "":
+		msg = fmt.Sprintf("✅ 「%s」に仕分けました。", category)
+	case result == model.ProcessResultProcessed:
+		msg = "✅ 仕分けが完了しました。"
+	case result == model.ProcessResultError:
+		msg = "⚠️ 仕分け中にエラーが発生しました。管理者に確認してください。"
+	default:
+		msg = fmt.Sprintf("回答を反映しました（%s）。", result)
+	}
+	if _, err := h.bot.PushMessage(notifyTo, linebot.NewTextMessage(msg)).WithContext(ctx).Do(); err != nil {
+		log.Printf("Error pushing review result: %v", err)
+	}
+}
+
+// replyCategoryChoices はカテゴリ選択のQuick Replyを返信
+func (h *Handler) replyCategoryChoices(replyToken, fileID string) {
+	categories := h.reviewResolver.ReviewCategories()
+
+	var items []*linebot.QuickReplyButton
+	for _, category := range categories {
+		items = append(items, linebot.NewQuickReplyButton("", linebot.NewPostbackAction(
+			category,
+			reviewPostbackData(reviewActionSet, fileID, category),
+			"",
+			category,
+			"",
+			"",
+		)))
+	}
+
+	msg := linebot.NewTextMessage("仕分け先のカテゴリを選んでください。").
+		WithQuickReplies(linebot.NewQuickReplyItems(items...))
+	if _, err := h.bot.ReplyMessage(replyToken, msg).Do(); err != nil {
+		log.Printf("Error replying category choices: %v", err)
+	}
+}
@@ -1,12 +1,16 @@
 package linebot
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"strings"
 	"sync"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
 )
 
 type QuickReplyConfig struct {
@@ -32,6 +36,7 @@ type FlexTemplate struct {
 }
 
 type Service struct {
+	settingsPath   string
 	settings       *Settings
 	template       *FlexTemplate
 	helpTemplate   *FlexTemplate
@@ -40,35 +45,86 @@ type Service struct {
 }
 
 func NewService(settingsPath string) (*Service, error) {
-	s, err := loadSettings(settingsPath)
+	svc := &Service{settingsPath: settingsPath}
+	apply, err := svc.PrepareReload(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	apply()
+	return svc, nil
+}
+
+// PrepareReload は設定ファイルとFlexテンプレートを読み込んで検証し、適用関数を返す
+// 検証に失敗した場合は現在の設定を変更しない
+func (s *Service) PrepareReload(ctx context.Context) (func(), error) {
+	settings, err := loadSettings(s.settingsPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load settings: %w", err)
 	}
+	if err := settings.validate(); err != nil {
+		return nil, fmt.Errorf("invalid settings %s: %w", s.settingsPath, err)
+	}
 
-	t, err := loadTemplate(s.FlexTemplatePath)
+	t, err := loadTemplate(settings.FlexTemplatePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load template from %s: %w", s.FlexTemplatePath, err)
+		return nil, fmt.Errorf("failed to load template from %s: %w", settings.FlexTemplatePath, err)
+	}
+	// プレースホルダ置換後もJSONとして成立するか確認
+	if _, err := t.build(map[string]string{"TITLE": "t", "SUBTITLE": "s", "NOTEBOOKLM_URL": "u", "EXAMPLE_1": "e1", "EXAMPLE_2": "e2"}); err != nil {
+		return nil, fmt.Errorf("invalid template %s: %w", settings.FlexTemplatePath, err)
 	}
 
-	h, err := loadTemplate(s.HelpTemplatePath)
+	h, err := loadTemplate(settings.HelpTemplatePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load help template from %s: %w", s.HelpTemplatePath, err)
+		return nil, fmt.Errorf("failed to load help template from %s: %w", settings.HelpTemplatePath, err)
 	}
 
-	a, err := loadTemplate(s.AITipsTemplatePath)
+	a, err := loadTemplate(settings.AITipsTemplatePath)
 	if err != nil {
 		// AI Tips テンプレートがない場合は警告のみ（後方互換性）
 		log.Printf("Warning: ai_tips_template_path not found or failed to load: %v", err)
 	}
 
-	return &Service{
-		settings:       s,
-		template:       t,
-		helpTemplate:   h,
-		aiTipsTemplate: a,
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.settings = settings
+		s.template = t
+		s.helpTemplate = h
+		s.aiTipsTemplate = a
 	}, nil
 }
 
+// Fingerprint は設定ファイルとテンプレートの変更検知用の値を返す
+func (s *Service) Fingerprint(ctx context.Context) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return config.FileFingerprint(
+		s.settingsPath,
+		s.settings.FlexTemplatePath,
+		s.settings.HelpTemplatePath,
+		s.settings.AITipsTemplatePath,
+	), nil
+}
+
+// validate は設定内容を検証する
+func (s *Settings) validate() error {
+	if len(s.Triggers) == 0 {
+		return errors.New("triggers is empty")
+	}
+	seen := make(map[string]string, len(s.Triggers))
+	for category, trigger := range s.Triggers {
+		if trigger == "" {
+			return fmt.Errorf("triggers.%s is empty", category)
+		}
+		if other, ok := seen[trigger]; ok {
+			return fmt.Errorf("triggers.%s and triggers.%s share %q", other, category, trigger)
+		}
+		seen[trigger] = category
+	}
+	return nil
+}
+
 func loadSettings(path string) (*Settings, error) {
 	b, err := os.ReadFile(path)
 	if err != nil {
@@ -13,6 +13,10 @@ type AnalysisResult struct {
 	Date             string  `json:"date"`
 	Summary          string  `json:"summary"`
 	ConfidenceScore  float64 `json:"confidence_score"`
+	// 発行元（学校・園・塾・クラブ名、先生の名前など。子供の特定に使う）
+	Issuer string `json:"issuer,omitempty"`
+	// 仕分けルールで指定されたファイル名テンプレート（AIは出力しない）
+	FilenameTemplate string `json:"filename_template,omitempty"`
 	// 内部処理用フィールド
 	FiscalYear         int      `json:"-"`
 	TargetChildren     []string `json:"-"`
@@ -37,6 +41,13 @@ type Event struct {
 	Description string  `json:"description"`
 }
 
+// CalendarDestination は予定の登録先カレンダーと、予定の色・招待する家族のメールアドレス
+type CalendarDestination struct {
+	CalendarID string   `json:"calendar_id"`
+	ColorID    string   `json:"color_id,omitempty"`
+	Attendees  []string `json:"attendees,omitempty"`
+}
+
 // Task はタスク
 type Task struct {
 	Title   string `json:"title"`
@@ -57,7 +68,8 @@ type PubSubMessage struct {
 
 // FileData はPub/Subメッセージのデータ
 type FileData struct {
-	FileID string `json:"file_id"`
+	FileID   string `json:"file_id"`
+	TenantID string `json:"tenant_id,omitempty"`
 }
 
 // FileInfo はGoogle Driveファイル情報
@@ -66,6 +78,8 @@ type FileInfo struct {
 	Name     string   `json:"name"`
 	MimeType string   `json:"mimeType"`
 	Parents  []string `json:"parents"`
+	// アップロードしたユーザーのメールアドレス（取得できない場合は空）
+	Uploader string `json:"uploader,omitempty"`
 }
 
 // ProcessResult はファイル処理結果
@@ -75,6 +89,12 @@ const (
 	ProcessResultProcessed ProcessResult = "PROCESSED"
 	ProcessResultSkipped   ProcessResult = "SKIPPED"
 	ProcessResultError     ProcessResult = "ERROR"
+	// 信頼度が低く、家族のレビュー待ちとなった
+	ProcessResultPendingReview ProcessResult = "PENDING_REVIEW"
+	// 既存の書類と同一と判定され、「重複」フォルダへ移動した
+	ProcessResultDuplicate ProcessResult = "DUPLICATE"
+	// 複数の書類に分割してInboxに戻した（分割後のファイルはそれぞれ別に処理する）
+	ProcessResultSplit ProcessResult = "SPLIT"
 )
 
 // OCRBundle はOCR結果の構造化データ
@@ -92,8 +112,490 @@ type OCRBundle struct {
 
 // DocumentBundle は統合解析結果
 type DocumentBundle struct {
-	Analysis       *AnalysisResult  `json:"analysis"`
-	EventsAndTasks *EventsAndTasks  `json:"events_and_tasks"`
-	OCRBundle      *OCRBundle       `json:"ocr_bundle"`
-	Warnings       []string         `json:"warnings,omitempty"`
+	Analysis       *AnalysisResult `json:"analysis"`
+	EventsAndTasks *EventsAndTasks `json:"events_and_tasks"`
+	OCRBundle      *OCRBundle      `json:"ocr_bundle"`
+	Warnings       []string        `json:"warnings,omitempty"`
+}
+
+// ProcessingState はファイル処理台帳上の進行状態
+type ProcessingState string
+
+const (
+	ProcessingStateReceived   ProcessingState = "received"
+	ProcessingStateDownloaded ProcessingState = "downloaded"
+	ProcessingStateAnalyzed   ProcessingState = "analyzed"
+	ProcessingStateRenamed    ProcessingState = "renamed"
+	ProcessingStateMoved      ProcessingState = "moved"
+	ProcessingStateCompleted  ProcessingState = "completed" // 追加アクションまで完了
+	ProcessingStateSkipped    ProcessingState = "skipped"
+	// 失敗してデッドレターに入った状態（管理者の再処理・破棄待ち）
+	ProcessingStateDeadLettered ProcessingState = "dead_lettered"
+	// 低信頼度の分類結果が家族のレビュー待ちの状態
+	ProcessingStateAwaitingReview ProcessingState = "awaiting_review"
+	// 既存の書類の重複として「重複」フォルダへ移動した状態
+	ProcessingStateDuplicate ProcessingState = "duplicate"
+	// 複数の書類に分割し、元のPDFを「分割済み」フォルダへ移動した状態
+	ProcessingStateSplit ProcessingState = "split"
+)
+
+// IsTerminal は再処理不要な状態かどうかを返す
+// デッドレター・レビュー待ちは自動再開の対象外とし、管理者・家族の操作でのみ再処理する
+func (s ProcessingState) IsTerminal() bool {
+	switch s {
+	case ProcessingStateCompleted, ProcessingStateSkipped, ProcessingStateDeadLettered, ProcessingStateAwaitingReview,
+		ProcessingStateDuplicate, ProcessingStateSplit:
+		return true
+	}
+	return false
+}
+
+// LedgerTransition は状態遷移の履歴1件
+type LedgerTransition struct {
+	State  ProcessingState `json:"state"`
+	At     time.Time       `json:"at"`
+	Detail string          `json:"detail,omitempty"`
+}
+
+// StepName はファイル処理パイプラインのステップ名
+type StepName string
+
+const (
+	StepDedup              StepName = "dedup"
+	StepSplit              StepName = "split"
+	StepAnalyze            StepName = "analyze"
+	StepResolveDestination StepName = "resolve_destination"
+	StepRename             StepName = "rename"
+	StepMove               StepName = "move"
+	StepPhotos             StepName = "photos"
+	StepCalendar           StepName = "calendar"
+	StepTasks              StepName = "tasks"
+	StepNotebookLM         StepName = "notebooklm"
+	StepMedicalExpense     StepName = "medical_expense"
+	StepFinance            StepName = "finance"
+)
+
+// StepStatus はステップの実行結果
+type StepStatus string
+
+const (
+	StepStatusSucceeded StepStatus = "succeeded"
+	StepStatusSkipped   StepStatus = "skipped"
+	StepStatusFailed    StepStatus = "failed"
+)
+
+// StepRecord はステップごとの実行記録
+type StepRecord struct {
+	Status    StepStatus `json:"status"`
+	Attempts  int        `json:"attempts"`
+	Error     string     `json:"error,omitempty"`
+	Detail    string     `json:"detail,omitempty"`
+	Progress  int        `json:"progress,omitempty"` // 部分的に完了した件数（Photosのページ数など）
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Done は再実行不要なステップかどうかを返す
+func (r *StepRecord) Done() bool {
+	return r != nil && (r.Status == StepStatusSucceeded || r.Status == StepStatusSkipped)
+}
+
+// LedgerEntry は処理台帳における1ファイル分の記録
+type LedgerEntry struct {
+	FileID              string                   `json:"file_id"`
+	FileName            string                   `json:"file_name,omitempty"`
+	State               ProcessingState          `json:"state"`
+	Attempts            int                      `json:"attempts"`
+	LastError           string                   `json:"last_error,omitempty"`
+	Owner               string                   `json:"owner,omitempty"`
+	LeaseUntil          time.Time                `json:"lease_until,omitempty"`
+	NewFileName         string                   `json:"new_file_name,omitempty"`
+	DestinationFolderID string                   `json:"destination_folder_id,omitempty"`
+	History             []LedgerTransition       `json:"history,omitempty"`
+	Steps               map[StepName]*StepRecord `json:"steps,omitempty"`
+	Review              *ReviewRequest           `json:"review,omitempty"`
+	ReviewDecision      *ReviewDecision          `json:"review_decision,omitempty"`
+	// 重複判定用の指紋と、重複と判定された場合の元のファイル
+	Fingerprint *DocumentFingerprint `json:"fingerprint,omitempty"`
+	DuplicateOf *DedupEntry          `json:"duplicate_of,omitempty"`
+	// 知覚ハッシュのみで一致した書類（同じ様式の別の書類の場合があるため、解析後に家族に確認する）
+	SimilarTo *DedupEntry `json:"similar_to,omitempty"`
+	// 複数の書類を含むPDFの分割結果
+	Split *SplitResult `json:"split,omitempty"`
+	// AIが使えず、ローカルOCRとキーワードルールで仮分類した
+	Provisional bool `json:"provisional,omitempty"`
+	// 仕分けルールに一致してAIを使わずに分類した場合のルール名
+	SortingRule string `json:"sorting_rule,omitempty"`
+	// 保存期間を過ぎた書類の整理状況
+	Retention *RetentionState `json:"retention,omitempty"`
+	// 再試行時にGeminiを再度呼ばないための解析結果キャッシュ
+	Analysis       *AnalysisResult `json:"analysis,omitempty"`
+	EventsAndTasks *EventsAndTasks `json:"events_and_tasks,omitempty"`
+	OCRBundle      *OCRBundle      `json:"ocr_bundle,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// Transition は状態を遷移させ履歴に記録する
+func (e *LedgerEntry) Transition(state ProcessingState, detail string) {
+	now := time.Now()
+	e.State = state
+	e.LastError = ""
+	e.UpdatedAt = now
+	e.History = append(e.History, LedgerTransition{State: state, At: now, Detail: detail})
+}
+
+// Step はステップ記録を取得（なければ作成）する
+func (e *LedgerEntry) Step(name StepName) *StepRecord {
+	if e.Steps == nil {
+		e.Steps = make(map[StepName]*StepRecord)
+	}
+	rec, ok := e.Steps[name]
+	if !ok {
+		rec = &StepRecord{}
+		e.Steps[name] = rec
+	}
+	return rec
+}
+
+// Fail は現在の状態を保ったままエラーを記録する
+func (e *LedgerEntry) Fail(err error) {
+	e.LastError = err.Error()
+	e.UpdatedAt = time.Now()
+}
+
+// DeadLetterEntry は処理に失敗したファイルの記録
+type DeadLetterEntry struct {
+	FileID      string     `json:"file_id"`
+	FileName    string     `json:"file_name,omitempty"`
+	Error       string     `json:"error"`
+	FailedSteps []StepName `json:"failed_steps,omitempty"`
+	Attempts    int        `json:"attempts"`
+	// デッドレター投入時の台帳状態（再処理時に復元する）
+	PreviousState ProcessingState `json:"previous_state"`
+	// 「要確認」フォルダへ移動した場合の移動元フォルダ
+	MovedToReview  bool      `json:"moved_to_review"`
+	OriginalParent string    `json:"original_parent,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// DocumentFingerprint は書類の重複判定に使う指紋
+type DocumentFingerprint struct {
+	SHA256 string `json:"sha256"`
+	// 画像（PDFはページごとの縮小画像）の知覚ハッシュ（dHash）
+	PageHashes []uint64 `json:"page_hashes,omitempty"`
+}
+
+// DedupEntry は重複判定インデックスの1件（仕分け済みの書類）
+type DedupEntry struct {
+	FileID      string              `json:"file_id"`
+	FileName    string              `json:"file_name,omitempty"`
+	Fingerprint DocumentFingerprint `json:"fingerprint"`
+	// 知覚ハッシュのみで一致した場合のハミング距離（完全一致は0）
+	Distance   int       `json:"distance,omitempty"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// PageClassification はPDFの1ページ分の判定結果（書類の区切りの検出に使う）
+type PageClassification struct {
+	Page              int    `json:"page"`
+	StartsNewDocument bool   `json:"starts_new_document"`
+	Title             string `json:"title"`
+}
+
+// PageClassifications はページ単位の判定結果
+type PageClassifications struct {
+	Pages []PageClassification `json:"pages"`
+}
+
+// PageRange は1つの書類に当たるページ範囲（1始まり、End を含む）
+type PageRange struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Title string `json:"title,omitempty"`
+}
+
+// SplitResult はPDFの分割結果
+type SplitResult struct {
+	Ranges []PageRange `json:"ranges"`
+	// 作成済みの分割ファイル（Ranges と同じ順。途中で中断した場合は作成済みの分のみ）
+	PartFileIDs []string `json:"part_file_ids,omitempty"`
+}
+
+// ProcessingPlan はドライラン（プレビュー）時の処理予定
+// Drive・Calendar・Tasks・Photos には一切変更を加えずに算出する
+type ProcessingPlan struct {
+	FileID          string          `json:"file_id"`
+	OriginalName    string          `json:"original_name"`
+	MimeType        string          `json:"mime_type"`
+	Analysis        *AnalysisResult `json:"analysis"`
+	FiscalYear      int             `json:"fiscal_year,omitempty"`
+	TargetChildren  []string        `json:"target_children,omitempty"`
+	DestinationPath string          `json:"destination_path"`
+	// 移動先が既存フォルダの場合のみ設定
+	DestinationFolderID string          `json:"destination_folder_id,omitempty"`
+	FoldersToCreate     []string        `json:"folders_to_create,omitempty"`
+	NewFileName         string          `json:"new_file_name"`
+	UploadToPhotos      bool            `json:"upload_to_photos"`
+	Events              []Event         `json:"events,omitempty"`
+	Tasks               []Task          `json:"tasks,omitempty"`
+	NotebookLM          *NotebookLMPlan `json:"notebooklm,omitempty"`
+	Warnings            []string        `json:"warnings,omitempty"`
+	// 予定の登録先（calendar_routes で振り分けた結果）
+	Calendars []CalendarDestination `json:"calendars,omitempty"`
+	// 実際に処理した場合の結果（PROCESSED / PENDING_REVIEW / DUPLICATE / SPLIT）
+	ExpectedResult ProcessResult `json:"expected_result"`
+	// 完全一致した仕分け済みの書類（「重複」フォルダへ移動する場合）
+	DuplicateOf *DedupEntry `json:"duplicate_of,omitempty"`
+	// 見た目が似ている仕分け済みの書類（レビューで重複か確認する場合）
+	SimilarTo *DedupEntry `json:"similar_to,omitempty"`
+	// 書類ごとに分割する場合の分割ファイル名
+	SplitParts []string `json:"split_parts,omitempty"`
+	// 医療費・家計の記録の予定
+	MedicalExpense *MedicalExpense `json:"medical_expense,omitempty"`
+	Finance        *FinanceRecord  `json:"finance,omitempty"`
+	// 処理台帳・重複判定インデックス・医療費・家計への記録の予定
+	LedgerWrites []string `json:"ledger_writes,omitempty"`
+}
+
+// NotebookLMPlan はNotebookLM同期の予定
+type NotebookLMPlan struct {
+	DocName string `json:"doc_name"`
+	Entry   string `json:"entry"`
+}
+
+// ReviewRequest は家族に確認を依頼する分類結果
+type ReviewRequest struct {
+	FileID          string    `json:"file_id"`
+	FileName        string    `json:"file_name"`
+	Category        string    `json:"category"`
+	SubCategory     string    `json:"sub_category,omitempty"`
+	TargetChildren  []string  `json:"target_children,omitempty"`
+	TargetAdult     string    `json:"target_adult,omitempty"`
+	NewFileName     string    `json:"new_file_name"`
+	ConfidenceScore float64   `json:"confidence_score"`
+	RequestedAt     time.Time `json:"requested_at"`
+	// 見た目が似ている仕分け済みの書類（重複の可能性がある場合のみ）
+	SimilarTo *DedupEntry `json:"similar_to,omitempty"`
+}
+
+// ReviewDecision はレビューの回答
+type ReviewDecision struct {
+	Approved  bool      `json:"approved"`            // 提案どおり承認
+	Category  string    `json:"category,omitempty"`  // カテゴリ変更時の新カテゴリ
+	Duplicate bool      `json:"duplicate,omitempty"` // 似た書類の重複として「重複」フォルダへ移動
+	DecidedBy string    `json:"decided_by,omitempty"`
+	DecidedAt time.Time `json:"decided_at"`
+}
+
+// ConfigReloadStatus は設定のホットリロード状況
+type ConfigReloadStatus struct {
+	Sources      []string   `json:"sources"`
+	LoadedAt     time.Time  `json:"loaded_at"`
+	LastAttempt  *time.Time `json:"last_attempt,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	ReloadCount  int        `json:"reload_count"`
+	RejectCount  int        `json:"reject_count"`
+	PollInterval string     `json:"poll_interval,omitempty"`
+}
+
+// AIUsageRecord はAI呼び出し1回分のトークン使用量
+type AIUsageRecord struct {
+	Time         time.Time `json:"time"`
+	Backend      string    `json:"backend"`
+	Model        string    `json:"model"`
+	Kind         string    `json:"kind"`
+	FileID       string    `json:"file_id,omitempty"`
+	Escalation   bool      `json:"escalation"` // 信頼度不足による高精度モデルへのエスカレーション
+	PromptTokens int       `json:"prompt_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	TotalTokens  int       `json:"total_tokens"`
+	CostUSD      float64   `json:"cost_usd"`
+}
+
+// AIUsageTotals はトークン使用量の集計値
+type AIUsageTotals struct {
+	Calls        int     `json:"calls"`
+	Escalations  int     `json:"escalations"`
+	PromptTokens int     `json:"prompt_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	TotalTokens  int     `json:"total_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// Add は1回分の使用量を加算する
+func (t *AIUsageTotals) Add(r AIUsageRecord) {
+	t.Calls++
+	if r.Escalation {
+		t.Escalations++
+	}
+	t.PromptTokens += r.PromptTokens
+	t.OutputTokens += r.OutputTokens
+	t.TotalTokens += r.TotalTokens
+	t.CostUSD += r.CostUSD
+}
+
+// AIUsageFileTotals はファイル単位の集計値
+type AIUsageFileTotals struct {
+	FileID string `json:"file_id"`
+	AIUsageTotals
+}
+
+// AIUsageSummary は月次の使用量集計
+type AIUsageSummary struct {
+	Month string `json:"month"` // YYYY-MM
+	AIUsageTotals
+	ByModel map[string]*AIUsageTotals `json:"by_model"`
+	// 費用の大きい順（上位のみ）
+	TopFiles []*AIUsageFileTotals `json:"top_files"`
+
+	BudgetUSD        float64 `json:"budget_usd,omitempty"`
+	BudgetExceeded   bool    `json:"budget_exceeded"`
+	EscalationPaused bool    `json:"escalation_paused"` // 予算超過によりエスカレーションを停止中
+}
+
+// 医療費の区分（国税庁「医療費集計フォーム」の列）
+const (
+	MedicalExpenseKindTreatment = "診療・治療"
+	MedicalExpenseKindMedicine  = "医薬品購入"
+	MedicalExpenseKindCare      = "介護保険サービス"
+	MedicalExpenseKindOther     = "その他の医療費"
+)
+
+// MedicalExpense は医療費控除の対象となる1件の支払い（領収書1枚分）
+type MedicalExpense struct {
+	FileID   string `json:"file_id"`
+	FileName string `json:"file_name,omitempty"`
+	// AIが抽出する項目
+	Date          string    `json:"date"`          // 支払日（YYYY-MM-DD）
+	Patient       string    `json:"patient"`       // 医療を受けた人（名寄せ後の正規名）
+	Provider      string    `json:"provider"`      // 病院・薬局などの支払先
+	Kind          string    `json:"kind"`          // MedicalExpenseKind*
+	Amount        int       `json:"amount"`        // 支払った医療費の金額（円）
+	Reimbursement int       `json:"reimbursement"` // うち保険金などで補填される金額（円）
+	RecordedAt    time.Time `json:"recorded_at"`
+}
+
+// MedicalExpenseSummary は1年分（1月〜12月の支払い）の医療費の集計
+type MedicalExpenseSummary struct {
+	Year               int               `json:"year"`
+	Count              int               `json:"count"`
+	TotalAmount        int               `json:"total_amount"`
+	TotalReimbursement int               `json:"total_reimbursement"`
+	NetAmount          int               `json:"net_amount"`         // 補填額を差し引いた金額
+	DeductionEstimate  int               `json:"deduction_estimate"` // 足切り額10万円で計算した控除額の目安
+	ByPatient          map[string]int    `json:"by_patient"`         // 医療を受けた人ごとの支払額
+	Expenses           []*MedicalExpense `json:"expenses,omitempty"`
+}
+
+// 家計の書類の種類
+const (
+	FinanceDocumentBill      = "請求書"    // 公共料金・カード・学費などの請求
+	FinanceDocumentStatement = "明細"     // 口座・カードの利用明細、引き落としの通知
+	FinanceDocumentTax       = "税金・保険料" // 住民税・固定資産税・社会保険料などの納付書・通知
+	FinanceDocumentOther     = "その他"
+)
+
+// FinanceRecord は10_マネー・税務の書類から読み取った1件の支払い
+type FinanceRecord struct {
+	FileID   string `json:"file_id"`
+	FileName string `json:"file_name,omitempty"`
+	// AIが抽出する項目
+	DocumentType string `json:"document_type"` // FinanceDocument*
+	Payee        string `json:"payee"`         // 支払先・発行元
+	Amount       int    `json:"amount"`        // 請求・支払金額（円）
+	DueDate      string `json:"due_date"`      // 支払期日・引き落とし日（YYYY-MM-DD、なければ空）
+	PeriodStart  string `json:"period_start"`  // 請求対象期間（YYYY-MM-DD、なければ空）
+	PeriodEnd    string `json:"period_end"`
+	Account      string `json:"account"` // 口座・カード番号（下4桁以外はマスク）
+	// 集計する月（YYYY-MM）。支払期日 → 対象期間の末日 → 書類の日付の順に決める
+	Month      string    `json:"month"`
+	TaskID     string    `json:"task_id,omitempty"` // 支払期日をGoogle Tasksに登録した場合のID
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// FinancePayeeTotal は支払先ごとの合計
+type FinancePayeeTotal struct {
+	Payee string `json:"payee"`
+	Count int    `json:"count"`
+	Total int    `json:"total"`
+}
+
+// FinanceMonthTotal は月ごとの合計
+type FinanceMonthTotal struct {
+	Month string `json:"month"`
+	Count int    `json:"count"`
+	Total int    `json:"total"`
+}
+
+// FinanceReport は家計の支払いの集計（月別なら ByPayee、支払先別なら ByMonth を設定）
+type FinanceReport struct {
+	Month   string              `json:"month,omitempty"`
+	Payee   string              `json:"payee,omitempty"`
+	Count   int                 `json:"count"`
+	Total   int                 `json:"total"`
+	ByPayee []FinancePayeeTotal `json:"by_payee,omitempty"` // 金額の大きい順
+	ByMonth []FinanceMonthTotal `json:"by_month,omitempty"` // 古い月から順
+	Records []*FinanceRecord    `json:"records"`
+}
+
+// RetentionState は保存期間を過ぎた書類の整理状況（処理台帳のエントリに記録）
+type RetentionState struct {
+	Action    string    `json:"action"`              // archive / trash
+	ExpiresOn string    `json:"expires_on"`          // 保存期限（YYYY-MM-DD）
+	WarnedAt  time.Time `json:"warned_at,omitempty"` // 法定の保存期間がある書類の削除を予告した日時
+	DoneAt    time.Time `json:"done_at,omitempty"`   // アーカイブ・削除した日時
+}
+
+// RetentionItem は整理ジョブで扱った1件の書類
+type RetentionItem struct {
+	FileID      string `json:"file_id"`
+	FileName    string `json:"file_name"`
+	Category    string `json:"category"`
+	SubCategory string `json:"sub_category,omitempty"`
+	ExpiresOn   string `json:"expires_on"`
+	LegalYears  int    `json:"legal_years,omitempty"`
+	DeleteAfter string `json:"delete_after,omitempty"` // 削除を予告した書類の削除予定日（YYYY-MM-DD）
+	Error       string `json:"error,omitempty"`
+}
+
+// RetentionResult は整理ジョブの実行結果
+type RetentionResult struct {
+	DryRun   bool            `json:"dry_run"`
+	Archived []RetentionItem `json:"archived"`
+	Trashed  []RetentionItem `json:"trashed"`
+	Warned   []RetentionItem `json:"warned"`  // 今回削除を予告した書類（猶予期間の後に削除）
+	Pending  []RetentionItem `json:"pending"` // 予告済みで猶予期間中の書類
+	Failed   []RetentionItem `json:"failed"`
+	// 1回の実行の上限（Retention.MaxPerRun）に達し、次回に持ち越した件数
+	Deferred int `json:"deferred"`
+}
+
+// RolloverChild は新年度の子供の学年
+type RolloverChild struct {
+	Name          string   `json:"name"`
+	PreviousGrade int      `json:"previous_grade"`
+	Grade         int      `json:"grade"` // 小1=1、年長=-1 …
+	Label         string   `json:"label"` // 小4、きりん組 など
+	Emoji         string   `json:"emoji,omitempty"`
+	Graduated     bool     `json:"graduated,omitempty"` // 高校卒業後（大人カテゴリに振り分ける）
+	Folders       []string `json:"folders,omitempty"`   // 作成したフォルダ（子供・教育のフォルダからの相対パス）
+}
+
+// RolloverGroup は新年度の共有グループ（同じクラスの兄弟など）の状況
+type RolloverGroup struct {
+	ClassName  string   `json:"class_name"`
+	FolderName string   `json:"folder_name"`
+	Children   []string `json:"children"`
+	Applies    bool     `json:"applies"`
+	Reasons    []string `json:"reasons,omitempty"` // 新年度に当てはまらない理由（設定の見直しが必要）
+	Folders    []string `json:"folders,omitempty"`
+}
+
+// RolloverReport は年度替わりの準備の結果
+type RolloverReport struct {
+	FiscalYear   int             `json:"fiscal_year"`
+	DryRun       bool            `json:"dry_run"`
+	Children     []RolloverChild `json:"children"`
+	SharedGroups []RolloverGroup `json:"shared_groups"`
+	Errors       []string        `json:"errors,omitempty"` // フォルダ作成に失敗したもの
 }
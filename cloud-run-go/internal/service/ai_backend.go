@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+)
+
+// GenerateKind は生成リクエストの種類（フェイクの応答切り替えやログに使う）
+type GenerateKind string
+
+const (
+	GenerateKindAnalysis GenerateKind = "analysis"
+	GenerateKindCombined GenerateKind = "combined"
+	GenerateKindEvents   GenerateKind = "events"
+	GenerateKindOCR      GenerateKind = "ocr"
+	GenerateKindPages    GenerateKind = "pages"
+	GenerateKindMedical  GenerateKind = "medical_expense"
+	GenerateKindFinance  GenerateKind = "finance"
+)
+
+// ErrEmptyResponse はモデルが応答を返さなかったことを示す
+var ErrEmptyResponse = errors.New("empty response from model")
+
+// GenerateRequest はドキュメント1件とプロンプトからJSONを生成するリクエスト
+type GenerateRequest struct {
+	Kind     GenerateKind
+	Model    string
+	Data     []byte
+	MimeType string
+	Prompt   string
+	// Schema は応答のJSONスキーマ（nil の場合はJSONであることのみ指定）
+	Schema *ResponseSchema
+	// Escalation は信頼度不足による高精度モデルへのエスカレーションかどうか（使用量の記録用）
+	Escalation bool
+}
+
+// GenerateResponse はモデルの応答（JSON文字列）とトークン使用量
+type GenerateResponse struct {
+	Text         string
+	PromptTokens int
+	OutputTokens int
+	TotalTokens  int
+}
+
+// ModelBackend はAIRouterが利用するモデルAPIの抽象
+// モデルの使い分け（Flash → Pro）はAIRouter側で行い、バックエンドは1回の呼び出しだけを担う
+type ModelBackend interface {
+	Name() string
+	// Models は一次解析用とエスカレーション用のモデル名を返す
+	Models() (fast, high string)
+	Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error)
+	Close() error
+}
+
+// NewModelBackend は設定に応じたバックエンドを作成
+func NewModelBackend(ctx context.Context, cfg config.AIBackendConfig) (ModelBackend, error) {
+	switch cfg.Provider {
+	case "", "gemini":
+		return NewGeminiBackend(ctx)
+	case "openai":
+		return NewOpenAIBackend(cfg, NewPDFProcessor())
+	default:
+		return nil, fmt.Errorf("unknown AI_PROVIDER: %s", cfg.Provider)
+	}
+}
@@ -3,216 +3,200 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"strings"
 	"time"
 
-	secretmanager "cloud.google.com/go/secretmanager/apiv1"
-	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
-	"github.com/google/generative-ai-go/genai"
 	"github.com/leo-sagawa/homedocmanager/internal/config"
 	"github.com/leo-sagawa/homedocmanager/internal/model"
-	"google.golang.org/api/option"
 )
 
-// AIRouter はGemini Flash/Proを使い分けるAIルーター
+// DocumentAnalyzer はドキュメント解析の抽象（FileSorterが利用する）
+type DocumentAnalyzer interface {
+	// schema は分類結果のスキーマ（世帯ごとのカテゴリを列挙したもの。nil の場合はカテゴリを限定しない）
+	AnalyzeDocument(ctx context.Context, data []byte, mimeType string, prompt string, schema *ResponseSchema, useFlashFirst bool) (*model.AnalysisResult, error)
+	AnalyzeDocumentFull(ctx context.Context, data []byte, mimeType, fileName, analysisPrompt string, analysisSchema *ResponseSchema) (*model.DocumentBundle, error)
+	ExtractEventsAndTasks(ctx context.Context, data []byte, mimeType string, fileName string) (*model.EventsAndTasks, error)
+	ExtractOCRBundle(ctx context.Context, data []byte, mimeType string) (*model.OCRBundle, error)
+	// ClassifyPages はPDFの各ページが新しい書類の先頭かを判定する（書類の分割に使う）
+	ClassifyPages(ctx context.Context, data []byte, mimeType string, fileName string, pageCount int) ([]model.PageClassification, error)
+	// ExtractMedicalExpense は医療費の領収書から支払日・受診者・支払先・金額を読み取る（医療費控除の集計に使う）
+	ExtractMedicalExpense(ctx context.Context, data []byte, mimeType string, fileName string) (*model.MedicalExpense, error)
+	// ExtractFinance は請求書・明細・納付書から支払先・金額・期日・対象期間・口座を読み取る（家計の集計に使う）
+	ExtractFinance(ctx context.Context, data []byte, mimeType string, fileName string) (*model.FinanceRecord, error)
+}
+
+// AIRouter は軽量モデル（Flash相当）/高精度モデル（Pro相当）を使い分けるAIルーター
+// モデルAPIの呼び出しは ModelBackend に委譲する
 type AIRouter struct {
-	apiKey string
-	client *genai.Client
+	backend   ModelBackend
+	fastModel string
+	highModel string
+	// トークン使用量の記録・月次予算（任意）
+	usage *UsageTracker
 }
 
-// NewAIRouter は新しいAIRouterを作成
-func NewAIRouter(ctx context.Context) (*AIRouter, error) {
-	apiKey, err := getAPIKey(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get API key: %w", err)
-	}
+var _ DocumentAnalyzer = (*AIRouter)(nil)
 
-	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+// NewAIRouter は設定（AI_PROVIDER）に応じたバックエンドでAIRouterを作成
+func NewAIRouter(ctx context.Context) (*AIRouter, error) {
+	backend, err := NewModelBackend(ctx, config.AIBackend)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create genai client: %w", err)
+		return nil, err
 	}
-
-	return &AIRouter{
-		apiKey: apiKey,
-		client: client,
-	}, nil
+	return NewAIRouterWithBackend(backend), nil
 }
 
-// getAPIKey はSecret ManagerからGemini APIキーを取得
-func getAPIKey(ctx context.Context) (string, error) {
-	// Secret Managerクライアントを作成
-	client, err := secretmanager.NewClient(ctx)
-	if err != nil {
-		// フォールバック: 環境変数から取得
-		log.Printf("Secret Manager client creation failed: %v, falling back to env var", err)
-		return getFallbackAPIKey()
-	}
-	defer client.Close()
-
-	// Secret Managerからキーを取得
-	name := fmt.Sprintf("projects/%s/secrets/%s/versions/latest",
-		config.GCPProjectID,
-		config.SecretGeminiAPIKey)
-
-	req := &secretmanagerpb.AccessSecretVersionRequest{
-		Name: name,
-	}
-
-	result, err := client.AccessSecretVersion(ctx, req)
-	if err != nil {
-		// フォールバック: 環境変数から取得
-		log.Printf("Secret Manager access failed: %v, falling back to env var", err)
-		return getFallbackAPIKey()
-	}
+// NewAIRouterWithBackend は指定したバックエンドでAIRouterを作成
+func NewAIRouterWithBackend(backend ModelBackend) *AIRouter {
+	fast, high := backend.Models()
+	log.Printf("AIRouter backend: %s (fast: %s, high: %s)", backend.Name(), fast, high)
+	return &AIRouter{backend: backend, fastModel: fast, highModel: high}
+}
 
-	return string(result.Payload.Data), nil
+// SetUsageTracker は使用量の記録先を設定する
+func (r *AIRouter) SetUsageTracker(t *UsageTracker) {
+	r.usage = t
 }
 
-// getFallbackAPIKey は環境変数からAPIキーを取得
-func getFallbackAPIKey() (string, error) {
-	apiKey := config.GetEnv("GEMINI_API_KEY", "")
-	if apiKey == "" {
-		return "", fmt.Errorf("GEMINI_API_KEY not found in environment variables")
+// canEscalate は高精度モデルへのエスカレーションが可能かを返す
+// 月次予算を超過している場合はエスカレーションせず、軽量モデルの結果を使う
+func (r *AIRouter) canEscalate() bool {
+	if !config.AIRouter.EnableProEscalation {
+		return false
 	}
-	return apiKey, nil
+	if r.usage != nil && !r.usage.EscalationAllowed() {
+		log.Printf("月次予算超過のためProへのエスカレーションを停止中")
+		return false
+	}
+	return true
 }
 
 // AnalyzeDocument はドキュメントを解析（AIルーターパターン）
-func (r *AIRouter) AnalyzeDocument(ctx context.Context, data []byte, mimeType string, prompt string, useFlashFirst bool) (*model.AnalysisResult, error) {
+func (r *AIRouter) AnalyzeDocument(ctx context.Context, data []byte, mimeType string, prompt string, schema *ResponseSchema, useFlashFirst bool) (*model.AnalysisResult, error) {
+	if schema == nil {
+		schema = AnalysisSchema(nil)
+	}
 	if useFlashFirst {
-		// 第一段階: Gemini Flash
-		log.Println("Gemini Flash で解析開始")
-		flashResult, err := r.callGemini(ctx, config.GeminiModelsConfig.Flash, data, mimeType, prompt)
+		// 第一段階: 軽量モデル
+		log.Printf("%s で解析開始", r.fastModel)
+		flashResult, err := r.analyze(ctx, r.fastModel, false, data, mimeType, prompt, schema)
 		if err == nil && r.isConfident(flashResult) {
 			log.Printf("Flash解析成功（信頼度: %.2f）", flashResult.ConfidenceScore)
 			return flashResult, nil
 		}
 
-		// 第二段階: Gemini Proへエスカレーション
-		if config.AIRouter.EnableProEscalation {
+		// 第二段階: 高精度モデルへエスカレーション
+		if r.canEscalate() {
 			score := 0.0
 			if flashResult != nil {
 				score = flashResult.ConfidenceScore
 			}
 			log.Printf("信頼度が低いためProにエスカレーション (score: %.2f)", score)
-			return r.callGemini(ctx, config.GeminiModelsConfig.Pro, data, mimeType, prompt)
+			return r.analyze(ctx, r.highModel, true, data, mimeType, prompt, schema)
 		}
 
 		return flashResult, err
 	}
 
 	// Pro直接呼び出し
-	return r.callGemini(ctx, config.GeminiModelsConfig.Pro, data, mimeType, prompt)
+	return r.analyze(ctx, r.highModel, false, data, mimeType, prompt, schema)
 }
 
 // AnalyzeDocumentFull は解析・予定抽出・OCRを1回で行う（統合版）
-func (r *AIRouter) AnalyzeDocumentFull(ctx context.Context, data []byte, mimeType, fileName, analysisPrompt string) (*model.DocumentBundle, error) {
+func (r *AIRouter) AnalyzeDocumentFull(ctx context.Context, data []byte, mimeType, fileName, analysisPrompt string, analysisSchema *ResponseSchema) (*model.DocumentBundle, error) {
 	eventsPrompt := buildEventsAndTasksPrompt(fileName)
 	ocrPrompt := buildOCRBundlePrompt()
 	combinedPrompt := buildCombinedPrompt(analysisPrompt, eventsPrompt, ocrPrompt)
+	schema := DocumentBundleSchema(analysisSchema)
 
-	bundle, err := r.callGeminiCombined(ctx, config.GeminiModelsConfig.Flash, data, mimeType, combinedPrompt)
+	bundle, err := r.analyzeCombined(ctx, r.fastModel, false, data, mimeType, combinedPrompt, schema)
 	if err == nil && r.isCombinedConfident(bundle) {
 		return bundle, nil
 	}
 
-	if config.AIRouter.EnableProEscalation {
+	if r.canEscalate() {
 		log.Printf("統合解析が不十分なためProにエスカレーション")
-		return r.callGeminiCombined(ctx, config.GeminiModelsConfig.Pro, data, mimeType, combinedPrompt)
+		return r.analyzeCombined(ctx, r.highModel, true, data, mimeType, combinedPrompt, schema)
 	}
 
 	return bundle, err
 }
 
-// callGemini はGemini APIを呼び出し
-func (r *AIRouter) callGemini(ctx context.Context, modelName string, data []byte, mimeType string, prompt string) (*model.AnalysisResult, error) {
-	genModel := r.client.GenerativeModel(modelName) // Rename variable to avoid shadowing package name
-	genModel.GenerationConfig.ResponseMIMEType = "application/json"
-
-	// 画像/PDFとプロンプトを送信
-	// mimeTypeがimage/jpeg等の場合はImageDataでも良いが、汎用的にBlobを使用可能か確認
-	// genai.ImageDataは shortcut for Blob(mimeType, data) with validation for image types?
-	// PDFの場合はBlobを使う必要がある。
-
-	var dataPart genai.Part
-	if mimeType == "application/pdf" {
-		dataPart = genai.Blob{
-			MIMEType: mimeType,
-			Data:     data,
-		}
-	} else {
-		// 画像（JPEG, PNG等）
-		// mimeTypeから拡張子部分を取得（簡易的）
-		format := "jpeg"
-		if len(mimeType) > 6 {
-			format = mimeType[6:]
-		}
-		dataPart = genai.ImageData(format, data)
-	}
-
-	resp, err := genModel.GenerateContent(ctx,
-		dataPart,
-		genai.Text(prompt),
-	)
+// generate はバックエンドを呼び出し、使用量を記録して応答テキストを返す
+func (r *AIRouter) generate(ctx context.Context, kind GenerateKind, modelName string, escalation bool, data []byte, mimeType, prompt string, schema *ResponseSchema) (string, error) {
+	resp, err := r.backend.Generate(ctx, GenerateRequest{
+		Kind:       kind,
+		Model:      modelName,
+		Data:       data,
+		MimeType:   mimeType,
+		Prompt:     prompt,
+		Schema:     schema,
+		Escalation: escalation,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("gemini API call failed (%s): %w", modelName, err)
-	}
-
-	// JSONレスポンスをパース
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("no response from gemini API")
-	}
-
-	// レスポンステキストを取得
-	text := fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0])
-
-	var result model.AnalysisResult
-	if err := json.Unmarshal([]byte(text), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+		return "", fmt.Errorf("%s API call failed (%s): %w", r.backend.Name(), modelName, err)
+	}
+
+	if r.usage != nil {
+		err := r.usage.Record(model.AIUsageRecord{
+			Backend:      r.backend.Name(),
+			Model:        modelName,
+			Kind:         string(kind),
+			FileID:       usageFileID(ctx),
+			Escalation:   escalation,
+			PromptTokens: resp.PromptTokens,
+			OutputTokens: resp.OutputTokens,
+			TotalTokens:  resp.TotalTokens,
+		})
+		if err != nil {
+			log.Printf("Warning: failed to record AI usage: %v", err)
+		}
 	}
-
-	return &result, nil
+	return resp.Text, nil
 }
 
-// callGeminiCombined は統合レスポンスを取得
-func (r *AIRouter) callGeminiCombined(ctx context.Context, modelName string, data []byte, mimeType string, prompt string) (*model.DocumentBundle, error) {
-	genModel := r.client.GenerativeModel(modelName)
-	genModel.GenerationConfig.ResponseMIMEType = "application/json"
+// generateJSON はスキーマを指定して生成し、応答を補正・検証してから out にデコードする
+// 補正しても違反が残る場合は、違反内容をプロンプトに追記して SchemaRetries 回まで再生成する
+// 最終的に違反が残った場合は ErrSchemaViolation と最後の応答テキストを返す
+func (r *AIRouter) generateJSON(ctx context.Context, kind GenerateKind, modelName string, escalation bool, data []byte, mimeType, prompt string, schema *ResponseSchema, out any) (string, error) {
+	attemptPrompt := prompt
+	for attempt := 0; ; attempt++ {
+		text, err := r.generate(ctx, kind, modelName, escalation, data, mimeType, attemptPrompt, schema)
+		if err != nil {
+			return "", err
+		}
 
-	var dataPart genai.Part
-	if mimeType == "application/pdf" {
-		dataPart = genai.Blob{
-			MIMEType: mimeType,
-			Data:     data,
+		violations := decodeWithSchema(text, schema, out)
+		if len(violations) == 0 {
+			return text, nil
 		}
-	} else {
-		format := "jpeg"
-		if len(mimeType) > 6 {
-			format = mimeType[6:]
+		if attempt >= config.AIRouter.SchemaRetries {
+			return text, fmt.Errorf("%w (%s, %s): %s", ErrSchemaViolation, kind, modelName, strings.Join(violations, "; "))
 		}
-		dataPart = genai.ImageData(format, data)
-	}
-
-	resp, err := genModel.GenerateContent(ctx,
-		dataPart,
-		genai.Text(prompt),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("gemini API call failed (%s): %w", modelName, err)
+		log.Printf("応答がスキーマに違反しているため再生成します (%s, %s): %s", kind, modelName, strings.Join(violations, "; "))
+		attemptPrompt = buildSchemaRepairPrompt(prompt, violations)
 	}
+}
 
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("no response from gemini API")
+// analyze は分類結果を取得
+func (r *AIRouter) analyze(ctx context.Context, modelName string, escalation bool, data []byte, mimeType string, prompt string, schema *ResponseSchema) (*model.AnalysisResult, error) {
+	var result model.AnalysisResult
+	if _, err := r.generateJSON(ctx, GenerateKindAnalysis, modelName, escalation, data, mimeType, prompt, schema, &result); err != nil {
+		return nil, err
 	}
+	return &result, nil
+}
 
-	text := fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0])
-
+// analyzeCombined は統合レスポンスを取得
+func (r *AIRouter) analyzeCombined(ctx context.Context, modelName string, escalation bool, data []byte, mimeType string, prompt string, schema *ResponseSchema) (*model.DocumentBundle, error) {
 	var bundle model.DocumentBundle
-	if err := json.Unmarshal([]byte(text), &bundle); err != nil {
-		return nil, fmt.Errorf("failed to parse combined JSON response: %w", err)
+	if _, err := r.generateJSON(ctx, GenerateKindCombined, modelName, escalation, data, mimeType, prompt, schema, &bundle); err != nil {
+		return nil, err
 	}
-
 	return &bundle, nil
 }
 
@@ -224,6 +208,7 @@ func (r *AIRouter) isConfident(result *model.AnalysisResult) bool {
 	return result.ConfidenceScore >= config.AIRouter.ConfidenceThreshold
 }
 
+// isCombinedConfident は統合解析結果の信頼度をチェック
 func (r *AIRouter) isCombinedConfident(bundle *model.DocumentBundle) bool {
 	if bundle == nil || bundle.Analysis == nil {
 		return false
@@ -332,49 +317,198 @@ func buildCombinedPrompt(analysisPrompt, eventsPrompt, ocrPrompt string) string
 `, analysisPrompt, eventsPrompt, ocrPrompt)
 }
 
-// ExtractEventsAndTasks はドキュメントから予定とタスクを抽出
-func (r *AIRouter) ExtractEventsAndTasks(ctx context.Context, data []byte, mimeType string, fileName string) (*model.EventsAndTasks, error) {
-	prompt := buildEventsAndTasksPrompt(fileName)
-
-	genModel := r.client.GenerativeModel(config.GeminiModelsConfig.Flash) // Rename to genModel
-	genModel.GenerationConfig.ResponseMIMEType = "application/json"
+// ErrSchemaViolation は応答がスキーマに合わず、再生成しても直らなかったことを示す
+var ErrSchemaViolation = errors.New("response does not match schema")
 
-	var dataPart genai.Part
-	if mimeType == "application/pdf" {
-		dataPart = genai.Blob{
-			MIMEType: mimeType,
-			Data:     data,
-		}
-	} else {
-		format := "jpeg"
-		if len(mimeType) > 6 {
-			format = mimeType[6:]
+// decodeWithSchema は応答を補正・検証し、違反がなければ out にデコードする
+func decodeWithSchema(text string, schema *ResponseSchema, out any) []string {
+	var raw any
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		return []string{fmt.Sprintf("JSONとして解析できません: %v", err)}
+	}
+	if schema != nil {
+		raw = schema.Repair(raw)
+		if violations := schema.Validate(raw); len(violations) > 0 {
+			return violations
 		}
-		dataPart = genai.ImageData(format, data)
 	}
-
-	resp, err := genModel.GenerateContent(ctx,
-		dataPart,
-		genai.Text(prompt),
-	)
+	repaired, err := json.Marshal(raw)
 	if err != nil {
-		return nil, fmt.Errorf("gemini API call failed: %w", err)
+		return []string{fmt.Sprintf("JSONに変換できません: %v", err)}
+	}
+	if err := json.Unmarshal(repaired, out); err != nil {
+		return []string{fmt.Sprintf("JSONの型が一致しません: %v", err)}
 	}
+	return nil
+}
 
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("no response from gemini API")
+// buildSchemaRepairPrompt は前回の違反内容を伝えて再生成させるプロンプトを作る
+func buildSchemaRepairPrompt(prompt string, violations []string) string {
+	const maxViolations = 10
+	if len(violations) > maxViolations {
+		violations = append(violations[:maxViolations:maxViolations], fmt.Sprintf("ほか%d件", len(violations)-maxViolations))
 	}
+	return fmt.Sprintf(`%s
 
-	text := fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0])
+## 前回の出力の問題
+前回の出力は次の点で指定の出力形式に違反していました。すべて修正し、JSONのみを出力してください。
+- %s
+`, prompt, strings.Join(violations, "\n- "))
+}
+
+// ExtractEventsAndTasks はドキュメントから予定とタスクを抽出
+func (r *AIRouter) ExtractEventsAndTasks(ctx context.Context, data []byte, mimeType string, fileName string) (*model.EventsAndTasks, error) {
+	prompt := buildEventsAndTasksPrompt(fileName)
 
 	var result model.EventsAndTasks
-	if err := json.Unmarshal([]byte(text), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	if _, err := r.generateJSON(ctx, GenerateKindEvents, r.fastModel, false, data, mimeType, prompt, EventsAndTasksSchema(), &result); err != nil {
+		return nil, err
 	}
-
 	return &result, nil
 }
 
+// ClassifyPages はページ単位で書類の区切りを判定
+func (r *AIRouter) ClassifyPages(ctx context.Context, data []byte, mimeType string, fileName string, pageCount int) ([]model.PageClassification, error) {
+	prompt := buildPageClassificationPrompt(fileName, pageCount)
+
+	var result model.PageClassifications
+	if _, err := r.generateJSON(ctx, GenerateKindPages, r.fastModel, false, data, mimeType, prompt, PageClassificationSchema(), &result); err != nil {
+		return nil, err
+	}
+	return result.Pages, nil
+}
+
+func buildPageClassificationPrompt(fileName string, pageCount int) string {
+	return fmt.Sprintf(`
+あなたは家庭の書類を整理するアシスタントです。
+このPDFは、複数の書類（学校のお便り、請求書、行政からの通知など）をまとめてスキャンしたものかもしれません。
+全%dページについて、ページごとに次のJSON形式で回答してください。
+
+{
+  "pages": [
+    {"page": 1, "starts_new_document": true, "title": "書類の件名"}
+  ]
+}
+
+## 判断基準
+- 1ページ目は必ず starts_new_document を true にしてください
+- 差出人・宛名・件名・レイアウトが変わり、前のページとは別の書類と判断できる場合のみ true にしてください
+- ページ番号（「2/3」など）が続いている、前のページの続きの文章・表である場合は false にしてください
+- 判断に迷う場合は false（前のページと同じ書類）としてください
+- title は同じ書類のページには同じ件名を入れてください
+
+## ファイル名
+%s
+`, pageCount, fileName)
+}
+
+// ExtractMedicalExpense は医療費の領収書を読み取る
+func (r *AIRouter) ExtractMedicalExpense(ctx context.Context, data []byte, mimeType string, fileName string) (*model.MedicalExpense, error) {
+	prompt := buildMedicalExpensePrompt(fileName)
+
+	// 金額は小数で返されることがあるため、float64 で受けてから丸める
+	var result struct {
+		Date          string  `json:"date"`
+		Patient       string  `json:"patient"`
+		Provider      string  `json:"provider"`
+		Kind          string  `json:"kind"`
+		Amount        float64 `json:"amount"`
+		Reimbursement float64 `json:"reimbursement"`
+	}
+	if _, err := r.generateJSON(ctx, GenerateKindMedical, r.fastModel, false, data, mimeType, prompt, MedicalExpenseSchema(), &result); err != nil {
+		return nil, err
+	}
+	return &model.MedicalExpense{
+		Date:          result.Date,
+		Patient:       result.Patient,
+		Provider:      result.Provider,
+		Kind:          result.Kind,
+		Amount:        int(math.Round(result.Amount)),
+		Reimbursement: int(math.Round(result.Reimbursement)),
+	}, nil
+}
+
+func buildMedicalExpensePrompt(fileName string) string {
+	return fmt.Sprintf(`
+あなたは医療費控除の集計を手伝うアシスタントです。
+この医療費の領収書・明細書を読み取り、次のJSON形式で回答してください。
+
+{
+  "date": "支払日（YYYY-MM-DD）",
+  "patient": "医療を受けた人の氏名",
+  "provider": "病院・薬局などの名称",
+  "kind": "診療・治療 / 医薬品購入 / 介護保険サービス / その他の医療費 のいずれか",
+  "amount": 0,
+  "reimbursement": 0
+}
+
+## 判断基準
+- amount は窓口で実際に支払った金額（「領収金額」「お支払額」など）です。保険点数や総医療費ではありません
+- 病院・診療所・歯科の支払いは「診療・治療」、薬局・ドラッグストアの支払いは「医薬品購入」にしてください
+- 介護保険の施設・居宅サービスは「介護保険サービス」、通院の交通費・医療用器具などは「その他の医療費」にしてください
+- reimbursement は領収書に保険金・高額療養費などの補填額が記載されている場合のみ設定し、なければ0にしてください
+- 支払日が読み取れない場合は診療日を使ってください
+
+## ファイル名
+%s
+`, fileName)
+}
+
+// ExtractFinance は家計の書類を読み取る
+func (r *AIRouter) ExtractFinance(ctx context.Context, data []byte, mimeType string, fileName string) (*model.FinanceRecord, error) {
+	prompt := buildFinancePrompt(fileName)
+
+	var result struct {
+		DocumentType string  `json:"document_type"`
+		Payee        string  `json:"payee"`
+		Amount       float64 `json:"amount"`
+		DueDate      string  `json:"due_date"`
+		PeriodStart  string  `json:"period_start"`
+		PeriodEnd    string  `json:"period_end"`
+		Account      string  `json:"account"`
+	}
+	if _, err := r.generateJSON(ctx, GenerateKindFinance, r.fastModel, false, data, mimeType, prompt, FinanceSchema(), &result); err != nil {
+		return nil, err
+	}
+	return &model.FinanceRecord{
+		DocumentType: result.DocumentType,
+		Payee:        strings.TrimSpace(result.Payee),
+		Amount:       int(math.Round(result.Amount)),
+		DueDate:      result.DueDate,
+		PeriodStart:  result.PeriodStart,
+		PeriodEnd:    result.PeriodEnd,
+		Account:      result.Account,
+	}, nil
+}
+
+func buildFinancePrompt(fileName string) string {
+	return fmt.Sprintf(`
+あなたは家計簿の記録を手伝うアシスタントです。
+この請求書・利用明細・納付書などを読み取り、次のJSON形式で回答してください。
+
+{
+  "document_type": "請求書 / 明細 / 税金・保険料 / その他 のいずれか",
+  "payee": "支払先・発行元の名称",
+  "amount": 0,
+  "due_date": "支払期日（YYYY-MM-DD）",
+  "period_start": "請求対象期間の開始日（YYYY-MM-DD）",
+  "period_end": "請求対象期間の終了日（YYYY-MM-DD）",
+  "account": "口座番号・カード番号の下4桁"
+}
+
+## 判断基準
+- payee は「○○電力」「○○カード」「○○市」のように、毎回同じ表記になる正式名称にしてください
+- amount は今回の請求額・支払額（税込）です。明細の場合は合計額、金額がない通知の場合は0にしてください
+- due_date は支払期限・納期限・口座振替日です。記載がなければ null にしてください
+- period_start / period_end は「○月分」「ご利用期間」などの対象期間です。1か月分の場合はその月の初日と末日にしてください
+- account は口座番号・カード番号・お客様番号の**下4桁のみ**を返してください。それ以外の桁は絶対に含めないでください
+- 年が省略されている日付は書類の発行日から補ってください
+
+## ファイル名
+%s
+`, fileName)
+}
+
 // ExtractOCRText はドキュメントからプレーンテキストを抽出（OCR）- 互換性維持用ラッパー
 func (r *AIRouter) ExtractOCRText(ctx context.Context, data []byte, mimeType string) (string, error) {
 	bundle, err := r.ExtractOCRBundle(ctx, data, mimeType)
@@ -388,44 +522,10 @@ func (r *AIRouter) ExtractOCRText(ctx context.Context, data []byte, mimeType str
 func (r *AIRouter) ExtractOCRBundle(ctx context.Context, data []byte, mimeType string) (*model.OCRBundle, error) {
 	prompt := buildOCRBundlePrompt()
 
-	genModel := r.client.GenerativeModel(config.GeminiModelsConfig.Flash)
-	genModel.GenerationConfig.ResponseMIMEType = "application/json"
-
-	var dataPart genai.Part
-	if mimeType == "application/pdf" {
-		dataPart = genai.Blob{
-			MIMEType: mimeType,
-			Data:     data,
-		}
-	} else {
-		format := "jpeg"
-		if len(mimeType) > 6 {
-			format = mimeType[6:]
-		}
-		dataPart = genai.ImageData(format, data)
-	}
-
-	resp, err := genModel.GenerateContent(ctx,
-		dataPart,
-		genai.Text(prompt),
-	)
+	modelName := r.fastModel
+	bundle, err := r.extractOCRBundleWithModel(ctx, data, mimeType, modelName, false, prompt)
 	if err != nil {
-		return nil, fmt.Errorf("OCR extraction failed: %w", err)
-	}
-
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		return &model.OCRBundle{}, nil
-	}
-
-	// Partsを連結してテキストを取得
-	text := r.extractTextFromParts(resp.Candidates[0].Content.Parts)
-
-	var bundle model.OCRBundle
-	if err := json.Unmarshal([]byte(text), &bundle); err != nil {
-		// JSONパース失敗時はプレーンテキストとして扱う
-		log.Printf("OCRBundle JSONパース失敗、プレーンテキストとして処理: %v", err)
-		bundle.OCRText = text
-		bundle.ConfidenceScore = 0.5
+		return nil, err
 	}
 
 	// Flash結果が弱い場合はProにエスカレーション
@@ -433,76 +533,40 @@ func (r *AIRouter) ExtractOCRBundle(ctx context.Context, data []byte, mimeType s
 		bundle.Quality.NeedsHighModel ||
 		(bundle.Quality.Uncertain && len(bundle.Facts) < 3) ||
 		len(bundle.OCRText) < 200 {
-		if config.AIRouter.EnableProEscalation {
+		if r.canEscalate() {
 			log.Printf("OCR精度が低いためProにエスカレーション (score: %.2f, len: %d)", bundle.ConfidenceScore, len(bundle.OCRText))
-			return r.extractOCRBundleWithModel(ctx, data, mimeType, config.GeminiModelsConfig.Pro, prompt)
+			modelName = r.highModel
+			bundle, err = r.extractOCRBundleWithModel(ctx, data, mimeType, modelName, true, prompt)
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
 
-	log.Printf("OCRBundle抽出完了: %d文字, %d facts", len(bundle.OCRText), len(bundle.Facts))
-	return &bundle, nil
-}
-
-// extractTextFromParts はPartsからテキストを安全に抽出
-func (r *AIRouter) extractTextFromParts(parts []genai.Part) string {
-	var result string
-	for _, part := range parts {
-		switch v := part.(type) {
-		case genai.Text:
-			result += string(v)
-		default:
-			// Text以外のPartは文字列化
-			result += fmt.Sprintf("%v", v)
-		}
-	}
-	return result
+	log.Printf("OCRBundle抽出完了 (%s): %d文字, %d facts", modelName, len(bundle.OCRText), len(bundle.Facts))
+	return bundle, nil
 }
 
 // extractOCRBundleWithModel は指定モデルでOCRBundle抽出
-func (r *AIRouter) extractOCRBundleWithModel(ctx context.Context, data []byte, mimeType, modelName, prompt string) (*model.OCRBundle, error) {
-	genModel := r.client.GenerativeModel(modelName)
-	genModel.GenerationConfig.ResponseMIMEType = "application/json"
-
-	var dataPart genai.Part
-	if mimeType == "application/pdf" {
-		dataPart = genai.Blob{
-			MIMEType: mimeType,
-			Data:     data,
-		}
-	} else {
-		format := "jpeg"
-		if len(mimeType) > 6 {
-			format = mimeType[6:]
-		}
-		dataPart = genai.ImageData(format, data)
-	}
-
-	resp, err := genModel.GenerateContent(ctx,
-		dataPart,
-		genai.Text(prompt),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("OCR extraction (Pro) failed: %w", err)
-	}
-
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
+// 応答が空の場合は空のOCRBundleを返し、再生成してもスキーマに合わない場合はプレーンテキストとして扱う
+func (r *AIRouter) extractOCRBundleWithModel(ctx context.Context, data []byte, mimeType, modelName string, escalation bool, prompt string) (*model.OCRBundle, error) {
+	var bundle model.OCRBundle
+	text, err := r.generateJSON(ctx, GenerateKindOCR, modelName, escalation, data, mimeType, prompt, OCRBundleSchema(), &bundle)
+	if errors.Is(err, ErrEmptyResponse) {
 		return &model.OCRBundle{}, nil
 	}
-
-	// Partsを連結してテキストを取得
-	text := r.extractTextFromParts(resp.Candidates[0].Content.Parts)
-
-	var bundle model.OCRBundle
-	if err := json.Unmarshal([]byte(text), &bundle); err != nil {
-		bundle.OCRText = text
-		bundle.ConfidenceScore = 0.5
+	if errors.Is(err, ErrSchemaViolation) {
+		log.Printf("OCRBundle スキーマ検証失敗、プレーンテキストとして処理: %v", err)
+		return &model.OCRBundle{OCRText: text, ConfidenceScore: 0.5}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("OCR extraction failed: %w", err)
 	}
 
-	log.Printf("OCRBundle抽出完了 (Pro): %d文字, %d facts", len(bundle.OCRText), len(bundle.Facts))
 	return &bundle, nil
 }
 
-// Close はクライアントをクローズ
+// Close はバックエンドをクローズ
 func (r *AIRouter) Close() error {
-	return r.client.Close()
+	return r.backend.Close()
 }
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+func TestAIRouter_AnalyzeDocumentEscalation(t *testing.T) {
+	tests := []struct {
+		name       string
+		fastScore  float64
+		wantModels []string
+		wantScore  float64
+	}{
+		{"confident flash", config.AIRouter.ConfidenceThreshold, []string{"fake-fast"}, config.AIRouter.ConfidenceThreshold},
+		{"escalate to high", config.AIRouter.ConfidenceThreshold - 0.1, []string{"fake-fast", "fake-high"}, 0.95},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := NewFakeBackend()
+			backend.Respond = func(req GenerateRequest) (string, error) {
+				score := 0.95
+				if req.Model == backend.FastModel {
+					score = tt.fastScore
+				}
+				b, err := json.Marshal(model.AnalysisResult{Category: "30_ライフ・行政", ConfidenceScore: score})
+				return string(b), err
+			}
+			router := NewAIRouterWithBackend(backend)
+
+			result, err := router.AnalyzeDocument(context.Background(), []byte("img"), "image/jpeg", "prompt", nil, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.ConfidenceScore != tt.wantScore {
+				t.Fatalf("score = %.2f, want %.2f", result.ConfidenceScore, tt.wantScore)
+			}
+			calls := backend.Calls()
+			if len(calls) != len(tt.wantModels) {
+				t.Fatalf("calls = %d, want %d", len(calls), len(tt.wantModels))
+			}
+			for i, want := range tt.wantModels {
+				if calls[i].Model != want || calls[i].Kind != GenerateKindAnalysis {
+					t.Fatalf("call[%d] = %s/%s, want %s/%s", i, calls[i].Kind, calls[i].Model, GenerateKindAnalysis, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAIRouter_FakeBackendDefaults(t *testing.T) {
+	backend := NewFakeBackend()
+	router := NewAIRouterWithBackend(backend)
+	ctx := context.Background()
+
+	bundle, err := router.AnalyzeDocumentFull(ctx, []byte("%PDF"), "application/pdf", "a.pdf", "prompt", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bundle.Analysis == nil || bundle.EventsAndTasks == nil || bundle.OCRBundle == nil {
+		t.Fatalf("incomplete bundle: %+v", bundle)
+	}
+
+	ocr, err := router.ExtractOCRBundle(ctx, []byte("img"), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ocr.OCRText == "" {
+		t.Fatal("expected OCR text")
+	}
+
+	// 既定の応答はエスカレーションしない
+	calls := backend.Calls()
+	if len(calls) != 2 || calls[0].Kind != GenerateKindCombined || calls[1].Kind != GenerateKindOCR {
+		t.Fatalf("unexpected calls: %+v", calls)
+	}
+	for _, c := range calls {
+		if c.Model != "fake-fast" {
+			t.Fatalf("unexpected escalation: %+v", c)
+		}
+	}
+}
+
+func TestAIRouter_EmptyOCRResponse(t *testing.T) {
+	backend := NewFakeBackend()
+	backend.Respond = func(req GenerateRequest) (string, error) {
+		return "", ErrEmptyResponse
+	}
+	router := NewAIRouterWithBackend(backend)
+
+	bundle, err := router.ExtractOCRBundle(context.Background(), []byte("img"), "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bundle.OCRText != "" {
+		t.Fatalf("expected empty bundle, got %+v", bundle)
+	}
+}
@@ -25,34 +25,36 @@ type OAuthCredentials struct {
 	RefreshToken         string
 	ClientID             string
 	ClientSecret         string
+
+	mu sync.Mutex
 }
 
 var (
-	oauthCreds   *OAuthCredentials
+	oauthCreds   = make(map[string]*OAuthCredentials) // key: テナントID
 	oauthCredsMu sync.Mutex
 )
 
-// GetOAuthCredentials はOAuth認証情報を取得（シングルトン）
-func GetOAuthCredentials(ctx context.Context) (*OAuthCredentials, error) {
+// GetOAuthCredentials はテナントのOAuth認証情報を取得（テナントごとに1つ）
+func GetOAuthCredentials(ctx context.Context, tenant *config.Tenant) (*OAuthCredentials, error) {
 	oauthCredsMu.Lock()
 	defer oauthCredsMu.Unlock()
 
-	if oauthCreds != nil {
-		return oauthCreds, nil
+	if creds, ok := oauthCreds[tenant.ID]; ok {
+		return creds, nil
 	}
 
-	creds, err := loadOAuthCredentials(ctx)
+	creds, err := loadOAuthCredentials(ctx, tenant)
 	if err != nil {
 		return nil, err
 	}
-	oauthCreds = creds
-	return oauthCreds, nil
+	oauthCreds[tenant.ID] = creds
+	return creds, nil
 }
 
 // GetAccessToken は有効なアクセストークンを取得（必要に応じてリフレッシュ）
 func (c *OAuthCredentials) GetAccessToken(ctx context.Context) (string, error) {
-	oauthCredsMu.Lock()
-	defer oauthCredsMu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
 	// 期限切れ（または未取得）の場合のみ更新
 	if c.AccessToken == "" || tokenExpiredSoon(c.AccessTokenExpiresAt) {
@@ -121,22 +123,24 @@ func tokenExpiredSoon(expiresAt time.Time) bool {
 	return time.Now().After(expiresAt.Add(-1 * time.Minute))
 }
 
-// loadOAuthCredentials はSecret Managerや環境変数から認証情報を読み込み
-func loadOAuthCredentials(ctx context.Context) (*OAuthCredentials, error) {
+// loadOAuthCredentials はSecret Managerや環境変数からテナントの認証情報を読み込み
+// クライアントID/シークレットは全テナント共通（同じOAuthアプリで各世帯が認可する）
+func loadOAuthCredentials(ctx context.Context, tenant *config.Tenant) (*OAuthCredentials, error) {
 	var refreshToken string
+	secretID := tenant.Secrets.OAuthRefreshToken
 
 	// Secret Managerから取得を試行
 	client, err := secretmanager.NewClient(ctx)
 	if err == nil {
 		defer client.Close()
 
-		secretName := fmt.Sprintf("projects/%s/secrets/OAUTH_REFRESH_TOKEN/versions/latest", config.GCPProjectID)
+		secretName := fmt.Sprintf("projects/%s/secrets/%s/versions/latest", config.GCPProjectID, secretID)
 		result, err := client.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{
 			Name: secretName,
 		})
 		if err == nil {
 			refreshToken = strings.TrimSpace(string(result.Payload.Data))
-			log.Println("OAuth refresh token loaded from Secret Manager")
+			log.Printf("OAuth refresh token loaded from Secret Manager (tenant=%s)", tenant.ID)
 		} else {
 			log.Printf("Secret Manager読み込み失敗: %v", err)
 		}
@@ -144,17 +148,17 @@ func loadOAuthCredentials(ctx context.Context) (*OAuthCredentials, error) {
 
 	// 環境変数フォールバック
 	if refreshToken == "" {
-		refreshToken = strings.TrimSpace(os.Getenv("OAUTH_REFRESH_TOKEN"))
-		if refreshToken == "" {
+		refreshToken = strings.TrimSpace(os.Getenv(secretID))
+		if refreshToken == "" && tenant.ID == config.DefaultTenantID {
 			refreshToken = strings.TrimSpace(os.Getenv("PHOTOS_REFRESH_TOKEN"))
 		}
 		if refreshToken != "" {
-			log.Println("OAuth refresh token loaded from environment variable")
+			log.Printf("OAuth refresh token loaded from environment variable (tenant=%s)", tenant.ID)
 		}
 	}
 
 	if refreshToken == "" {
-		return nil, fmt.Errorf("OAuth refresh token not found")
+		return nil, fmt.Errorf("OAuth refresh token not found for tenant %s", tenant.ID)
 	}
 
 	clientID := strings.TrimSpace(os.Getenv("OAUTH_CLIENT_ID"))
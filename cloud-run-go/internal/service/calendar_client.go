@@ -18,24 +18,32 @@ import (
 // CalendarClient はGoogle Calendar APIクライアント
 type CalendarClient struct {
 	oauthCreds *OAuthCredentials
-	calendarID string
+	household  *config.HouseholdStore
 }
 
 // NewCalendarClient は新しいCalendarClientを作成
-func NewCalendarClient(ctx context.Context) (*CalendarClient, error) {
-	creds, err := GetOAuthCredentials(ctx)
+func NewCalendarClient(ctx context.Context, tenant *config.Tenant, household *config.HouseholdStore) (*CalendarClient, error) {
+	creds, err := GetOAuthCredentials(ctx, tenant)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get OAuth credentials for Calendar: %w", err)
 	}
 
 	return &CalendarClient{
 		oauthCreds: creds,
-		calendarID: config.CalendarID,
+		household:  household,
 	}, nil
 }
 
-// CreateEvent はカレンダーイベントを作成
-func (cc *CalendarClient) CreateEvent(ctx context.Context, event *model.Event, notes string) (string, error) {
+// calendarID は登録先のカレンダーIDを返す（未指定の場合は現在の世帯設定のカレンダーID）
+func (cc *CalendarClient) calendarID(calendarID string) string {
+	if calendarID != "" {
+		return calendarID
+	}
+	return cc.household.Current().CalendarID
+}
+
+// CreateEvent は登録先のカレンダーにイベントを作成（色・招待する家族は登録先の設定に従う）
+func (cc *CalendarClient) CreateEvent(ctx context.Context, dest model.CalendarDestination, event *model.Event, notes string) (string, error) {
 	accessToken, err := cc.oauthCreds.GetAccessToken(ctx)
 	if err != nil {
 		return "", fmt.Errorf("failed to get access token: %w", err)
@@ -58,6 +66,16 @@ func (cc *CalendarClient) CreateEvent(ctx context.Context, event *model.Event, n
 	if event.Location != nil && *event.Location != "" {
 		eventBody["location"] = *event.Location
 	}
+	if dest.ColorID != "" {
+		eventBody["colorId"] = dest.ColorID
+	}
+	if len(dest.Attendees) > 0 {
+		attendees := make([]map[string]string, 0, len(dest.Attendees))
+		for _, email := range dest.Attendees {
+			attendees = append(attendees, map[string]string{"email": email})
+		}
+		eventBody["attendees"] = attendees
+	}
 
 	// 日時の設定
 	if event.StartTime != nil && *event.StartTime != "" {
@@ -107,8 +125,9 @@ func (cc *CalendarClient) CreateEvent(ctx context.Context, event *model.Event, n
 		return "", fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	url := fmt.Sprintf("https://www.googleapis.com/calendar/v3/calendars/%s/events", cc.calendarID)
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
+	// 招待メールは送らない（招待された家族のカレンダーには表示される）
+	apiURL := fmt.Sprintf("https://www.googleapis.com/calendar/v3/calendars/%s/events?sendUpdates=none", url.PathEscape(cc.calendarID(dest.CalendarID)))
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewReader(jsonBody))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -137,12 +156,12 @@ func (cc *CalendarClient) CreateEvent(ctx context.Context, event *model.Event, n
 		return "", fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	log.Printf("イベント作成成功: %s", event.Title)
+	log.Printf("イベント作成成功: %s (%s)", event.Title, cc.calendarID(dest.CalendarID))
 	return result.HTMLLink, nil
 }
 
-// EventExists は同じタイトルと日付のイベントが既に存在するかチェック
-func (cc *CalendarClient) EventExists(ctx context.Context, title string, dateStr string) (bool, error) {
+// EventExists は登録先のカレンダーに同じタイトルと日付のイベントが既に存在するかチェック
+func (cc *CalendarClient) EventExists(ctx context.Context, calendarID string, title string, dateStr string) (bool, error) {
 	accessToken, err := cc.oauthCreds.GetAccessToken(ctx)
 	if err != nil {
 		return false, fmt.Errorf("failed to get access token: %w", err)
@@ -155,7 +174,7 @@ func (cc *CalendarClient) EventExists(ctx context.Context, title string, dateStr
 	endDate := startDate.AddDate(0, 0, 1)
 
 	apiURL := fmt.Sprintf("https://www.googleapis.com/calendar/v3/calendars/%s/events?timeMin=%s&timeMax=%s&q=%s",
-		cc.calendarID,
+		url.PathEscape(cc.calendarID(calendarID)),
 		url.QueryEscape(startDate.Format(time.RFC3339)),
 		url.QueryEscape(endDate.Format(time.RFC3339)),
 		url.QueryEscape(title),
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// calendarDestinations は予定の対象（子供・大人）から登録先のカレンダーを求める
+// 同じカレンダーに一致したルートは1件にまとめ（色は先に一致したルート、招待は和集合）、ルートがなければ calendar_id に登録する
+func calendarDestinations(household *config.Household, result *model.AnalysisResult) []model.CalendarDestination {
+	routes := household.CalendarRoutesFor(result.TargetChildren, result.TargetAdult)
+	if len(routes) == 0 {
+		return []model.CalendarDestination{{CalendarID: household.CalendarID}}
+	}
+
+	var destinations []model.CalendarDestination
+	index := map[string]int{}
+	for _, route := range routes {
+		calendarID := route.CalendarID
+		if calendarID == "" {
+			calendarID = household.CalendarID
+		}
+		i, ok := index[calendarID]
+		if !ok {
+			i = len(destinations)
+			index[calendarID] = i
+			destinations = append(destinations, model.CalendarDestination{CalendarID: calendarID, ColorID: route.ColorID})
+		}
+		dest := &destinations[i]
+		if dest.ColorID == "" {
+			dest.ColorID = route.ColorID
+		}
+		for _, attendee := range route.Attendees {
+			if email := household.AttendeeEmail(attendee); email != "" && !contains(dest.Attendees, email) {
+				dest.Attendees = append(dest.Attendees, email)
+			}
+		}
+	}
+	return destinations
+}
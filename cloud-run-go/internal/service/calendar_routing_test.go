@@ -0,0 +1,40 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+func TestCalendarDestinations(t *testing.T) {
+	household := newTestHousehold(t).Current()
+	family := household.CalendarID
+	both := []string{"papa@example.com", "mama@example.com"}
+
+	tests := []struct {
+		name   string
+		result *model.AnalysisResult
+		want   []model.CalendarDestination
+	}{
+		{"child", &model.AnalysisResult{TargetChildren: []string{"太郎"}}, []model.CalendarDestination{{CalendarID: family, ColorID: "9", Attendees: both}}},
+		{"other child", &model.AnalysisResult{TargetChildren: []string{"花子"}}, []model.CalendarDestination{{CalendarID: family, ColorID: "4", Attendees: []string{"mama@example.com"}}}},
+		{"children on the same calendar", &model.AnalysisResult{TargetChildren: []string{"花子", "太郎"}}, []model.CalendarDestination{{CalendarID: family, ColorID: "9", Attendees: both}}},
+		{"adult with own calendar", &model.AnalysisResult{TargetAdult: "父"}, []model.CalendarDestination{{CalendarID: "papa-calendar-id@group.calendar.google.com"}}},
+		{"unmatched uses default route", &model.AnalysisResult{TargetAdult: "母"}, []model.CalendarDestination{{CalendarID: family, Attendees: both}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calendarDestinations(household, tt.result); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("calendarDestinations() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	noRoutes := &config.Household{CalendarID: "family@example.com"}
+	got := calendarDestinations(noRoutes, &model.AnalysisResult{TargetChildren: []string{"太郎"}})
+	if want := []model.CalendarDestination{{CalendarID: "family@example.com"}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("without routes = %+v, want %+v", got, want)
+	}
+}
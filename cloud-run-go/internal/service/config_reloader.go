@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// ReloadSource はホットリロード対象の設定
+type ReloadSource interface {
+	// Fingerprint は変更検知用の値を返す（値が変わったら再読み込みする）
+	Fingerprint(ctx context.Context) (string, error)
+	// PrepareReload は設定を読み込んで検証し、適用関数を返す
+	// 検証に失敗した場合は現在の設定を変更しない
+	PrepareReload(ctx context.Context) (func(), error)
+}
+
+type namedReloadSource struct {
+	name   string
+	source ReloadSource
+}
+
+// ConfigReloader は設定ファイルの再読み込みを行う
+// 全ての設定の検証に通った場合のみ、登録順にまとめて差し替える
+type ConfigReloader struct {
+	mu           sync.Mutex
+	sources      []namedReloadSource
+	fingerprints map[string]string
+	status       model.ConfigReloadStatus
+}
+
+// NewConfigReloader は新しいConfigReloaderを作成
+func NewConfigReloader() *ConfigReloader {
+	return &ConfigReloader{
+		fingerprints: make(map[string]string),
+		status:       model.ConfigReloadStatus{LoadedAt: time.Now()},
+	}
+}
+
+// Register はリロード対象を登録する（依存される設定を先に登録すること）
+func (r *ConfigReloader) Register(ctx context.Context, name string, source ReloadSource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sources = append(r.sources, namedReloadSource{name: name, source: source})
+	r.status.Sources = append(r.status.Sources, name)
+	if fp, err := source.Fingerprint(ctx); err == nil {
+		r.fingerprints[name] = fp
+	}
+}
+
+// Reload は全ての設定を再読み込みする
+// いずれかが不正な場合はどれも適用せず、現在の設定を維持する
+func (r *ConfigReloader) Reload(ctx context.Context) (model.ConfigReloadStatus, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reloadLocked(ctx)
+}
+
+func (r *ConfigReloader) reloadLocked(ctx context.Context) (model.ConfigReloadStatus, error) {
+	now := time.Now()
+	r.status.LastAttempt = &now
+
+	applies := make([]func(), 0, len(r.sources))
+	var errs []error
+	for _, s := range r.sources {
+		apply, err := s.source.PrepareReload(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+			continue
+		}
+		applies = append(applies, apply)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		r.status.LastError = err.Error()
+		r.status.RejectCount++
+		log.Printf("Config reload rejected, keeping current config: %v", err)
+		return r.status, err
+	}
+
+	for _, apply := range applies {
+		apply()
+	}
+	for _, s := range r.sources {
+		if fp, err := s.source.Fingerprint(ctx); err == nil {
+			r.fingerprints[s.name] = fp
+		}
+	}
+	r.status.LoadedAt = now
+	r.status.LastError = ""
+	r.status.ReloadCount++
+	log.Printf("Config reloaded: %s", strings.Join(r.status.Sources, ", "))
+	return r.status, nil
+}
+
+// Status は現在のリロード状況を返す
+func (r *ConfigReloader) Status() model.ConfigReloadStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+// CheckAndReload は変更があった場合のみ再読み込みする
+// 不正な設定は同じ内容で繰り返し試行しないよう、検知した値を記録しておく
+func (r *ConfigReloader) CheckAndReload(ctx context.Context) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed := false
+	for _, s := range r.sources {
+		fp, err := s.source.Fingerprint(ctx)
+		if err != nil {
+			log.Printf("Warning: config fingerprint failed for %s: %v", s.name, err)
+			continue
+		}
+		if fp != r.fingerprints[s.name] {
+			r.fingerprints[s.name] = fp
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+
+	log.Printf("Config change detected, reloading")
+	_, err := r.reloadLocked(ctx)
+	return true, err
+}
+
+// Watch は一定間隔で設定の変更を確認し、変更があれば再読み込みする（ctx終了まで）
+func (r *ConfigReloader) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	r.mu.Lock()
+	r.status.PollInterval = interval.String()
+	r.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = r.CheckAndReload(ctx)
+		}
+	}
+}
+
+// householdReloadSource は世帯設定ファイルのリロード対象
+type householdReloadSource struct {
+	store  *config.HouseholdStore
+	source string
+	fetch  config.HouseholdFetcher
+}
+
+// NewHouseholdReloadSource は世帯設定をリロード対象として扱うReloadSourceを作成
+func NewHouseholdReloadSource(store *config.HouseholdStore, source string, fetch config.HouseholdFetcher) ReloadSource {
+	return &householdReloadSource{store: store, source: source, fetch: fetch}
+}
+
+func (s *householdReloadSource) Fingerprint(ctx context.Context) (string, error) {
+	fileID, ok := strings.CutPrefix(s.source, "drive://")
+	if !ok {
+		return config.FileFingerprint(s.source), nil
+	}
+	if s.fetch == nil {
+		return "", errors.New("drive fetcher is not configured")
+	}
+	data, err := s.fetch(ctx, fileID)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (s *householdReloadSource) PrepareReload(ctx context.Context) (func(), error) {
+	h, err := config.LoadHousehold(ctx, s.source, s.fetch)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		// LoadHousehold で検証済みのためエラーにはならない
+		if err := s.store.Replace(h); err != nil {
+			log.Printf("Warning: household replace failed: %v", err)
+		}
+	}, nil
+}
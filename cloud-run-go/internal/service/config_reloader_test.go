@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type stubReloadSource struct {
+	fingerprint string
+	err         error
+	applied     int
+}
+
+func (s *stubReloadSource) Fingerprint(ctx context.Context) (string, error) {
+	return s.fingerprint, nil
+}
+
+func (s *stubReloadSource) PrepareReload(ctx context.Context) (func(), error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return func() { s.applied++ }, nil
+}
+
+func TestConfigReloader_RejectsAllOnInvalid(t *testing.T) {
+	ctx := context.Background()
+	good := &stubReloadSource{fingerprint: "a"}
+	bad := &stubReloadSource{fingerprint: "b", err: errors.New("broken")}
+
+	r := NewConfigReloader()
+	r.Register(ctx, "good", good)
+	r.Register(ctx, "bad", bad)
+
+	status, err := r.Reload(ctx)
+	if err == nil {
+		t.Fatal("expected reload error")
+	}
+	if good.applied != 0 {
+		t.Fatal("valid source must not be applied when another source is invalid")
+	}
+	if status.RejectCount != 1 || status.LastError == "" {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+
+	bad.err = nil
+	if _, err := r.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if good.applied != 1 || bad.applied != 1 {
+		t.Fatalf("expected both sources applied: good=%d bad=%d", good.applied, bad.applied)
+	}
+	if st := r.Status(); st.LastError != "" || st.ReloadCount != 1 {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+}
+
+func TestConfigReloader_CheckAndReload(t *testing.T) {
+	ctx := context.Background()
+	src := &stubReloadSource{fingerprint: "v1"}
+
+	r := NewConfigReloader()
+	r.Register(ctx, "src", src)
+
+	if changed, err := r.CheckAndReload(ctx); changed || err != nil {
+		t.Fatalf("unchanged config should not reload: changed=%v err=%v", changed, err)
+	}
+
+	src.fingerprint = "v2"
+	src.err = errors.New("invalid")
+	if changed, err := r.CheckAndReload(ctx); !changed || err == nil {
+		t.Fatalf("expected rejected reload: changed=%v err=%v", changed, err)
+	}
+	// 同じ内容の不正な設定は再試行しない
+	if changed, _ := r.CheckAndReload(ctx); changed {
+		t.Fatal("rejected fingerprint should not be retried")
+	}
+
+	src.fingerprint = "v3"
+	src.err = nil
+	if changed, err := r.CheckAndReload(ctx); !changed || err != nil {
+		t.Fatalf("expected reload: changed=%v err=%v", changed, err)
+	}
+	if src.applied != 1 {
+		t.Fatalf("applied = %d, want 1", src.applied)
+	}
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// ErrDeadLetterNotFound はデッドレターに該当ファイルがない場合のエラー
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetterStore は処理に失敗したファイルを保持するストア
+type DeadLetterStore interface {
+	Put(ctx context.Context, entry *model.DeadLetterEntry) error
+	// Get はエントリを取得する。存在しない場合は nil を返す
+	Get(ctx context.Context, fileID string) (*model.DeadLetterEntry, error)
+	List(ctx context.Context) ([]*model.DeadLetterEntry, error)
+	Delete(ctx context.Context, fileID string) error
+}
+
+// FileDeadLetterStore はディレクトリ上のJSONファイルでデッドレターを管理する実装
+type FileDeadLetterStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileDeadLetterStore は新しいFileDeadLetterStoreを作成
+func NewFileDeadLetterStore(dir string) (*FileDeadLetterStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create dead letter dir: %w", err)
+	}
+	return &FileDeadLetterStore{dir: dir}, nil
+}
+
+// Put はエントリを保存（同じファイルIDは上書き）
+func (s *FileDeadLetterStore) Put(ctx context.Context, entry *model.DeadLetterEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeJSONAtomic(s.dir, entry.FileID, entry)
+}
+
+// Get はエントリを取得
+func (s *FileDeadLetterStore) Get(ctx context.Context, fileID string) (*model.DeadLetterEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(fileID)
+}
+
+// List は全エントリを新しい順で取得
+func (s *FileDeadLetterStore) List(ctx context.Context) ([]*model.DeadLetterEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letter dir: %w", err)
+	}
+
+	var entries []*model.DeadLetterEntry
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		entry, err := s.load(strings.TrimSuffix(f.Name(), ".json"))
+		if err != nil || entry == nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.After(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
+
+// Delete はエントリを削除
+func (s *FileDeadLetterStore) Delete(ctx context.Context, fileID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(filepath.Join(s.dir, fileID+".json")); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrDeadLetterNotFound
+		}
+		return fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+	return nil
+}
+
+func (s *FileDeadLetterStore) load(fileID string) (*model.DeadLetterEntry, error) {
+	b, err := os.ReadFile(filepath.Join(s.dir, fileID+".json"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read dead letter: %w", err)
+	}
+	var entry model.DeadLetterEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse dead letter: %w", err)
+	}
+	return &entry, nil
+}
+
+// SetDeadLetterStore はデッドレターストアを設定（未設定時は失敗ファイルを台帳に残すのみ）
+func (fs *FileSorter) SetDeadLetterStore(store DeadLetterStore) {
+	fs.deadLetters = store
+}
+
+// deadLetter は失敗したファイルをデッドレターに入れ、未仕分けなら「要確認」フォルダへ移動する
+func (fs *FileSorter) deadLetter(ctx context.Context, fileInfo *model.FileInfo, cause error) {
+	if fs.deadLetters == nil {
+		return
+	}
+
+	entry, err := fs.ledger.Get(ctx, fileInfo.ID)
+	if err != nil || entry == nil {
+		log.Printf("Warning: デッドレター登録のための台帳参照失敗 (%s): %v", fileInfo.ID, err)
+		return
+	}
+
+	dl := &model.DeadLetterEntry{
+		FileID:        fileInfo.ID,
+		FileName:      fileInfo.Name,
+		Error:         cause.Error(),
+		FailedSteps:   failedSteps(entry),
+		Attempts:      entry.Attempts,
+		PreviousState: entry.State,
+		CreatedAt:     time.Now(),
+	}
+
+	// 移動前に失敗したファイルのみ「要確認」フォルダへ移す（仕分け済みのファイルはそのまま）
+	if !reached(entry, model.ProcessingStateMoved) && len(fileInfo.Parents) > 0 {
+		reviewFolderID, err := fs.reviewFolderID(ctx)
+		if err != nil {
+			log.Printf("Warning: 要確認フォルダの取得失敗: %v", err)
+		} else if err := fs.driveClient.MoveFile(ctx, fileInfo.ID, reviewFolderID); err != nil {
+			log.Printf("Warning: 要確認フォルダへの移動失敗 (%s): %v", fileInfo.Name, err)
+		} else {
+			dl.MovedToReview = true
+			dl.OriginalParent = fileInfo.Parents[0]
+		}
+	}
+
+	if err := fs.deadLetters.Put(ctx, dl); err != nil {
+		log.Printf("Warning: デッドレター登録失敗 (%s): %v", fileInfo.ID, err)
+		return
+	}
+	fs.updateEntry(ctx, fileInfo.ID, func(e *model.LedgerEntry) {
+		e.Transition(model.ProcessingStateDeadLettered, dl.Error)
+		e.LastError = dl.Error
+	})
+	log.Printf("デッドレターに登録: %s (steps=%v, attempts=%d)", fileInfo.Name, dl.FailedSteps, dl.Attempts)
+}
+
+// reviewFolderID は「要確認」フォルダのIDを取得
+func (fs *FileSorter) reviewFolderID(ctx context.Context) (string, error) {
+	if config.DeadLetter.ReviewFolderID != "" {
+		return config.DeadLetter.ReviewFolderID, nil
+	}
+	return fs.driveClient.GetOrCreateFolder(ctx, config.DeadLetter.ReviewFolder, fs.household.Current().FolderIDs["SOURCE"])
+}
+
+// failedSteps は台帳上で失敗しているステップ名を返す
+func failedSteps(entry *model.LedgerEntry) []model.StepName {
+	var steps []model.StepName
+	for name, rec := range entry.Steps {
+		if rec.Status == model.StepStatusFailed {
+			steps = append(steps, name)
+		}
+	}
+	sort.Slice(steps, func(i, j int) bool { return steps[i] < steps[j] })
+	return steps
+}
+
+// ListDeadLetters はデッドレターの一覧を取得
+func (fs *FileSorter) ListDeadLetters(ctx context.Context) ([]*model.DeadLetterEntry, error) {
+	if fs.deadLetters == nil {
+		return nil, nil
+	}
+	return fs.deadLetters.List(ctx)
+}
+
+// ReplayDeadLetter はデッドレターのファイルを再処理する
+// 「要確認」フォルダへ移動していた場合は元のフォルダに戻し、成功済みのステップは再実行しない
+func (fs *FileSorter) ReplayDeadLetter(ctx context.Context, fileID string) (model.ProcessResult, error) {
+	dl, err := fs.takeDeadLetter(ctx, fileID)
+	if err != nil {
+		return model.ProcessResultError, err
+	}
+
+	if dl.MovedToReview {
+		if err := fs.driveClient.MoveFile(ctx, fileID, dl.OriginalParent); err != nil {
+			// 戻せなかった場合はデッドレターに残す
+			if putErr := fs.deadLetters.Put(ctx, dl); putErr != nil {
+				log.Printf("Warning: デッドレター再登録失敗 (%s): %v", fileID, putErr)
+			}
+			return model.ProcessResultError, fmt.Errorf("元のフォルダへの移動失敗: %w", err)
+		}
+	}
+
+	if _, err := fs.ledger.Update(ctx, fileID, func(e *model.LedgerEntry) {
+		resetForReplay(e, dl.PreviousState)
+	}); err != nil {
+		return model.ProcessResultError, fmt.Errorf("処理台帳の更新失敗: %w", err)
+	}
+
+	log.Printf("デッドレターから再処理: %s (%s)", dl.FileName, fileID)
+	return fs.ProcessFile(ctx, fileID), nil
+}
+
+// resetForReplay はデッドレターに登録される前の状態に戻し、試行回数を数え直す
+// （回数が残っていると1回の失敗で再びデッドレターになり、解析も最初からローカルOCRになるため）
+func resetForReplay(e *model.LedgerEntry, previous model.ProcessingState) {
+	e.Attempts = 0
+	for _, rec := range e.Steps {
+		if rec.Status == model.StepStatusFailed {
+			rec.Attempts = 0
+		}
+	}
+	e.Transition(previous, "デッドレターから再処理")
+}
+
+// DiscardDeadLetter はデッドレターから削除し、以後の処理対象外とする（ファイルはそのまま残す）
+func (fs *FileSorter) DiscardDeadLetter(ctx context.Context, fileID string) error {
+	dl, err := fs.takeDeadLetter(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	fs.recordState(ctx, fileID, model.ProcessingStateSkipped, "デッドレターから破棄")
+	log.Printf("デッドレターから破棄: %s (%s)", dl.FileName, fileID)
+	return nil
+}
+
+// takeDeadLetter はデッドレターを取得してストアから取り除く
+func (fs *FileSorter) takeDeadLetter(ctx context.Context, fileID string) (*model.DeadLetterEntry, error) {
+	if fs.deadLetters == nil {
+		return nil, ErrDeadLetterNotFound
+	}
+	dl, err := fs.deadLetters.Get(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if dl == nil {
+		return nil, ErrDeadLetterNotFound
+	}
+	if err := fs.deadLetters.Delete(ctx, fileID); err != nil {
+		return nil, err
+	}
+	return dl, nil
+}
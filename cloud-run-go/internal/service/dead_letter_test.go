@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+func TestFileDeadLetterStore_PutListDelete(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileDeadLetterStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if err := store.Put(ctx, &model.DeadLetterEntry{FileID: "old", Error: "e1", CreatedAt: now.Add(-time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(ctx, &model.DeadLetterEntry{FileID: "new", Error: "e2", FailedSteps: []model.StepName{model.StepMove}, CreatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := store.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].FileID != "new" || entries[0].FailedSteps[0] != model.StepMove {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+
+	if err := store.Delete(ctx, "new"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := store.Get(ctx, "new"); err != nil || got != nil {
+		t.Fatalf("entry should be deleted: %+v err=%v", got, err)
+	}
+	if err := store.Delete(ctx, "new"); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Fatalf("expected ErrDeadLetterNotFound, got %v", err)
+	}
+}
+
+func TestFailedSteps(t *testing.T) {
+	entry := &model.LedgerEntry{Steps: map[model.StepName]*model.StepRecord{
+		model.StepAnalyze:  {Status: model.StepStatusSucceeded},
+		model.StepTasks:    {Status: model.StepStatusFailed},
+		model.StepCalendar: {Status: model.StepStatusFailed},
+	}}
+	got := failedSteps(entry)
+	if len(got) != 2 || got[0] != model.StepCalendar || got[1] != model.StepTasks {
+		t.Fatalf("unexpected failed steps: %v", got)
+	}
+}
+
+func TestResetForReplay_FailsAgainWithoutDeadLettering(t *testing.T) {
+	ctx := context.Background()
+	ledger, err := NewFileLedger(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 解析の再試行を使い切ってデッドレターになったファイル
+	if _, err := ledger.Update(ctx, "file1", func(e *model.LedgerEntry) {
+		e.Attempts = config.DeadLetter.MaxAttempts
+		e.Transition(model.ProcessingStateDeadLettered, "")
+		e.Steps = map[model.StepName]*model.StepRecord{
+			model.StepDedup:   {Status: model.StepStatusSucceeded, Attempts: 1},
+			model.StepAnalyze: {Status: model.StepStatusFailed, Attempts: config.API.MaxRetries * config.DeadLetter.MaxAttempts},
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ledger.Update(ctx, "file1", func(e *model.LedgerEntry) {
+		resetForReplay(e, model.ProcessingStateReceived)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 再処理で1回失敗しても、まだデッドレターにもローカルOCRにもならない
+	entry, acquired, err := ledger.Acquire(ctx, "file1", "test", time.Minute)
+	if err != nil || !acquired {
+		t.Fatalf("Acquire() = %v, %v", acquired, err)
+	}
+	if entry.State != model.ProcessingStateReceived {
+		t.Fatalf("state = %s, want %s", entry.State, model.ProcessingStateReceived)
+	}
+	if isFinalAttempt(entry, model.StepAnalyze) && config.API.MaxRetries > 1 {
+		t.Fatal("analysis should not go straight to local OCR after replay")
+	}
+	entry.Fail(errors.New("temporary"))
+	if entry.Attempts >= config.DeadLetter.MaxAttempts {
+		t.Fatalf("attempts = %d, should be below %d after one failure", entry.Attempts, config.DeadLetter.MaxAttempts)
+	}
+	if entry.Steps[model.StepDedup].Attempts != 1 {
+		t.Fatal("succeeded steps should keep their attempts")
+	}
+}
@@ -0,0 +1,310 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // 知覚ハッシュ計算用のデコーダ登録
+	_ "image/jpeg" // 知覚ハッシュ計算用のデコーダ登録
+	_ "image/png"  // 知覚ハッシュ計算用のデコーダ登録
+	"log"
+	"math/bits"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// errDuplicate はパイプラインを重複書類として中断したことを示す
+var errDuplicate = errors.New("duplicate document")
+
+// DedupIndex は仕分け済み書類の指紋を保持し、重複を検索するインデックス
+type DedupIndex interface {
+	// Lookup は指紋が一致する書類を返す（excludeFileID 自身は除く）。該当なしの場合は nil を返す
+	Lookup(ctx context.Context, fp *model.DocumentFingerprint, excludeFileID string) (*model.DedupEntry, error)
+	Record(ctx context.Context, entry *model.DedupEntry) error
+}
+
+// FileDedupIndex はインスタンスごとのJSON Lines（dedup-index.<instance>.jsonl）に追記する実装
+// 他のインスタンスが記録した書類も見つけられるよう、検索のたびにすべてのファイルを読み直す
+type FileDedupIndex struct {
+	log       instanceLog
+	threshold int
+	mu        sync.Mutex
+}
+
+// NewFileDedupIndex は新しいFileDedupIndexを作成（threshold は知覚ハッシュのハミング距離の許容値）
+func NewFileDedupIndex(dir string, threshold int) (*FileDedupIndex, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create dedup dir: %w", err)
+	}
+	compactInstanceLogs(dir, instanceID, time.Now())
+	return &FileDedupIndex{log: newInstanceLog(dir, "dedup-index"), threshold: threshold}, nil
+}
+
+// Lookup はSHA-256の完全一致を優先し、なければ知覚ハッシュの距離が最も近い書類を返す
+func (x *FileDedupIndex) Lookup(ctx context.Context, fp *model.DocumentFingerprint, excludeFileID string) (*model.DedupEntry, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	var best *model.DedupEntry
+	err := x.log.scan(func(line []byte) bool {
+		var e model.DedupEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			// 書き込み途中の行などは読み飛ばす
+			return true
+		}
+		if e.FileID == excludeFileID {
+			return true
+		}
+		// 完全一致は、先に見つかった距離0の見た目が同じ書類より優先する
+		if fp.SHA256 != "" && fp.SHA256 == e.Fingerprint.SHA256 {
+			e.Distance = 0
+			best = &e
+			return false
+		}
+		distance, ok := fingerprintDistance(fp, &e.Fingerprint, x.threshold)
+		if !ok || (best != nil && best.Distance <= distance) {
+			return true
+		}
+		e.Distance = distance
+		best = &e
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dedup index: %w", err)
+	}
+	return best, nil
+}
+
+// Record は書類の指紋を自インスタンスのファイルに追記する
+func (x *FileDedupIndex) Record(ctx context.Context, entry *model.DedupEntry) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if err := x.log.append(entry); err != nil {
+		return fmt.Errorf("failed to write dedup entry: %w", err)
+	}
+	return nil
+}
+
+// fingerprintDistance は2つの指紋の距離を返す
+// SHA-256が一致すれば0、そうでなければページ数が同じ場合に限りページごとのハミング距離の最大値で判定する
+func fingerprintDistance(a, b *model.DocumentFingerprint, threshold int) (int, bool) {
+	if a.SHA256 != "" && a.SHA256 == b.SHA256 {
+		return 0, true
+	}
+	if len(a.PageHashes) == 0 || len(a.PageHashes) != len(b.PageHashes) {
+		return 0, false
+	}
+	maxDistance := 0
+	for i := range a.PageHashes {
+		if d := bits.OnesCount64(a.PageHashes[i] ^ b.PageHashes[i]); d > maxDistance {
+			maxDistance = d
+		}
+	}
+	return maxDistance, maxDistance <= threshold
+}
+
+// perceptualHash は画像の64ビットdHash（隣接ブロックの明暗差）を計算する
+// 解像度・圧縮率・軽微な明るさの違いに影響されにくく、再スキャンや再保存した画像も近い値になる
+func perceptualHash(data []byte) (uint64, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return dHash(img), nil
+}
+
+// dHash は画像を9x8のグレースケールに縮小し、各行で左のブロックが右より暗ければ1を立てる
+func dHash(img image.Image) uint64 {
+	const w, h = 9, 8
+	var grid [h][w]float64
+
+	b := img.Bounds()
+	for gy := 0; gy < h; gy++ {
+		y0, y1 := blockRange(b.Min.Y, b.Dy(), gy, h)
+		for gx := 0; gx < w; gx++ {
+			x0, x1 := blockRange(b.Min.X, b.Dx(), gx, w)
+			grid[gy][gx] = meanLuminance(img, x0, x1, y0, y1)
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w-1; x++ {
+			hash <<= 1
+			if grid[y][x] < grid[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// blockRange は長さ size を n 分割した i 番目の範囲を返す（最低1ピクセル）
+func blockRange(min, size, i, n int) (int, int) {
+	start := min + i*size/n
+	end := min + (i+1)*size/n
+	if end <= start {
+		end = start + 1
+	}
+	return start, end
+}
+
+// meanLuminance は範囲内の平均輝度を返す（大きな画像は間引いて計算する）
+func meanLuminance(img image.Image, x0, x1, y0, y1 int) float64 {
+	const maxSamples = 32
+	stepX := max(1, (x1-x0)/maxSamples)
+	stepY := max(1, (y1-y0)/maxSamples)
+
+	var sum float64
+	var n int
+	for y := y0; y < y1; y += stepY {
+		for x := x0; x < x1; x += stepX {
+			r, g, b, _ := img.At(x, y).RGBA()
+			sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// SetDedupIndex は重複判定インデックスを設定（未設定時は重複判定を行わない）
+func (fs *FileSorter) SetDedupIndex(index DedupIndex) {
+	fs.dedupIndex = index
+}
+
+// fingerprint は書類の指紋を計算する
+// 知覚ハッシュは画像そのもの、PDFは低解像度で描画した先頭ページ群から求め、計算できない場合はSHA-256のみとする
+func (fs *FileSorter) fingerprint(data []byte, mimeType string) *model.DocumentFingerprint {
+	sum := sha256.Sum256(data)
+	fp := &model.DocumentFingerprint{SHA256: hex.EncodeToString(sum[:])}
+
+	pages := [][]byte{data}
+	if fs.pdfProcessor.IsPDF(mimeType) {
+		images, err := fs.pdfProcessor.ConvertPDFToImages(data, config.Dedup.PreviewDPI)
+		if err != nil {
+			log.Printf("Warning: 重複判定用のPDF描画失敗: %v", err)
+			return fp
+		}
+		pages = images
+	}
+	if len(pages) > config.Dedup.MaxPages {
+		pages = pages[:config.Dedup.MaxPages]
+	}
+
+	hashes := make([]uint64, 0, len(pages))
+	for i, page := range pages {
+		hash, err := perceptualHash(page)
+		if err != nil {
+			// 一部のページだけの知覚ハッシュは誤判定につながるため使わない
+			log.Printf("Warning: 知覚ハッシュ計算失敗 (page %d): %v", i+1, err)
+			return fp
+		}
+		hashes = append(hashes, hash)
+	}
+	fp.PageHashes = hashes
+	return fp
+}
+
+// stepDedup は書類の指紋を計算し、仕分け済みの書類と重複していないかを調べる
+// SHA-256が一致した場合のみ重複とし、知覚ハッシュのみの一致は同じ様式の別の書類（毎月のお便りなど）の
+// 可能性があるため、解析後に家族のレビューで確認する
+func (fs *FileSorter) stepDedup(ctx context.Context, r *pipelineRun) (string, error) {
+	if fs.dedupIndex == nil || !config.Dedup.Enabled {
+		return "", skipStep("重複判定無効")
+	}
+	if r.entry.Analysis != nil {
+		return "", skipStep("解析済み")
+	}
+
+	data, err := fs.fileData(ctx, r)
+	if err != nil {
+		return "", err
+	}
+	fp := fs.fingerprint(data, r.fileInfo.MimeType)
+
+	// インデックスの障害で仕分けを止めないよう、検索失敗時は重複なしとして続行する
+	match, err := fs.dedupIndex.Lookup(ctx, fp, r.fileInfo.ID)
+	if err != nil {
+		log.Printf("Warning: 重複判定インデックスの検索失敗: %v", err)
+	}
+
+	exact := match != nil && fp.SHA256 != "" && match.Fingerprint.SHA256 == fp.SHA256
+	fs.updateRun(ctx, r, func(e *model.LedgerEntry) {
+		e.Fingerprint = fp
+		if exact {
+			e.DuplicateOf = match
+		} else {
+			e.SimilarTo = match
+		}
+	})
+	if exact {
+		return fmt.Sprintf("duplicate of %s", match.FileID), nil
+	}
+	if match != nil {
+		return fmt.Sprintf("similar to %s (distance=%d)", match.FileID, match.Distance), nil
+	}
+	return fmt.Sprintf("sha256=%.12s pages=%d", fp.SHA256, len(fp.PageHashes)), nil
+}
+
+// parkDuplicate は重複書類を「重複」フォルダへ移動し、説明欄に元のファイルへのリンクを残す
+func (fs *FileSorter) parkDuplicate(ctx context.Context, r *pipelineRun) error {
+	original := r.entry.DuplicateOf
+
+	folderID, err := fs.duplicateFolderID(ctx)
+	if err != nil {
+		return fmt.Errorf("重複フォルダの取得失敗: %w", err)
+	}
+	if !contains(r.fileInfo.Parents, folderID) {
+		if err := fs.driveClient.MoveFile(ctx, r.fileInfo.ID, folderID); err != nil {
+			return fmt.Errorf("重複フォルダへの移動失敗: %w", err)
+		}
+	}
+
+	description := fmt.Sprintf("重複: 元のファイル %s\nhttps://drive.google.com/file/d/%s/view", original.FileName, original.FileID)
+	if err := fs.driveClient.SetDescription(ctx, r.fileInfo.ID, description); err != nil {
+		log.Printf("Warning: 重複ファイルの説明設定失敗 (%s): %v", r.fileInfo.Name, err)
+	}
+
+	fs.updateRun(ctx, r, func(e *model.LedgerEntry) {
+		e.Transition(model.ProcessingStateDuplicate, original.FileID)
+	})
+	log.Printf("重複書類のため移動: %s (元: %s, distance=%d)", r.fileInfo.Name, original.FileName, original.Distance)
+	return errDuplicate
+}
+
+// duplicateFolderID は「重複」フォルダのIDを取得
+func (fs *FileSorter) duplicateFolderID(ctx context.Context) (string, error) {
+	if config.Dedup.FolderID != "" {
+		return config.Dedup.FolderID, nil
+	}
+	return fs.driveClient.GetOrCreateFolder(ctx, config.Dedup.Folder, fs.household.Current().FolderIDs["SOURCE"])
+}
+
+// recordFingerprint は仕分けを終えた書類の指紋をインデックスに登録（失敗は処理を止めない）
+func (fs *FileSorter) recordFingerprint(ctx context.Context, r *pipelineRun) {
+	if fs.dedupIndex == nil || r.entry.Fingerprint == nil || r.entry.DuplicateOf != nil {
+		return
+	}
+	entry := &model.DedupEntry{
+		FileID:      r.fileInfo.ID,
+		FileName:    r.newFileName,
+		Fingerprint: *r.entry.Fingerprint,
+		RecordedAt:  time.Now(),
+	}
+	if err := fs.dedupIndex.Record(ctx, entry); err != nil {
+		log.Printf("Warning: 重複判定インデックスへの登録失敗 (%s): %v", r.fileInfo.Name, err)
+	}
+}
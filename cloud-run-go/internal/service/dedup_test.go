@@ -0,0 +1,237 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/bits"
+	"testing"
+
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// testDocumentImage は書類風の画像（白地に黒い帯）を生成する
+func testDocumentImage(w, h int, bands []int) image.Image {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetGray(x, y, color.Gray{Y: 240})
+		}
+	}
+	for _, top := range bands {
+		y0 := top * h / 100
+		for y := y0; y < y0+h/20 && y < h; y++ {
+			for x := w / 10; x < w*(3+top%5)/10; x++ {
+				img.SetGray(x, y, color.Gray{Y: 20})
+			}
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image, quality int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestPerceptualHash(t *testing.T) {
+	letter := []int{10, 25, 40, 55, 80}
+	original, err := perceptualHash(encodePNG(t, testDocumentImage(800, 1100, letter)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		data        []byte
+		wantSimilar bool
+	}{
+		{"rescanned at lower resolution", encodeJPEG(t, testDocumentImage(400, 550, letter), 60), true},
+		{"different letter", encodeJPEG(t, testDocumentImage(800, 1100, []int{5, 15, 70, 90}), 90), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := perceptualHash(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := bits.OnesCount64(original ^ hash)
+			if similar := d <= 4; similar != tt.wantSimilar {
+				t.Fatalf("distance = %d, wantSimilar %v", d, tt.wantSimilar)
+			}
+		})
+	}
+
+	if _, err := perceptualHash([]byte("not an image")); err == nil {
+		t.Fatal("expected decode error")
+	}
+}
+
+func TestFileDedupIndex_Lookup(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	index, err := NewFileDedupIndex(dir, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index.log.instance = "a"
+	// 別のインスタンスが同じディレクトリに記録した書類も検索対象になる
+	other, err := NewFileDedupIndex(dir, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.log.instance = "b"
+
+	// 未登録の状態では一致なし
+	if match, err := index.Lookup(ctx, &model.DocumentFingerprint{SHA256: "aaa"}, ""); err != nil || match != nil {
+		t.Fatalf("match = %+v, err = %v", match, err)
+	}
+
+	if err := index.Record(ctx, &model.DedupEntry{FileID: "letter", FileName: "20250401_遠足.pdf", Fingerprint: model.DocumentFingerprint{SHA256: "aaa", PageHashes: []uint64{0xF0F0, 0x0F0F}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Record(ctx, &model.DedupEntry{FileID: "photo", FileName: "運動会.jpg", Fingerprint: model.DocumentFingerprint{SHA256: "bbb", PageHashes: []uint64{0xFF00FF00}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		fp           model.DocumentFingerprint
+		exclude      string
+		wantFileID   string
+		wantDistance int
+	}{
+		{"same content", model.DocumentFingerprint{SHA256: "aaa"}, "", "letter", 0},
+		{"rescan within threshold", model.DocumentFingerprint{SHA256: "ccc", PageHashes: []uint64{0xF0F1, 0x0F0B}}, "", "letter", 1},
+		{"one page too different", model.DocumentFingerprint{SHA256: "ccc", PageHashes: []uint64{0xF0F0, 0xFFFF}}, "", "", 0},
+		{"page count differs", model.DocumentFingerprint{SHA256: "ccc", PageHashes: []uint64{0xF0F0}}, "", "", 0},
+		{"re-encoded photo", model.DocumentFingerprint{SHA256: "ddd", PageHashes: []uint64{0xFF00FF03}}, "", "photo", 2},
+		{"own record is excluded", model.DocumentFingerprint{SHA256: "aaa"}, "letter", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := index.Lookup(ctx, &tt.fp, tt.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantFileID == "" {
+				if match != nil {
+					t.Fatalf("unexpected match: %+v", match)
+				}
+				return
+			}
+			if match == nil || match.FileID != tt.wantFileID || match.Distance != tt.wantDistance {
+				t.Fatalf("match = %+v, want %s (distance %d)", match, tt.wantFileID, tt.wantDistance)
+			}
+		})
+	}
+}
+
+func TestFileDedupIndex_LookupPrefersExactMatch(t *testing.T) {
+	ctx := context.Background()
+	index, err := NewFileDedupIndex(t.TempDir(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 見た目が同じ（距離0）別の書類が、完全一致の書類より先に記録されている
+	pages := []uint64{0xF0F0, 0x0F0F}
+	if err := index.Record(ctx, &model.DedupEntry{FileID: "lookalike", Fingerprint: model.DocumentFingerprint{SHA256: "bbb", PageHashes: pages}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Record(ctx, &model.DedupEntry{FileID: "original", Fingerprint: model.DocumentFingerprint{SHA256: "aaa", PageHashes: pages}}); err != nil {
+		t.Fatal(err)
+	}
+
+	match, err := index.Lookup(ctx, &model.DocumentFingerprint{SHA256: "aaa", PageHashes: pages}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match == nil || match.FileID != "original" || match.Distance != 0 {
+		t.Fatalf("match = %+v, want the exact match", match)
+	}
+}
+
+func TestStepDedup(t *testing.T) {
+	ctx := context.Background()
+	ledger, err := NewFileLedger(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := NewFileDedupIndex(t.TempDir(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := &FileSorter{ledger: ledger, pdfProcessor: NewPDFProcessor()}
+
+	data := encodePNG(t, testDocumentImage(200, 280, []int{10, 50}))
+	newRun := func(id string) *pipelineRun {
+		return &pipelineRun{
+			fileInfo: &model.FileInfo{ID: id, Name: id + ".png", MimeType: "image/png"},
+			entry:    &model.LedgerEntry{FileID: id},
+			data:     data,
+		}
+	}
+
+	// インデックス未設定ならスキップ
+	first := newRun("first")
+	if _, err := fs.stepDedup(ctx, first); err == nil {
+		t.Fatal("expected skip without index")
+	}
+
+	fs.SetDedupIndex(index)
+	if _, err := fs.stepDedup(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if first.entry.DuplicateOf != nil || first.entry.Fingerprint == nil || len(first.entry.Fingerprint.PageHashes) != 1 {
+		t.Fatalf("unexpected entry: %+v", first.entry)
+	}
+
+	// 仕分け完了時に登録され、同じ内容の2通目は重複と判定される
+	first.newFileName = "20250401_通知.png"
+	fs.recordFingerprint(ctx, first)
+
+	second := newRun("second")
+	if _, err := fs.stepDedup(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	dup := second.entry.DuplicateOf
+	if dup == nil || dup.FileID != "first" || dup.FileName != "20250401_通知.png" || dup.Distance != 0 {
+		t.Fatalf("duplicate_of = %+v", dup)
+	}
+
+	// 重複と判定されたファイルはインデックスに登録しない
+	fs.recordFingerprint(ctx, second)
+	if match, _ := index.Lookup(ctx, second.entry.Fingerprint, "first"); match != nil {
+		t.Fatalf("duplicate should not be indexed: %+v", match)
+	}
+
+	// 知覚ハッシュのみの一致（再スキャン・同じ様式の別の書類）は重複とせず、解析後のレビューで確認する
+	rescanned := newRun("rescanned")
+	rescanned.data = encodeJPEG(t, testDocumentImage(200, 280, []int{10, 50}), 70)
+	if _, err := fs.stepDedup(ctx, rescanned); err != nil {
+		t.Fatal(err)
+	}
+	if rescanned.entry.DuplicateOf != nil {
+		t.Fatalf("perceptual match should not be parked: %+v", rescanned.entry.DuplicateOf)
+	}
+	if similar := rescanned.entry.SimilarTo; similar == nil || similar.FileID != "first" {
+		t.Fatalf("similar_to = %+v", similar)
+	}
+}
@@ -6,8 +6,12 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"github.com/leo-sagawa/homedocmanager/internal/model"
 )
 
 // DiscordNotifier はDiscord Webhookで通知を送信するクライアント
@@ -122,24 +126,181 @@ func (d *DiscordNotifier) NotifyInboxScanResult(total, processed, skipped, error
 	d.send(discordPayload{Embeds: []discordEmbed{embed}})
 }
 
-// send はDiscord Webhookにペイロードを送信する
+// NotifyBudgetExceeded はAI使用量の月次予算超過をDiscordに通知する
+func (d *DiscordNotifier) NotifyBudgetExceeded(summary *model.AIUsageSummary) {
+	if d == nil || summary == nil {
+		return
+	}
+
+	embed := discordEmbed{
+		Title:       "AI使用量が月次予算を超過しました",
+		Description: "今月はProへのエスカレーションを停止し、Flashの結果で処理します。",
+		Color:       colorYellow,
+		Fields: []discordField{
+			{Name: "対象月", Value: summary.Month, Inline: true},
+			{Name: "費用", Value: fmt.Sprintf("$%.2f / $%.2f", summary.CostUSD, summary.BudgetUSD), Inline: true},
+			{Name: "呼び出し", Value: fmt.Sprintf("%d回（エスカレーション %d回）", summary.Calls, summary.Escalations), Inline: true},
+			{Name: "トークン", Value: fmt.Sprintf("%d", summary.TotalTokens), Inline: true},
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	d.send(discordPayload{Embeds: []discordEmbed{embed}})
+}
+
+// NotifyMedicalExpenses は確定申告の時期に前年の医療費の集計をDiscordに通知する
+func (d *DiscordNotifier) NotifyMedicalExpenses(summary *model.MedicalExpenseSummary) {
+	if d == nil || summary == nil {
+		return
+	}
+
+	description := "医療費控除の目安は足切り額のみで計算しています。所得が200万円未満の場合は控除額が増えることがあります。"
+	if summary.DeductionEstimate == 0 {
+		description = "医療費が足切り額に届いていないため、医療費控除の対象外の見込みです。"
+	}
+	patients := make([]string, 0, len(summary.ByPatient))
+	for name, amount := range summary.ByPatient {
+		if name == "" {
+			name = "（不明）"
+		}
+		patients = append(patients, fmt.Sprintf("%s: %d円", name, amount))
+	}
+	sort.Strings(patients)
+
+	fields := []discordField{
+		{Name: "対象年", Value: fmt.Sprintf("%d年", summary.Year), Inline: true},
+		{Name: "件数", Value: fmt.Sprintf("%d件", summary.Count), Inline: true},
+		{Name: "支払額", Value: fmt.Sprintf("%d円（補填 %d円）", summary.TotalAmount, summary.TotalReimbursement), Inline: true},
+		{Name: "控除額の目安", Value: fmt.Sprintf("%d円", summary.DeductionEstimate), Inline: true},
+	}
+	if len(patients) > 0 {
+		fields = append(fields, discordField{Name: "受診者別", Value: truncate(strings.Join(patients, "\n"), 1000)})
+	}
+
+	embed := discordEmbed{
+		Title:       fmt.Sprintf("%d年の医療費控除の集計", summary.Year),
+		Description: description,
+		Color:       colorGreen,
+		Fields:      fields,
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+	}
+
+	d.send(discordPayload{Embeds: []discordEmbed{embed}})
+}
+
+// NotifyRetention は保存期間の整理ジョブの結果をDiscordに通知する
+// 法定の保存期間がある書類の削除予告を含む場合は、削除予定日とともに一覧を送る
+func (d *DiscordNotifier) NotifyRetention(result *model.RetentionResult) {
+	if d == nil || result == nil || result.DryRun {
+		return
+	}
+	if len(result.Warned)+len(result.Trashed)+len(result.Archived)+len(result.Failed) == 0 {
+		return
+	}
+
+	// 削除予告の一覧は WarnRetention で送信済みのため、ここでは件数のみ
+	color := colorGreen
+	if len(result.Warned) > 0 {
+		color = colorYellow
+	}
+	if len(result.Failed) > 0 {
+		color = colorRed
+	}
+
+	embed := discordEmbed{
+		Title: "保存期間を過ぎた書類の整理",
+		Color: color,
+		Fields: []discordField{
+			{Name: "アーカイブ", Value: fmt.Sprintf("%d件", len(result.Archived)), Inline: true},
+			{Name: "削除", Value: fmt.Sprintf("%d件", len(result.Trashed)), Inline: true},
+			{Name: "削除予告", Value: fmt.Sprintf("%d件", len(result.Warned)), Inline: true},
+			{Name: "失敗", Value: fmt.Sprintf("%d件", len(result.Failed)), Inline: true},
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	d.send(discordPayload{Embeds: []discordEmbed{embed}})
+}
+
+// retentionWarningMaxRunes は削除予告1通の一覧の最大文字数（Embedの説明文は4096文字まで）
+const retentionWarningMaxRunes = 3500
+
+// WarnRetention は法定の保存期間がある書類の削除予告を送る
+// 一覧が長い場合は複数のメッセージに分け、送信できたメッセージに載せた書類だけを返す
+func (d *DiscordNotifier) WarnRetention(items []model.RetentionItem) ([]model.RetentionItem, error) {
+	batches := retentionWarningBatches(items, retentionWarningMaxRunes)
+	var delivered []model.RetentionItem
+	for i, batch := range batches {
+		lines := make([]string, 0, len(batch))
+		for _, item := range batch {
+			lines = append(lines, retentionWarningLine(item))
+		}
+		title := "保存期間を過ぎた書類の削除予告"
+		if len(batches) > 1 {
+			title += fmt.Sprintf("（%d/%d）", i+1, len(batches))
+		}
+		embed := discordEmbed{
+			Title:       title,
+			Description: "法定の保存期間がある書類の保存期限が過ぎました。残す場合は削除予定日までに保存期間の設定を見直してください。\n" + strings.Join(lines, "\n"),
+			Color:       colorYellow,
+			Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		}
+		if err := d.post(discordPayload{Embeds: []discordEmbed{embed}}); err != nil {
+			return delivered, err
+		}
+		delivered = append(delivered, batch...)
+	}
+	return delivered, nil
+}
+
+func retentionWarningLine(item model.RetentionItem) string {
+	return fmt.Sprintf("• %s（%s、法定%d年）→ %s に削除", item.FileName, item.Category, item.LegalYears, item.DeleteAfter)
+}
+
+// retentionWarningBatches は一覧が maxRunes 文字に収まるように書類を分ける（書類の途中では分けない）
+func retentionWarningBatches(items []model.RetentionItem, maxRunes int) [][]model.RetentionItem {
+	var batches [][]model.RetentionItem
+	var batch []model.RetentionItem
+	size := 0
+	for _, item := range items {
+		n := utf8.RuneCountInString(retentionWarningLine(item)) + 1
+		if len(batch) > 0 && size+n > maxRunes {
+			batches = append(batches, batch)
+			batch, size = nil, 0
+		}
+		batch = append(batch, item)
+		size += n
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// send はDiscord Webhookにペイロードを送信する（失敗はログに記録するのみ）
 func (d *DiscordNotifier) send(payload discordPayload) {
+	if err := d.post(payload); err != nil {
+		log.Printf("Discord通知: %v", err)
+	}
+}
+
+// post はDiscord Webhookにペイロードを送信し、届かなかった場合はエラーを返す
+func (d *DiscordNotifier) post(payload discordPayload) error {
 	body, err := json.Marshal(payload)
 	if err != nil {
-		log.Printf("Discord通知: JSONエンコード失敗: %v", err)
-		return
+		return fmt.Errorf("JSONエンコード失敗: %w", err)
 	}
 
 	resp, err := d.httpClient.Post(d.webhookURL, "application/json", bytes.NewReader(body))
 	if err != nil {
-		log.Printf("Discord通知: 送信失敗: %v", err)
-		return
+		return fmt.Errorf("送信失敗: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		log.Printf("Discord通知: HTTPエラー: %d", resp.StatusCode)
+		return fmt.Errorf("HTTPエラー: %d", resp.StatusCode)
 	}
+	return nil
 }
 
 func truncate(s string, max int) string {
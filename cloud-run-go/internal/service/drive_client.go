@@ -1,6 +1,7 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -23,13 +24,15 @@ type DriveClient struct {
 	oauthDriveService *drive.Service // OAuth認証 (About/Doc作成用), SA fallback
 	docsService       *docs.Service  // OAuth認証 (Docs編集用), SA fallback
 	oauthCreds        *OAuthCredentials
+	tenantID          string // Watchチャンネルの振り分け用
+	webhookToken      string
 	folderCache       map[string]string // key: "parentID:folderName", value: folderID
 	folderMu          sync.Mutex
 }
 
 // NewDriveClient は新しいDriveClientを作成
 // SA (Service Account) をファイル操作+Watchに、OAuthをAbout/Docs/Doc作成に使用
-func NewDriveClient(ctx context.Context) (*DriveClient, error) {
+func NewDriveClient(ctx context.Context, tenant *config.Tenant) (*DriveClient, error) {
 	// 1. SA Drive サービスを常に作成（ファイル操作用）
 	saService, err := drive.NewService(ctx, option.WithScopes(drive.DriveScope))
 	if err != nil {
@@ -37,12 +40,14 @@ func NewDriveClient(ctx context.Context) (*DriveClient, error) {
 	}
 
 	client := &DriveClient{
-		service:     saService,
-		folderCache: make(map[string]string),
+		service:      saService,
+		tenantID:     tenant.ID,
+		webhookToken: strings.TrimSpace(tenant.DriveWebhookToken),
+		folderCache:  make(map[string]string),
 	}
 
 	// 2. OAuth 認証を試行
-	creds, err := GetOAuthCredentials(ctx)
+	creds, err := GetOAuthCredentials(ctx, tenant)
 	if err != nil {
 		log.Printf("Warning: OAuth not available, using SA for all operations: %v", err)
 		// SA fallback
@@ -114,7 +119,7 @@ func (c *DriveClient) GetOAuthDriveService() *drive.Service {
 // GetFile はファイル情報を取得
 func (c *DriveClient) GetFile(ctx context.Context, fileID string) (*model.FileInfo, error) {
 	file, err := c.service.Files.Get(fileID).
-		Fields("id, name, mimeType, parents").
+		Fields("id, name, mimeType, parents, owners(emailAddress), lastModifyingUser(emailAddress)").
 		SupportsAllDrives(true).
 		Context(ctx).
 		Do()
@@ -127,9 +132,22 @@ func (c *DriveClient) GetFile(ctx context.Context, fileID string) (*model.FileIn
 		Name:     file.Name,
 		MimeType: file.MimeType,
 		Parents:  file.Parents,
+		Uploader: fileUploader(file),
 	}, nil
 }
 
+// fileUploader はアップロードしたユーザーを返す
+// 共有ドライブには所有者がいないため、最終更新者（アップロード直後はアップロードしたユーザー）を優先する
+func fileUploader(file *drive.File) string {
+	if file.LastModifyingUser != nil && file.LastModifyingUser.EmailAddress != "" {
+		return file.LastModifyingUser.EmailAddress
+	}
+	if len(file.Owners) > 0 {
+		return file.Owners[0].EmailAddress
+	}
+	return ""
+}
+
 // DownloadFile はファイルをダウンロード（堅牢化版）
 func (c *DriveClient) DownloadFile(ctx context.Context, fileID string) ([]byte, error) {
 	maxRetries := 5
@@ -219,6 +237,52 @@ func (c *DriveClient) RenameFile(ctx context.Context, fileID string, newName str
 	return nil
 }
 
+// UploadFile はファイルをアップロードし、作成したファイルIDを返す
+// SAにはストレージ容量がないため、OAuth認証（ユーザーの容量）で作成する
+func (c *DriveClient) UploadFile(ctx context.Context, name, mimeType, parentID string, data []byte) (string, error) {
+	file := &drive.File{
+		Name:     name,
+		MimeType: mimeType,
+		Parents:  []string{parentID},
+	}
+	created, err := c.oauthDriveService.Files.Create(file).
+		Media(bytes.NewReader(data)).
+		Fields("id").
+		SupportsAllDrives(true).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	log.Printf("ファイルアップロード成功: %s (%s)", name, created.Id)
+	return created.Id, nil
+}
+
+// SetDescription はファイルの説明を変更
+func (c *DriveClient) SetDescription(ctx context.Context, fileID string, description string) error {
+	_, err := c.service.Files.Update(fileID, &drive.File{
+		Description: description,
+	}).SupportsAllDrives(true).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to set description: %w", err)
+	}
+	return nil
+}
+
+// TrashFile はファイルをゴミ箱に移動（Driveの保持期間内であれば復元できる）
+func (c *DriveClient) TrashFile(ctx context.Context, fileID string) error {
+	_, err := c.service.Files.Update(fileID, &drive.File{
+		Trashed: true,
+	}).SupportsAllDrives(true).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to trash file: %w", err)
+	}
+
+	log.Printf("ファイルをゴミ箱に移動: %s", fileID)
+	return nil
+}
+
 // GetOrCreateFolder はフォルダを取得または作成（排他制御付き）
 func (c *DriveClient) GetOrCreateFolder(ctx context.Context, folderName string, parentID string) (string, error) {
 	cacheKey := fmt.Sprintf("%s:%s", parentID, folderName)
@@ -233,20 +297,11 @@ func (c *DriveClient) GetOrCreateFolder(ctx context.Context, folderName string,
 	}
 
 	// フォルダが存在するか検索
-	query := fmt.Sprintf("name='%s' and '%s' in parents and mimeType='application/vnd.google-apps.folder' and trashed=false", folderName, parentID)
-	fileList, err := c.service.Files.List().
-		Q(query).
-		Fields("files(id, name)").
-		Context(ctx).
-		Do()
+	folderID, err := c.findFolderLocked(ctx, folderName, parentID)
 	if err != nil {
-		return "", fmt.Errorf("failed to search folder: %w", err)
+		return "", err
 	}
-
-	// フォルダが存在する場合はキャッシュに追加して返す
-	if len(fileList.Files) > 0 {
-		folderID := fileList.Files[0].Id
-		c.folderCache[cacheKey] = folderID
+	if folderID != "" {
 		return folderID, nil
 	}
 
@@ -272,6 +327,37 @@ func (c *DriveClient) GetOrCreateFolder(ctx context.Context, folderName string,
 	return createdFolder.Id, nil
 }
 
+// FindFolder は既存フォルダを検索（存在しない場合は空文字。作成はしない）
+func (c *DriveClient) FindFolder(ctx context.Context, folderName string, parentID string) (string, error) {
+	c.folderMu.Lock()
+	defer c.folderMu.Unlock()
+
+	if cachedID, exists := c.folderCache[fmt.Sprintf("%s:%s", parentID, folderName)]; exists {
+		return cachedID, nil
+	}
+	return c.findFolderLocked(ctx, folderName, parentID)
+}
+
+// findFolderLocked はフォルダを検索し、見つかればキャッシュに追加（folderMu取得済みで呼ぶ）
+func (c *DriveClient) findFolderLocked(ctx context.Context, folderName string, parentID string) (string, error) {
+	query := fmt.Sprintf("name='%s' and '%s' in parents and mimeType='application/vnd.google-apps.folder' and trashed=false", folderName, parentID)
+	fileList, err := c.service.Files.List().
+		Q(query).
+		Fields("files(id, name)").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to search folder: %w", err)
+	}
+
+	if len(fileList.Files) == 0 {
+		return "", nil
+	}
+	folderID := fileList.Files[0].Id
+	c.folderCache[fmt.Sprintf("%s:%s", parentID, folderName)] = folderID
+	return folderID, nil
+}
+
 // ListFilesInFolder はフォルダ内のファイル一覧を取得
 func (c *DriveClient) ListFilesInFolder(ctx context.Context, folderID string, limit int) ([]*model.FileInfo, error) {
 	query := fmt.Sprintf("'%s' in parents and trashed=false", folderID)
@@ -301,6 +387,28 @@ func (c *DriveClient) ListFilesInFolder(ctx context.Context, folderID string, li
 	return files, nil
 }
 
+// FindFileIDsByName はフォルダ内の同名ファイルのIDを返す（ファイル名の重複確認用）
+func (c *DriveClient) FindFileIDsByName(ctx context.Context, folderID, name string) ([]string, error) {
+	escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(name)
+	query := fmt.Sprintf("name='%s' and '%s' in parents and trashed=false", escaped, folderID)
+	fileList, err := c.service.Files.List().
+		Q(query).
+		Fields("files(id)").
+		SupportsAllDrives(true).
+		IncludeItemsFromAllDrives(true).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find files by name: %w", err)
+	}
+
+	ids := make([]string, 0, len(fileList.Files))
+	for _, f := range fileList.Files {
+		ids = append(ids, f.Id)
+	}
+	return ids, nil
+}
+
 // GetAbout はストレージ情報を取得（OAuth Drive使用: ユーザー情報を返す）
 func (c *DriveClient) GetAbout(ctx context.Context) (map[string]interface{}, error) {
 	about, err := c.oauthDriveService.About.Get().
@@ -339,6 +447,9 @@ type WatchInfo struct {
 	StartPageToken string
 }
 
+// WatchChannelPrefix はWatchチャンネルIDの接頭辞（homedocmanager-{テナントID}-{連番}）
+const WatchChannelPrefix = "homedocmanager-"
+
 // StartWatch はフォルダの変更監視を開始（SA使用: 共有フォルダの変更を監視）
 func (c *DriveClient) StartWatch(ctx context.Context, webhookURL string) (*WatchInfo, error) {
 	// 変更開始トークンを取得
@@ -347,8 +458,8 @@ func (c *DriveClient) StartWatch(ctx context.Context, webhookURL string) (*Watch
 		return nil, fmt.Errorf("failed to get start page token: %w", err)
 	}
 
-	// チャンネルIDを生成（ユニークな識別子）
-	channelID := fmt.Sprintf("homedocmanager-%d", time.Now().UnixNano())
+	// チャンネルIDを生成（ユニークな識別子。通知の振り分けのためテナントIDを含める）
+	channelID := fmt.Sprintf("%s%s-%d", WatchChannelPrefix, c.tenantID, time.Now().UnixNano())
 
 	// 有効期限を7日後に設定（Drive APIの最大値）
 	expiration := time.Now().Add(7 * 24 * time.Hour).UnixMilli()
@@ -360,8 +471,8 @@ func (c *DriveClient) StartWatch(ctx context.Context, webhookURL string) (*Watch
 		Address:    webhookURL,
 		Expiration: expiration,
 	}
-	if token := strings.TrimSpace(config.DriveWebhookToken); token != "" {
-		channel.Token = token
+	if c.webhookToken != "" {
+		channel.Token = c.webhookToken
 	}
 
 	watchedChannel, err := c.service.Changes.Watch(startPageToken.StartPageToken, channel).Context(ctx).Do()
@@ -441,40 +552,3 @@ func (c *DriveClient) GetChanges(ctx context.Context, pageToken string) ([]strin
 
 	return fileIDs, nextPageToken, nil
 }
-
-// ファイル処理済みマーカー
-const fileProcessedMarker = "file_processed"
-
-// IsFileProcessed はファイルが既に処理済みかチェック
-func (c *DriveClient) IsFileProcessed(ctx context.Context, fileID string) bool {
-	file, err := c.service.Files.Get(fileID).
-		Fields("properties").
-		SupportsAllDrives(true).
-		Context(ctx).
-		Do()
-	if err != nil {
-		log.Printf("処理済みチェックエラー: %v", err)
-		return false
-	}
-
-	return file.Properties != nil && file.Properties[fileProcessedMarker] == "true"
-}
-
-// MarkFileAsProcessed はファイルを処理済みとしてマーク
-func (c *DriveClient) MarkFileAsProcessed(ctx context.Context, fileID string) error {
-	file := &drive.File{
-		Properties: map[string]string{
-			fileProcessedMarker: "true",
-		},
-	}
-
-	_, err := c.service.Files.Update(fileID, file).
-		SupportsAllDrives(true).
-		Context(ctx).
-		Do()
-	if err != nil {
-		return fmt.Errorf("処理済みマーキングエラー: %w", err)
-	}
-
-	return nil
-}
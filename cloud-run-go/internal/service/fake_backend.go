@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// FakeBackend はテスト用の決定的なバックエンド
+// 種類ごとに固定の結果を返し、受け取ったリクエストを記録する
+type FakeBackend struct {
+	FastModel string
+	HighModel string
+
+	// 種類ごとの応答（nil の場合は既定値）
+	Analysis *model.AnalysisResult
+	Events   *model.EventsAndTasks
+	OCR      *model.OCRBundle
+	Pages    []model.PageClassification
+	Medical  *model.MedicalExpense
+	Finance  *model.FinanceRecord
+
+	// Respond を指定した場合は上記より優先する（モデルごとに応答を変える場合など）
+	Respond func(req GenerateRequest) (string, error)
+
+	mu    sync.Mutex
+	calls []GenerateRequest
+}
+
+// NewFakeBackend は既定の応答を返すFakeBackendを作成
+func NewFakeBackend() *FakeBackend {
+	return &FakeBackend{FastModel: "fake-fast", HighModel: "fake-high"}
+}
+
+// Name はバックエンド名を返す
+func (b *FakeBackend) Name() string {
+	return "fake"
+}
+
+// Models は一次解析用・エスカレーション用のモデル名を返す
+func (b *FakeBackend) Models() (string, string) {
+	return b.FastModel, b.HighModel
+}
+
+// Generate はリクエストを記録し、種類に応じた固定のJSONを返す
+func (b *FakeBackend) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	b.mu.Lock()
+	b.calls = append(b.calls, req)
+	b.mu.Unlock()
+
+	if b.Respond != nil {
+		text, err := b.Respond(req)
+		if err != nil {
+			return nil, err
+		}
+		return fakeResponse(req, text), nil
+	}
+
+	var v any
+	switch req.Kind {
+	case GenerateKindAnalysis:
+		v = b.analysis()
+	case GenerateKindEvents:
+		v = b.events()
+	case GenerateKindOCR:
+		v = b.ocr()
+	case GenerateKindPages:
+		v = b.pages()
+	case GenerateKindMedical:
+		v = b.medical()
+	case GenerateKindFinance:
+		v = b.finance()
+	case GenerateKindCombined:
+		v = &model.DocumentBundle{Analysis: b.analysis(), EventsAndTasks: b.events(), OCRBundle: b.ocr()}
+	default:
+		return nil, ErrEmptyResponse
+	}
+	text, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return fakeResponse(req, string(text)), nil
+}
+
+// fakeResponse はバイト数をトークン数とみなした応答を作る
+func fakeResponse(req GenerateRequest, text string) *GenerateResponse {
+	prompt := len(req.Prompt) + len(req.Data)
+	return &GenerateResponse{
+		Text:         text,
+		PromptTokens: prompt,
+		OutputTokens: len(text),
+		TotalTokens:  prompt + len(text),
+	}
+}
+
+// Calls は受け取ったリクエストを呼び出し順に返す
+func (b *FakeBackend) Calls() []GenerateRequest {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]GenerateRequest(nil), b.calls...)
+}
+
+// Close は何もしない
+func (b *FakeBackend) Close() error {
+	return nil
+}
+
+func (b *FakeBackend) analysis() *model.AnalysisResult {
+	if b.Analysis != nil {
+		return b.Analysis
+	}
+	return &model.AnalysisResult{
+		Category:        "50_写真・その他",
+		Date:            "20250101",
+		Summary:         "テスト書類",
+		ConfidenceScore: 1.0,
+	}
+}
+
+func (b *FakeBackend) events() *model.EventsAndTasks {
+	if b.Events != nil {
+		return b.Events
+	}
+	return &model.EventsAndTasks{Events: []model.Event{}, Tasks: []model.Task{}}
+}
+
+func (b *FakeBackend) ocr() *model.OCRBundle {
+	if b.OCR != nil {
+		return b.OCR
+	}
+	// エスカレーション条件（本文200バイト未満など）に掛からない内容にする
+	return &model.OCRBundle{
+		OCRText:         strings.Repeat("テスト書類の本文です。", 10),
+		Facts:           []string{"2025年1月1日", "1,000円", "テスト"},
+		Summary:         "テスト書類",
+		ConfidenceScore: 1.0,
+	}
+}
+
+func (b *FakeBackend) medical() *model.MedicalExpense {
+	if b.Medical != nil {
+		return b.Medical
+	}
+	return &model.MedicalExpense{
+		Date:     "2025-01-01",
+		Provider: "テストクリニック",
+		Kind:     model.MedicalExpenseKindTreatment,
+		Amount:   1000,
+	}
+}
+
+func (b *FakeBackend) finance() *model.FinanceRecord {
+	if b.Finance != nil {
+		return b.Finance
+	}
+	return &model.FinanceRecord{
+		DocumentType: model.FinanceDocumentBill,
+		Payee:        "テスト電力",
+		Amount:       5000,
+		DueDate:      "2025-01-27",
+	}
+}
+
+func (b *FakeBackend) pages() *model.PageClassifications {
+	if b.Pages != nil {
+		return &model.PageClassifications{Pages: b.Pages}
+	}
+	// 既定では全ページを1つの書類とみなす（分割しない）
+	return &model.PageClassifications{Pages: []model.PageClassification{{Page: 1, StartsNewDocument: true, Title: "テスト書類"}}}
+}
@@ -2,10 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/leo-sagawa/homedocmanager/internal/config"
@@ -14,7 +14,7 @@ import (
 
 // FileSorter はファイル仕分けサービス
 type FileSorter struct {
-	aiRouter       *AIRouter
+	aiRouter       DocumentAnalyzer
 	pdfProcessor   *PDFProcessor
 	driveClient    *DriveClient
 	photosClient   *PhotosClient
@@ -23,14 +23,30 @@ type FileSorter struct {
 	notebooklmSync *NotebookLMSync
 	gradeManager   *GradeManager
 
-	// 並行処理制御
-	processingMu    sync.Mutex
-	processingFiles map[string]bool
+	// 世帯設定（フォルダID・家族構成）
+	household *config.HouseholdStore
+
+	// 処理台帳（並行処理制御・途中失敗からの再開）
+	ledger  ProcessingLedger
+	ownerID string
+
+	// 失敗ファイルの退避先（任意）
+	deadLetters DeadLetterStore
+	// 低信頼度の分類結果の通知先（任意）
+	reviewNotifier ReviewNotifier
+	// 仕分け済み書類の重複判定インデックス（任意）
+	dedupIndex DedupIndex
+	// AIが使えない場合のローカルOCR（任意）
+	localOCR *LocalOCR
+	// 医療費控除の集計台帳（任意）
+	medicalExpenses MedicalExpenseLedger
+	// 家計の支払いの記録（任意）
+	finance FinanceLedger
 }
 
 // NewFileSorter は新しいFileSorterを作成
 func NewFileSorter(
-	aiRouter *AIRouter,
+	aiRouter DocumentAnalyzer,
 	pdfProcessor *PDFProcessor,
 	driveClient *DriveClient,
 	photosClient *PhotosClient,
@@ -38,38 +54,54 @@ func NewFileSorter(
 	tasksClient *TasksClient,
 	notebooklmSync *NotebookLMSync,
 	gradeManager *GradeManager,
+	ledger ProcessingLedger,
+	household *config.HouseholdStore,
 ) *FileSorter {
 	return &FileSorter{
-		aiRouter:        aiRouter,
-		pdfProcessor:    pdfProcessor,
-		driveClient:     driveClient,
-		photosClient:    photosClient,
-		calendarClient:  calendarClient,
-		tasksClient:     tasksClient,
-		notebooklmSync:  notebooklmSync,
-		gradeManager:    gradeManager,
-		processingFiles: make(map[string]bool),
+		aiRouter:       aiRouter,
+		pdfProcessor:   pdfProcessor,
+		driveClient:    driveClient,
+		photosClient:   photosClient,
+		calendarClient: calendarClient,
+		tasksClient:    tasksClient,
+		notebooklmSync: notebooklmSync,
+		gradeManager:   gradeManager,
+		household:      household,
+		ledger:         ledger,
+		ownerID:        ledgerOwnerID(),
 	}
 }
 
+// processingStateOrder は処理状態の進行順
+var processingStateOrder = map[model.ProcessingState]int{
+	model.ProcessingStateReceived:   0,
+	model.ProcessingStateDownloaded: 1,
+	model.ProcessingStateAnalyzed:   2,
+	model.ProcessingStateRenamed:    3,
+	model.ProcessingStateMoved:      4,
+	model.ProcessingStateCompleted:  5,
+}
+
+// reached は台帳上で指定状態まで到達済みかを判定
+func reached(entry *model.LedgerEntry, state model.ProcessingState) bool {
+	if entry == nil {
+		return false
+	}
+	return processingStateOrder[entry.State] >= processingStateOrder[state]
+}
+
 // ProcessFile はファイルを処理
 func (fs *FileSorter) ProcessFile(ctx context.Context, fileID string) model.ProcessResult {
-	// インメモリロックによる並行処理防止（最優先）
-	fs.processingMu.Lock()
-	if fs.processingFiles[fileID] {
-		fs.processingMu.Unlock()
-		log.Printf("別のリクエストで処理中のためスキップ: %s", fileID)
+	// 処理台帳で完了済みかを確認
+	existing, err := fs.ledger.Get(ctx, fileID)
+	if err != nil {
+		log.Printf("処理台帳の参照失敗: %v", err)
+		return model.ProcessResultError
+	}
+	if existing != nil && existing.State.IsTerminal() {
+		log.Printf("既に処理済みのファイルです: %s (%s)", fileID, existing.State)
 		return model.ProcessResultSkipped
 	}
-	fs.processingFiles[fileID] = true
-	fs.processingMu.Unlock()
-
-	// 処理完了時にロックを解放
-	defer func() {
-		fs.processingMu.Lock()
-		delete(fs.processingFiles, fileID)
-		fs.processingMu.Unlock()
-	}()
 
 	// ファイル情報を取得
 	fileInfo, err := fs.driveClient.GetFile(ctx, fileID)
@@ -79,7 +111,8 @@ func (fs *FileSorter) ProcessFile(ctx context.Context, fileID string) model.Proc
 	}
 
 	// Inbox（SOURCE）フォルダ以外のファイルは仕分け（移動・リネーム）対象外とする
-	sourceID := config.FolderIDs["SOURCE"]
+	// ただしリネーム以降で中断したファイルは再開対象
+	sourceID := fs.household.Current().FolderIDs["SOURCE"]
 	inInbox := false
 	for _, parentID := range fileInfo.Parents {
 		if parentID == sourceID {
@@ -87,99 +120,111 @@ func (fs *FileSorter) ProcessFile(ctx context.Context, fileID string) model.Proc
 			break
 		}
 	}
+	resuming := reached(existing, model.ProcessingStateRenamed)
 
-	if !inInbox {
+	if !inInbox && !resuming {
 		log.Printf("Inbox以外のフォルダにあるためスキップします: %s (Parents: %v)", fileInfo.Name, fileInfo.Parents)
+		if existing != nil {
+			// 途中まで処理されたが手動で移動されたファイルは滞留扱いにしない
+			fs.recordState(ctx, fileID, model.ProcessingStateSkipped, "Inbox外に移動済み")
+		}
 		return model.ProcessResultSkipped
 	}
 
-	log.Printf("処理開始: %s", fileInfo.Name)
-
-	// 処理済みファイルのチェック（並行処理での重複防止）
-	if fs.driveClient.IsFileProcessed(ctx, fileID) {
-		log.Printf("既に処理済みのファイルです: %s", fileInfo.Name)
-		return model.ProcessResultSkipped
-	}
-
-	// 即座に処理中マーカーを設定（並行通知からの重複防止）
-	if err := fs.driveClient.MarkFileAsProcessed(ctx, fileID); err != nil {
-		log.Printf("Warning: 処理中マーカー設定失敗: %v", err)
-		// エラーでも続行（既に他のプロセスが処理中の可能性）
-	}
-
 	// 対応ファイル形式をチェック
 	if !fs.isSupportedMimeType(fileInfo.MimeType) {
 		log.Printf("非対応のファイル形式のためスキップ: %s", fileInfo.MimeType)
 		return model.ProcessResultSkipped
 	}
 
-	// ファイルをダウンロード
-	fileBytes, err := fs.driveClient.DownloadFile(ctx, fileID)
+	// 処理権を取得（並行通知・複数インスタンスからの重複防止）
+	entry, acquired, err := fs.ledger.Acquire(ctx, fileID, fs.ownerID, config.ProcessingLedger.LeaseDuration)
 	if err != nil {
-		log.Printf("ファイルダウンロード失敗: %v", err)
+		log.Printf("処理権の取得失敗: %v", err)
 		return model.ProcessResultError
 	}
-
-	// Geminiで解析 (PDFもそのまま渡す)
-	var (
-		analysisResult *model.AnalysisResult
-		combined       *model.DocumentBundle
-	)
-	if config.EnableCombinedGemini {
-		prompt := fs.createAnalysisPrompt(fileInfo.Name)
-		bundle, combinedErr := fs.aiRouter.AnalyzeDocumentFull(ctx, fileBytes, fileInfo.MimeType, fileInfo.Name, prompt)
-		if combinedErr != nil || bundle == nil || bundle.Analysis == nil {
-			log.Printf("統合解析失敗、フォールバックします: %v", combinedErr)
-		} else {
-			combined = bundle
-			analysisResult = bundle.Analysis
-		}
+	if !acquired {
+		log.Printf("別のリクエストで処理中のためスキップ: %s", fileID)
+		return model.ProcessResultSkipped
 	}
-
-	if analysisResult == nil {
-		analysisResult, err = fs.analyzeDocument(ctx, fileBytes, fileInfo.MimeType, fileInfo.Name)
-		if err != nil {
-			log.Printf("Gemini解析失敗: %v", err)
-			return model.ProcessResultError
+	defer func() {
+		if err := fs.ledger.Release(context.WithoutCancel(ctx), fileID, fs.ownerID); err != nil {
+			log.Printf("Warning: 処理権の解放失敗: %v", err)
 		}
-	}
+	}()
 
-	log.Printf("解析結果: category=%s, child=%s, date=%s, summary=%s",
-		analysisResult.Category, analysisResult.ChildName, analysisResult.Date, analysisResult.Summary)
+	if entry.State.IsTerminal() {
+		log.Printf("既に処理済みのファイルです: %s", fileInfo.Name)
+		return model.ProcessResultSkipped
+	}
 
-	// 子供の特定とフォルダ解決ロジック
-	if analysisResult.Category == "40_子供・教育" {
-		fs.processChildEducation(analysisResult)
+	if entry.Attempts > 1 {
+		log.Printf("処理再開: %s (state=%s, attempt=%d)", fileInfo.Name, entry.State, entry.Attempts)
+	} else {
+		log.Printf("処理開始: %s", fileInfo.Name)
 	}
+	fs.updateEntry(ctx, fileID, func(e *model.LedgerEntry) { e.FileName = fileInfo.Name })
 
-	// 移動先フォルダを決定
-	destinationFolderID, err := fs.getDestinationFolder(ctx, analysisResult)
-	if err != nil {
-		log.Printf("移動先フォルダ決定失敗: %v", err)
+	if err := fs.runPipeline(ctx, fileInfo, entry); err != nil {
+		if errors.Is(err, errAwaitingReview) {
+			return model.ProcessResultPendingReview
+		}
+		if errors.Is(err, errDuplicate) {
+			return model.ProcessResultDuplicate
+		}
+		if errors.Is(err, errSplit) {
+			return model.ProcessResultSplit
+		}
+		log.Printf("処理失敗: %s: %v", fileInfo.Name, err)
+		fs.updateEntry(ctx, fileID, func(e *model.LedgerEntry) { e.Fail(err) })
+		if entry.Attempts >= config.DeadLetter.MaxAttempts {
+			fs.deadLetter(ctx, fileInfo, err)
+		}
 		return model.ProcessResultError
 	}
 
-	// 新しいファイル名を生成
-	newFileName := fs.generateNewFilename(analysisResult, fileInfo.Name)
+	return model.ProcessResultProcessed
+}
 
-	// ファイルをリネーム
-	if err := fs.driveClient.RenameFile(ctx, fileID, newFileName); err != nil {
-		log.Printf("ファイルリネーム失敗: %v", err)
-		return model.ProcessResultError
+// recordState は台帳に状態遷移を記録（記録失敗は処理を止めない）
+func (fs *FileSorter) recordState(ctx context.Context, fileID string, state model.ProcessingState, detail string) {
+	fs.updateEntry(ctx, fileID, func(e *model.LedgerEntry) { e.Transition(state, detail) })
+}
+
+// updateEntry は台帳エントリを更新（記録失敗は処理を止めない）
+func (fs *FileSorter) updateEntry(ctx context.Context, fileID string, fn func(*model.LedgerEntry)) {
+	if _, err := fs.ledger.Update(ctx, fileID, fn); err != nil {
+		log.Printf("Warning: 処理台帳の更新失敗 (%s): %v", fileID, err)
 	}
+}
 
-	// ファイルを移動
-	if err := fs.driveClient.MoveFile(ctx, fileID, destinationFolderID); err != nil {
-		log.Printf("ファイル移動失敗: %v", err)
-		return model.ProcessResultError
+// FindStuckFiles は途中で止まっている台帳エントリを取得
+func (fs *FileSorter) FindStuckFiles(ctx context.Context) ([]*model.LedgerEntry, error) {
+	entries, err := fs.ledger.List(ctx)
+	if err != nil {
+		return nil, err
 	}
+	return FindStuckEntries(entries, time.Now(), config.ProcessingLedger.StuckAfter), nil
+}
 
-	log.Printf("処理完了: %s → %s", fileInfo.Name, newFileName)
+// ListLedger は台帳の全エントリを取得
+func (fs *FileSorter) ListLedger(ctx context.Context) ([]*model.LedgerEntry, error) {
+	return fs.ledger.List(ctx)
+}
 
-	// 追加アクション
-	fs.performAdditionalActions(ctx, fileBytes, fileInfo.MimeType, newFileName, fileID, analysisResult, combined)
+// ResumeStuckFiles は滞留しているファイルの処理を再開
+func (fs *FileSorter) ResumeStuckFiles(ctx context.Context) (map[string]model.ProcessResult, error) {
+	stuck, err := fs.FindStuckFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	return model.ProcessResultProcessed
+	results := make(map[string]model.ProcessResult, len(stuck))
+	for _, entry := range stuck {
+		log.Printf("滞留ファイルを再開: %s (state=%s, last_error=%s)", entry.FileID, entry.State, entry.LastError)
+		results[entry.FileID] = fs.ProcessFile(ctx, entry.FileID)
+	}
+	return results, nil
 }
 
 // processChildEducation は子供・教育カテゴリの特殊処理
@@ -192,8 +237,17 @@ func (fs *FileSorter) processChildEducation(result *model.AnalysisResult) {
 	var targetChildren []string
 	if result.ChildName != "" {
 		targetChildren = []string{result.ChildName}
-	} else if result.TargetGradeClass != "" {
-		targetChildren = fs.gradeManager.IdentifyChildren(result.TargetGradeClass, fiscalYear)
+	} else {
+		if result.TargetGradeClass != "" {
+			targetChildren = fs.gradeManager.IdentifyChildren(result.TargetGradeClass, fiscalYear)
+		}
+		// 学校名・担任・習い事の手がかりで特定（学年で特定できない場合、複数の子供に絞り込む場合）
+		if len(targetChildren) != 1 {
+			clueText := strings.Join([]string{result.Issuer, result.TargetGradeClass, result.Summary}, "\n")
+			if byProfile := fs.gradeManager.IdentifyChildrenByProfile(clueText, fiscalYear); len(byProfile) > 0 {
+				targetChildren = narrowChildren(targetChildren, byProfile)
+			}
+		}
 	}
 
 	result.TargetChildren = targetChildren
@@ -221,329 +275,54 @@ func (fs *FileSorter) processChildEducation(result *model.AnalysisResult) {
 	}
 }
 
-// getDestinationFolder は移動先フォルダIDを取得
-func (fs *FileSorter) getDestinationFolder(ctx context.Context, result *model.AnalysisResult) (string, error) {
-	category := result.Category
-
-	// 写真・その他
-	if result.IsPhoto || category == "50_写真・その他" {
-		return config.FolderIDs["PHOTO_OTHER"], nil
-	}
-
-	// 子供・教育
-	if category == "40_子供・教育" {
-		return fs.getChildrenEduFolder(ctx, result)
-	}
-
-	// 年度サブフォルダ対象カテゴリ
-	for _, c := range config.CategoriesWithYearSubfolder {
-		if category == c {
-			return fs.getFolderWithYearSubfolder(ctx, category, result)
+// narrowChildren は学年で特定した子供を手がかりで特定した子供に絞り込む（重ならない場合は手がかりを優先）
+func narrowChildren(byGrade, byProfile []string) []string {
+	var narrowed []string
+	for _, child := range byGrade {
+		if contains(byProfile, child) {
+			narrowed = append(narrowed, child)
 		}
 	}
-
-	// デフォルト
-	folderID, exists := config.CategoryMap[category]
-	if !exists {
-		return config.FolderIDs["PHOTO_OTHER"], nil
-	}
-	return folderID, nil
-}
-
-// getFolderWithYearSubfolder は年度サブフォルダを取得
-func (fs *FileSorter) getFolderWithYearSubfolder(ctx context.Context, category string, result *model.AnalysisResult) (string, error) {
-	baseFolderID := config.CategoryMap[category]
-
-	fiscalYear := result.FiscalYear
-	if fiscalYear == 0 {
-		fiscalYear = fs.gradeManager.CalculateFiscalYear(result.Date)
-	}
-
-	yearFolderID, err := fs.driveClient.GetOrCreateFolder(
-		ctx,
-		fmt.Sprintf("%d年度", fiscalYear),
-		baseFolderID,
-	)
-	if err != nil {
-		return "", err
-	}
-
-	return yearFolderID, nil
-}
-
-// getChildrenEduFolder は子供・教育用のフォルダを取得
-func (fs *FileSorter) getChildrenEduFolder(ctx context.Context, result *model.AnalysisResult) (string, error) {
-	baseFolderID := config.FolderIDs["CHILDREN_EDU"]
-
-	// フォルダ名を決定
-	folderName := result.ResolvedFolderName
-	if folderName == "" {
-		folderName = result.ChildName
-	}
-	if folderName == "" {
-		folderName = "共通・学校全般"
-	}
-
-	// 子供名フォルダ
-	childFolderID, err := fs.driveClient.GetOrCreateFolder(ctx, folderName, baseFolderID)
-	if err != nil {
-		return "", err
-	}
-
-	// 年度フォルダ
-	fiscalYear := result.FiscalYear
-	if fiscalYear == 0 {
-		fiscalYear = fs.gradeManager.CalculateFiscalYear(result.Date)
-	}
-
-	yearFolderID, err := fs.driveClient.GetOrCreateFolder(
-		ctx,
-		fmt.Sprintf("%d年度", fiscalYear),
-		childFolderID,
-	)
-	if err != nil {
-		return "", err
-	}
-
-	// サブカテゴリフォルダ
-	subCategory := result.SubCategory
-	if subCategory == "" {
-		subCategory = "01_お便り・スケジュール"
+	if len(narrowed) == 0 {
+		return byProfile
 	}
-
-	subCategoryFolderID, err := fs.driveClient.GetOrCreateFolder(ctx, subCategory, yearFolderID)
-	if err != nil {
-		return "", err
-	}
-
-	return subCategoryFolderID, nil
+	return narrowed
 }
 
-// generateNewFilename は新しいファイル名を生成
-func (fs *FileSorter) generateNewFilename(result *model.AnalysisResult, originalName string) string {
-	date := result.Date
-	if date == "" {
-		date = time.Now().Format("20060102")
-	}
-
-	summary := result.Summary
-	if summary == "" {
-		summary = "document"
-	}
-
-	// 拡張子を取得
-	parts := strings.Split(originalName, ".")
-	extension := "pdf"
-	if len(parts) > 1 {
-		extension = parts[len(parts)-1]
+// getDestinationFolder は移動先フォルダIDを取得（途中のフォルダがなければ作成）
+func (fs *FileSorter) getDestinationFolder(ctx context.Context, result *model.AnalysisResult) (string, error) {
+	folderID, segments := fs.destinationPath(result)
+	for _, name := range segments {
+		var err error
+		folderID, err = fs.driveClient.GetOrCreateFolder(ctx, name, folderID)
+		if err != nil {
+			return "", err
+		}
 	}
-
-	return fmt.Sprintf("%s_%s.%s", date, summary, extension)
+	return folderID, nil
 }
 
-// performAdditionalActions は追加アクション（Photos, Calendar, NotebookLM）を実行
-func (fs *FileSorter) performAdditionalActions(
-	ctx context.Context,
-	data []byte,
-	mimeType string,
-	fileName string,
-	fileID string,
-	result *model.AnalysisResult,
-	combined *model.DocumentBundle,
-) {
+// destinationPath は移動先をカテゴリのベースフォルダIDと、その下のサブフォルダ名の列で返す
+// サブフォルダの階層はカテゴリごとのパステンプレート（folderTemplate）で決まる
+func (fs *FileSorter) destinationPath(result *model.AnalysisResult) (string, []string) {
 	category := result.Category
-	subCategory := result.SubCategory
-
-	// Google Photosアップロード判定
-	shouldUploadToPhotos := category == "50_写真・その他" ||
-		(category == "40_子供・教育" && subCategory == "03_記録・作品・成績")
-
-	if fs.photosClient != nil && shouldUploadToPhotos {
-		description := fmt.Sprintf("【%s】%s_%s", category, result.Date, result.Summary)
-
-		if mimeType == "application/pdf" {
-			// PDFを画像に変換してアップロード
-			images, err := fs.pdfProcessor.ConvertPDFToImages(data, config.DPI.Photos)
-			if err != nil {
-				log.Printf("PDF画像変換失敗: %v", err)
-			} else {
-				for i, imgData := range images {
-					pageDesc := fmt.Sprintf("%s (Page %d/%d)", description, i+1, len(images))
-					_, err := fs.photosClient.UploadImage(ctx, imgData, pageDesc)
-					if err != nil {
-						log.Printf("Google Photosアップロード失敗 (Page %d): %v", i+1, err)
-					}
-				}
-			}
-		} else {
-			// そのままアップロード
-			_, err := fs.photosClient.UploadImage(ctx, data, description)
-			if err != nil {
-				log.Printf("Google Photosアップロード失敗: %v", err)
-			}
-		}
-	}
-
-	// Calendar/Tasks登録判定
-	shouldRegisterCalendar := (category == "40_子供・教育" ||
-		(contains([]string{"10_マネー・税務", "30_ライフ・行政"}, category) && result.TargetAdult != ""))
+	household := fs.household.Current()
 
-	if shouldRegisterCalendar {
-		var precomputed *model.EventsAndTasks
-		if combined != nil && combined.EventsAndTasks != nil {
-			precomputed = combined.EventsAndTasks
-		}
-		fs.registerCalendarAndTasks(ctx, data, mimeType, fileName, fileID, result, precomputed)
+	// 写真・その他
+	if result.IsPhoto {
+		category = "50_写真・その他"
 	}
 
-	// NotebookLM同期
-	log.Printf("NotebookLM同期チェック: category=%s, subCategory=%s, sync_enabled=%v", category, subCategory, fs.notebooklmSync != nil)
-	if fs.notebooklmSync != nil && fs.notebooklmSync.ShouldSync(category, subCategory) {
-		// Avoid re-syncing the same file (saves OCR/Gemini cost).
-		if fs.notebooklmSync.IsAlreadySynced(ctx, fileID) {
-			log.Printf("NotebookLM同期済みのためスキップ: %s (%s)", fileName, fileID)
-			return
-		}
-
-		log.Printf("NotebookLM同期対象確定: %s", category)
-		// Driveカテゴリ → NotebookLMカテゴリに変換
-		notebookCategory, exists := config.NotebookLMCategoryMap[category]
-		if !exists {
-			log.Printf("NotebookLMカテゴリマッピングが見つかりません: %s", category)
-		} else {
-			log.Printf("NotebookLM変換後カテゴリ: %s", notebookCategory)
-			// OCRBundleを取得（Geminiで抽出）
-			var bundle *model.OCRBundle
-			if combined != nil && combined.OCRBundle != nil {
-				bundle = combined.OCRBundle
-			}
-			var ocrErr error
-			if bundle == nil || bundle.OCRText == "" {
-				bundle, ocrErr = fs.aiRouter.ExtractOCRBundle(ctx, data, mimeType)
-			}
-			if ocrErr != nil {
-				log.Printf("OCRBundle抽出失敗: %v", ocrErr)
-			} else if bundle != nil && bundle.OCRText != "" {
-				// 年度を計算
-				fiscalYear := result.FiscalYear
-				if fiscalYear == 0 {
-					fiscalYear = fs.gradeManager.CalculateFiscalYear(result.Date)
-				}
-
-				log.Printf("NotebookLM同期実行開始: %s (%d年度_%s)", fileName, fiscalYear, notebookCategory)
-				// NotebookLMに同期
-				err := fs.notebooklmSync.SyncFile(ctx, fileID, fileName, notebookCategory, bundle.OCRText, bundle.Facts, bundle.Summary, result.Date, fiscalYear)
-				if err != nil {
-					log.Printf("NotebookLM同期失敗: %v", err)
-				} else {
-					log.Printf("NotebookLM同期成功ログ: %s", fileName)
-				}
-			} else {
-				log.Printf("OCRテキストが空のため同期をスキップしました")
-			}
-		}
-	} else {
-		log.Printf("NotebookLM同期対象外またはサービス未初期化: category=%s", category)
+	folderID, exists := household.CategoryMap[category]
+	if !exists {
+		return household.FolderIDs["PHOTO_OTHER"], nil
 	}
+	return folderID, fs.folderSegments(result, fs.folderTemplate(category))
 }
 
-// registerCalendarAndTasks はカレンダー・タスクを登録
-func (fs *FileSorter) registerCalendarAndTasks(
-	ctx context.Context,
-	data []byte,
-	mimeType string,
-	fileName string,
-	fileID string,
-	analysisResult *model.AnalysisResult,
-	precomputed *model.EventsAndTasks,
-) {
-	if fs.calendarClient == nil && fs.tasksClient == nil {
-		return
-	}
-
-	log.Println("カレンダー・タスク抽出処理開始...")
-
-	eventsAndTasks := precomputed
-	if eventsAndTasks == nil {
-		var err error
-		eventsAndTasks, err = fs.aiRouter.ExtractEventsAndTasks(ctx, data, mimeType, fileName)
-		if err != nil {
-			log.Printf("カレンダー・タスク情報抽出失敗: %v", err)
-			return
-		}
-	}
-
-	// ファイルURL
-	fileURL := fmt.Sprintf("https://drive.google.com/file/d/%s/view", fileID)
-
-	// プレフィックス作成
-	titlePrefix := fs.createTitlePrefix(analysisResult)
-
-	// イベント登録
-	if fs.calendarClient != nil {
-		for _, event := range eventsAndTasks.Events {
-			eventTitle := titlePrefix + " " + event.Title
-			// 重複チェック
-			exists, err := fs.calendarClient.EventExists(ctx, eventTitle, event.Date)
-			if err != nil {
-				log.Printf("カレンダー重複チェック失敗: %v", err)
-			} else if exists {
-				log.Printf("カレンダーイベントは既に存在します: %s", eventTitle)
-				continue
-			}
-
-			event.Title = eventTitle
-			notes := fmt.Sprintf("📎 元のお便り: %s", fileURL)
-			_, err = fs.calendarClient.CreateEvent(ctx, &event, notes)
-			if err != nil {
-				log.Printf("イベント作成失敗: %v", err)
-			}
-		}
-	}
-
-	// タスク登録 (期日が同じものをマージ)
-	if fs.tasksClient != nil {
-		mergedTasks := make(map[string]*model.Task)
-		var dueDates []string // 順序維持のため
-
-		for _, task := range eventsAndTasks.Tasks {
-			if existing, ok := mergedTasks[task.DueDate]; ok {
-				existing.Title += " / " + task.Title
-				if task.Notes != "" {
-					if existing.Notes != "" {
-						existing.Notes += "\n"
-					}
-					existing.Notes += task.Notes
-				}
-			} else {
-				t := task
-				mergedTasks[task.DueDate] = &t
-				dueDates = append(dueDates, task.DueDate)
-			}
-		}
-
-		for _, dueDate := range dueDates {
-			task := mergedTasks[dueDate]
-			taskTitle := titlePrefix + " " + task.Title
-			// タイトル+期日での重複チェック
-			exists, err := fs.tasksClient.TaskExistsByTitleAndDate(ctx, taskTitle, task.DueDate)
-			if err != nil {
-				log.Printf("タスク重複チェック失敗: %v", err)
-			} else if exists {
-				log.Printf("タスクは既に存在します: %s (期日: %s)", taskTitle, task.DueDate)
-				continue
-			}
-
-			notes := fmt.Sprintf("📎 元のお便り: %s", fileURL)
-			if task.Notes != "" {
-				notes += "\n\n" + task.Notes
-			}
-			_, err = fs.tasksClient.CreateTask(ctx, task, notes)
-			if err != nil {
-				log.Printf("タスク作成失敗: %v", err)
-			}
-		}
-	}
+// generateNewFilename は新しいファイル名を生成（テンプレートは filenameTemplate を参照。同名ファイルの確認はしない）
+func (fs *FileSorter) generateNewFilename(result *model.AnalysisResult, originalName string) string {
+	return fs.renderFilename(result, originalName, 1)
 }
 
 // createTitlePrefix はタイトルプレフィックスを作成
@@ -584,23 +363,53 @@ func (fs *FileSorter) analyzeDocument(ctx context.Context, data []byte, mimeType
 	// プロンプトを作成
 	prompt := fs.createAnalysisPrompt(fileName)
 
-	return fs.aiRouter.AnalyzeDocument(ctx, data, mimeType, prompt, true)
+	return fs.aiRouter.AnalyzeDocument(ctx, data, mimeType, prompt, fs.analysisSchema(), true)
+}
+
+// analysisSchema は世帯のカテゴリを列挙した分類結果のスキーマを返す
+func (fs *FileSorter) analysisSchema() *ResponseSchema {
+	return AnalysisSchema(analysisCategories(fs.household.Current()))
+}
+
+// analysisCategories はAIに選ばせるカテゴリ（世帯のカテゴリから分類対象外を除いたもの）
+func analysisCategories(h *config.Household) []string {
+	var names []string
+	for _, name := range h.CategoryNames() {
+		if !config.UnclassifiedCategories[name] {
+			names = append(names, name)
+		}
+	}
+	return names
 }
 
 // createAnalysisPrompt は解析プロンプトを作成
 func (fs *FileSorter) createAnalysisPrompt(fileName string) string {
+	household := fs.household.Current()
+
 	// 子供の名寄せルール
 	childAliasesStr := ""
-	for name, aliases := range config.ChildAliases {
+	for name, aliases := range household.ChildAliases {
 		childAliasesStr += fmt.Sprintf("%s: %s\n", name, strings.Join(aliases, ", "))
 	}
 
 	// 大人の名寄せルール
 	adultAliasesStr := ""
-	for name, aliases := range config.AdultAliases {
+	for name, aliases := range household.AdultAliases {
 		adultAliasesStr += fmt.Sprintf("%s: %s\n", name, strings.Join(aliases, ", "))
 	}
 
+	// 子供の学校・担任・習い事（今年度）
+	fiscalYear := fs.gradeManager.CalculateFiscalYear("")
+	childProfilesStr := ""
+	for _, name := range household.ChildProfileNames() {
+		if line := childProfileLine(household.ChildProfiles[name], fiscalYear); line != "" {
+			childProfilesStr += fmt.Sprintf("%s: %s\n", name, line)
+		}
+	}
+	if childProfilesStr == "" {
+		childProfilesStr = "（設定なし）\n"
+	}
+
 	return fmt.Sprintf(`
 あなたは家庭内書類の整理アシスタントです。以下の画像を解析し、JSON形式で回答してください。
 
@@ -610,13 +419,17 @@ func (fs *FileSorter) createAnalysisPrompt(fileName string) string {
 ## 大人の名寄せルール
 %s
 
+## お子様の学校・担任・習い事（%d年度）
+%s
+
 ## 出力形式（必ずこのJSON形式で回答）
 {
   "category": "カテゴリ名",
   "child_name": "お子様の名前（名寄せ後の正規名。複数または不明時は空文字）",
   "target_adult": "大人の名前（名寄せ後の正規名。書類の宛先・対象者が大人の場合。不明時は空文字）",
   "target_grade_class": "対象となる学年やクラス名（例：小2、くるみ組、1年生）。固有名詞がない場合に抽出",
-  "sub_category": "サブカテゴリ（categoryが40_子供・教育の場合のみ）",
+  "issuer": "発行元（学校・園・塾・クラブ名、先生の名前など。不明時は空文字）",
+  "sub_category": "サブカテゴリ（categoryが40_子供・教育・60_ヘルス・医療の場合のみ）",
   "is_photo": false,
   "date": "YYYYMMDD形式の日付",
   "summary": "要約（15文字以内、ファイル名に使用）",
@@ -624,31 +437,53 @@ func (fs *FileSorter) createAnalysisPrompt(fileName string) string {
 }
 
 ## カテゴリ一覧
-- 10_マネー・税務
-- 20_プロジェクト・資産
-- 30_ライフ・行政
-- 40_子供・教育
-- 50_写真・その他
-- 90_ライブラリ
-
-## サブカテゴリ（40_子供・教育の場合のみ使用）
-- 01_お便り・スケジュール
-- 02_提出・手続き・重要
-- 03_記録・作品・成績
+- %s
+
+## サブカテゴリ（40_子供・教育の場合）
+- %s
+
+## サブカテゴリ（60_ヘルス・医療の場合）
+- %s
 
 ## 判断基準
 - 書類の宛先や対象者が大人（祖父母、父、母など）の場合は target_adult に正規名を設定
-- 子供関連の書類は child_name に設定し、categoryを「40_子供・教育」に
-- 医療・健康関連、役所・公共関連は「30_ライフ・行政」に分類
+- 子供関連の書類は child_name に設定し、categoryを「40_子供・教育」に（医療・健康関連を除く）
+- 医療・健康関連（医療費の領収書・調剤明細、検査結果・診断書、予防接種・健診）は「60_ヘルス・医療」に分類し、
+  受診した人が子供なら child_name、大人なら target_adult に正規名を設定
+- 役所・公共関連は「30_ライフ・行政」に分類
 - 金銭・銀行・税務関連は「10_マネー・税務」に分類
 - is_photoがtrueの場合は、categoryを「50_写真・その他」にしてください
 - 日付が不明な場合は本日の日付を使用してください
 - confidence_scoreは0.0〜1.0の範囲で、解析結果の信頼度を示してください
 - 学年やクラス名（「小2」「くるみ組」など）が記載されている場合は、target_grade_classに抽出してください
+- 学校名・担任の先生・塾や習い事の名前から対象のお子様が1人に決まる場合は child_name に正規名を設定してください
+- 書類の発行元（学校・園・塾・クラブ名、差出人の先生の名前）は issuer に抽出してください
 
 ## ファイル名
 %s
-`, childAliasesStr, adultAliasesStr, fileName)
+`, childAliasesStr, adultAliasesStr, fiscalYear, childProfilesStr, strings.Join(analysisCategories(household), "\n- "),
+		strings.Join(config.SubCategories, "\n- "), strings.Join(config.MedicalSubCategories, "\n- "), fileName)
+}
+
+// childProfileLine はプロンプトに載せる子供の手がかりを1行にまとめる
+func childProfileLine(profile *config.ChildProfile, fiscalYear int) string {
+	var parts []string
+	for _, school := range profile.SchoolsIn(fiscalYear) {
+		name := school.Name
+		if len(school.Aliases) > 0 {
+			name += "（" + strings.Join(school.Aliases, "、") + "）"
+		}
+		parts = append(parts, "学校・園 "+name)
+	}
+	if profile != nil {
+		if teacher := profile.HomeroomTeachers[fiscalYear]; teacher != "" {
+			parts = append(parts, "担任 "+teacher)
+		}
+		if len(profile.Activities) > 0 {
+			parts = append(parts, "塾・習い事 "+strings.Join(profile.Activities, "、"))
+		}
+	}
+	return strings.Join(parts, " / ")
 }
 
 // isSupportedMimeType は対応しているMIMEタイプかチェック
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// newTestHousehold はサンプルの世帯設定を読み込む
+func newTestHousehold(t *testing.T) *config.HouseholdStore {
+	t.Helper()
+	h, err := config.LoadHousehold(context.Background(), "../../resources/household/household.example.yaml", nil)
+	if err != nil {
+		t.Fatalf("LoadHousehold() error = %v", err)
+	}
+	return config.NewHouseholdStore(h)
+}
+
+func TestDestinationPath(t *testing.T) {
+	household := newTestHousehold(t)
+	fs := &FileSorter{gradeManager: NewGradeManager(household), household: household}
+	folderIDs := household.Current().FolderIDs
+
+	tests := []struct {
+		name     string
+		result   *model.AnalysisResult
+		wantBase string
+		wantPath []string
+	}{
+		{
+			name:     "photo",
+			result:   &model.AnalysisResult{Category: "30_ライフ・行政", IsPhoto: true},
+			wantBase: folderIDs["PHOTO_OTHER"],
+		},
+		{
+			name:     "children default folders",
+			result:   &model.AnalysisResult{Category: "40_子供・教育", Date: "20250510"},
+			wantBase: folderIDs["CHILDREN_EDU"],
+			wantPath: []string{"共通・学校全般", "2025年度", "01_お便り・スケジュール"},
+		},
+		{
+			name:     "children resolved folder",
+			result:   &model.AnalysisResult{Category: "40_子供・教育", Date: "20260115", ResolvedFolderName: "長男", SubCategory: "03_記録・作品・成績"},
+			wantBase: folderIDs["CHILDREN_EDU"],
+			wantPath: []string{"長男", "2025年度", "03_記録・作品・成績"},
+		},
+		{
+			name:     "monthly money folders",
+			result:   &model.AnalysisResult{Category: "10_マネー・税務", Date: "20260115"},
+			wantBase: folderIDs["MONEY_TAX"],
+			wantPath: []string{"2025年度", "01月"},
+		},
+		{
+			name:     "medical per adult",
+			result:   &model.AnalysisResult{Category: "60_ヘルス・医療", Date: "20250510", TargetAdult: "母"},
+			wantBase: folderIDs["HEALTH_MEDICAL"],
+			wantPath: []string{"母", "2025年", "01_領収書・明細"},
+		},
+		{
+			name:     "medical per child",
+			result:   &model.AnalysisResult{Category: "60_ヘルス・医療", Date: "20260115", ChildName: "花子", SubCategory: "03_予防接種・健診"},
+			wantBase: folderIDs["HEALTH_MEDICAL"],
+			wantPath: []string{"花子", "2026年", "03_予防接種・健診"},
+		},
+		{
+			name:     "medical without person uses fallback",
+			result:   &model.AnalysisResult{Category: "60_ヘルス・医療", Date: "20250510"},
+			wantBase: folderIDs["HEALTH_MEDICAL"],
+			wantPath: []string{"家族共通", "2025年", "01_領収書・明細"},
+		},
+		{
+			name:     "no template",
+			result:   &model.AnalysisResult{Category: "90_ライブラリ", Date: "20250510"},
+			wantBase: folderIDs["LIBRARY"],
+		},
+		{
+			name:     "unknown category",
+			result:   &model.AnalysisResult{Category: "99_不明"},
+			wantBase: folderIDs["PHOTO_OTHER"],
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, path := fs.destinationPath(tt.result)
+			if base != tt.wantBase || !reflect.DeepEqual(path, tt.wantPath) {
+				t.Fatalf("destinationPath() = %q %v, want %q %v", base, path, tt.wantBase, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestResolveAnalysis_DropsSubCategoryOfOtherCategory(t *testing.T) {
+	household := newTestHousehold(t)
+	fs := &FileSorter{gradeManager: NewGradeManager(household), household: household}
+
+	got := fs.resolveAnalysis(&model.AnalysisResult{Category: "60_ヘルス・医療", SubCategory: "01_お便り・スケジュール", Date: "20250510"})
+	if got.SubCategory != "" {
+		t.Fatalf("sub category should be cleared: %q", got.SubCategory)
+	}
+	got = fs.resolveAnalysis(&model.AnalysisResult{Category: "60_ヘルス・医療", SubCategory: "02_検査結果・診断書", Date: "20250510"})
+	if got.SubCategory != "02_検査結果・診断書" {
+		t.Fatalf("medical sub category should be kept: %q", got.SubCategory)
+	}
+}
+
+func TestResolveAnalysis_IdentifiesChildFromProfile(t *testing.T) {
+	household := newTestHousehold(t)
+	fs := &FileSorter{gradeManager: NewGradeManager(household), household: household}
+
+	tests := []struct {
+		name   string
+		result model.AnalysisResult
+		want   []string
+	}{
+		{"school name", model.AnalysisResult{Issuer: "○○市立 ○○小学校", Summary: "遠足のお知らせ", Date: "20250510"}, []string{"太郎"}},
+		{"school alias", model.AnalysisResult{Summary: "○○小 運動会", Date: "20250510"}, []string{"太郎"}},
+		{"activity", model.AnalysisResult{Issuer: "スイミングスクール◇◇", Date: "20250510"}, []string{"花子"}},
+		{"homeroom teacher of the year", model.AnalysisResult{Issuer: "山田先生", Date: "20250510"}, []string{"太郎"}},
+		{"homeroom teacher of another year", model.AnalysisResult{Issuer: "山田先生", Date: "20240510"}, nil},
+		{"school after leaving", model.AnalysisResult{Issuer: "○○保育園", Date: "20270510"}, nil},
+		{"narrows grade match", model.AnalysisResult{TargetGradeClass: "年長・小4", Issuer: "○○保育園", Date: "20250510"}, []string{"花子"}},
+		{"grade match kept", model.AnalysisResult{TargetGradeClass: "小4", Issuer: "○○保育園", Date: "20250510"}, []string{"太郎"}},
+		{"child name wins", model.AnalysisResult{ChildName: "花子", Issuer: "○○小学校", Date: "20250510"}, []string{"花子"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.result.Category = "40_子供・教育"
+			got := fs.resolveAnalysis(&tt.result)
+			if len(got.TargetChildren) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got.TargetChildren, tt.want) {
+				t.Fatalf("TargetChildren = %v, want %v", got.TargetChildren, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateAnalysisPrompt_ChildProfiles(t *testing.T) {
+	household := newTestHousehold(t)
+	fs := &FileSorter{gradeManager: NewGradeManager(household), household: household}
+
+	prompt := fs.createAnalysisPrompt("scan.pdf")
+	for _, want := range []string{"塾・習い事 △△学習塾、□□サッカークラブ", "塾・習い事 スイミングスクール◇◇", `"issuer"`} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt should contain %q", want)
+		}
+	}
+}
+
+func TestShouldSync_MedicalExcludedFromRAG(t *testing.T) {
+	orig := config.Medical.ExcludeFromRAG
+	defer func() { config.Medical.ExcludeFromRAG = orig }()
+	ns := &NotebookLMSync{}
+
+	config.Medical.ExcludeFromRAG = false
+	if !ns.ShouldSync("60_ヘルス・医療", "01_領収書・明細") {
+		t.Fatal("medical documents should sync by default")
+	}
+	config.Medical.ExcludeFromRAG = true
+	if ns.ShouldSync("60_ヘルス・医療", "01_領収書・明細") {
+		t.Fatal("medical documents should not sync when excluded")
+	}
+	if !ns.ShouldSync("30_ライフ・行政", "") {
+		t.Fatal("other categories should still sync")
+	}
+}
+
+func TestMergeTasksByDueDate(t *testing.T) {
+	merged := mergeTasksByDueDate([]model.Task{
+		{Title: "提出A", DueDate: "2025-05-01", Notes: "n1"},
+		{Title: "持ち物", DueDate: "2025-05-03"},
+		{Title: "提出B", DueDate: "2025-05-01", Notes: "n2"},
+	})
+	if len(merged) != 2 {
+		t.Fatalf("expected 2 tasks, got %d", len(merged))
+	}
+	if merged[0].Title != "提出A / 提出B" || merged[0].Notes != "n1\nn2" {
+		t.Fatalf("unexpected merge: %+v", merged[0])
+	}
+	if merged[1].Title != "持ち物" {
+		t.Fatalf("order not preserved: %+v", merged[1])
+	}
+}
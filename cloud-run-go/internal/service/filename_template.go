@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+var (
+	// illegalFilenameChars はDriveからダウンロードした際にOSで使えない文字・制御文字
+	illegalFilenameChars = regexp.MustCompile(`[\\/:*?"<>|\x00-\x1f\x7f]`)
+	// repeatedUnderscores は値が空のプレースホルダーで連続した区切り文字
+	repeatedUnderscores = regexp.MustCompile(`_{2,}`)
+)
+
+// filenameTemplate は分類結果に使うファイル名テンプレートを返す（仕分けルール → カテゴリ別 → 既定の順）
+func (fs *FileSorter) filenameTemplate(result *model.AnalysisResult) string {
+	if result.FilenameTemplate != "" {
+		return result.FilenameTemplate
+	}
+	if tmpl := fs.household.Current().FilenameTemplates[result.Category]; tmpl != "" {
+		return tmpl
+	}
+	return config.Filename.DefaultTemplate
+}
+
+// renderFilename はテンプレートからファイル名を生成する
+// seq は同名ファイルを避ける連番で、テンプレートに {seq} がない場合は2以上のときに「_2」のように末尾に付ける
+func (fs *FileSorter) renderFilename(result *model.AnalysisResult, originalName string, seq int) string {
+	hasSeq := false
+	base := fs.expandTemplate(fs.filenameTemplate(result), result, func(name, format string) (string, bool) {
+		switch name {
+		case "summary":
+			if result.Summary == "" {
+				return "document", true
+			}
+		case "seq":
+			hasSeq = true
+			return fmt.Sprintf("%0*d", config.SeqWidth(format), seq), true
+		}
+		return "", false
+	})
+	base = sanitizeFilename(base)
+	if base == "" {
+		base = "document"
+	}
+
+	suffix := ""
+	if !hasSeq && seq > 1 {
+		suffix = fmt.Sprintf("_%d", seq)
+	}
+	base = truncateRunes(base, config.Filename.MaxRunes-len(suffix)) + suffix
+
+	// 拡張子を取得
+	parts := strings.Split(originalName, ".")
+	extension := "pdf"
+	if len(parts) > 1 {
+		extension = parts[len(parts)-1]
+	}
+
+	return fmt.Sprintf("%s.%s", base, extension)
+}
+
+// uniqueFilename は移動先フォルダに同名のファイルがない名前を返す（自分自身は同名でもよい）
+func (fs *FileSorter) uniqueFilename(ctx context.Context, result *model.AnalysisResult, fileInfo *model.FileInfo, folderID string) (string, error) {
+	return findUniqueFilename(
+		func(seq int) string { return fs.renderFilename(result, fileInfo.Name, seq) },
+		func(name string) (bool, error) {
+			ids, err := fs.driveClient.FindFileIDsByName(ctx, folderID, name)
+			if err != nil {
+				return false, err
+			}
+			for _, id := range ids {
+				if id != fileInfo.ID {
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+	)
+}
+
+// findUniqueFilename は連番を1から増やし、exists が false になる最初の名前を返す
+func findUniqueFilename(candidate func(seq int) string, exists func(name string) (bool, error)) (string, error) {
+	for seq := 1; seq <= config.Filename.MaxCollisions; seq++ {
+		name := candidate(seq)
+		found, err := exists(name)
+		if err != nil {
+			return "", fmt.Errorf("同名ファイルの確認失敗: %w", err)
+		}
+		if !found {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("同名のファイルが%d件以上あります: %s", config.Filename.MaxCollisions, candidate(1))
+}
+
+// sanitizeFilename はファイル名に使えない文字を「_」に置き換え、空のプレースホルダーで残った区切り文字を整える
+func sanitizeFilename(name string) string {
+	name = illegalFilenameChars.ReplaceAllString(name, "_")
+	name = repeatedUnderscores.ReplaceAllString(name, "_")
+	return strings.Trim(name, "_ .")
+}
+
+// truncateRunes は文字単位（マルチバイト文字の途中で切らない）で最大 max 文字に切り詰める
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if max < 1 {
+		return ""
+	}
+	if len(runes) <= max {
+		return s
+	}
+	return strings.TrimRight(string(runes[:max]), "_ .")
+}
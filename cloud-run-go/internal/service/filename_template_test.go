@@ -0,0 +1,115 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+func TestRenderFilename(t *testing.T) {
+	household := newTestHousehold(t)
+	fs := &FileSorter{gradeManager: NewGradeManager(household), household: household}
+
+	tests := []struct {
+		name   string
+		result *model.AnalysisResult
+		orig   string
+		seq    int
+		want   string
+	}{
+		{
+			name:   "default template",
+			result: &model.AnalysisResult{Category: "30_ライフ・行政", Date: "20250401", Summary: "水道料金"},
+			orig:   "scan.jpg",
+			seq:    1,
+			want:   "20250401_水道料金.jpg",
+		},
+		{
+			name:   "category template with grade label",
+			result: &model.AnalysisResult{Category: "40_子供・教育", Date: "20250510", Summary: "遠足のお知らせ", TargetChildren: []string{"太郎"}, FiscalYear: 2025, ResolvedFolderName: "太郎", ResolvedLabel: "太郎"},
+			orig:   "scan.pdf",
+			seq:    1,
+			want:   "20250510_小4_遠足のお知らせ.pdf",
+		},
+		{
+			name:   "empty placeholder collapses separators",
+			result: &model.AnalysisResult{Category: "10_マネー・税務", Date: "20250131", Summary: "源泉徴収票"},
+			orig:   "scan.pdf",
+			seq:    1,
+			want:   "2025-01_源泉徴収票.pdf",
+		},
+		{
+			name:   "illegal characters",
+			result: &model.AnalysisResult{Category: "30_ライフ・行政", Date: "20250401", Summary: `A/B: "申請書"?`},
+			orig:   "scan.pdf",
+			seq:    1,
+			want:   "20250401_A_B_ _申請書.pdf",
+		},
+		{
+			name:   "collision suffix",
+			result: &model.AnalysisResult{Category: "30_ライフ・行政", Date: "20250401", Summary: "水道料金"},
+			orig:   "scan.pdf",
+			seq:    3,
+			want:   "20250401_水道料金_3.pdf",
+		},
+		{
+			name:   "seq placeholder",
+			result: &model.AnalysisResult{Category: "30_ライフ・行政", Date: "20250401", Summary: "領収書", FilenameTemplate: "{date}_{seq:3}_{summary}"},
+			orig:   "scan.pdf",
+			seq:    2,
+			want:   "20250401_002_領収書.pdf",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fs.renderFilename(tt.result, tt.orig, tt.seq); got != tt.want {
+				t.Fatalf("renderFilename() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderFilename_TruncatesOnRuneBoundary(t *testing.T) {
+	household := newTestHousehold(t)
+	fs := &FileSorter{household: household}
+	result := &model.AnalysisResult{
+		Category: "30_ライフ・行政",
+		Date:     "20250401",
+		Summary:  strings.Repeat("長い書類名", 30),
+	}
+
+	got := fs.renderFilename(result, "scan.pdf", 12)
+	base := strings.TrimSuffix(got, ".pdf")
+	if !utf8.ValidString(got) || utf8.RuneCountInString(base) != config.Filename.MaxRunes {
+		t.Fatalf("renderFilename() = %q (%d runes)", got, utf8.RuneCountInString(base))
+	}
+	if !strings.HasSuffix(base, "_12") {
+		t.Fatalf("collision suffix should survive truncation: %q", got)
+	}
+}
+
+func TestFindUniqueFilename(t *testing.T) {
+	candidate := func(seq int) string {
+		if seq == 1 {
+			return "a.pdf"
+		}
+		return "a_" + string(rune('0'+seq)) + ".pdf"
+	}
+
+	existing := map[string]bool{"a.pdf": true, "a_2.pdf": true}
+	got, err := findUniqueFilename(candidate, func(name string) (bool, error) { return existing[name], nil })
+	if err != nil || got != "a_3.pdf" {
+		t.Fatalf("findUniqueFilename() = %q, %v", got, err)
+	}
+
+	if _, err := findUniqueFilename(candidate, func(string) (bool, error) { return false, errors.New("drive down") }); err == nil {
+		t.Fatal("expected lookup error")
+	}
+	if _, err := findUniqueFilename(candidate, func(string) (bool, error) { return true, nil }); err == nil {
+		t.Fatal("expected error when every name is taken")
+	}
+}
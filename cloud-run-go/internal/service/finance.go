@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// FinanceLedger は家計の支払いの記録
+type FinanceLedger interface {
+	// Record は支払いを記録する（同じファイルを再度記録した場合は置き換える）
+	Record(ctx context.Context, record *model.FinanceRecord) error
+	// List はすべての記録を集計月・期日順に返す
+	List(ctx context.Context) ([]*model.FinanceRecord, error)
+}
+
+// FileFinanceLedger はインスタンスごとのJSON Lines（finance.<instance>.jsonl）に追記する実装
+// 再処理で同じファイルが複数回記録された場合は、読み込み時に記録日時が新しいものを採用する
+type FileFinanceLedger struct {
+	log instanceLog
+	mu  sync.Mutex
+}
+
+// NewFileFinanceLedger は新しいFileFinanceLedgerを作成
+func NewFileFinanceLedger(dir string) (*FileFinanceLedger, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create finance dir: %w", err)
+	}
+	compactInstanceLogs(dir, instanceID, time.Now())
+	return &FileFinanceLedger{log: newInstanceLog(dir, "finance")}, nil
+}
+
+// Record は記録を追記する
+func (l *FileFinanceLedger) Record(ctx context.Context, record *model.FinanceRecord) error {
+	if record.RecordedAt.IsZero() {
+		record.RecordedAt = time.Now()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.log.append(record); err != nil {
+		return fmt.Errorf("failed to write finance record: %w", err)
+	}
+	return nil
+}
+
+// List は記録を読み込む
+func (l *FileFinanceLedger) List(ctx context.Context) ([]*model.FinanceRecord, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	byFile := map[string]*model.FinanceRecord{}
+	err := l.log.scan(func(line []byte) bool {
+		var r model.FinanceRecord
+		if err := json.Unmarshal(line, &r); err != nil {
+			// 書き込み途中の行などは読み飛ばす
+			return true
+		}
+		if prev, ok := byFile[r.FileID]; !ok || !r.RecordedAt.Before(prev.RecordedAt) {
+			byFile[r.FileID] = &r
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read finance ledger: %w", err)
+	}
+
+	records := make([]*model.FinanceRecord, 0, len(byFile))
+	for _, r := range byFile {
+		records = append(records, r)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Month != records[j].Month {
+			return records[i].Month < records[j].Month
+		}
+		if records[i].DueDate != records[j].DueDate {
+			return records[i].DueDate < records[j].DueDate
+		}
+		return records[i].FileID < records[j].FileID
+	})
+	return records, nil
+}
+
+// FinanceMonthReport はその月の支払いを支払先ごとに集計する（支払先は空白を無視して比較）
+func FinanceMonthReport(month string, records []*model.FinanceRecord) *model.FinanceReport {
+	report := &model.FinanceReport{Month: month, Records: []*model.FinanceRecord{}}
+	byPayee := map[string]*model.FinancePayeeTotal{}
+	for _, r := range records {
+		if r.Month != month {
+			continue
+		}
+		report.Records = append(report.Records, r)
+		report.Count++
+		report.Total += r.Amount
+		key := normalizePayee(r.Payee)
+		t, ok := byPayee[key]
+		if !ok {
+			t = &model.FinancePayeeTotal{Payee: r.Payee}
+			byPayee[key] = t
+		}
+		t.Count++
+		t.Total += r.Amount
+	}
+	for _, t := range byPayee {
+		report.ByPayee = append(report.ByPayee, *t)
+	}
+	sort.Slice(report.ByPayee, func(i, j int) bool {
+		if report.ByPayee[i].Total != report.ByPayee[j].Total {
+			return report.ByPayee[i].Total > report.ByPayee[j].Total
+		}
+		return report.ByPayee[i].Payee < report.ByPayee[j].Payee
+	})
+	return report
+}
+
+// FinancePayeeReport はその支払先の支払いを月ごとに集計する（支払先は空白を無視して比較）
+func FinancePayeeReport(payee string, records []*model.FinanceRecord) *model.FinanceReport {
+	report := &model.FinanceReport{Payee: payee, Records: []*model.FinanceRecord{}}
+	key := normalizePayee(payee)
+	for _, r := range records {
+		if normalizePayee(r.Payee) != key {
+			continue
+		}
+		report.Records = append(report.Records, r)
+		report.Count++
+		report.Total += r.Amount
+		// records は集計月順のため、同じ月は末尾にまとまる
+		if n := len(report.ByMonth); n > 0 && report.ByMonth[n-1].Month == r.Month {
+			report.ByMonth[n-1].Count++
+			report.ByMonth[n-1].Total += r.Amount
+			continue
+		}
+		report.ByMonth = append(report.ByMonth, model.FinanceMonthTotal{Month: r.Month, Count: 1, Total: r.Amount})
+	}
+	return report
+}
+
+// UpcomingFinanceRecords は今日以降に支払期日がある記録を期日順に返す
+func UpcomingFinanceRecords(records []*model.FinanceRecord, now time.Time) []*model.FinanceRecord {
+	today := now.Format("2006-01-02")
+	upcoming := []*model.FinanceRecord{}
+	for _, r := range records {
+		if r.DueDate != "" && r.DueDate >= today {
+			upcoming = append(upcoming, r)
+		}
+	}
+	sort.SliceStable(upcoming, func(i, j int) bool { return upcoming[i].DueDate < upcoming[j].DueDate })
+	return upcoming
+}
+
+func normalizePayee(payee string) string {
+	return strings.Join(strings.Fields(payee), "")
+}
+
+// SetFinanceLedger は家計の支払いの記録先を設定（未設定時は記録しない）
+func (fs *FileSorter) SetFinanceLedger(ledger FinanceLedger) {
+	fs.finance = ledger
+}
+
+// stepFinance はマネー・税務の書類から支払先・金額・期日を読み取って記録し、
+// 支払期日がこれからのものはGoogle Tasksに登録する（予定・タスクのステップで同じ期日のタスクを登録済みの場合を除く）
+func (fs *FileSorter) stepFinance(ctx context.Context, r *pipelineRun) (string, error) {
+	if r.analysis.Category != "10_マネー・税務" {
+		return "", skipStep("マネー・税務以外")
+	}
+	if fs.finance == nil {
+		return "", skipStep("家計の記録未初期化")
+	}
+	if r.entry.Provisional {
+		return "", skipStep("AI未使用の仮分類")
+	}
+
+	data, err := fs.fileData(ctx, r)
+	if err != nil {
+		return "", err
+	}
+	record, err := fs.aiRouter.ExtractFinance(ctx, data, r.fileInfo.MimeType, r.fileInfo.Name)
+	if err != nil {
+		return "", fmt.Errorf("家計の書類の読み取り失敗: %w", err)
+	}
+	if record.Amount <= 0 && record.DueDate == "" {
+		return "", skipStep("金額・期日を読み取れない")
+	}
+
+	record.FileID = r.fileInfo.ID
+	record.FileName = r.newFileName
+	record.Account = maskAccount(record.Account)
+	record.Month = financeMonth(record, r.analysis.Date)
+
+	task := financeTask(record, fs.createTitlePrefix(r.analysis), time.Now())
+	switch {
+	case task == nil || fs.tasksClient == nil || !config.Finance.CreateDueTasks:
+	case dueTaskRegistered(r.entry, task.DueDate):
+		log.Printf("支払期日のタスクは登録済みです: %s (期日: %s)", record.Payee, task.DueDate)
+	default:
+		taskID, err := fs.createFinanceTask(ctx, task, r.fileInfo.ID)
+		if err != nil {
+			return "", err
+		}
+		record.TaskID = taskID
+	}
+
+	if err := fs.finance.Record(ctx, record); err != nil {
+		return "", fmt.Errorf("家計の記録失敗: %w", err)
+	}
+	log.Printf("家計の支払いを記録: %s %s %d円 (期日: %s)", record.Month, record.Payee, record.Amount, record.DueDate)
+	return fmt.Sprintf("%s %d円", record.Payee, record.Amount), nil
+}
+
+// createFinanceTask は支払期日のタスクを作成する（同じタイトル・期日のタスクがあれば作成しない）
+func (fs *FileSorter) createFinanceTask(ctx context.Context, task *model.Task, fileID string) (string, error) {
+	exists, err := fs.tasksClient.TaskExistsByTitleAndDate(ctx, task.Title, task.DueDate)
+	if err != nil {
+		log.Printf("タスク重複チェック失敗: %v", err)
+	} else if exists {
+		log.Printf("タスクは既に存在します: %s (期日: %s)", task.Title, task.DueDate)
+		return "", nil
+	}
+	notes := fmt.Sprintf("📎 元の書類: https://drive.google.com/file/d/%s/view", fileID)
+	taskID, err := fs.tasksClient.CreateTask(ctx, task, notes)
+	if err != nil {
+		return "", fmt.Errorf("支払期日のタスク作成失敗: %w", err)
+	}
+	return taskID, nil
+}
+
+// financeTask は支払期日のタスクを返す（期日がない・過ぎている場合は nil）
+// 利用明細・引き落としの通知は自動で支払われるため、タスクにしない
+func financeTask(record *model.FinanceRecord, titlePrefix string, now time.Time) *model.Task {
+	if record.DocumentType == model.FinanceDocumentStatement {
+		return nil
+	}
+	if record.DueDate == "" || record.DueDate < now.Format("2006-01-02") {
+		return nil
+	}
+	title := fmt.Sprintf("%s の支払い", record.Payee)
+	if record.Amount > 0 {
+		title = fmt.Sprintf("%s の支払い（%d円）", record.Payee, record.Amount)
+	}
+	if titlePrefix != "" {
+		title = titlePrefix + " " + title
+	}
+	var notes []string
+	if record.PeriodStart != "" || record.PeriodEnd != "" {
+		notes = append(notes, fmt.Sprintf("対象期間: %s〜%s", record.PeriodStart, record.PeriodEnd))
+	}
+	if record.Account != "" {
+		notes = append(notes, "口座・カード: "+record.Account)
+	}
+	return &model.Task{Title: title, DueDate: record.DueDate, Notes: strings.Join(notes, "\n")}
+}
+
+// dueTaskRegistered は同じ書類の予定・タスクのステップで、同じ期日のタスクを登録済みかを返す
+// 請求書はAIが支払いのタスクとしても抽出するため、支払期日のタスクを二重に作らない
+func dueTaskRegistered(entry *model.LedgerEntry, dueDate string) bool {
+	if rec := entry.Steps[model.StepTasks]; rec == nil || rec.Status != model.StepStatusSucceeded || entry.EventsAndTasks == nil {
+		return false
+	}
+	for _, task := range entry.EventsAndTasks.Tasks {
+		if task.DueDate == dueDate {
+			return true
+		}
+	}
+	return false
+}
+
+// financeMonth は集計する月を決める（支払期日 → 対象期間の末日 → 書類の日付（YYYYMMDD））
+func financeMonth(record *model.FinanceRecord, documentDate string) string {
+	for _, date := range []string{record.DueDate, record.PeriodEnd} {
+		if len(date) >= 7 {
+			return date[:7]
+		}
+	}
+	if len(documentDate) >= 6 {
+		return documentDate[:4] + "-" + documentDate[4:6]
+	}
+	return time.Now().Format("2006-01")
+}
+
+// maskAccount は口座・カード番号の下4桁以外の数字を「*」に置き換える
+// AIには下4桁のみを返すよう指示しているが、全桁を返した場合も記録に残さない
+func maskAccount(account string) string {
+	runes := []rune(strings.TrimSpace(account))
+	keep := 4
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] < '0' || runes[i] > '9' {
+			continue
+		}
+		if keep > 0 {
+			keep--
+			continue
+		}
+		runes[i] = '*'
+	}
+	return string(runes)
+}
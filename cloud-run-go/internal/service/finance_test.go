@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+func TestFileFinanceLedger_ListKeepsLatestRecord(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	ledger, err := NewFileFinanceLedger(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger.log.instance = "b"
+	// 別のインスタンスの記録もまとめて読む（同じファイルは記録日時が新しいものを採用）
+	other, err := NewFileFinanceLedger(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.log.instance = "a"
+
+	recorded := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+	records := []struct {
+		ledger *FileFinanceLedger
+		record *model.FinanceRecord
+	}{
+		{ledger, &model.FinanceRecord{FileID: "b", Month: "2025-05", Payee: "○○電力", Amount: 8000, RecordedAt: recorded}},
+		{other, &model.FinanceRecord{FileID: "a", Month: "2025-04", Payee: "○○ガス", Amount: 4000, RecordedAt: recorded}},
+		{other, &model.FinanceRecord{FileID: "b", Month: "2025-05", Payee: "○○電力", Amount: 8200, RecordedAt: recorded.Add(time.Hour)}},
+	}
+	for _, r := range records {
+		if err := r.ledger.Record(ctx, r.record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := ledger.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].FileID != "a" || got[1].Amount != 8200 {
+		t.Fatalf("List() = %+v", got)
+	}
+}
+
+func TestFinanceReports(t *testing.T) {
+	records := []*model.FinanceRecord{
+		{FileID: "1", Month: "2025-04", Payee: "○○電力", Amount: 9000, DueDate: "2025-04-25"},
+		{FileID: "2", Month: "2025-05", Payee: "○○ガス", Amount: 3000, DueDate: "2025-05-27"},
+		{FileID: "3", Month: "2025-05", Payee: "○○電力", Amount: 8000, DueDate: "2025-05-26"},
+		{FileID: "4", Month: "2025-05", Payee: "○○ 電力", Amount: 500},
+	}
+
+	month := FinanceMonthReport("2025-05", records)
+	wantByPayee := []model.FinancePayeeTotal{
+		{Payee: "○○電力", Count: 2, Total: 8500},
+		{Payee: "○○ガス", Count: 1, Total: 3000},
+	}
+	if month.Count != 3 || month.Total != 11500 || !reflect.DeepEqual(month.ByPayee, wantByPayee) {
+		t.Fatalf("FinanceMonthReport() = %+v", month)
+	}
+
+	payee := FinancePayeeReport("○○電力", records)
+	wantByMonth := []model.FinanceMonthTotal{
+		{Month: "2025-04", Count: 1, Total: 9000},
+		{Month: "2025-05", Count: 2, Total: 8500},
+	}
+	if payee.Total != 17500 || !reflect.DeepEqual(payee.ByMonth, wantByMonth) {
+		t.Fatalf("FinancePayeeReport() = %+v", payee)
+	}
+
+	now := time.Date(2025, 5, 26, 12, 0, 0, 0, time.Local)
+	upcoming := UpcomingFinanceRecords(records, now)
+	if len(upcoming) != 2 || upcoming[0].FileID != "3" || upcoming[1].FileID != "2" {
+		t.Fatalf("UpcomingFinanceRecords() = %+v", upcoming)
+	}
+}
+
+func TestMaskAccount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"1234", "1234"},
+		{"普通 1234567", "普通 ***4567"},
+		{"4980-1234-5678-9012", "****-****-****-9012"},
+		{"****1234", "****1234"},
+	}
+	for _, tt := range tests {
+		if got := maskAccount(tt.in); got != tt.want {
+			t.Errorf("maskAccount(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFinanceMonth(t *testing.T) {
+	tests := []struct {
+		name   string
+		record *model.FinanceRecord
+		want   string
+	}{
+		{"due date", &model.FinanceRecord{DueDate: "2025-06-10", PeriodEnd: "2025-04-30"}, "2025-06"},
+		{"period end", &model.FinanceRecord{PeriodEnd: "2025-04-30"}, "2025-04"},
+		{"document date", &model.FinanceRecord{}, "2025-03"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := financeMonth(tt.record, "20250315"); got != tt.want {
+				t.Fatalf("financeMonth() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFinanceTask(t *testing.T) {
+	now := time.Date(2025, 5, 20, 9, 0, 0, 0, time.Local)
+	record := &model.FinanceRecord{Payee: "○○電力", Amount: 8000, DueDate: "2025-05-26", Account: "***4567"}
+
+	task := financeTask(record, "[父]", now)
+	want := &model.Task{Title: "[父] ○○電力 の支払い（8000円）", DueDate: "2025-05-26", Notes: "口座・カード: ***4567"}
+	if !reflect.DeepEqual(task, want) {
+		t.Fatalf("financeTask() = %+v, want %+v", task, want)
+	}
+
+	past := &model.FinanceRecord{Payee: "○○電力", Amount: 8000, DueDate: "2025-05-19"}
+	if task := financeTask(past, "", now); task != nil {
+		t.Fatalf("past due date should not become a task: %+v", task)
+	}
+	if task := financeTask(&model.FinanceRecord{Payee: "○○銀行"}, "", now); task != nil {
+		t.Fatalf("record without due date should not become a task: %+v", task)
+	}
+	statement := &model.FinanceRecord{DocumentType: model.FinanceDocumentStatement, Payee: "○○カード", Amount: 52000, DueDate: "2025-06-10"}
+	if task := financeTask(statement, "", now); task != nil {
+		t.Fatalf("statement should not become a task: %+v", task)
+	}
+}
+
+func TestDueTaskRegistered(t *testing.T) {
+	tasks := &model.EventsAndTasks{Tasks: []model.Task{{Title: "電気料金の支払い", DueDate: "2025-05-26"}}}
+	tests := []struct {
+		name  string
+		entry *model.LedgerEntry
+		due   string
+		want  bool
+	}{
+		{"same due date", &model.LedgerEntry{EventsAndTasks: tasks, Steps: map[model.StepName]*model.StepRecord{model.StepTasks: {Status: model.StepStatusSucceeded}}}, "2025-05-26", true},
+		{"other due date", &model.LedgerEntry{EventsAndTasks: tasks, Steps: map[model.StepName]*model.StepRecord{model.StepTasks: {Status: model.StepStatusSucceeded}}}, "2025-06-26", false},
+		{"tasks step skipped", &model.LedgerEntry{EventsAndTasks: tasks, Steps: map[model.StepName]*model.StepRecord{model.StepTasks: {Status: model.StepStatusSkipped}}}, "2025-05-26", false},
+		{"tasks step not run", &model.LedgerEntry{EventsAndTasks: tasks}, "2025-05-26", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dueTaskRegistered(tt.entry, tt.due); got != tt.want {
+				t.Fatalf("dueTaskRegistered() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStepFinance(t *testing.T) {
+	ctx := context.Background()
+	household := newTestHousehold(t)
+	ledger, err := NewFileFinanceLedger(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := NewFakeBackend()
+	backend.Finance = &model.FinanceRecord{
+		DocumentType: model.FinanceDocumentBill,
+		Payee:        "○○電力",
+		Amount:       8000,
+		PeriodStart:  "2025-04-01",
+		PeriodEnd:    "2025-04-30",
+		Account:      "1234567",
+	}
+	fs := &FileSorter{
+		aiRouter:  NewAIRouterWithBackend(backend),
+		household: household,
+		finance:   ledger,
+	}
+
+	r := &pipelineRun{
+		fileInfo:    &model.FileInfo{ID: "bill", Name: "scan.pdf", MimeType: "application/pdf"},
+		entry:       &model.LedgerEntry{FileID: "bill"},
+		data:        []byte("data"),
+		analysis:    &model.AnalysisResult{Category: "10_マネー・税務", Date: "20250510", TargetAdult: "父"},
+		newFileName: "2025-05_電気料金.pdf",
+	}
+	if _, err := fs.stepFinance(ctx, r); err != nil {
+		t.Fatal(err)
+	}
+	records, err := ledger.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Month != "2025-04" || records[0].Account != "***4567" || records[0].FileName != "2025-05_電気料金.pdf" {
+		t.Fatalf("recorded = %+v", records)
+	}
+
+	var skipped *stepSkipped
+	r.analysis = &model.AnalysisResult{Category: "30_ライフ・行政"}
+	if _, err := fs.stepFinance(ctx, r); !errors.As(err, &skipped) {
+		t.Fatalf("expected skip for other categories, got %v", err)
+	}
+}
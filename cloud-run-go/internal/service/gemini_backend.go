@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/google/generative-ai-go/genai"
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"google.golang.org/api/option"
+)
+
+// GeminiBackend はGemini APIを使うバックエンド
+type GeminiBackend struct {
+	client *genai.Client
+}
+
+// NewGeminiBackend は新しいGeminiBackendを作成
+func NewGeminiBackend(ctx context.Context) (*GeminiBackend, error) {
+	apiKey, err := getAPIKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create genai client: %w", err)
+	}
+
+	return &GeminiBackend{client: client}, nil
+}
+
+// getAPIKey はSecret ManagerからGemini APIキーを取得
+func getAPIKey(ctx context.Context) (string, error) {
+	// Secret Managerクライアントを作成
+	client, err := secretmanager.NewClient(ctx)
+	if err != nil {
+		// フォールバック: 環境変数から取得
+		log.Printf("Secret Manager client creation failed: %v, falling back to env var", err)
+		return getFallbackAPIKey()
+	}
+	defer client.Close()
+
+	// Secret Managerからキーを取得
+	name := fmt.Sprintf("projects/%s/secrets/%s/versions/latest",
+		config.GCPProjectID,
+		config.SecretGeminiAPIKey)
+
+	req := &secretmanagerpb.AccessSecretVersionRequest{
+		Name: name,
+	}
+
+	result, err := client.AccessSecretVersion(ctx, req)
+	if err != nil {
+		// フォールバック: 環境変数から取得
+		log.Printf("Secret Manager access failed: %v, falling back to env var", err)
+		return getFallbackAPIKey()
+	}
+
+	return string(result.Payload.Data), nil
+}
+
+// getFallbackAPIKey は環境変数からAPIキーを取得
+func getFallbackAPIKey() (string, error) {
+	apiKey := config.GetEnv("GEMINI_API_KEY", "")
+	if apiKey == "" {
+		return "", fmt.Errorf("GEMINI_API_KEY not found in environment variables")
+	}
+	return apiKey, nil
+}
+
+// Name はバックエンド名を返す
+func (b *GeminiBackend) Name() string {
+	return "gemini"
+}
+
+// Models はFlash/Proのモデル名を返す
+func (b *GeminiBackend) Models() (string, string) {
+	return config.GeminiModelsConfig.Flash, config.GeminiModelsConfig.Pro
+}
+
+// Generate はGemini APIを呼び出し、JSONレスポンスを返す
+func (b *GeminiBackend) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	genModel := b.client.GenerativeModel(req.Model)
+	genModel.GenerationConfig.ResponseMIMEType = "application/json"
+	if req.Schema != nil {
+		genModel.GenerationConfig.ResponseSchema = toGenaiSchema(req.Schema)
+	}
+
+	// PDFはBlob、画像（JPEG, PNG等）はImageDataで送信
+	var dataPart genai.Part
+	if req.MimeType == "application/pdf" {
+		dataPart = genai.Blob{
+			MIMEType: req.MimeType,
+			Data:     req.Data,
+		}
+	} else {
+		// mimeTypeから拡張子部分を取得（簡易的）
+		format := "jpeg"
+		if len(req.MimeType) > 6 {
+			format = req.MimeType[6:]
+		}
+		dataPart = genai.ImageData(format, req.Data)
+	}
+
+	resp, err := genModel.GenerateContent(ctx,
+		dataPart,
+		genai.Text(req.Prompt),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		return nil, ErrEmptyResponse
+	}
+
+	// Partsを連結してテキストを取得
+	result := &GenerateResponse{Text: extractTextFromParts(resp.Candidates[0].Content.Parts)}
+	if u := resp.UsageMetadata; u != nil {
+		result.PromptTokens = int(u.PromptTokenCount)
+		result.OutputTokens = int(u.CandidatesTokenCount)
+		result.TotalTokens = int(u.TotalTokenCount)
+	}
+	return result, nil
+}
+
+// toGenaiSchema はレスポンススキーマをGeminiの形式に変換する
+// Geminiは正規表現・数値範囲に対応していないため、説明文に含めて応答後の検証で確認する
+func toGenaiSchema(s *ResponseSchema) *genai.Schema {
+	out := &genai.Schema{
+		Description: s.Description,
+		Nullable:    s.Nullable,
+		Enum:        s.Enum,
+		Required:    s.Required,
+	}
+	switch s.Type {
+	case SchemaObject:
+		out.Type = genai.TypeObject
+	case SchemaArray:
+		out.Type = genai.TypeArray
+	case SchemaNumber:
+		out.Type = genai.TypeNumber
+	case SchemaBoolean:
+		out.Type = genai.TypeBoolean
+	default:
+		out.Type = genai.TypeString
+	}
+	if len(s.Enum) > 0 {
+		out.Format = "enum"
+	}
+	if s.Format != "" {
+		out.Description = strings.TrimSpace(out.Description + " 形式: " + strings.ToUpper(s.Format))
+	}
+	if s.Minimum != nil && s.Maximum != nil {
+		out.Description = strings.TrimSpace(fmt.Sprintf("%s 範囲: %g〜%g", out.Description, *s.Minimum, *s.Maximum))
+	}
+	if s.Items != nil {
+		out.Items = toGenaiSchema(s.Items)
+	}
+	if s.Properties != nil {
+		out.Properties = make(map[string]*genai.Schema, len(s.Properties))
+		for name, p := range s.Properties {
+			out.Properties[name] = toGenaiSchema(p)
+		}
+	}
+	return out
+}
+
+// extractTextFromParts はPartsからテキストを安全に抽出
+func extractTextFromParts(parts []genai.Part) string {
+	var result string
+	for _, part := range parts {
+		switch v := part.(type) {
+		case genai.Text:
+			result += string(v)
+		default:
+			// Text以外のPartは文字列化
+			result += fmt.Sprintf("%v", v)
+		}
+	}
+	return result
+}
+
+// Close はクライアントをクローズ
+func (b *GeminiBackend) Close() error {
+	return b.client.Close()
+}
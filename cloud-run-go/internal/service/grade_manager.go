@@ -9,11 +9,18 @@ import (
 )
 
 // GradeManager は学年管理サービス
-type GradeManager struct{}
+type GradeManager struct {
+	household *config.HouseholdStore
+}
 
 // NewGradeManager は新しいGradeManagerを作成
-func NewGradeManager() *GradeManager {
-	return &GradeManager{}
+func NewGradeManager(household *config.HouseholdStore) *GradeManager {
+	return &GradeManager{household: household}
+}
+
+// grades は現在の世帯設定の学年設定を返す
+func (gm *GradeManager) grades() config.GradeConfig {
+	return gm.household.Current().Grades
 }
 
 // CalculateFiscalYear は日付文字列から年度を計算
@@ -55,13 +62,13 @@ func (gm *GradeManager) IdentifyChildren(gradeClassText string, fiscalYear int)
 	var children []string
 
 	// 1. まず個別の子供の学年から判定（より具体的な指定を優先）
-	for childName, baseGrade := range config.GradeConfigSettings.ChildrenBaseGrades {
-		yearDiff := fiscalYear - config.GradeConfigSettings.BaseFiscalYear
-		currentGrade := baseGrade + yearDiff
-
-		// 学年文字列から判定（例：「小2」「年長」）
-		if gm.matchesGrade(gradeClassText, currentGrade) {
-			children = append(children, childName)
+	seen := map[string]bool{}
+	for _, mention := range gm.ResolveGradeMentions(gradeClassText, fiscalYear) {
+		for _, child := range mention.Children {
+			if !seen[child] {
+				seen[child] = true
+				children = append(children, child)
+			}
 		}
 	}
 
@@ -71,7 +78,7 @@ func (gm *GradeManager) IdentifyChildren(gradeClassText string, fiscalYear int)
 	}
 
 	// 2. 個別の一致がない場合のみ、共有グループのクラス名から判定
-	for className, group := range config.GradeConfigSettings.SharedGroups {
+	for className, group := range gm.grades().SharedGroups {
 		if strings.Contains(gradeClassText, className) {
 			return group.Children
 		}
@@ -80,34 +87,73 @@ func (gm *GradeManager) IdentifyChildren(gradeClassText string, fiscalYear int)
 	return children
 }
 
-// matchesGrade は学年文字列が一致するかチェック
+// IdentifyChildrenByProfile は学校名・担任・習い事の手がかりから子供を特定する
+// 空白・全角英数字の違いは無視し、その年度に在籍している学校と担任のみを照合する
+func (gm *GradeManager) IdentifyChildrenByProfile(text string, fiscalYear int) []string {
+	household := gm.household.Current()
+	normalized := normalizeClueText(text)
+	if normalized == "" {
+		return nil
+	}
+
+	var children []string
+	for _, child := range household.ChildProfileNames() {
+		for _, clue := range household.ChildProfiles[child].Clues(fiscalYear) {
+			if c := normalizeClueText(clue); c != "" && strings.Contains(normalized, c) {
+				children = append(children, child)
+				break
+			}
+		}
+	}
+	return children
+}
+
+// normalizeClueText は手がかりの照合用に空白を除き、全角英数字を半角にそろえる
+func normalizeClueText(s string) string {
+	return strings.Join(strings.Fields(normalizeGradeText(s)), "")
+}
+
+// ResolveGradeMentions は本文中の学年の表記ごとに、その年度に該当する子供を求める
+func (gm *GradeManager) ResolveGradeMentions(text string, fiscalYear int) []GradeMention {
+	grades := gm.grades()
+	mentions := ParseGradeMentions(text, grades.PreschoolClasses)
+	for i := range mentions {
+		for _, child := range sortedKeys(grades.ChildrenBaseGrades) {
+			if containsInt(mentions[i].Grades, gm.GetChildGrade(child, fiscalYear)) {
+				mentions[i].Children = append(mentions[i].Children, child)
+			}
+		}
+	}
+	return mentions
+}
+
+// matchesGrade は学年文字列が指定の学年を含むかチェック
 func (gm *GradeManager) matchesGrade(text string, grade int) bool {
-	// 小学校の場合
-	if grade >= 1 && grade <= 6 {
-		gradeStr := strconv.Itoa(grade)
-		if strings.Contains(text, "小"+gradeStr) || strings.Contains(text, gradeStr+"年生") {
+	for _, mention := range ParseGradeMentions(text, gm.grades().PreschoolClasses) {
+		if containsInt(mention.Grades, grade) {
 			return true
 		}
 	}
+	return false
+}
 
-	// 保育園の場合
-	if classInfo, exists := config.GradeConfigSettings.PreschoolClasses[grade]; exists {
-		if strings.Contains(text, classInfo.Name) {
+func containsInt(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
 			return true
 		}
 	}
-
 	return false
 }
 
 // IsGraduated は高校卒業しているかチェック
 func (gm *GradeManager) IsGraduated(childName string, fiscalYear int) bool {
-	baseGrade, exists := config.GradeConfigSettings.ChildrenBaseGrades[childName]
+	baseGrade, exists := gm.grades().ChildrenBaseGrades[childName]
 	if !exists {
 		return false
 	}
 
-	yearDiff := fiscalYear - config.GradeConfigSettings.BaseFiscalYear
+	yearDiff := fiscalYear - gm.grades().BaseFiscalYear
 	currentGrade := baseGrade + yearDiff
 
 	return currentGrade > config.ChildGraduationGrade
@@ -115,21 +161,24 @@ func (gm *GradeManager) IsGraduated(childName string, fiscalYear int) bool {
 
 // GetChildGrade は子供の現在の学年を取得
 func (gm *GradeManager) GetChildGrade(childName string, fiscalYear int) int {
-	baseGrade, exists := config.GradeConfigSettings.ChildrenBaseGrades[childName]
+	baseGrade, exists := gm.grades().ChildrenBaseGrades[childName]
 	if !exists {
 		return 0
 	}
 
-	yearDiff := fiscalYear - config.GradeConfigSettings.BaseFiscalYear
+	yearDiff := fiscalYear - gm.grades().BaseFiscalYear
 	return baseGrade + yearDiff
 }
 
 // GetGradeInfo は学年情報（ラベルと絵文字）を取得
 func (gm *GradeManager) GetGradeInfo(grade int) (string, string) {
-	// 保育園の場合
-	if classInfo, exists := config.GradeConfigSettings.PreschoolClasses[grade]; exists {
+	// 保育園の場合（クラス名の設定がなければ年少・年中・年長）
+	if classInfo, exists := gm.grades().PreschoolClasses[grade]; exists {
 		return classInfo.Name, classInfo.Emoji
 	}
+	if label, ok := preschoolLabels[grade]; ok {
+		return label, ""
+	}
 
 	// 小学校の場合
 	if grade >= 1 && grade <= 6 {
@@ -146,6 +195,11 @@ func (gm *GradeManager) GetGradeInfo(grade int) (string, string) {
 		return "高" + strconv.Itoa(grade-9), ""
 	}
 
+	// 大学の場合
+	if grade >= 13 && grade <= 16 {
+		return "大" + strconv.Itoa(grade-12), ""
+	}
+
 	return "", ""
 }
 
@@ -156,7 +210,7 @@ func (gm *GradeManager) ResolveFolderName(children []string) (string, string, st
 	}
 
 	// 共有グループに該当するかチェック
-	for _, group := range config.GradeConfigSettings.SharedGroups {
+	for _, group := range gm.grades().SharedGroups {
 		if gm.matchesGroup(children, group.Children) {
 			return group.FolderName, group.Label, group.Label
 		}
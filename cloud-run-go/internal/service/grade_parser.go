@@ -0,0 +1,274 @@
+package service
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+)
+
+// 学年の番号（GradeConfig.ChildrenBaseGrades と同じ数え方）
+// 年少=-3、年中=-2、年長=-1、小1〜小6=1〜6、中1〜中3=7〜9、高1〜高3=10〜12、大1〜大4=13〜16
+const (
+	gradeNenshou = -3
+	gradeNenchou = -1
+)
+
+// preschoolLabels はクラス名の設定がない場合の保育園・幼稚園の学年
+var preschoolLabels = map[int]string{gradeNenshou: "年少", gradeNenshou + 1: "年中", gradeNenchou: "年長"}
+
+// schoolStage は学校段階（学年の番号の開始位置と学年数）
+type schoolStage struct {
+	offset int
+	years  int
+}
+
+var (
+	stageElementary = schoolStage{offset: 0, years: 6}
+	stageJunior     = schoolStage{offset: 6, years: 3}
+	stageHigh       = schoolStage{offset: 9, years: 3}
+	stageUniversity = schoolStage{offset: 12, years: 4}
+)
+
+// grades は段階の学年 from〜to の番号を返す（段階の範囲外は除く）
+func (s schoolStage) grades(from, to int) []int {
+	if from > to {
+		from, to = to, from
+	}
+	var grades []int
+	for n := max(from, 1); n <= min(to, s.years); n++ {
+		grades = append(grades, s.offset+n)
+	}
+	return grades
+}
+
+// gradeMentionPattern は学年の表記（先に書いたものを優先して照合する）
+//   - 全学年・全園児・低学年・中学年・高学年・年少・年中・年長
+//   - 小3・中2・高1・大2・大学2年（「○○大学附属小学校」のような学年のない「大学」、「最大3名」のような語の途中は対象外）
+//   - 小学3年生・中学校2年・高校1年・3年生・3年2組・3〜6年・3・4年生
+var gradeMentionPattern = regexp.MustCompile(
+	`全学年|全園児|低学年|中学年|高学年|年少|年中|年長` +
+		`|大学\s*([1-4])\s*年生?` +
+		`|(小学校|小学|中学校|中学|高等学校|高校)?\s*([1-6])(?:\s*([〜～~\-ー・、,])\s*([1-6]))?\s*年(生|[0-9A-Za-z]+組)?` +
+		`|([小中高大])([1-6])`,
+)
+
+// kanjiGradeReplacer は「小学三年」「中二」のような漢数字の学年を半角数字にする
+var kanjiGradeReplacer = func() *strings.Replacer {
+	var pairs []string
+	for i, kanji := range []string{"一", "二", "三", "四", "五", "六"} {
+		digit := strconv.Itoa(i + 1)
+		pairs = append(pairs, kanji+"年", digit+"年")
+		for _, stage := range []string{"小", "中", "高", "大"} {
+			pairs = append(pairs, stage+kanji, stage+digit)
+		}
+	}
+	return strings.NewReplacer(pairs...)
+}()
+
+// GradeMention は本文中の学年の表記と、それが指す学年・子供
+type GradeMention struct {
+	Text     string   `json:"text"`
+	Grades   []int    `json:"grades"`
+	Children []string `json:"children,omitempty"`
+}
+
+// ParseGradeMentions は学年・クラスの表記を解析する
+// 段階のない表記（「3年生」「全学年」など）は直前に「中学校の」「高校」のような段階があればその段階、なければ小学校とみなす
+// （「6年生 中学校入学説明会」のように離れた位置の段階は使わない）
+// preschoolClasses のクラス名（「きりん組」など）もその学年として扱う
+func ParseGradeMentions(text string, preschoolClasses map[int]config.PreschoolClass) []GradeMention {
+	text = normalizeGradeText(text)
+
+	var mentions []GradeMention
+	for _, loc := range gradeMentionPattern.FindAllStringSubmatchIndex(text, -1) {
+		m := func(i int) string {
+			if loc[2*i] < 0 {
+				return ""
+			}
+			return text[loc[2*i]:loc[2*i+1]]
+		}
+		whole := m(0)
+		var grades []int
+		adjacent := adjacentStage(text[:loc[0]])
+		switch {
+		case whole == "全学年":
+			grades = adjacent.grades(1, adjacent.years)
+		case whole == "全園児":
+			grades = preschoolGrades(preschoolClasses)
+		case whole == "低学年":
+			grades = stageElementary.grades(1, 2)
+		case whole == "中学年":
+			grades = stageElementary.grades(3, 4)
+		case whole == "高学年":
+			grades = stageElementary.grades(5, 6)
+		case whole == "年少":
+			grades = []int{gradeNenshou}
+		case whole == "年中":
+			if hasAnyPrefix(text[loc[1]:], "行事", "無休") {
+				continue
+			}
+			grades = []int{gradeNenshou + 1}
+		case whole == "年長":
+			grades = []int{gradeNenchou}
+		case strings.HasPrefix(whole, "大学"):
+			grades = stageUniversity.grades(atoi(m(1)), atoi(m(1)))
+		case m(7) != "":
+			// 「最大3名」「期間中1回」「最高3回」「小一時間」のような学年でない表記を除く
+			if !stageBoundaryBefore(text[:loc[0]]) || hasAnyPrefix(text[loc[1]:], gradeCounters...) {
+				continue
+			}
+			grades = stageForPrefix(m(7), stageElementary).grades(atoi(m(8)), atoi(m(8)))
+		default:
+			prefix, sep, suffix := m(2), m(4), m(6)
+			// 「令和6年」「2025年」「3年後」のような学年でない「N年」を除く
+			if prefix == "" && sep == "" && suffix == "" {
+				continue
+			}
+			if prefix == "" && !gradeBoundaryBefore(text[:loc[0]]) {
+				continue
+			}
+			if hasAnyPrefix(text[loc[1]:], "度", "月", "前", "後", "間", "目") {
+				continue
+			}
+			stage := stageForPrefix(prefix, adjacent)
+			from, to := atoi(m(3)), atoi(m(3))
+			if m(5) != "" {
+				to = atoi(m(5))
+			}
+			// 直前の段階から推定した場合、その段階にない学年（「中学校の5年生」など）は小学校とみなす
+			if prefix == "" && len(stage.grades(max(from, to), max(from, to))) == 0 {
+				stage = stageElementary
+			}
+			if sep == "・" || sep == "、" || sep == "," {
+				grades = append(stage.grades(from, from), stage.grades(to, to)...)
+			} else {
+				grades = stage.grades(from, to)
+			}
+		}
+		if len(grades) > 0 {
+			mentions = append(mentions, GradeMention{Text: whole, Grades: grades})
+		}
+	}
+
+	// 保育園・幼稚園のクラス名
+	for _, grade := range sortedGradeKeys(preschoolClasses) {
+		name := preschoolClasses[grade].Name
+		if name != "" && strings.Contains(text, name) {
+			mentions = append(mentions, GradeMention{Text: name, Grades: []int{grade}})
+		}
+	}
+	return mentions
+}
+
+// normalizeGradeText は全角英数字を半角に、漢数字の学年を半角数字にそろえる
+func normalizeGradeText(text string) string {
+	text = strings.Map(func(r rune) rune {
+		if (r >= '０' && r <= '９') || (r >= 'Ａ' && r <= 'Ｚ') || (r >= 'ａ' && r <= 'ｚ') {
+			return r - 0xFEE0
+		}
+		return r
+	}, text)
+	return kanjiGradeReplacer.Replace(text)
+}
+
+// adjacentStage は段階のない表記の直前（空白・「の」を除く）にある段階を返す（なければ小学校）
+func adjacentStage(before string) schoolStage {
+	before = strings.TrimRight(before, " 　の")
+	switch {
+	case hasAnySuffix(before, "中学校", "中学"):
+		return stageJunior
+	case hasAnySuffix(before, "高等学校", "高校"):
+		return stageHigh
+	}
+	return stageElementary
+}
+
+func stageForPrefix(prefix string, context schoolStage) schoolStage {
+	switch {
+	case strings.HasPrefix(prefix, "小"):
+		return stageElementary
+	case strings.HasPrefix(prefix, "中"):
+		return stageJunior
+	case strings.HasPrefix(prefix, "高"):
+		return stageHigh
+	case strings.HasPrefix(prefix, "大"):
+		return stageUniversity
+	}
+	return context
+}
+
+// gradeCounters は「小3」のような略記の直後に続くと学年ではなくなる助数詞など
+var gradeCounters = []string{
+	"0", "1", "2", "3", "4", "5", "6", "7", "8", "9",
+	"時", "回", "名", "人", "個", "枚", "日", "分", "秒", "点", "位", "番", "倍", "割", "本", "台", "件", "歳", "才", "円", "万", "千", "百", "十", "階", "度", "号", "週", "ヶ", "ケ", "か月", "カ月",
+}
+
+// stageBoundaryBefore は「小3」のような略記の直前が語の区切り（文頭・空白・記号、または「新小1」の「新」）かを返す
+// 「最大3名」「期間中1回」「最高3回」のように漢字・かなの続きの場合は学年として扱わない
+func stageBoundaryBefore(before string) bool {
+	r, _ := utf8.DecodeLastRuneInString(before)
+	if r == utf8.RuneError {
+		return true
+	}
+	if r == '新' {
+		return stageBoundaryBefore(strings.TrimSuffix(before, "新"))
+	}
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// gradeBoundaryBefore は段階のない「N年」の直前が年号・数字の続きでないかを返す
+func gradeBoundaryBefore(before string) bool {
+	r, _ := utf8.DecodeLastRuneInString(strings.TrimRight(before, " 　"))
+	if r == utf8.RuneError {
+		return true
+	}
+	return !(r >= '0' && r <= '9') && r != '和' && r != '成' && r != '元'
+}
+
+func preschoolGrades(preschoolClasses map[int]config.PreschoolClass) []int {
+	grades := []int{gradeNenshou, gradeNenshou + 1, gradeNenchou}
+	for _, grade := range sortedGradeKeys(preschoolClasses) {
+		if grade < gradeNenshou || grade > gradeNenchou {
+			grades = append(grades, grade)
+		}
+	}
+	sort.Ints(grades)
+	return grades
+}
+
+func sortedGradeKeys(m map[int]config.PreschoolClass) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func hasAnyPrefix(s string, prefixes ...string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnySuffix(s string, suffixes ...string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+)
+
+func TestParseGradeMentions(t *testing.T) {
+	preschool := map[int]config.PreschoolClass{-1: {Name: "きりん組"}, -2: {Name: "うさぎ組"}}
+
+	tests := []struct {
+		text string
+		want []int
+	}{
+		{"小2", []int{2}},
+		{"小学3年生", []int{3}},
+		{"小学校三年", []int{3}},
+		{"２年生", []int{2}},
+		{"4年2組", []int{4}},
+		{"3〜6年", []int{3, 4, 5, 6}},
+		{"3・4年生", []int{3, 4}},
+		{"5・6年生保護者", []int{5, 6}},
+		{"全学年", []int{1, 2, 3, 4, 5, 6}},
+		{"低学年", []int{1, 2}},
+		{"高学年", []int{5, 6}},
+		{"中学年", []int{3, 4}},
+		{"中学2年", []int{8}},
+		{"中2", []int{8}},
+		{"中学校 全学年", []int{7, 8, 9}},
+		{"○○中学校 1年生", []int{7}},
+		{"高1", []int{10}},
+		{"高校3年生", []int{12}},
+		{"中学校の2年生", []int{8}},
+		{"中学校の5年生", []int{5}},
+		{"大学2年", []int{14}},
+		// 離れた位置の段階は使わない
+		{"6年生 中学校入学説明会のお知らせ", []int{6}},
+		{"3年生 高校見学会", []int{3}},
+		{"○○大学附属小学校 3年生", []int{3}},
+		{"○○大学附属小学校", nil},
+		{"年少", []int{-3}},
+		{"年中", []int{-2}},
+		{"年長さん", []int{-1}},
+		{"きりん組", []int{-1}},
+		{"全園児", []int{-3, -2, -1}},
+		// 学年ではない「N年」
+		{"令和6年4月1日", nil},
+		{"2025年", nil},
+		{"3年後", nil},
+		{"令和5〜6年", nil},
+		{"年中行事", nil},
+		{"中6", nil},
+		// 語の途中の「大3」「中1」「高3」「小1」
+		{"定員は最大3名です", nil},
+		{"期間中1回まで", nil},
+		{"最高3回", nil},
+		{"小一時間ほどかかります", nil},
+		{"対象：小3〜", []int{3}},
+		{"太郎（中1）", []int{7}},
+		{"新小1保護者会", []int{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			var got []int
+			for _, m := range ParseGradeMentions(tt.text, preschool) {
+				got = append(got, m.Grades...)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ParseGradeMentions(%q) grades = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveGradeMentions(t *testing.T) {
+	household := newTestHousehold(t)
+	household.Current().Grades.ChildrenBaseGrades["次郎"] = 9 // 2024年度に中3
+	household.Current().ChildAliases["次郎"] = []string{"次郎"}
+	gm := NewGradeManager(household)
+
+	// 2025年度: 太郎=小4、花子=きりん組（年長）、次郎=高1
+	mentions := gm.ResolveGradeMentions("小4・年長・高1の保護者様", 2025)
+	got := map[string][]string{}
+	for _, m := range mentions {
+		got[m.Text] = m.Children
+	}
+	want := map[string][]string{"小4": {"太郎"}, "年長": {"花子"}, "高1": {"次郎"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ResolveGradeMentions() = %+v", mentions)
+	}
+
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"全学年", []string{"太郎"}},
+		{"3〜6年生", []string{"太郎"}},
+		{"1・2年生", nil},
+		{"きりん組", []string{"花子"}},
+		{"高校1年", []string{"次郎"}},
+		{"小4 きりん組", []string{"太郎", "花子"}},
+	}
+	for _, tt := range tests {
+		if got := gm.IdentifyChildren(tt.text, 2025); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("IdentifyChildren(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestGetGradeInfo(t *testing.T) {
+	household := newTestHousehold(t)
+	gm := NewGradeManager(household)
+
+	tests := []struct {
+		grade int
+		label string
+		emoji string
+	}{
+		{-3, "ひよこ組", "🐥"},
+		{1, "小1", ""},
+		{8, "中2", ""},
+		{12, "高3", ""},
+		{13, "大1", ""},
+		{17, "", ""},
+	}
+	for _, tt := range tests {
+		label, emoji := gm.GetGradeInfo(tt.grade)
+		if label != tt.label || emoji != tt.emoji {
+			t.Errorf("GetGradeInfo(%d) = %q, %q, want %q, %q", tt.grade, label, emoji, tt.label, tt.emoji)
+		}
+	}
+
+	household.Current().Grades.PreschoolClasses = nil
+	if label, _ := gm.GetGradeInfo(-2); label != "年中" {
+		t.Errorf("GetGradeInfo(-2) without class names = %q, want 年中", label)
+	}
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// instanceID はこのプロセスが書き込むファイルの識別子
+// Cloud Run ではリビジョン名＋インスタンスIDにする（インスタンスの起動ごとにファイルが増えるため、古いものは compactInstanceLogs でまとめる）
+var instanceID = newInstanceID()
+
+// instanceLogCompactAfter はこの時間更新のない他インスタンスのファイルを分割前のファイルにまとめる
+// Cloud Run はアイドルのインスタンスをこれより早く停止するため、まとめた後に追記されることはない
+const instanceLogCompactAfter = 24 * time.Hour
+
+func newInstanceID() string {
+	id := cloudRunInstanceID()
+	if id == "" {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			id = fmt.Sprintf("pid%d", os.Getpid())
+		} else {
+			id = hex.EncodeToString(b)
+		}
+	}
+	if rev := os.Getenv("K_REVISION"); rev != "" {
+		id = rev + "-" + id
+	}
+	// ファイル名の区切り（.）やパスに使えない文字を除く
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' {
+			return r
+		}
+		return '-'
+	}, id)
+}
+
+// cloudRunInstanceID はメタデータサーバーからインスタンスIDを取得し、短くして返す（Cloud Run以外では空）
+func cloudRunInstanceID() string {
+	if os.Getenv("K_SERVICE") == "" {
+		return ""
+	}
+	req, err := http.NewRequest(http.MethodGet, "http://metadata.google.internal/computeMetadata/v1/instance/id", nil)
+	if err != nil {
+		return ""
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := (&http.Client{Timeout: 2 * time.Second}).Do(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK || len(body) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:6])
+}
+
+// instanceLog はインスタンスごとに分けたJSON Lines（<name>.<instanceID>.jsonl）
+// Cloud Run の複数インスタンスが GCS FUSE 上の同じファイルに追記すると、後から閉じた側の内容で上書きされて記録が失われる
+// そのため書き込みは自インスタンスのファイルだけに行い、読み込みは分割前の <name>.jsonl を含むすべてのファイルをまとめる
+type instanceLog struct {
+	dir      string
+	name     string
+	instance string
+}
+
+func newInstanceLog(dir, name string) instanceLog {
+	return instanceLog{dir: dir, name: name, instance: instanceID}
+}
+
+// paths は読み込むファイルを返す（分割前のファイル → インスタンスごとのファイルの順）
+func (l instanceLog) paths() ([]string, error) {
+	shards, err := filepath.Glob(filepath.Join(l.dir, l.name+".*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(shards)
+	var paths []string
+	if _, err := os.Stat(filepath.Join(l.dir, l.name+".jsonl")); err == nil {
+		paths = append(paths, filepath.Join(l.dir, l.name+".jsonl"))
+	}
+	return append(paths, shards...), nil
+}
+
+// append は1件を自インスタンスのファイルに追記する
+func (l instanceLog) append(v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(l.dir, l.name+"."+l.instance+".jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	// GCS FUSE では Close でアップロードされるため、エラーを返す
+	return f.Close()
+}
+
+// scan はすべてのファイルの行を順に fn に渡す（fn が false を返すと中断する）
+func (l instanceLog) scan(fn func(line []byte) bool) error {
+	paths, err := l.paths()
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		done, err := scanLines(path, fn)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+	return nil
+}
+
+func scanLines(path string, fn func(line []byte) bool) (bool, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if !fn(scanner.Bytes()) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// compactInstanceLogs は dir 内の他インスタンスのファイルのうち、しばらく更新のないものを分割前の <name>.jsonl にまとめる
+// 起動時に呼び、読み込みのたびに開くファイルが増え続けないようにする（失敗してもファイルが残るだけで記録は失わない）
+func compactInstanceLogs(dir, instance string, now time.Time) {
+	shards, err := filepath.Glob(filepath.Join(dir, "*.*.jsonl"))
+	if err != nil {
+		log.Printf("Warning: 記録ファイルの一覧取得失敗 (%s): %v", dir, err)
+		return
+	}
+	byName := map[string][]string{}
+	for _, path := range shards {
+		parts := strings.Split(filepath.Base(path), ".")
+		if len(parts) != 3 || parts[1] == instance {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || now.Sub(info.ModTime()) < instanceLogCompactAfter {
+			continue
+		}
+		byName[parts[0]] = append(byName[parts[0]], path)
+	}
+	for name, paths := range byName {
+		l := instanceLog{dir: dir, name: name, instance: instance}
+		if err := l.compact(paths); err != nil {
+			log.Printf("Warning: 記録ファイルの圧縮失敗 (%s/%s): %v", dir, name, err)
+		}
+	}
+}
+
+// compact は shards の内容を分割前のファイルに追記してから shards を削除する
+// 同時に圧縮しないようロックファイルを使い、中断後にやり直しても同じ行が重複しないようにする
+func (l instanceLog) compact(shards []string) error {
+	lockPath := filepath.Join(l.dir, l.name+".compact.lock")
+	lock, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			// 他のインスタンスが圧縮中（異常終了で残ったロックは時間が経てば取り除く）
+			if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > instanceLogCompactAfter {
+				os.Remove(lockPath)
+			}
+			return nil
+		}
+		return err
+	}
+	lock.Close()
+	defer os.Remove(lockPath)
+
+	basePath := filepath.Join(l.dir, l.name+".jsonl")
+	var merged bytes.Buffer
+	seen := map[string]bool{}
+	for _, path := range append([]string{basePath}, shards...) {
+		if _, err := scanLines(path, func(line []byte) bool {
+			if len(line) > 0 && !seen[string(line)] {
+				seen[string(line)] = true
+				merged.Write(line)
+				merged.WriteByte('\n')
+			}
+			return true
+		}); err != nil {
+			return err
+		}
+	}
+
+	tmpPath := basePath + ".tmp"
+	if err := os.WriteFile(tmpPath, merged.Bytes(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, basePath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	for _, path := range shards {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
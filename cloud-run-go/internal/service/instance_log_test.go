@@ -0,0 +1,65 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewInstanceID(t *testing.T) {
+	t.Setenv("K_SERVICE", "")
+	t.Setenv("K_REVISION", "homedocmanager-go-00012-abc")
+
+	id := newInstanceID()
+	if !strings.HasPrefix(id, "homedocmanager-go-00012-abc-") || strings.ContainsAny(id, "./") {
+		t.Fatalf("newInstanceID() = %q", id)
+	}
+}
+
+func TestCompactInstanceLogs(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	write := func(name, content string, age time.Duration) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("finance.jsonl", "{\"id\":1}\n", 72*time.Hour)
+	write("finance.old.jsonl", "{\"id\":2}\n{\"id\":3}\n", 48*time.Hour)
+	write("finance.recent.jsonl", "{\"id\":4}\n", time.Hour)
+	write("finance.self.jsonl", "{\"id\":5}\n", 48*time.Hour)
+	// 前回の圧縮が削除前に中断し、まとめた行が残っている
+	write("finance.crashed.jsonl", "{\"id\":1}\n{\"id\":6}\n", 48*time.Hour)
+
+	compactInstanceLogs(dir, "self", now)
+
+	for name, want := range map[string]bool{
+		"finance.old.jsonl":     false,
+		"finance.crashed.jsonl": false,
+		"finance.recent.jsonl":  true, // 他のインスタンスが書き込み中の可能性がある
+		"finance.self.jsonl":    true, // 自インスタンスのファイル
+	} {
+		if _, err := os.Stat(filepath.Join(dir, name)); (err == nil) != want {
+			t.Errorf("%s exists = %v, want %v", name, err == nil, want)
+		}
+	}
+
+	var lines []string
+	if err := (instanceLog{dir: dir, name: "finance", instance: "self"}).scan(func(line []byte) bool {
+		lines = append(lines, string(line))
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`{"id":1}`, `{"id":6}`, `{"id":2}`, `{"id":3}`, `{"id":4}`, `{"id":5}`}
+	if strings.Join(lines, " ") != strings.Join(want, " ") {
+		t.Fatalf("lines = %v, want %v", lines, want)
+	}
+}
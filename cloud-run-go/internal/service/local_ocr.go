@@ -0,0 +1,129 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// LocalOCR はtesseract CLIによるローカルOCR
+// Gemini が使えない間も仮の分類とNotebookLM同期ができるよう、OCRBundle を埋める
+type LocalOCR struct {
+	command      string
+	languages    string
+	pdfProcessor *PDFProcessor
+}
+
+// NewLocalOCR は新しいLocalOCRを作成（tesseract が見つからない場合はエラー）
+func NewLocalOCR(cfg config.LocalOCRConfig, pdfProcessor *PDFProcessor) (*LocalOCR, error) {
+	path, err := exec.LookPath(cfg.Command)
+	if err != nil {
+		return nil, fmt.Errorf("tesseract not found: %w", err)
+	}
+	return &LocalOCR{command: path, languages: cfg.Languages, pdfProcessor: pdfProcessor}, nil
+}
+
+// ExtractOCRBundle はドキュメントのテキストを読み取る（PDFはページごとの画像に変換してから読み取る）
+func (o *LocalOCR) ExtractOCRBundle(ctx context.Context, data []byte, mimeType string) (*model.OCRBundle, error) {
+	pages := [][]byte{data}
+	if o.pdfProcessor.IsPDF(mimeType) {
+		images, err := o.pdfProcessor.ConvertPDFToImages(data, config.DPI.Internal)
+		if err != nil {
+			return nil, fmt.Errorf("PDF画像変換失敗: %w", err)
+		}
+		pages = images
+	}
+
+	texts := make([]string, 0, len(pages))
+	for i, page := range pages {
+		text, err := o.recognize(ctx, page)
+		if err != nil {
+			return nil, fmt.Errorf("tesseract失敗 (page %d): %w", i+1, err)
+		}
+		texts = append(texts, text)
+	}
+	text := strings.TrimSpace(strings.Join(texts, "\n\n"))
+	log.Printf("ローカルOCR完了: %dページ, %d文字", len(pages), len([]rune(text)))
+
+	bundle := &model.OCRBundle{
+		OCRText:         text,
+		Facts:           extractFacts(text),
+		Summary:         summarizeText(text),
+		ConfidenceScore: config.LocalOCR.Confidence,
+	}
+	bundle.Quality.Uncertain = true
+	bundle.Quality.Notes = "ローカルOCR（tesseract）による仮の読み取り"
+	return bundle, nil
+}
+
+// SetLocalOCR はローカルOCRを設定（未設定時はAIの解析に失敗したファイルは再試行・デッドレターとなる）
+func (fs *FileSorter) SetLocalOCR(ocr *LocalOCR) {
+	fs.localOCR = ocr
+}
+
+// analyzeLocally はローカルOCRとキーワードルールで仮分類する
+// 予定・タスクはAIなしでは抽出できないため空とし、OCR結果はNotebookLM同期に使う
+func (fs *FileSorter) analyzeLocally(ctx context.Context, data []byte, fileInfo *model.FileInfo) (*model.AnalysisResult, *model.DocumentBundle, error) {
+	bundle, err := fs.localOCR.ExtractOCRBundle(ctx, data, fileInfo.MimeType)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ローカルOCR失敗: %w", err)
+	}
+
+	household := fs.household.Current()
+	result := ClassifyByRules(bundle.OCRText, household, household.FallbackRules, time.Now())
+	if result.Summary == "" {
+		result.Summary = strings.TrimSuffix(fileInfo.Name, filepath.Ext(fileInfo.Name))
+	}
+	fs.logAnalysis(result)
+
+	return result, &model.DocumentBundle{
+		Analysis:       result,
+		EventsAndTasks: &model.EventsAndTasks{Events: []model.Event{}, Tasks: []model.Task{}},
+		OCRBundle:      bundle,
+		Warnings:       []string{"AIが使えないためローカルOCRで仮分類"},
+	}, nil
+}
+
+// recognize は1枚の画像を標準入力から読み取る
+func (o *LocalOCR) recognize(ctx context.Context, image []byte) (string, error) {
+	cmd := exec.CommandContext(ctx, o.command, "stdin", "stdout", "-l", o.languages)
+	cmd.Stdin = bytes.NewReader(image)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%v, stderr: %s", err, stderr.String())
+	}
+	return normalizeOCRText(stdout.String()), nil
+}
+
+// normalizeOCRText はtesseractの出力を整える
+// 日本語は文字間に空白が入りやすいため、全角文字に挟まれた空白を取り除く
+func normalizeOCRText(text string) string {
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		runes := []rune(strings.TrimSpace(line))
+		var b strings.Builder
+		for i, r := range runes {
+			if r == ' ' && i > 0 && i+1 < len(runes) && isWideRune(runes[i-1]) && isWideRune(runes[i+1]) {
+				continue
+			}
+			b.WriteRune(r)
+		}
+		out = append(out, b.String())
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+func isWideRune(r rune) bool {
+	return r >= 0x3000
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// MedicalExpenseLedger は医療費控除の集計台帳
+type MedicalExpenseLedger interface {
+	// Record は支払いを記録する（同じファイルを再度記録した場合は置き換える）
+	Record(ctx context.Context, expense *model.MedicalExpense) error
+	// List はその年（1月〜12月）に支払った医療費を支払日順に返す
+	List(ctx context.Context, year int) ([]*model.MedicalExpense, error)
+}
+
+// FileMedicalExpenseLedger は年ごと・インスタンスごとのJSON Lines（medical-expenses-YYYY.<instance>.jsonl）に追記する実装
+// 再処理で同じファイルが複数回記録された場合は、読み込み時に記録日時が新しいものを採用する
+// （支払日の年が変わった場合も、すべての年の記録から最新のものを選ぶため前の年には残らない）
+type FileMedicalExpenseLedger struct {
+	dir      string
+	instance string
+	mu       sync.Mutex
+}
+
+// NewFileMedicalExpenseLedger は新しいFileMedicalExpenseLedgerを作成
+func NewFileMedicalExpenseLedger(dir string) (*FileMedicalExpenseLedger, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create medical expense dir: %w", err)
+	}
+	compactInstanceLogs(dir, instanceID, time.Now())
+	return &FileMedicalExpenseLedger{dir: dir, instance: instanceID}, nil
+}
+
+func (l *FileMedicalExpenseLedger) log(year int) instanceLog {
+	return instanceLog{dir: l.dir, name: fmt.Sprintf("medical-expenses-%d", year), instance: l.instance}
+}
+
+// Record は支払日の年のファイルに追記する
+func (l *FileMedicalExpenseLedger) Record(ctx context.Context, expense *model.MedicalExpense) error {
+	date, err := time.Parse("2006-01-02", expense.Date)
+	if err != nil {
+		return fmt.Errorf("invalid medical expense date %q: %w", expense.Date, err)
+	}
+	if expense.RecordedAt.IsZero() {
+		expense.RecordedAt = time.Now()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.log(date.Year()).append(expense); err != nil {
+		return fmt.Errorf("failed to write medical expense: %w", err)
+	}
+	return nil
+}
+
+// List はその年に支払った記録を読み込む
+// ファイルごとの最新の記録をすべての年から選んでから、支払日の年で絞り込む
+func (l *FileMedicalExpenseLedger) List(ctx context.Context, year int) ([]*model.MedicalExpense, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	years, err := l.years()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list medical expense ledgers: %w", err)
+	}
+	byFile := map[string]*model.MedicalExpense{}
+	for _, y := range years {
+		err := l.log(y).scan(func(line []byte) bool {
+			var e model.MedicalExpense
+			if err := json.Unmarshal(line, &e); err != nil {
+				// 書き込み途中の行などは読み飛ばす
+				return true
+			}
+			if prev, ok := byFile[e.FileID]; !ok || !e.RecordedAt.Before(prev.RecordedAt) {
+				byFile[e.FileID] = &e
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read medical expense ledger: %w", err)
+		}
+	}
+
+	prefix := fmt.Sprintf("%04d-", year)
+	expenses := make([]*model.MedicalExpense, 0, len(byFile))
+	for _, e := range byFile {
+		if strings.HasPrefix(e.Date, prefix) {
+			expenses = append(expenses, e)
+		}
+	}
+	sort.Slice(expenses, func(i, j int) bool {
+		if expenses[i].Date != expenses[j].Date {
+			return expenses[i].Date < expenses[j].Date
+		}
+		return expenses[i].FileID < expenses[j].FileID
+	})
+	return expenses, nil
+}
+
+// years は記録のある年を昇順で返す
+func (l *FileMedicalExpenseLedger) years() ([]int, error) {
+	paths, err := filepath.Glob(filepath.Join(l.dir, "medical-expenses-*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	seen := map[int]bool{}
+	var years []int
+	for _, path := range paths {
+		name := strings.TrimPrefix(filepath.Base(path), "medical-expenses-")
+		year, err := strconv.Atoi(name[:min(4, len(name))])
+		if err != nil || seen[year] {
+			continue
+		}
+		seen[year] = true
+		years = append(years, year)
+	}
+	sort.Ints(years)
+	return years, nil
+}
+
+// SummarizeMedicalExpenses は1年分の医療費を集計する
+// 控除額は足切り額（config.MedicalExpenses.DeductionThreshold）で計算した目安で、所得による5%基準は考慮しない
+func SummarizeMedicalExpenses(year int, expenses []*model.MedicalExpense) *model.MedicalExpenseSummary {
+	summary := &model.MedicalExpenseSummary{
+		Year:      year,
+		Count:     len(expenses),
+		ByPatient: map[string]int{},
+		Expenses:  expenses,
+	}
+	for _, e := range expenses {
+		summary.TotalAmount += e.Amount
+		summary.TotalReimbursement += cappedReimbursement(e)
+		summary.ByPatient[e.Patient] += e.Amount
+	}
+	summary.NetAmount = summary.TotalAmount - summary.TotalReimbursement
+	deduction := summary.NetAmount - config.MedicalExpenses.DeductionThreshold
+	summary.DeductionEstimate = max(0, min(deduction, config.MedicalExpenses.DeductionLimit))
+	return summary
+}
+
+// medicalExpenseCSVHeader は国税庁「医療費集計フォーム」の列（この順で貼り付けられる）
+var medicalExpenseCSVHeader = []string{
+	"医療を受けた人",
+	"病院・薬局などの支払先の名称",
+	model.MedicalExpenseKindTreatment,
+	model.MedicalExpenseKindMedicine,
+	model.MedicalExpenseKindCare,
+	model.MedicalExpenseKindOther,
+	"支払った医療費の金額",
+	"左のうち、補填される金額",
+	"支払年月日",
+}
+
+// WriteMedicalExpenseCSV は医療費集計フォームと同じ列のCSVを書き出す
+// Excelで文字化けしないようUTF-8のBOMを付け、区分の列には「該当する」を入れる
+func WriteMedicalExpenseCSV(w io.Writer, expenses []*model.MedicalExpense) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.UseCRLF = true
+	if err := cw.Write(medicalExpenseCSVHeader); err != nil {
+		return err
+	}
+	for _, e := range expenses {
+		if err := cw.Write(medicalExpenseRow(e)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// medicalExpenseRow は医療費集計フォームの1行（medicalExpenseCSVHeader の順）を返す
+func medicalExpenseRow(e *model.MedicalExpense) []string {
+	row := []string{e.Patient, e.Provider, "", "", "", "", strconv.Itoa(e.Amount), strconv.Itoa(cappedReimbursement(e)), strings.ReplaceAll(e.Date, "-", "/")}
+	for i, kind := range medicalExpenseCSVHeader[2:6] {
+		if e.Kind == kind {
+			row[2+i] = "該当する"
+		}
+	}
+	return row
+}
+
+// cappedReimbursement は補填額を返す（医療費集計フォームと同じく、その支払いの金額を上限とする）
+func cappedReimbursement(e *model.MedicalExpense) int {
+	return max(0, min(e.Reimbursement, e.Amount))
+}
+
+// InFilingSeason は確定申告の時期（config.MedicalExpenses.FilingSeasonStart〜End）かを返す
+func InFilingSeason(now time.Time) bool {
+	today := now.Format("01-02")
+	return config.MedicalExpenses.FilingSeasonStart <= today && today <= config.MedicalExpenses.FilingSeasonEnd
+}
+
+// SetMedicalExpenseLedger は医療費の集計台帳を設定（未設定時は医療費を記録しない）
+func (fs *FileSorter) SetMedicalExpenseLedger(ledger MedicalExpenseLedger) {
+	fs.medicalExpenses = ledger
+}
+
+// stepMedicalExpense は医療費の領収書から支払いを読み取り、医療費控除の集計台帳に記録する
+func (fs *FileSorter) stepMedicalExpense(ctx context.Context, r *pipelineRun) (string, error) {
+	if !isMedicalReceipt(r.analysis) {
+		return "", skipStep("医療費の領収書以外")
+	}
+	if fs.medicalExpenses == nil {
+		return "", skipStep("医療費台帳未初期化")
+	}
+	if r.entry.Provisional {
+		return "", skipStep("AI未使用の仮分類")
+	}
+
+	data, err := fs.fileData(ctx, r)
+	if err != nil {
+		return "", err
+	}
+	expense, err := fs.aiRouter.ExtractMedicalExpense(ctx, data, r.fileInfo.MimeType, r.fileInfo.Name)
+	if err != nil {
+		return "", fmt.Errorf("医療費読み取り失敗: %w", err)
+	}
+	if expense.Amount <= 0 {
+		return "", skipStep("金額を読み取れない")
+	}
+
+	expense.FileID = r.fileInfo.ID
+	expense.FileName = r.newFileName
+	expense.Reimbursement = cappedReimbursement(expense)
+	expense.Patient = fs.medicalPatient(expense.Patient, r.analysis)
+	if err := fs.medicalExpenses.Record(ctx, expense); err != nil {
+		return "", fmt.Errorf("医療費台帳への記録失敗: %w", err)
+	}
+	log.Printf("医療費を記録: %s %s %s %d円", expense.Date, expense.Patient, expense.Provider, expense.Amount)
+	return fmt.Sprintf("%s %d円", expense.Patient, expense.Amount), nil
+}
+
+// isMedicalReceipt は医療費の領収書かを判定（サブカテゴリ未設定は領収書として扱う）
+func isMedicalReceipt(result *model.AnalysisResult) bool {
+	return result.Category == "60_ヘルス・医療" &&
+		(result.SubCategory == "" || result.SubCategory == "01_領収書・明細")
+}
+
+// medicalPatient は領収書の氏名を名寄せし、わからなければ分類結果の受診者を使う
+func (fs *FileSorter) medicalPatient(name string, result *model.AnalysisResult) string {
+	household := fs.household.Current()
+	if p := matchAlias(name, household.AdultAliases); p != "" {
+		return p
+	}
+	if p := matchAlias(name, household.ChildAliases); p != "" {
+		return p
+	}
+	if person := fs.placeholderValue(result, "person", ""); person != "" {
+		return person
+	}
+	return name
+}
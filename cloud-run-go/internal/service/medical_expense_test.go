@@ -0,0 +1,360 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+func TestFileMedicalExpenseLedger_ListKeepsLatestRecord(t *testing.T) {
+	ctx := context.Background()
+	ledger, err := NewFileMedicalExpenseLedger(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := []*model.MedicalExpense{
+		{FileID: "b", Date: "2025-03-10", Patient: "太郎", Amount: 1200},
+		{FileID: "a", Date: "2025-01-05", Patient: "父", Amount: 3000},
+		{FileID: "b", Date: "2025-03-10", Patient: "太郎", Amount: 1500}, // 再処理で金額を訂正
+		{FileID: "c", Date: "2024-12-28", Patient: "母", Amount: 800},
+	}
+	for _, r := range records {
+		if err := ledger.Record(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ledger.Record(ctx, &model.MedicalExpense{FileID: "d", Date: "2025/04/01"}); err == nil {
+		t.Fatal("expected error for invalid date")
+	}
+
+	got, err := ledger.List(ctx, 2025)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].FileID != "a" || got[1].FileID != "b" || got[1].Amount != 1500 {
+		t.Fatalf("List(2025) = %+v", got)
+	}
+
+	empty, err := ledger.List(ctx, 2023)
+	if err != nil || len(empty) != 0 {
+		t.Fatalf("List(2023) = %+v, %v", empty, err)
+	}
+}
+
+func TestFileMedicalExpenseLedger_MergesInstances(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	a, err := NewFileMedicalExpenseLedger(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.instance = "a"
+	b, err := NewFileMedicalExpenseLedger(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.instance = "b"
+
+	recorded := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+	// インスタンスbの訂正が新しい（ファイルの読み込み順によらず記録日時で採用する）
+	if err := b.Record(ctx, &model.MedicalExpense{FileID: "x", Date: "2025-04-01", Amount: 1500, RecordedAt: recorded.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Record(ctx, &model.MedicalExpense{FileID: "x", Date: "2025-04-01", Amount: 1200, RecordedAt: recorded}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Record(ctx, &model.MedicalExpense{FileID: "y", Date: "2025-04-02", Amount: 500}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := b.List(ctx, 2025)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].FileID != "x" || got[0].Amount != 1500 || got[1].FileID != "y" {
+		t.Fatalf("List(2025) = %+v", got)
+	}
+}
+
+func TestFileMedicalExpenseLedger_PaymentYearChanged(t *testing.T) {
+	ctx := context.Background()
+	ledger, err := NewFileMedicalExpenseLedger(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorded := time.Date(2025, 1, 10, 9, 0, 0, 0, time.UTC)
+	// 最初の読み取りで年を誤り、再処理で支払日を訂正した
+	if err := ledger.Record(ctx, &model.MedicalExpense{FileID: "x", Date: "2024-12-28", Amount: 1200, RecordedAt: recorded}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.Record(ctx, &model.MedicalExpense{FileID: "x", Date: "2025-01-06", Amount: 1200, RecordedAt: recorded.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	old, err := ledger.List(ctx, 2024)
+	if err != nil || len(old) != 0 {
+		t.Fatalf("List(2024) = %+v, %v (the old row should not remain)", old, err)
+	}
+	got, err := ledger.List(ctx, 2025)
+	if err != nil || len(got) != 1 || got[0].Date != "2025-01-06" {
+		t.Fatalf("List(2025) = %+v, %v", got, err)
+	}
+}
+
+func TestSummarizeMedicalExpenses(t *testing.T) {
+	tests := []struct {
+		name      string
+		expenses  []*model.MedicalExpense
+		wantNet   int
+		wantDeduc int
+	}{
+		{
+			name:     "below threshold",
+			expenses: []*model.MedicalExpense{{Patient: "父", Amount: 50000}},
+			wantNet:  50000,
+		},
+		{
+			name: "reimbursement is subtracted",
+			expenses: []*model.MedicalExpense{
+				{Patient: "父", Amount: 150000, Reimbursement: 20000},
+				{Patient: "太郎", Amount: 30000},
+			},
+			wantNet:   160000,
+			wantDeduc: 60000,
+		},
+		{
+			name: "reimbursement is capped at each payment",
+			expenses: []*model.MedicalExpense{
+				{Patient: "父", Amount: 120000, Reimbursement: 200000},
+				{Patient: "太郎", Amount: 30000},
+			},
+			wantNet: 30000,
+		},
+		{
+			name:      "capped at limit",
+			expenses:  []*model.MedicalExpense{{Patient: "母", Amount: 3000000}},
+			wantNet:   3000000,
+			wantDeduc: 2000000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SummarizeMedicalExpenses(2025, tt.expenses)
+			if got.NetAmount != tt.wantNet || got.DeductionEstimate != tt.wantDeduc || got.Count != len(tt.expenses) {
+				t.Fatalf("SummarizeMedicalExpenses() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestWriteMedicalExpenseCSV(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteMedicalExpenseCSV(&buf, []*model.MedicalExpense{
+		{Patient: "太郎", Provider: "○○小児科", Kind: model.MedicalExpenseKindTreatment, Amount: 1500, Date: "2025-03-10"},
+		{Patient: "母", Provider: "△△薬局", Kind: model.MedicalExpenseKindMedicine, Amount: 2400, Reimbursement: 400, Date: "2025-04-02"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "\ufeff" +
+		"医療を受けた人,病院・薬局などの支払先の名称,診療・治療,医薬品購入,介護保険サービス,その他の医療費,支払った医療費の金額,左のうち、補填される金額,支払年月日\r\n" +
+		"太郎,○○小児科,該当する,,,,1500,0,2025/03/10\r\n" +
+		"母,△△薬局,,該当する,,,2400,400,2025/04/02\r\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("WriteMedicalExpenseCSV() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteMedicalExpenseXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteMedicalExpenseXLSX(&buf, []*model.MedicalExpense{
+		{Patient: "太郎", Provider: "○○小児科 & 内科", Kind: model.MedicalExpenseKindTreatment, Amount: 1500, Date: "2025-03-10"},
+		{Patient: "母", Provider: "△△薬局", Kind: model.MedicalExpenseKindMedicine, Amount: 2400, Reimbursement: 3000, Date: "2025-04-02"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[f.Name] = string(body)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Fatalf("missing part %s", name)
+		}
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Style  string `xml:"s,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal([]byte(parts["xl/worksheets/sheet1.xml"]), &sheet); err != nil {
+		t.Fatal(err)
+	}
+	if len(sheet.Rows) != 3 || len(sheet.Rows[0].Cells) != len(medicalExpenseCSVHeader) {
+		t.Fatalf("unexpected sheet: %+v", sheet)
+	}
+	cells := map[string]string{}
+	for _, row := range sheet.Rows {
+		for _, c := range row.Cells {
+			cells[c.Ref] = c.Value + c.Inline + "|" + c.Style
+		}
+	}
+	want := map[string]string{
+		"A1": "医療を受けた人|",
+		"B2": "○○小児科 & 内科|",
+		"C2": "該当する|",
+		"G2": "1500|",
+		"H2": "0|",
+		"I2": "45726|1", // 2025/3/10
+		"D3": "該当する|",
+		"H3": "2400|", // 補填額は支払額まで
+	}
+	for ref, v := range want {
+		if cells[ref] != v {
+			t.Errorf("%s = %q, want %q", ref, cells[ref], v)
+		}
+	}
+}
+
+func TestInFilingSeason(t *testing.T) {
+	tests := []struct {
+		date string
+		want bool
+	}{
+		{"2026-01-31", false},
+		{"2026-02-01", true},
+		{"2026-03-15", true},
+		{"2026-03-16", false},
+	}
+	for _, tt := range tests {
+		now, _ := time.Parse("2006-01-02", tt.date)
+		if got := InFilingSeason(now); got != tt.want {
+			t.Errorf("InFilingSeason(%s) = %v, want %v", tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestStepMedicalExpense(t *testing.T) {
+	ctx := context.Background()
+	household := newTestHousehold(t)
+	ledger, err := NewFileMedicalExpenseLedger(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		analysis    *model.AnalysisResult
+		provisional bool
+		extracted   *model.MedicalExpense
+		wantSkip    bool
+		wantPatient string
+	}{
+		{
+			name:        "receipt with alias",
+			analysis:    &model.AnalysisResult{Category: "60_ヘルス・医療", SubCategory: "01_領収書・明細", TargetAdult: "父"},
+			extracted:   &model.MedicalExpense{Date: "2025-05-01", Patient: "パパ", Provider: "○○内科", Kind: model.MedicalExpenseKindTreatment, Amount: 2300},
+			wantPatient: "父",
+		},
+		{
+			name:        "patient unreadable falls back to classification",
+			analysis:    &model.AnalysisResult{Category: "60_ヘルス・医療", ChildName: "花子"},
+			extracted:   &model.MedicalExpense{Date: "2025-06-01", Provider: "△△薬局", Kind: model.MedicalExpenseKindMedicine, Amount: 800},
+			wantPatient: "花子",
+		},
+		{
+			name:     "test results are not receipts",
+			analysis: &model.AnalysisResult{Category: "60_ヘルス・医療", SubCategory: "02_検査結果・診断書"},
+			wantSkip: true,
+		},
+		{
+			name:        "provisional",
+			analysis:    &model.AnalysisResult{Category: "60_ヘルス・医療"},
+			provisional: true,
+			wantSkip:    true,
+		},
+		{
+			name:      "no amount",
+			analysis:  &model.AnalysisResult{Category: "60_ヘルス・医療"},
+			extracted: &model.MedicalExpense{Date: "2025-06-01", Kind: model.MedicalExpenseKindOther},
+			wantSkip:  true,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := NewFakeBackend()
+			backend.Medical = tt.extracted
+			fs := &FileSorter{
+				aiRouter:        NewAIRouterWithBackend(backend),
+				household:       household,
+				medicalExpenses: ledger,
+			}
+			fileID := string(rune('a' + i))
+			r := &pipelineRun{
+				fileInfo:    &model.FileInfo{ID: fileID, Name: "scan.pdf", MimeType: "application/pdf"},
+				entry:       &model.LedgerEntry{FileID: fileID, Provisional: tt.provisional},
+				data:        []byte("data"),
+				analysis:    tt.analysis,
+				newFileName: "receipt.pdf",
+			}
+
+			_, err := fs.stepMedicalExpense(ctx, r)
+			var skipped *stepSkipped
+			if tt.wantSkip {
+				if !errors.As(err, &skipped) {
+					t.Fatalf("expected skip, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expenses, err := ledger.List(ctx, 2025)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got *model.MedicalExpense
+			for _, e := range expenses {
+				if e.FileID == fileID {
+					got = e
+				}
+			}
+			if got == nil || got.Patient != tt.wantPatient || got.FileName != "receipt.pdf" || !strings.HasPrefix(got.Date, "2025-") {
+				t.Fatalf("recorded = %+v", got)
+			}
+		})
+	}
+}
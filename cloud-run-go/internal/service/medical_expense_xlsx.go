@@ -0,0 +1,117 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// xlsxの固定部分（1シート・日付の書式のみの最小構成）
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`</Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="医療費集計" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`
+	// セルの書式は 0: 標準、1: 日付（yyyy/m/d）
+	xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy/m/d"/></numFmts>` +
+		`<fonts count="1"><font><sz val="11"/><name val="Yu Gothic"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs>` +
+		`</styleSheet>`
+)
+
+// xlsxEpoch はExcelの日付のシリアル値の起点
+var xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// WriteMedicalExpenseXLSX は医療費集計フォームと同じ列のExcelファイル（.xlsx）を書き出す
+// 金額は数値、支払年月日は日付のセルにするため、国税庁の医療費集計フォームにそのまま貼り付けてe-Taxに読み込める
+func WriteMedicalExpenseXLSX(w io.Writer, expenses []*model.MedicalExpense) error {
+	zw := zip.NewWriter(w)
+	parts := []struct {
+		name string
+		body []byte
+	}{
+		{"[Content_Types].xml", []byte(xlsxContentTypes)},
+		{"_rels/.rels", []byte(xlsxRootRels)},
+		{"xl/workbook.xml", []byte(xlsxWorkbook)},
+		{"xl/_rels/workbook.xml.rels", []byte(xlsxWorkbookRels)},
+		{"xl/styles.xml", []byte(xlsxStyles)},
+		{"xl/worksheets/sheet1.xml", medicalExpenseSheet(expenses)},
+	}
+	for _, part := range parts {
+		pw, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := pw.Write(part.body); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// medicalExpenseSheet は見出しと明細のシートを作る（金額の列は数値、支払年月日の列は日付）
+func medicalExpenseSheet(expenses []*model.MedicalExpense) []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	writeRow := func(r int, cells []string, typed bool) {
+		fmt.Fprintf(&b, `<row r="%d">`, r)
+		for i, value := range cells {
+			ref := fmt.Sprintf("%c%d", 'A'+i, r)
+			switch {
+			case value == "":
+				continue
+			case typed && (i == 6 || i == 7):
+				fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, value)
+			case typed && i == 8:
+				if date, err := time.Parse("2006/01/02", value); err == nil {
+					fmt.Fprintf(&b, `<c r="%s" s="1"><v>%s</v></c>`, ref, strconv.Itoa(int(date.Sub(xlsxEpoch).Hours()/24)))
+					continue
+				}
+				fallthrough
+			default:
+				fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t>`, ref)
+				xml.EscapeText(&b, []byte(value))
+				b.WriteString(`</t></is></c>`)
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+
+	writeRow(1, medicalExpenseCSVHeader, false)
+	for i, e := range expenses {
+		writeRow(i+2, medicalExpenseRow(e), true)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.Bytes()
+}
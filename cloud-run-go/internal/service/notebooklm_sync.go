@@ -17,15 +17,17 @@ import (
 // NotebookLMSync はNotebookLM同期サービス
 type NotebookLMSync struct {
 	driveClient *DriveClient
+	household   *config.HouseholdStore
 	mu          sync.Mutex
 }
 
 const processedMarker = "notebooklm_synced"
 
 // NewNotebookLMSync は新しいNotebookLMSyncを作成
-func NewNotebookLMSync(ctx context.Context, driveClient *DriveClient) (*NotebookLMSync, error) {
+func NewNotebookLMSync(ctx context.Context, driveClient *DriveClient, household *config.HouseholdStore) (*NotebookLMSync, error) {
 	return &NotebookLMSync{
 		driveClient: driveClient,
+		household:   household,
 	}, nil
 }
 
@@ -35,6 +37,10 @@ func (ns *NotebookLMSync) ShouldSync(category, subCategory string) bool {
 	if config.NotebookLMSyncExcludeCategories[category] {
 		return false
 	}
+	// 医療書類をRAGソースに含めない設定か
+	if config.Medical.ExcludeFromRAG && category == "60_ヘルス・医療" {
+		return false
+	}
 	// サブカテゴリが除外されているか
 	if excludedSubs, ok := config.NotebookLMSyncExcludeSubCategories[category]; ok {
 		for _, sub := range excludedSubs {
@@ -78,6 +84,17 @@ func (ns *NotebookLMSync) SyncFile(ctx context.Context, fileID, fileName, notebo
 	return nil
 }
 
+// PreviewEntry は同期先ドキュメント名と追記されるテキストを返す（Driveには書き込まない）
+func (ns *NotebookLMSync) PreviewEntry(fileID, fileName, notebookCategory, ocrText string, facts []string, summary, dateStr string, fiscalYear int) (string, string) {
+	entryText := ns.formatEntry(formatDateForNotebook(dateStr), fileName, fileID, ocrText, facts, summary, notebookCategory)
+	return accumulatedDocName(fiscalYear, notebookCategory), entryText
+}
+
+// accumulatedDocName は年度別・カテゴリ別統合ドキュメントの名前
+func accumulatedDocName(fiscalYear int, notebookCategory string) string {
+	return fmt.Sprintf("%d年度_%s", fiscalYear, notebookCategory)
+}
+
 // formatDateForNotebook はYYYYMMDD形式をYYYY/MM/DD形式に変換
 func formatDateForNotebook(dateStr string) string {
 	if len(dateStr) != 8 {
@@ -124,12 +141,12 @@ func (ns *NotebookLMSync) formatEntry(formattedDate, fileName, fileID, ocrText s
 
 // getOrCreateAccumulatedDoc は年度別・カテゴリ別統合ドキュメントを取得または作成
 func (ns *NotebookLMSync) getOrCreateAccumulatedDoc(ctx context.Context, fiscalYear int, notebookCategory string) (string, string, error) {
-	syncFolderID := config.FolderIDs["NOTEBOOKLM_SYNC"]
+	syncFolderID := ns.household.Current().FolderIDs["NOTEBOOKLM_SYNC"]
 	if syncFolderID == "" {
 		return "", "", fmt.Errorf("NOTEBOOKLM_SYNCフォルダIDが設定されていません")
 	}
 
-	docName := fmt.Sprintf("%d年度_%s", fiscalYear, notebookCategory)
+	docName := accumulatedDocName(fiscalYear, notebookCategory)
 
 	// 既存のドキュメントを検索
 	docID, mimeType, err := ns.findDocByName(ctx, docName, syncFolderID)
@@ -210,11 +227,11 @@ func (ns *NotebookLMSync) createUnifiedDoc(ctx context.Context, docName, parentI
 	}
 
 	// オーナー権限を転送（設定されている場合）
-	if config.NotebookLMOwnerEmail != "" {
+	if ownerEmail := ns.household.Current().NotebookLMOwnerEmail; ownerEmail != "" {
 		permission := &drive.Permission{
 			Role:         "owner",
 			Type:         "user",
-			EmailAddress: config.NotebookLMOwnerEmail,
+			EmailAddress: ownerEmail,
 		}
 		_, err := ns.driveClient.oauthDriveService.Permissions.Create(docID, permission).
 			TransferOwnership(true).
@@ -223,7 +240,7 @@ func (ns *NotebookLMSync) createUnifiedDoc(ctx context.Context, docName, parentI
 		if err != nil {
 			log.Printf("オーナー権限の転送に失敗しました（容量制限に注意）: %v", err)
 		} else {
-			log.Printf("ファイルのオーナー権限を転送しました: %s", config.NotebookLMOwnerEmail)
+			log.Printf("ファイルのオーナー権限を転送しました: %s", ownerEmail)
 		}
 	}
 
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+)
+
+// OpenAIBackend はOpenAI互換の Chat Completions API を使うバックエンド
+// llama.cpp server や Ollama などのローカルモデルサーバーを想定（画像入力に対応したモデルが必要）
+type OpenAIBackend struct {
+	baseURL      string
+	apiKey       string
+	fastModel    string
+	highModel    string
+	httpClient   *http.Client
+	pdfProcessor *PDFProcessor
+}
+
+// NewOpenAIBackend は新しいOpenAIBackendを作成
+func NewOpenAIBackend(cfg config.AIBackendConfig, pdfProcessor *PDFProcessor) (*OpenAIBackend, error) {
+	if cfg.OpenAIBaseURL == "" {
+		return nil, errors.New("OPENAI_BASE_URL is required")
+	}
+	if cfg.OpenAIModel == "" {
+		return nil, errors.New("OPENAI_MODEL is required")
+	}
+	highModel := cfg.OpenAIHighModel
+	if highModel == "" {
+		highModel = cfg.OpenAIModel
+	}
+	return &OpenAIBackend{
+		baseURL:      strings.TrimRight(cfg.OpenAIBaseURL, "/"),
+		apiKey:       cfg.OpenAIAPIKey,
+		fastModel:    cfg.OpenAIModel,
+		highModel:    highModel,
+		httpClient:   &http.Client{Timeout: cfg.RequestTimeout},
+		pdfProcessor: pdfProcessor,
+	}, nil
+}
+
+// Name はバックエンド名を返す
+func (b *OpenAIBackend) Name() string {
+	return "openai"
+}
+
+// Models は一次解析用・エスカレーション用のモデル名を返す
+func (b *OpenAIBackend) Models() (string, string) {
+	return b.fastModel, b.highModel
+}
+
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+type openAIMessage struct {
+	Role    string              `json:"role"`
+	Content []openAIContentPart `json:"content"`
+}
+
+type openAIChatRequest struct {
+	Model          string               `json:"model"`
+	Messages       []openAIMessage      `json:"messages"`
+	ResponseFormat openAIResponseFormat `json:"response_format"`
+	Temperature    float64              `json:"temperature"`
+}
+
+type openAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+type openAIJSONSchema struct {
+	Name   string         `json:"name"`
+	Schema map[string]any `json:"schema"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// Generate は Chat Completions API を呼び出し、JSONレスポンスを返す
+func (b *OpenAIBackend) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	parts, err := b.imageParts(req.Data, req.MimeType)
+	if err != nil {
+		return nil, err
+	}
+	parts = append(parts, openAIContentPart{Type: "text", Text: req.Prompt})
+
+	body := openAIChatRequest{
+		Model:    req.Model,
+		Messages: []openAIMessage{{Role: "user", Content: parts}},
+	}
+	body.ResponseFormat.Type = "json_object"
+	if req.Schema != nil {
+		body.ResponseFormat = openAIResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &openAIJSONSchema{Name: string(req.Kind), Schema: req.Schema.JSONSchema()},
+		}
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, b.baseURL+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if b.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+b.apiKey)
+	}
+
+	resp, err := b.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, truncate(string(respBody), 500))
+	}
+
+	var chat openAIChatResponse
+	if err := json.Unmarshal(respBody, &chat); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(chat.Choices) == 0 || strings.TrimSpace(chat.Choices[0].Message.Content) == "" {
+		return nil, ErrEmptyResponse
+	}
+
+	return &GenerateResponse{
+		Text:         stripCodeFence(chat.Choices[0].Message.Content),
+		PromptTokens: chat.Usage.PromptTokens,
+		OutputTokens: chat.Usage.CompletionTokens,
+		TotalTokens:  chat.Usage.TotalTokens,
+	}, nil
+}
+
+// imageParts はドキュメントを画像のdata URLに変換する（PDFはページごとの画像に変換）
+func (b *OpenAIBackend) imageParts(data []byte, mimeType string) ([]openAIContentPart, error) {
+	images := [][]byte{data}
+	imageMime := mimeType
+	if b.pdfProcessor.IsPDF(mimeType) {
+		pages, err := b.pdfProcessor.ConvertPDFToImages(data, config.DPI.Internal)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert PDF for %s backend: %w", b.Name(), err)
+		}
+		images = pages
+		imageMime = "image/jpeg"
+	}
+
+	parts := make([]openAIContentPart, 0, len(images)+1)
+	for _, img := range images {
+		parts = append(parts, openAIContentPart{
+			Type:     "image_url",
+			ImageURL: &openAIImageURL{URL: "data:" + imageMime + ";base64," + base64.StdEncoding.EncodeToString(img)},
+		})
+	}
+	return parts, nil
+}
+
+// stripCodeFence はローカルモデルが付けがちな ```json ... ``` を取り除く
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	text = strings.TrimPrefix(text, "```")
+	if i := strings.Index(text, "\n"); i >= 0 {
+		text = text[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+}
+
+// Close は何もしない（解放すべきリソースを持たない）
+func (b *OpenAIBackend) Close() error {
+	return nil
+}
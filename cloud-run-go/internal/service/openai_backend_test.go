@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+)
+
+func TestOpenAIBackend_Generate(t *testing.T) {
+	var got openAIChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("Authorization = %q", auth)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		var resp openAIChatResponse
+		resp.Choices = make([]struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		}, 1)
+		// ローカルモデルがコードブロックで囲んだ場合も取り出せること
+		resp.Choices[0].Message.Content = "```json\n{\"category\":\"30_ライフ・行政\"}\n```"
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	backend, err := NewOpenAIBackend(config.AIBackendConfig{
+		OpenAIBaseURL: server.URL + "/v1/",
+		OpenAIAPIKey:  "secret",
+		OpenAIModel:   "local-vl",
+	}, NewPDFProcessor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fast, high := backend.Models(); fast != "local-vl" || high != "local-vl" {
+		t.Fatalf("Models() = %s, %s", fast, high)
+	}
+
+	resp, err := backend.Generate(context.Background(), GenerateRequest{
+		Kind:     GenerateKindAnalysis,
+		Model:    "local-vl",
+		Data:     []byte("img"),
+		MimeType: "image/png",
+		Prompt:   "classify",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text != `{"category":"30_ライフ・行政"}` {
+		t.Fatalf("Text = %q", resp.Text)
+	}
+
+	if got.Model != "local-vl" || got.ResponseFormat.Type != "json_object" || len(got.Messages) != 1 {
+		t.Fatalf("unexpected request: %+v", got)
+	}
+	parts := got.Messages[0].Content
+	if len(parts) != 2 || parts[0].ImageURL == nil || !strings.HasPrefix(parts[0].ImageURL.URL, "data:image/png;base64,") || parts[1].Text != "classify" {
+		t.Fatalf("unexpected content parts: %+v", parts)
+	}
+}
+
+func TestOpenAIBackend_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not loaded", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	backend, err := NewOpenAIBackend(config.AIBackendConfig{OpenAIBaseURL: server.URL, OpenAIModel: "m"}, NewPDFProcessor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = backend.Generate(context.Background(), GenerateRequest{Model: "m", Data: []byte("img"), MimeType: "image/jpeg"})
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("expected status error, got %v", err)
+	}
+
+	if _, err := NewOpenAIBackend(config.AIBackendConfig{OpenAIBaseURL: server.URL}, NewPDFProcessor()); err == nil {
+		t.Fatal("expected error when model is not configured")
+	}
+}
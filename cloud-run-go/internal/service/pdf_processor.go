@@ -10,6 +10,8 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+
+	"github.com/leo-sagawa/homedocmanager/internal/model"
 )
 
 // PDFProcessor はPDF処理を行うサービス
@@ -83,6 +85,114 @@ func (p *PDFProcessor) ConvertPDFToImages(pdfBytes []byte, dpi int) ([][]byte, e
 	return images, nil
 }
 
+// ExtractText はPDFのテキストレイヤーを抽出（pdftotext を使用。スキャン画像のみのPDFは空になる）
+func (p *PDFProcessor) ExtractText(pdfBytes []byte) (string, error) {
+	cmd := exec.Command("pdftotext", "-enc", "UTF-8", "-", "-")
+	cmd.Stdin = bytes.NewReader(pdfBytes)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("pdftotext failed: %v, stderr: %s", err, stderr.String())
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// PageCount はPDFのページ数を返す（pdfinfo を使用）
+func (p *PDFProcessor) PageCount(pdfBytes []byte) (int, error) {
+	tmpDir, err := os.MkdirTemp("", "pdfinfo-*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	pdfPath := filepath.Join(tmpDir, "input.pdf")
+	if err := os.WriteFile(pdfPath, pdfBytes, 0644); err != nil {
+		return 0, fmt.Errorf("failed to write temp pdf: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("pdfinfo", pdfPath)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return 0, fmt.Errorf("pdfinfo failed: %v, stderr: %s", err, stderr.String())
+	}
+	return parsePdfinfoPages(stdout.String())
+}
+
+// parsePdfinfoPages は pdfinfo の出力から "Pages:" の値を取り出す
+func parsePdfinfoPages(output string) (int, error) {
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.HasPrefix(line, "Pages:") {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "Pages:")))
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid page count: %q", line)
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("page count not found in pdfinfo output")
+}
+
+// SplitPDF はPDFをページ範囲ごとの別々のPDFに分割（pdfseparate / pdfunite を使用）
+func (p *PDFProcessor) SplitPDF(pdfBytes []byte, ranges []model.PageRange) ([][]byte, error) {
+	tmpDir, err := os.MkdirTemp("", "pdfsplit-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	pdfPath := filepath.Join(tmpDir, "input.pdf")
+	if err := os.WriteFile(pdfPath, pdfBytes, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write temp pdf: %w", err)
+	}
+
+	// 1ページずつのPDFに分解してから、範囲ごとに結合する
+	pagePattern := filepath.Join(tmpDir, "page-%d.pdf")
+	if err := runPoppler("pdfseparate", pdfPath, pagePattern); err != nil {
+		return nil, err
+	}
+
+	parts := make([][]byte, 0, len(ranges))
+	for i, r := range ranges {
+		if r.Start < 1 || r.End < r.Start {
+			return nil, fmt.Errorf("invalid page range: %d-%d", r.Start, r.End)
+		}
+		partPath := fmt.Sprintf(pagePattern, r.Start)
+		if r.End > r.Start {
+			args := make([]string, 0, r.End-r.Start+2)
+			for page := r.Start; page <= r.End; page++ {
+				args = append(args, fmt.Sprintf(pagePattern, page))
+			}
+			partPath = filepath.Join(tmpDir, fmt.Sprintf("part-%d.pdf", i+1))
+			if err := runPoppler("pdfunite", append(args, partPath)...); err != nil {
+				return nil, err
+			}
+		}
+		data, err := os.ReadFile(partPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read split pdf (pages %d-%d): %w", r.Start, r.End, err)
+		}
+		parts = append(parts, data)
+	}
+
+	log.Printf("PDFを%d個のファイルに分割しました", len(parts))
+	return parts, nil
+}
+
+// runPoppler は poppler-utils のコマンドを実行
+func runPoppler(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %v, stderr: %s", name, err, stderr.String())
+	}
+	return nil
+}
+
 func sortPdftoppmJPGs(names []string) {
 	type item struct {
 		name    string
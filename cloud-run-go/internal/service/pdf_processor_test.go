@@ -42,3 +42,14 @@ func TestParsePdftoppmPageNumber(t *testing.T) {
 	}
 }
 
+func TestParsePdfinfoPages(t *testing.T) {
+	output := "Title:          scan\nProducer:       ScanSnap\nPages:          12\nEncrypted:      no\n"
+	got, err := parsePdfinfoPages(output)
+	if err != nil || got != 12 {
+		t.Fatalf("parsePdfinfoPages() = (%d, %v), want 12", got, err)
+	}
+
+	if _, err := parsePdfinfoPages("Title: scan\n"); err == nil {
+		t.Fatal("expected error without Pages line")
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"io"
 	"log"
 	"net/http"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
 )
 
 // PhotosClient はGoogle Photos APIクライアント
@@ -16,8 +18,8 @@ type PhotosClient struct {
 }
 
 // NewPhotosClient は新しいPhotosClientを作成
-func NewPhotosClient(ctx context.Context) (*PhotosClient, error) {
-	creds, err := GetOAuthCredentials(ctx)
+func NewPhotosClient(ctx context.Context, tenant *config.Tenant) (*PhotosClient, error) {
+	creds, err := GetOAuthCredentials(ctx, tenant)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get OAuth credentials for Photos: %w", err)
 	}
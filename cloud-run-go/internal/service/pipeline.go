@@ -0,0 +1,603 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// pipelineRun は1ファイル分のパイプライン実行状態
+// 台帳エントリから復元できる値（解析結果、リネーム後の名前、移動先）はステップ間で共有する
+type pipelineRun struct {
+	fileInfo *model.FileInfo
+	entry    *model.LedgerEntry
+
+	data                []byte                // 必要になった時点でダウンロード
+	analysis            *model.AnalysisResult // 台帳の解析結果に子供・教育の解決を適用したもの
+	destinationFolderID string
+	newFileName         string
+}
+
+// pipelineStep はパイプラインの1ステップ
+type pipelineStep struct {
+	name model.StepName
+	run  func(ctx context.Context, r *pipelineRun) (string, error)
+}
+
+// stepSkipped はステップを実行対象外として記録するためのエラー
+type stepSkipped struct {
+	reason string
+}
+
+func (s *stepSkipped) Error() string {
+	return s.reason
+}
+
+func skipStep(reason string) error {
+	return &stepSkipped{reason: reason}
+}
+
+// runPipeline はステップ単位で処理を実行し、各ステップの結果を台帳に記録する
+// 成功済み（またはスキップ済み）のステップは再実行しない
+func (fs *FileSorter) runPipeline(ctx context.Context, fileInfo *model.FileInfo, entry *model.LedgerEntry) error {
+	ctx = withUsageFileID(ctx, fileInfo.ID)
+	r := &pipelineRun{
+		fileInfo:            fileInfo,
+		entry:               entry,
+		destinationFolderID: entry.DestinationFolderID,
+		newFileName:         entry.NewFileName,
+	}
+
+	// 重複判定: 仕分け済みの書類と同一なら解析せずに「重複」フォルダへ移動する
+	if err := fs.runStep(ctx, r, pipelineStep{model.StepDedup, fs.stepDedup}); err != nil {
+		return err
+	}
+	if r.entry.DuplicateOf != nil {
+		return fs.parkDuplicate(ctx, r)
+	}
+
+	// 分割: 複数の書類を含むPDFは書類ごとのファイルにしてInboxへ戻し、元のPDFは分類しない
+	if err := fs.runStep(ctx, r, pipelineStep{model.StepSplit, fs.stepSplit}); err != nil {
+		return err
+	}
+	if r.entry.Split != nil {
+		return fs.archiveSplitSource(ctx, r)
+	}
+
+	// 解析・仕分け: 失敗した時点で中断（後続ステップは前段の結果に依存する）
+	if err := fs.runStep(ctx, r, pipelineStep{model.StepAnalyze, fs.stepAnalyze}); err != nil {
+		return err
+	}
+	r.analysis = fs.resolveAnalysis(r.entry.Analysis)
+
+	// 信頼度が低い場合は家族のレビュー待ちにする（回答後に ResolveReview から再開）
+	if fs.needsReview(r) {
+		return fs.parkForReview(ctx, r)
+	}
+
+	coreSteps := []pipelineStep{
+		{model.StepResolveDestination, fs.stepResolveDestination},
+		{model.StepRename, fs.stepRename},
+		{model.StepMove, fs.stepMove},
+	}
+	for _, step := range coreSteps {
+		if err := fs.runStep(ctx, r, step); err != nil {
+			return err
+		}
+	}
+	if r.newFileName == "" {
+		r.newFileName = fileInfo.Name
+	}
+
+	// 追加アクション: 互いに独立しているため、失敗しても他のステップは続行する
+	actionSteps := []pipelineStep{
+		{model.StepPhotos, fs.stepPhotos},
+		{model.StepCalendar, fs.stepCalendar},
+		{model.StepTasks, fs.stepTasks},
+		{model.StepNotebookLM, fs.stepNotebookLM},
+		{model.StepMedicalExpense, fs.stepMedicalExpense},
+		{model.StepFinance, fs.stepFinance},
+	}
+	var failed []string
+	for _, step := range actionSteps {
+		if err := fs.runStep(ctx, r, step); err != nil {
+			failed = append(failed, string(step.name))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("追加アクション失敗: %s", strings.Join(failed, ", "))
+	}
+
+	fs.updateRun(ctx, r, func(e *model.LedgerEntry) { e.Transition(model.ProcessingStateCompleted, "") })
+	return nil
+}
+
+// runStep は完了済みでなければステップを実行し、失敗時はバックオフ付きで再試行する
+func (fs *FileSorter) runStep(ctx context.Context, r *pipelineRun, step pipelineStep) error {
+	if rec := r.entry.Steps[step.name]; rec.Done() {
+		return nil
+	}
+
+	maxAttempts := config.API.MaxRetries
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	delay := time.Duration(config.API.RetryDelayMS) * time.Millisecond
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		detail, err := step.run(ctx, r)
+
+		var skipped *stepSkipped
+		switch {
+		case err == nil:
+			fs.recordStep(ctx, r, step.name, model.StepStatusSucceeded, detail, nil)
+			return nil
+		case errors.As(err, &skipped):
+			fs.recordStep(ctx, r, step.name, model.StepStatusSkipped, skipped.reason, nil)
+			return nil
+		}
+
+		lastErr = err
+		log.Printf("ステップ失敗: %s (%s, attempt=%d/%d): %v", r.fileInfo.Name, step.name, attempt, maxAttempts, err)
+		fs.recordStep(ctx, r, step.name, model.StepStatusFailed, "", err)
+
+		if attempt == maxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s: %w", step.name, ctx.Err())
+		case <-time.After(delay << (attempt - 1)):
+		}
+	}
+	return fmt.Errorf("%s: %w", step.name, lastErr)
+}
+
+// isFinalAttempt は今回がステップの最後の試行かを返す（これまでの失敗回数から判定）
+func isFinalAttempt(entry *model.LedgerEntry, name model.StepName) bool {
+	attempts := 0
+	if rec := entry.Steps[name]; rec != nil {
+		attempts = rec.Attempts
+	}
+	return attempts+1 >= config.API.MaxRetries
+}
+
+// recordStep はステップの実行結果を台帳に記録
+func (fs *FileSorter) recordStep(ctx context.Context, r *pipelineRun, name model.StepName, status model.StepStatus, detail string, err error) {
+	fs.updateRun(ctx, r, func(e *model.LedgerEntry) {
+		rec := e.Step(name)
+		rec.Status = status
+		rec.Attempts++
+		rec.UpdatedAt = time.Now()
+		if detail != "" {
+			rec.Detail = detail
+		}
+		rec.Error = ""
+		if err != nil {
+			rec.Error = err.Error()
+		}
+	})
+}
+
+// updateRun は台帳エントリを更新し、実行中のスナップショットにも反映する
+// 台帳への保存に失敗しても処理は止めず、メモリ上の状態で続行する
+func (fs *FileSorter) updateRun(ctx context.Context, r *pipelineRun, fn func(*model.LedgerEntry)) {
+	updated, err := fs.ledger.Update(ctx, r.fileInfo.ID, fn)
+	if err != nil {
+		log.Printf("Warning: 処理台帳の更新失敗 (%s): %v", r.fileInfo.ID, err)
+		fn(r.entry)
+		return
+	}
+	r.entry = updated
+}
+
+// fileData はファイル本体を取得（1回の実行中はキャッシュする）
+func (fs *FileSorter) fileData(ctx context.Context, r *pipelineRun) ([]byte, error) {
+	if r.data != nil {
+		return r.data, nil
+	}
+	data, err := fs.driveClient.DownloadFile(ctx, r.fileInfo.ID)
+	if err != nil {
+		return nil, fmt.Errorf("ファイルダウンロード失敗: %w", err)
+	}
+	r.data = data
+	if !reached(r.entry, model.ProcessingStateDownloaded) {
+		fs.updateRun(ctx, r, func(e *model.LedgerEntry) {
+			e.Transition(model.ProcessingStateDownloaded, fmt.Sprintf("%d bytes", len(data)))
+		})
+	}
+	return data, nil
+}
+
+// resolveAnalysis は台帳の解析結果を複製し、子供の特定とフォルダ解決を適用する
+// 台帳には Gemini の生の結果のみを保存し、年度・学年に依存する値は毎回導出する
+func (fs *FileSorter) resolveAnalysis(cached *model.AnalysisResult) *model.AnalysisResult {
+	result := *cached
+	if !config.ValidSubCategory(result.Category, result.SubCategory) {
+		result.SubCategory = ""
+	}
+	if result.Category == "40_子供・教育" {
+		fs.processChildEducation(&result)
+	}
+	return &result
+}
+
+// stepAnalyze はGeminiで解析し、結果を台帳にキャッシュする
+func (fs *FileSorter) stepAnalyze(ctx context.Context, r *pipelineRun) (string, error) {
+	if r.entry.Analysis != nil {
+		return r.entry.Analysis.Category, nil
+	}
+
+	data, err := fs.fileData(ctx, r)
+	if err != nil {
+		return "", err
+	}
+
+	// 仕分けルールに一致した書類はAIを使わずに分類する
+	if match := fs.matchSortingRule(ctx, data, r.fileInfo); match != nil {
+		fs.logAnalysis(match.analysis)
+		fs.updateRun(ctx, r, func(e *model.LedgerEntry) {
+			e.Analysis = match.analysis
+			if match.ocrBundle != nil {
+				e.OCRBundle = match.ocrBundle
+			}
+			e.SortingRule = match.rule.Name
+			e.Transition(model.ProcessingStateAnalyzed, match.analysis.Category)
+		})
+		return fmt.Sprintf("%s (rule: %s)", match.analysis.Category, match.rule.Name), nil
+	}
+
+	provisional := false
+	analysisResult, combined, err := fs.analyzeWithGemini(ctx, data, r.fileInfo)
+	if err != nil {
+		if fs.localOCR == nil || !isFinalAttempt(r.entry, model.StepAnalyze) {
+			return "", err
+		}
+		// 再試行してもAIが使えない場合はローカルOCRとルールで仮分類して仕分けを進める
+		log.Printf("AI解析失敗のためローカルOCRで仮分類します: %v", err)
+		analysisResult, combined, err = fs.analyzeLocally(ctx, data, r.fileInfo)
+		if err != nil {
+			return "", err
+		}
+		provisional = true
+	}
+
+	fs.updateRun(ctx, r, func(e *model.LedgerEntry) {
+		e.Analysis = analysisResult
+		if combined != nil {
+			e.EventsAndTasks = combined.EventsAndTasks
+			e.OCRBundle = combined.OCRBundle
+		}
+		e.Provisional = provisional
+		e.Transition(model.ProcessingStateAnalyzed, analysisResult.Category)
+	})
+	return analysisResult.Category, nil
+}
+
+// analyzeWithGemini はGeminiで解析する（統合解析が有効なら予定・OCRもまとめて取得）
+// 統合解析に失敗した場合は分類のみの解析にフォールバックし、combined は nil を返す
+func (fs *FileSorter) analyzeWithGemini(ctx context.Context, data []byte, fileInfo *model.FileInfo) (*model.AnalysisResult, *model.DocumentBundle, error) {
+	// Geminiで解析 (PDFもそのまま渡す)
+	if config.EnableCombinedGemini {
+		prompt := fs.createAnalysisPrompt(fileInfo.Name)
+		bundle, err := fs.aiRouter.AnalyzeDocumentFull(ctx, data, fileInfo.MimeType, fileInfo.Name, prompt, fs.analysisSchema())
+		if err == nil && bundle != nil && bundle.Analysis != nil {
+			fs.logAnalysis(bundle.Analysis)
+			return bundle.Analysis, bundle, nil
+		}
+		log.Printf("統合解析失敗、フォールバックします: %v", err)
+	}
+
+	analysisResult, err := fs.analyzeDocument(ctx, data, fileInfo.MimeType, fileInfo.Name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Gemini解析失敗: %w", err)
+	}
+	fs.logAnalysis(analysisResult)
+	return analysisResult, nil, nil
+}
+
+// logAnalysis は解析結果をログ出力
+func (fs *FileSorter) logAnalysis(result *model.AnalysisResult) {
+	log.Printf("解析結果: category=%s, child=%s, date=%s, summary=%s",
+		result.Category, result.ChildName, result.Date, result.Summary)
+}
+
+// stepResolveDestination は移動先フォルダを決定
+func (fs *FileSorter) stepResolveDestination(ctx context.Context, r *pipelineRun) (string, error) {
+	destinationFolderID, err := fs.getDestinationFolder(ctx, r.analysis)
+	if err != nil {
+		return "", fmt.Errorf("移動先フォルダ決定失敗: %w", err)
+	}
+	r.destinationFolderID = destinationFolderID
+	fs.updateRun(ctx, r, func(e *model.LedgerEntry) { e.DestinationFolderID = destinationFolderID })
+	return destinationFolderID, nil
+}
+
+// stepRename は新しいファイル名を生成してリネーム
+func (fs *FileSorter) stepRename(ctx context.Context, r *pipelineRun) (string, error) {
+	newFileName, err := fs.uniqueFilename(ctx, r.analysis, r.fileInfo, r.destinationFolderID)
+	if err != nil {
+		return "", err
+	}
+	if newFileName != r.fileInfo.Name {
+		if err := fs.driveClient.RenameFile(ctx, r.fileInfo.ID, newFileName); err != nil {
+			return "", fmt.Errorf("ファイルリネーム失敗: %w", err)
+		}
+	}
+	r.newFileName = newFileName
+	fs.updateRun(ctx, r, func(e *model.LedgerEntry) {
+		e.NewFileName = newFileName
+		e.Transition(model.ProcessingStateRenamed, newFileName)
+	})
+	return newFileName, nil
+}
+
+// stepMove はファイルを移動先フォルダへ移動（移動直後に中断した場合は移動済みとして扱う）
+func (fs *FileSorter) stepMove(ctx context.Context, r *pipelineRun) (string, error) {
+	if !contains(r.fileInfo.Parents, r.destinationFolderID) {
+		if err := fs.driveClient.MoveFile(ctx, r.fileInfo.ID, r.destinationFolderID); err != nil {
+			return "", fmt.Errorf("ファイル移動失敗: %w", err)
+		}
+	}
+	fs.updateRun(ctx, r, func(e *model.LedgerEntry) {
+		e.Transition(model.ProcessingStateMoved, r.destinationFolderID)
+	})
+	fs.recordFingerprint(ctx, r)
+	log.Printf("処理完了: %s → %s", r.fileInfo.Name, r.newFileName)
+	return r.destinationFolderID, nil
+}
+
+// stepPhotos はGoogle Photosへアップロード
+// PDFはページ単位で進捗を記録し、再試行時はアップロード済みのページを飛ばす
+func (fs *FileSorter) stepPhotos(ctx context.Context, r *pipelineRun) (string, error) {
+	result := r.analysis
+	if !shouldUploadToPhotos(result) {
+		return "", skipStep("対象外カテゴリ")
+	}
+	if fs.photosClient == nil {
+		return "", skipStep("Photosクライアント未初期化")
+	}
+
+	data, err := fs.fileData(ctx, r)
+	if err != nil {
+		return "", err
+	}
+	description := fmt.Sprintf("【%s】%s_%s", result.Category, result.Date, result.Summary)
+
+	if r.fileInfo.MimeType != "application/pdf" {
+		if _, err := fs.photosClient.UploadImage(ctx, data, description); err != nil {
+			return "", fmt.Errorf("Google Photosアップロード失敗: %w", err)
+		}
+		return "1 image", nil
+	}
+
+	// PDFを画像に変換してアップロード
+	images, err := fs.pdfProcessor.ConvertPDFToImages(data, config.DPI.Photos)
+	if err != nil {
+		return "", fmt.Errorf("PDF画像変換失敗: %w", err)
+	}
+	uploaded := r.entry.Step(model.StepPhotos).Progress
+	for i := uploaded; i < len(images); i++ {
+		pageDesc := fmt.Sprintf("%s (Page %d/%d)", description, i+1, len(images))
+		if _, err := fs.photosClient.UploadImage(ctx, images[i], pageDesc); err != nil {
+			return "", fmt.Errorf("Google Photosアップロード失敗 (Page %d): %w", i+1, err)
+		}
+		page := i + 1
+		fs.updateRun(ctx, r, func(e *model.LedgerEntry) { e.Step(model.StepPhotos).Progress = page })
+	}
+	return fmt.Sprintf("%d pages", len(images)), nil
+}
+
+// shouldRegisterCalendar はカレンダー・タスク登録の対象かを判定
+func shouldRegisterCalendar(result *model.AnalysisResult) bool {
+	return result.Category == "40_子供・教育" ||
+		(contains([]string{"10_マネー・税務", "30_ライフ・行政"}, result.Category) && result.TargetAdult != "")
+}
+
+// eventsAndTasks は予定・タスクを取得（台帳にキャッシュ済みならGeminiを呼ばない）
+func (fs *FileSorter) eventsAndTasks(ctx context.Context, r *pipelineRun) (*model.EventsAndTasks, error) {
+	if r.entry.EventsAndTasks != nil {
+		return r.entry.EventsAndTasks, nil
+	}
+
+	log.Println("カレンダー・タスク抽出処理開始...")
+	data, err := fs.fileData(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	eventsAndTasks, err := fs.aiRouter.ExtractEventsAndTasks(ctx, data, r.fileInfo.MimeType, r.newFileName)
+	if err != nil {
+		return nil, fmt.Errorf("カレンダー・タスク情報抽出失敗: %w", err)
+	}
+	fs.updateRun(ctx, r, func(e *model.LedgerEntry) { e.EventsAndTasks = eventsAndTasks })
+	return eventsAndTasks, nil
+}
+
+// stepCalendar はカレンダーに予定を登録（既存の予定は重複チェックで飛ばす）
+func (fs *FileSorter) stepCalendar(ctx context.Context, r *pipelineRun) (string, error) {
+	if !shouldRegisterCalendar(r.analysis) {
+		return "", skipStep("対象外カテゴリ")
+	}
+	if fs.calendarClient == nil {
+		return "", skipStep("Calendarクライアント未初期化")
+	}
+	if r.entry.Provisional {
+		return "", skipStep("AI未使用の仮分類")
+	}
+
+	eventsAndTasks, err := fs.eventsAndTasks(ctx, r)
+	if err != nil {
+		return "", err
+	}
+
+	fileURL := fmt.Sprintf("https://drive.google.com/file/d/%s/view", r.fileInfo.ID)
+	titlePrefix := fs.createTitlePrefix(r.analysis)
+	// 対象の子供・大人ごとの登録先（calendar_routes）
+	destinations := calendarDestinations(fs.household.Current(), r.analysis)
+
+	created, failed := 0, 0
+	for _, event := range eventsAndTasks.Events {
+		eventTitle := titlePrefix + " " + event.Title
+		event.Title = eventTitle
+		for _, dest := range destinations {
+			// 重複チェック（登録先のカレンダーごと）
+			exists, err := fs.calendarClient.EventExists(ctx, dest.CalendarID, eventTitle, event.Date)
+			if err != nil {
+				log.Printf("カレンダー重複チェック失敗: %v", err)
+			} else if exists {
+				log.Printf("カレンダーイベントは既に存在します: %s (%s)", eventTitle, dest.CalendarID)
+				continue
+			}
+
+			notes := fmt.Sprintf("📎 元のお便り: %s", fileURL)
+			if _, err := fs.calendarClient.CreateEvent(ctx, dest, &event, notes); err != nil {
+				log.Printf("イベント作成失敗: %v", err)
+				failed++
+				continue
+			}
+			created++
+		}
+	}
+	if failed > 0 {
+		return "", fmt.Errorf("イベント作成失敗: %d/%d件", failed, len(eventsAndTasks.Events)*len(destinations))
+	}
+	return fmt.Sprintf("%d events", created), nil
+}
+
+// stepTasks はタスクを登録 (期日が同じものをマージ、既存のタスクは重複チェックで飛ばす)
+func (fs *FileSorter) stepTasks(ctx context.Context, r *pipelineRun) (string, error) {
+	if !shouldRegisterCalendar(r.analysis) {
+		return "", skipStep("対象外カテゴリ")
+	}
+	if fs.tasksClient == nil {
+		return "", skipStep("Tasksクライアント未初期化")
+	}
+	if r.entry.Provisional {
+		return "", skipStep("AI未使用の仮分類")
+	}
+
+	eventsAndTasks, err := fs.eventsAndTasks(ctx, r)
+	if err != nil {
+		return "", err
+	}
+
+	fileURL := fmt.Sprintf("https://drive.google.com/file/d/%s/view", r.fileInfo.ID)
+	titlePrefix := fs.createTitlePrefix(r.analysis)
+
+	tasks := mergeTasksByDueDate(eventsAndTasks.Tasks)
+	created, failed := 0, 0
+	for _, task := range tasks {
+		taskTitle := titlePrefix + " " + task.Title
+		// タイトル+期日での重複チェック
+		exists, err := fs.tasksClient.TaskExistsByTitleAndDate(ctx, taskTitle, task.DueDate)
+		if err != nil {
+			log.Printf("タスク重複チェック失敗: %v", err)
+		} else if exists {
+			log.Printf("タスクは既に存在します: %s (期日: %s)", taskTitle, task.DueDate)
+			continue
+		}
+
+		notes := fmt.Sprintf("📎 元のお便り: %s", fileURL)
+		if task.Notes != "" {
+			notes += "\n\n" + task.Notes
+		}
+		if _, err := fs.tasksClient.CreateTask(ctx, task, notes); err != nil {
+			log.Printf("タスク作成失敗: %v", err)
+			failed++
+			continue
+		}
+		created++
+	}
+	if failed > 0 {
+		return "", fmt.Errorf("タスク作成失敗: %d/%d件", failed, len(tasks))
+	}
+	return fmt.Sprintf("%d tasks", created), nil
+}
+
+// mergeTasksByDueDate は期日が同じタスクを1件にまとめる（最初に出現した順を維持）
+func mergeTasksByDueDate(tasks []model.Task) []*model.Task {
+	mergedTasks := make(map[string]*model.Task)
+	var merged []*model.Task
+
+	for _, task := range tasks {
+		if existing, ok := mergedTasks[task.DueDate]; ok {
+			existing.Title += " / " + task.Title
+			if task.Notes != "" {
+				if existing.Notes != "" {
+					existing.Notes += "\n"
+				}
+				existing.Notes += task.Notes
+			}
+		} else {
+			t := task
+			mergedTasks[task.DueDate] = &t
+			merged = append(merged, &t)
+		}
+	}
+	return merged
+}
+
+// shouldUploadToPhotos はGoogle Photosへのアップロード対象かを判定
+func shouldUploadToPhotos(result *model.AnalysisResult) bool {
+	return result.Category == "50_写真・その他" ||
+		(result.Category == "40_子供・教育" && result.SubCategory == "03_記録・作品・成績")
+}
+
+// stepNotebookLM はNotebookLM用の蓄積ドキュメントへ同期
+func (fs *FileSorter) stepNotebookLM(ctx context.Context, r *pipelineRun) (string, error) {
+	result := r.analysis
+	log.Printf("NotebookLM同期チェック: category=%s, subCategory=%s, sync_enabled=%v", result.Category, result.SubCategory, fs.notebooklmSync != nil)
+	if fs.notebooklmSync == nil || !fs.notebooklmSync.ShouldSync(result.Category, result.SubCategory) {
+		return "", skipStep("同期対象外またはサービス未初期化")
+	}
+
+	// Avoid re-syncing the same file (saves OCR/Gemini cost).
+	if fs.notebooklmSync.IsAlreadySynced(ctx, r.fileInfo.ID) {
+		log.Printf("NotebookLM同期済みのためスキップ: %s (%s)", r.newFileName, r.fileInfo.ID)
+		return "already synced", nil
+	}
+
+	// Driveカテゴリ → NotebookLMカテゴリに変換
+	notebookCategory, exists := config.NotebookLMCategoryMap[result.Category]
+	if !exists {
+		return "", skipStep("NotebookLMカテゴリマッピングなし: " + result.Category)
+	}
+
+	// OCRBundleを取得（台帳にキャッシュがなければGeminiで抽出）
+	bundle := r.entry.OCRBundle
+	if bundle == nil || bundle.OCRText == "" {
+		data, err := fs.fileData(ctx, r)
+		if err != nil {
+			return "", err
+		}
+		bundle, err = fs.aiRouter.ExtractOCRBundle(ctx, data, r.fileInfo.MimeType)
+		if err != nil {
+			return "", fmt.Errorf("OCRBundle抽出失敗: %w", err)
+		}
+		if bundle == nil || bundle.OCRText == "" {
+			return "", skipStep("OCRテキストが空")
+		}
+		fs.updateRun(ctx, r, func(e *model.LedgerEntry) { e.OCRBundle = bundle })
+	}
+
+	// 年度を計算
+	fiscalYear := result.FiscalYear
+	if fiscalYear == 0 {
+		fiscalYear = fs.gradeManager.CalculateFiscalYear(result.Date)
+	}
+
+	log.Printf("NotebookLM同期実行開始: %s (%d年度_%s)", r.newFileName, fiscalYear, notebookCategory)
+	err := fs.notebooklmSync.SyncFile(ctx, r.fileInfo.ID, r.newFileName, notebookCategory, bundle.OCRText, bundle.Facts, bundle.Summary, result.Date, fiscalYear)
+	if err != nil {
+		return "", fmt.Errorf("NotebookLM同期失敗: %w", err)
+	}
+	log.Printf("NotebookLM同期成功ログ: %s", r.newFileName)
+	return fmt.Sprintf("%d年度_%s", fiscalYear, notebookCategory), nil
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+func TestRunStep_RetriesAndSkipsCompletedSteps(t *testing.T) {
+	ctx := context.Background()
+	ledger, err := NewFileLedger(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	origRetries, origDelay := config.API.MaxRetries, config.API.RetryDelayMS
+	config.API.MaxRetries, config.API.RetryDelayMS = 3, 1
+	defer func() { config.API.MaxRetries, config.API.RetryDelayMS = origRetries, origDelay }()
+
+	fs := &FileSorter{ledger: ledger}
+	r := &pipelineRun{fileInfo: &model.FileInfo{ID: "file1", Name: "a.pdf"}, entry: &model.LedgerEntry{FileID: "file1"}}
+
+	calls := 0
+	step := pipelineStep{model.StepPhotos, func(ctx context.Context, r *pipelineRun) (string, error) {
+		calls++
+		if calls == 1 {
+			return "", errors.New("temporary")
+		}
+		return "ok", nil
+	}}
+
+	if err := fs.runStep(ctx, r, step); err != nil {
+		t.Fatalf("runStep failed: %v", err)
+	}
+	rec := r.entry.Steps[model.StepPhotos]
+	if rec.Status != model.StepStatusSucceeded || rec.Attempts != 2 || rec.Error != "" {
+		t.Fatalf("unexpected record: %+v", rec)
+	}
+
+	// 成功済みのステップは再実行しない
+	if err := fs.runStep(ctx, r, step); err != nil || calls != 2 {
+		t.Fatalf("completed step re-ran: calls=%d err=%v", calls, err)
+	}
+
+	saved, err := ledger.Get(ctx, "file1")
+	if err != nil || !saved.Steps[model.StepPhotos].Done() {
+		t.Fatalf("step outcome not persisted: %+v err=%v", saved, err)
+	}
+}
+
+func TestRunStep_RecordsSkipAndFailure(t *testing.T) {
+	ctx := context.Background()
+	ledger, err := NewFileLedger(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	origRetries, origDelay := config.API.MaxRetries, config.API.RetryDelayMS
+	config.API.MaxRetries, config.API.RetryDelayMS = 2, 1
+	defer func() { config.API.MaxRetries, config.API.RetryDelayMS = origRetries, origDelay }()
+
+	fs := &FileSorter{ledger: ledger}
+	r := &pipelineRun{fileInfo: &model.FileInfo{ID: "file1", Name: "a.pdf"}, entry: &model.LedgerEntry{FileID: "file1"}}
+
+	skip := pipelineStep{model.StepCalendar, func(ctx context.Context, r *pipelineRun) (string, error) {
+		return "", skipStep("対象外カテゴリ")
+	}}
+	if err := fs.runStep(ctx, r, skip); err != nil {
+		t.Fatalf("skipped step returned error: %v", err)
+	}
+	if rec := r.entry.Steps[model.StepCalendar]; rec.Status != model.StepStatusSkipped || !rec.Done() {
+		t.Fatalf("unexpected record: %+v", rec)
+	}
+
+	fail := pipelineStep{model.StepTasks, func(ctx context.Context, r *pipelineRun) (string, error) {
+		return "", errors.New("boom")
+	}}
+	if err := fs.runStep(ctx, r, fail); err == nil {
+		t.Fatal("expected error")
+	}
+	if rec := r.entry.Steps[model.StepTasks]; rec.Status != model.StepStatusFailed || rec.Attempts != 2 || rec.Error != "boom" {
+		t.Fatalf("unexpected record: %+v", rec)
+	}
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// PreviewFile はドライランで処理予定を算出する
+// Geminiでの解析は実際に行うが、Drive・Calendar・Tasks・Photos・処理台帳には一切書き込まない
+// 重複判定・PDF分割・レビュー待ちの判定は実際の処理と同じ条件で行い、書き込む予定の台帳を LedgerWrites に返す
+func (fs *FileSorter) PreviewFile(ctx context.Context, fileID string) (*model.ProcessingPlan, error) {
+	ctx = withUsageFileID(ctx, fileID)
+	fileInfo, err := fs.driveClient.GetFile(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("ファイル情報取得失敗: %w", err)
+	}
+	if !fs.isSupportedMimeType(fileInfo.MimeType) {
+		return nil, fmt.Errorf("非対応のファイル形式です: %s", fileInfo.MimeType)
+	}
+
+	plan := &model.ProcessingPlan{
+		FileID:         fileInfo.ID,
+		OriginalName:   fileInfo.Name,
+		MimeType:       fileInfo.MimeType,
+		ExpectedResult: model.ProcessResultProcessed,
+	}
+	if !contains(fileInfo.Parents, fs.household.Current().FolderIDs["SOURCE"]) {
+		plan.Warnings = append(plan.Warnings, "Inbox外のファイルのため、通常の処理では仕分け対象外です")
+	}
+
+	// 途中まで処理したファイルは台帳の解析結果・レビューの回答を引き継ぐ（台帳は参照のみ）
+	entry := &model.LedgerEntry{FileID: fileInfo.ID}
+	if existing, err := fs.ledger.Get(ctx, fileInfo.ID); err != nil {
+		return nil, fmt.Errorf("処理台帳の参照失敗: %w", err)
+	} else if existing != nil {
+		copied := *existing
+		entry = &copied
+	}
+
+	data, err := fs.driveClient.DownloadFile(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("ファイルダウンロード失敗: %w", err)
+	}
+
+	// 重複判定・PDF分割は解析前のファイルのみ（stepDedup・stepSplit と同じ条件）
+	if entry.Analysis == nil {
+		if fs.previewDedup(ctx, data, fileInfo, entry, plan) {
+			return plan, nil
+		}
+		if split, err := fs.previewSplit(ctx, data, fileInfo, plan); err != nil {
+			return nil, err
+		} else if split {
+			return plan, nil
+		}
+	}
+
+	var raw *model.AnalysisResult
+	var combined *model.DocumentBundle
+	switch {
+	case entry.Analysis != nil:
+		raw = entry.Analysis
+		combined = &model.DocumentBundle{Analysis: raw, EventsAndTasks: entry.EventsAndTasks, OCRBundle: entry.OCRBundle}
+		plan.Warnings = append(plan.Warnings, "処理台帳の解析結果を使用します")
+	default:
+		if match := fs.matchSortingRule(ctx, data, fileInfo); match != nil {
+			raw = match.analysis
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("仕分けルール「%s」に一致したためAIの分類は行いません", match.rule.Name))
+		} else {
+			raw, combined, err = fs.analyzeWithGemini(ctx, data, fileInfo)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	result := fs.resolveAnalysis(raw)
+	plan.Analysis = raw
+	plan.FiscalYear = result.FiscalYear
+	if plan.FiscalYear == 0 {
+		plan.FiscalYear = fs.gradeManager.CalculateFiscalYear(result.Date)
+	}
+	plan.TargetChildren = result.TargetChildren
+	if result.Category != raw.Category {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("カテゴリを %s から %s に変更（卒業済み）", raw.Category, result.Category))
+	}
+	plan.NewFileName = fs.generateNewFilename(result, fileInfo.Name)
+
+	// レビュー待ちになる場合は移動先・追加アクションは家族の回答後に決まる
+	if fs.needsReview(&pipelineRun{fileInfo: fileInfo, entry: entry, analysis: result}) {
+		plan.ExpectedResult = model.ProcessResultPendingReview
+		plan.SimilarTo = entry.SimilarTo
+		if entry.SimilarTo != nil {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("仕分け済みの %s と似ているため家族のレビュー待ちになります", entry.SimilarTo.FileName))
+		} else {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("信頼度 %.2f のため家族のレビュー待ちになります", raw.ConfidenceScore))
+		}
+		plan.LedgerWrites = append(plan.LedgerWrites, fmt.Sprintf("処理台帳: %s", model.ProcessingStateAwaitingReview))
+		log.Printf("ドライラン: %s → レビュー待ち", fileInfo.Name)
+		return plan, nil
+	}
+
+	// 移動先フォルダ（存在しないフォルダは作成予定として返す）
+	if err := fs.previewDestination(ctx, result, plan); err != nil {
+		return nil, fmt.Errorf("移動先フォルダ決定失敗: %w", err)
+	}
+	plan.UploadToPhotos = shouldUploadToPhotos(result) && fs.photosClient != nil
+	plan.LedgerWrites = append(plan.LedgerWrites, fmt.Sprintf("処理台帳: %s", model.ProcessingStateCompleted))
+	if fs.dedupIndex != nil && config.Dedup.Enabled {
+		plan.LedgerWrites = append(plan.LedgerWrites, "重複判定インデックス: "+plan.NewFileName)
+	}
+
+	// カレンダー・タスク
+	if shouldRegisterCalendar(result) && (fs.calendarClient != nil || fs.tasksClient != nil) {
+		var eventsAndTasks *model.EventsAndTasks
+		if combined != nil {
+			eventsAndTasks = combined.EventsAndTasks
+		}
+		if eventsAndTasks == nil {
+			eventsAndTasks, err = fs.aiRouter.ExtractEventsAndTasks(ctx, data, fileInfo.MimeType, plan.NewFileName)
+			if err != nil {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("カレンダー・タスク情報抽出失敗: %v", err))
+			}
+		}
+		if eventsAndTasks != nil {
+			titlePrefix := fs.createTitlePrefix(result)
+			if fs.calendarClient != nil {
+				for _, event := range eventsAndTasks.Events {
+					event.Title = titlePrefix + " " + event.Title
+					plan.Events = append(plan.Events, event)
+				}
+				if len(plan.Events) > 0 {
+					plan.Calendars = calendarDestinations(fs.household.Current(), result)
+				}
+			}
+			if fs.tasksClient != nil {
+				for _, task := range mergeTasksByDueDate(eventsAndTasks.Tasks) {
+					task.Title = titlePrefix + " " + task.Title
+					plan.Tasks = append(plan.Tasks, *task)
+				}
+			}
+		}
+	}
+
+	// NotebookLM
+	if fs.notebooklmSync != nil && fs.notebooklmSync.ShouldSync(result.Category, result.SubCategory) {
+		if notebookCategory, ok := config.NotebookLMCategoryMap[result.Category]; ok {
+			var bundle *model.OCRBundle
+			if combined != nil {
+				bundle = combined.OCRBundle
+			}
+			if bundle == nil || bundle.OCRText == "" {
+				bundle, err = fs.aiRouter.ExtractOCRBundle(ctx, data, fileInfo.MimeType)
+				if err != nil {
+					plan.Warnings = append(plan.Warnings, fmt.Sprintf("OCRBundle抽出失敗: %v", err))
+				}
+			}
+			if bundle != nil && bundle.OCRText != "" {
+				docName, notebookEntry := fs.notebooklmSync.PreviewEntry(fileInfo.ID, plan.NewFileName, notebookCategory, bundle.OCRText, bundle.Facts, bundle.Summary, result.Date, plan.FiscalYear)
+				plan.NotebookLM = &model.NotebookLMPlan{DocName: docName, Entry: notebookEntry}
+			}
+		}
+	}
+
+	// 医療費・家計（stepMedicalExpense・stepFinance と同じ条件）
+	if isMedicalReceipt(result) && fs.medicalExpenses != nil && !entry.Provisional {
+		expense, err := fs.aiRouter.ExtractMedicalExpense(ctx, data, fileInfo.MimeType, fileInfo.Name)
+		switch {
+		case err != nil:
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("医療費読み取り失敗: %v", err))
+		case expense.Amount > 0:
+			expense.FileID = fileInfo.ID
+			expense.FileName = plan.NewFileName
+			expense.Reimbursement = cappedReimbursement(expense)
+			expense.Patient = fs.medicalPatient(expense.Patient, result)
+			plan.MedicalExpense = expense
+			plan.LedgerWrites = append(plan.LedgerWrites, fmt.Sprintf("医療費台帳: %s %s %d円", expense.Date, expense.Patient, expense.Amount))
+		}
+	}
+	if result.Category == "10_マネー・税務" && fs.finance != nil && !entry.Provisional {
+		record, err := fs.aiRouter.ExtractFinance(ctx, data, fileInfo.MimeType, fileInfo.Name)
+		switch {
+		case err != nil:
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("家計の書類の読み取り失敗: %v", err))
+		case record.Amount > 0 || record.DueDate != "":
+			record.FileID = fileInfo.ID
+			record.FileName = plan.NewFileName
+			record.Account = maskAccount(record.Account)
+			record.Month = financeMonth(record, result.Date)
+			plan.Finance = record
+			plan.LedgerWrites = append(plan.LedgerWrites, fmt.Sprintf("家計の記録: %s %s %d円", record.Month, record.Payee, record.Amount))
+			if task := financeTask(record, fs.createTitlePrefix(result), time.Now()); task != nil && fs.tasksClient != nil && config.Finance.CreateDueTasks && !hasTaskDue(plan.Tasks, task.DueDate) {
+				plan.Tasks = append(plan.Tasks, *task)
+			}
+		}
+	}
+
+	log.Printf("ドライラン: %s → %s/%s", fileInfo.Name, plan.DestinationPath, plan.NewFileName)
+	return plan, nil
+}
+
+// previewDedup は重複判定インデックスを参照のみで検索する（重複として「重複」フォルダへ移動する場合は true）
+func (fs *FileSorter) previewDedup(ctx context.Context, data []byte, fileInfo *model.FileInfo, entry *model.LedgerEntry, plan *model.ProcessingPlan) bool {
+	if fs.dedupIndex == nil || !config.Dedup.Enabled {
+		return false
+	}
+	fp := fs.fingerprint(data, fileInfo.MimeType)
+	match, err := fs.dedupIndex.Lookup(ctx, fp, fileInfo.ID)
+	if err != nil {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("重複判定インデックスの検索失敗: %v", err))
+		return false
+	}
+	if match == nil || fp.SHA256 == "" || match.Fingerprint.SHA256 != fp.SHA256 {
+		entry.SimilarTo = match
+		return false
+	}
+
+	plan.ExpectedResult = model.ProcessResultDuplicate
+	plan.DuplicateOf = match
+	plan.DestinationPath = config.Dedup.Folder
+	plan.Warnings = append(plan.Warnings, fmt.Sprintf("仕分け済みの %s と同じファイルのため「%s」フォルダへ移動します", match.FileName, config.Dedup.Folder))
+	plan.LedgerWrites = append(plan.LedgerWrites, fmt.Sprintf("処理台帳: %s", model.ProcessingStateDuplicate))
+	log.Printf("ドライラン: %s → 重複 (元: %s)", fileInfo.Name, match.FileName)
+	return true
+}
+
+// previewSplit はPDFの書類の区切りを判定する（複数の書類に分割する場合は true）
+func (fs *FileSorter) previewSplit(ctx context.Context, data []byte, fileInfo *model.FileInfo, plan *model.ProcessingPlan) (bool, error) {
+	if !config.PDFSplit.Enabled || !fs.pdfProcessor.IsPDF(fileInfo.MimeType) {
+		return false, nil
+	}
+	ranges, err := fs.splitRanges(ctx, data, fileInfo)
+	var skipped *stepSkipped
+	if errors.As(err, &skipped) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if len(ranges) < 2 {
+		return false, nil
+	}
+
+	plan.ExpectedResult = model.ProcessResultSplit
+	for _, rg := range ranges {
+		plan.SplitParts = append(plan.SplitParts, splitPartName(fileInfo.Name, rg))
+	}
+	plan.Warnings = append(plan.Warnings, fmt.Sprintf("%d件の書類に分割してInboxにアップロードし、元のPDFは分類しません", len(ranges)))
+	plan.LedgerWrites = append(plan.LedgerWrites, fmt.Sprintf("処理台帳: %s", model.ProcessingStateSplit))
+	for _, name := range plan.SplitParts {
+		plan.LedgerWrites = append(plan.LedgerWrites, fmt.Sprintf("処理台帳: %s (%s)", model.ProcessingStateReceived, name))
+	}
+	log.Printf("ドライラン: %s → %d件に分割", fileInfo.Name, len(ranges))
+	return true, nil
+}
+
+// hasTaskDue は同じ期日のタスクがあるかを判定
+func hasTaskDue(tasks []model.Task, dueDate string) bool {
+	for _, task := range tasks {
+		if task.DueDate == dueDate {
+			return true
+		}
+	}
+	return false
+}
+
+// previewDestination は移動先のパスを解決する。存在しないサブフォルダは作成せず FoldersToCreate に積む
+func (fs *FileSorter) previewDestination(ctx context.Context, result *model.AnalysisResult, plan *model.ProcessingPlan) error {
+	baseID, segments := fs.destinationPath(result)
+
+	baseName := baseID
+	if base, err := fs.driveClient.GetFile(ctx, baseID); err == nil {
+		baseName = base.Name
+	}
+	path := []string{baseName}
+
+	folderID := baseID
+	for _, name := range segments {
+		path = append(path, name)
+		if folderID == "" {
+			plan.FoldersToCreate = append(plan.FoldersToCreate, strings.Join(path, "/"))
+			continue
+		}
+		id, err := fs.driveClient.FindFolder(ctx, name, folderID)
+		if err != nil {
+			return err
+		}
+		if id == "" {
+			plan.FoldersToCreate = append(plan.FoldersToCreate, strings.Join(path, "/"))
+		}
+		folderID = id
+	}
+
+	plan.DestinationPath = strings.Join(path, "/")
+	plan.DestinationFolderID = folderID
+	return nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+func TestPreviewDedup(t *testing.T) {
+	ctx := context.Background()
+	index, err := NewFileDedupIndex(t.TempDir(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := &FileSorter{pdfProcessor: NewPDFProcessor(), reviewNotifier: &stubReviewNotifier{}}
+	fs.SetDedupIndex(index)
+
+	data := encodePNG(t, testDocumentImage(200, 280, []int{10, 50}))
+	if err := index.Record(ctx, &model.DedupEntry{
+		FileID:      "first",
+		FileName:    "20250401_通知.png",
+		Fingerprint: *fs.fingerprint(data, "image/png"),
+		RecordedAt:  time.Now(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 同じ内容のファイルは「重複」フォルダへ移動する予定になり、解析・仕分けは行わない
+	second := &model.FileInfo{ID: "second", Name: "second.png", MimeType: "image/png"}
+	plan := &model.ProcessingPlan{ExpectedResult: model.ProcessResultProcessed}
+	if !fs.previewDedup(ctx, data, second, &model.LedgerEntry{FileID: "second"}, plan) {
+		t.Fatal("expected duplicate")
+	}
+	if plan.ExpectedResult != model.ProcessResultDuplicate || plan.DuplicateOf == nil || plan.DuplicateOf.FileID != "first" {
+		t.Fatalf("plan = %+v", plan)
+	}
+	if len(plan.LedgerWrites) != 1 {
+		t.Fatalf("ledger_writes = %v", plan.LedgerWrites)
+	}
+
+	// 知覚ハッシュのみの一致は信頼度が高くてもレビュー待ちになる
+	rescanned := &model.FileInfo{ID: "rescanned", Name: "rescanned.jpg", MimeType: "image/jpeg"}
+	entry := &model.LedgerEntry{FileID: "rescanned"}
+	plan = &model.ProcessingPlan{ExpectedResult: model.ProcessResultProcessed}
+	if fs.previewDedup(ctx, encodeJPEG(t, testDocumentImage(200, 280, []int{10, 50}), 70), rescanned, entry, plan) {
+		t.Fatalf("perceptual match should not be parked: %+v", plan.DuplicateOf)
+	}
+	if entry.SimilarTo == nil || entry.SimilarTo.FileID != "first" {
+		t.Fatalf("similar_to = %+v", entry.SimilarTo)
+	}
+	if !fs.needsReview(&pipelineRun{fileInfo: rescanned, entry: entry, analysis: &model.AnalysisResult{ConfidenceScore: 1}}) {
+		t.Fatal("expected review for similar document")
+	}
+
+	// ドライランではインデックスに登録しない
+	if match, _ := index.Lookup(ctx, fs.fingerprint(data, "image/png"), "first"); match != nil {
+		t.Fatalf("preview should not be indexed: %+v", match)
+	}
+}
@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// ProcessingLedger はファイルごとの処理状態を永続化する台帳
+// 実装を差し替えることで保存先（ローカルファイル、共有ボリューム等）を切り替えられる
+type ProcessingLedger interface {
+	// Acquire はファイルの処理権を取得する。他の処理者がリース中の場合は false を返す
+	Acquire(ctx context.Context, fileID, owner string, lease time.Duration) (*model.LedgerEntry, bool, error)
+	// Release は処理権を解放する
+	Release(ctx context.Context, fileID, owner string) error
+	// Update はエントリを読み込み、fnで更新して保存する
+	Update(ctx context.Context, fileID string, fn func(*model.LedgerEntry)) (*model.LedgerEntry, error)
+	// Get はエントリを取得する。存在しない場合は nil を返す
+	Get(ctx context.Context, fileID string) (*model.LedgerEntry, error)
+	// List は全エントリを取得する
+	List(ctx context.Context) ([]*model.LedgerEntry, error)
+}
+
+// FileLedger はディレクトリ上のJSONファイルで台帳を管理する実装
+// 1ファイル1エントリ（{fileID}.json）とし、処理権は O_EXCL で作成するロックファイルで排他する
+type FileLedger struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// ledgerLock はロックファイルの内容
+type ledgerLock struct {
+	Owner      string    `json:"owner"`
+	LeaseUntil time.Time `json:"lease_until"`
+}
+
+// NewFileLedger は新しいFileLedgerを作成
+func NewFileLedger(dir string) (*FileLedger, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create ledger dir: %w", err)
+	}
+	return &FileLedger{dir: dir}, nil
+}
+
+// Acquire は処理権を取得し、試行回数を加算したエントリを返す
+func (l *FileLedger) Acquire(ctx context.Context, fileID, owner string, lease time.Duration) (*model.LedgerEntry, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	leaseUntil := time.Now().Add(lease)
+	acquired, err := l.createLock(fileID, ledgerLock{Owner: owner, LeaseUntil: leaseUntil})
+	if err != nil {
+		return nil, false, err
+	}
+	if !acquired {
+		// リース切れのロックは奪取する（処理中にクラッシュしたケース）
+		if !l.lockExpired(fileID, lease) {
+			return nil, false, nil
+		}
+		if err := os.Remove(l.lockPath(fileID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, false, fmt.Errorf("failed to remove stale lock: %w", err)
+		}
+		acquired, err = l.createLock(fileID, ledgerLock{Owner: owner, LeaseUntil: leaseUntil})
+		if err != nil || !acquired {
+			return nil, false, err
+		}
+	}
+
+	entry, err := l.load(fileID)
+	if err != nil {
+		os.Remove(l.lockPath(fileID))
+		return nil, false, err
+	}
+	if entry == nil {
+		now := time.Now()
+		entry = &model.LedgerEntry{FileID: fileID, CreatedAt: now, UpdatedAt: now}
+		entry.Transition(model.ProcessingStateReceived, "")
+	}
+	entry.Attempts++
+	entry.Owner = owner
+	entry.LeaseUntil = leaseUntil
+
+	if err := l.save(entry); err != nil {
+		os.Remove(l.lockPath(fileID))
+		return nil, false, err
+	}
+	return entry, true, nil
+}
+
+// Release は自身が保持している処理権を解放
+func (l *FileLedger) Release(ctx context.Context, fileID, owner string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	current, err := l.readLock(fileID)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if current.Owner != owner {
+		return fmt.Errorf("lock is owned by %s", current.Owner)
+	}
+
+	entry, err := l.load(fileID)
+	if err != nil {
+		return err
+	}
+	if entry != nil {
+		entry.Owner = ""
+		entry.LeaseUntil = time.Time{}
+		if err := l.save(entry); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Remove(l.lockPath(fileID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove lock: %w", err)
+	}
+	return nil
+}
+
+// Update はエントリを更新して保存
+func (l *FileLedger) Update(ctx context.Context, fileID string, fn func(*model.LedgerEntry)) (*model.LedgerEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, err := l.load(fileID)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		now := time.Now()
+		entry = &model.LedgerEntry{FileID: fileID, CreatedAt: now, UpdatedAt: now}
+	}
+	fn(entry)
+	if err := l.save(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Get はエントリを取得
+func (l *FileLedger) Get(ctx context.Context, fileID string) (*model.LedgerEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.load(fileID)
+}
+
+// List は全エントリを更新日時の新しい順で取得
+func (l *FileLedger) List(ctx context.Context) ([]*model.LedgerEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	files, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ledger dir: %w", err)
+	}
+
+	var entries []*model.LedgerEntry
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		entry, err := l.load(strings.TrimSuffix(f.Name(), ".json"))
+		if err != nil || entry == nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].UpdatedAt.After(entries[j].UpdatedAt)
+	})
+	return entries, nil
+}
+
+func (l *FileLedger) entryPath(fileID string) string {
+	return filepath.Join(l.dir, fileID+".json")
+}
+
+func (l *FileLedger) lockPath(fileID string) string {
+	return filepath.Join(l.dir, fileID+".lock")
+}
+
+// createLock はロックファイルを排他的に作成する。既に存在する場合は false
+func (l *FileLedger) createLock(fileID string, lock ledgerLock) (bool, error) {
+	f, err := os.OpenFile(l.lockPath(fileID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to create lock: %w", err)
+	}
+	defer f.Close()
+
+	if err := json.NewEncoder(f).Encode(lock); err != nil {
+		return false, fmt.Errorf("failed to write lock: %w", err)
+	}
+	return true, nil
+}
+
+// lockExpired はロックのリースが切れているかを判定する
+// 内容が読めない（他インスタンスが書き込み中など）場合は更新時刻から判断する
+func (l *FileLedger) lockExpired(fileID string, lease time.Duration) bool {
+	current, err := l.readLock(fileID)
+	if err == nil {
+		return !time.Now().Before(current.LeaseUntil)
+	}
+	info, statErr := os.Stat(l.lockPath(fileID))
+	if statErr != nil {
+		return errors.Is(statErr, os.ErrNotExist)
+	}
+	return time.Since(info.ModTime()) > lease
+}
+
+func (l *FileLedger) readLock(fileID string) (*ledgerLock, error) {
+	b, err := os.ReadFile(l.lockPath(fileID))
+	if err != nil {
+		return nil, err
+	}
+	var lock ledgerLock
+	if err := json.Unmarshal(b, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lock: %w", err)
+	}
+	return &lock, nil
+}
+
+func (l *FileLedger) load(fileID string) (*model.LedgerEntry, error) {
+	b, err := os.ReadFile(l.entryPath(fileID))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read ledger entry: %w", err)
+	}
+	var entry model.LedgerEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse ledger entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// save は一時ファイル経由でアトミックに書き込む
+func (l *FileLedger) save(entry *model.LedgerEntry) error {
+	return writeJSONAtomic(l.dir, entry.FileID, entry)
+}
+
+// writeJSONAtomic は {dir}/{name}.json に一時ファイル経由でアトミックに書き込む
+func writeJSONAtomic(dir, name string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", name, err)
+	}
+	tmp, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, name+".json")); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to commit %s: %w", name, err)
+	}
+	return nil
+}
+
+// FindStuckEntries は未完了のまま一定時間更新がなく、リースも切れているエントリを抽出
+func FindStuckEntries(entries []*model.LedgerEntry, now time.Time, stuckAfter time.Duration) []*model.LedgerEntry {
+	var stuck []*model.LedgerEntry
+	for _, e := range entries {
+		if e.State.IsTerminal() {
+			continue
+		}
+		if !e.LeaseUntil.IsZero() && now.Before(e.LeaseUntil) {
+			continue
+		}
+		if now.Sub(e.UpdatedAt) < stuckAfter {
+			continue
+		}
+		stuck = append(stuck, e)
+	}
+	return stuck
+}
+
+// ledgerOwnerID は台帳上の処理者IDを生成（インスタンス＋プロセス単位）
+func ledgerOwnerID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	if rev := os.Getenv("K_REVISION"); rev != "" {
+		host = rev + "/" + host
+	}
+	return fmt.Sprintf("%s/%d", host, os.Getpid())
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+func TestFileLedger_AcquireIsExclusive(t *testing.T) {
+	ctx := context.Background()
+	ledger, err := NewFileLedger(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, ok, err := ledger.Acquire(ctx, "file1", "a", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("first acquire failed: ok=%v err=%v", ok, err)
+	}
+	if entry.State != model.ProcessingStateReceived || entry.Attempts != 1 {
+		t.Fatalf("unexpected entry: state=%s attempts=%d", entry.State, entry.Attempts)
+	}
+
+	if _, ok, err := ledger.Acquire(ctx, "file1", "b", time.Minute); err != nil || ok {
+		t.Fatalf("second acquire should be rejected: ok=%v err=%v", ok, err)
+	}
+
+	if err := ledger.Release(ctx, "file1", "a"); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+
+	entry, ok, err = ledger.Acquire(ctx, "file1", "b", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("acquire after release failed: ok=%v err=%v", ok, err)
+	}
+	if entry.Attempts != 2 {
+		t.Fatalf("expected attempts=2, got %d", entry.Attempts)
+	}
+}
+
+func TestFileLedger_StaleLockIsTakenOver(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	ledger, err := NewFileLedger(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, _ := ledger.Acquire(ctx, "file1", "crashed", -time.Second); !ok {
+		t.Fatalf("initial acquire failed")
+	}
+	if _, ok, err := ledger.Acquire(ctx, "file1", "b", time.Minute); err != nil || !ok {
+		t.Fatalf("expired lease should be taken over: ok=%v err=%v", ok, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "file1.lock")); err != nil {
+		t.Fatalf("lock file should exist: %v", err)
+	}
+}
+
+func TestFileLedger_UpdatePersistsTransitions(t *testing.T) {
+	ctx := context.Background()
+	ledger, err := NewFileLedger(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ledger.Update(ctx, "file1", func(e *model.LedgerEntry) {
+		e.Transition(model.ProcessingStateDownloaded, "")
+		e.Transition(model.ProcessingStateAnalyzed, "")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ledger.Get(ctx, "file1")
+	if err != nil || got == nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if got.State != model.ProcessingStateAnalyzed || len(got.History) != 2 {
+		t.Fatalf("unexpected entry: state=%s history=%d", got.State, len(got.History))
+	}
+
+	missing, err := ledger.Get(ctx, "nope")
+	if err != nil || missing != nil {
+		t.Fatalf("expected nil entry for unknown file, got %v (err=%v)", missing, err)
+	}
+}
+
+func TestFindStuckEntries(t *testing.T) {
+	now := time.Now()
+	entries := []*model.LedgerEntry{
+		{FileID: "done", State: model.ProcessingStateCompleted, UpdatedAt: now.Add(-time.Hour)},
+		{FileID: "stuck", State: model.ProcessingStateRenamed, UpdatedAt: now.Add(-time.Hour)},
+		{FileID: "recent", State: model.ProcessingStateRenamed, UpdatedAt: now.Add(-time.Minute)},
+		{FileID: "leased", State: model.ProcessingStateAnalyzed, UpdatedAt: now.Add(-time.Hour), LeaseUntil: now.Add(time.Minute)},
+	}
+
+	stuck := FindStuckEntries(entries, now, 15*time.Minute)
+	if len(stuck) != 1 || stuck[0].FileID != "stuck" {
+		t.Fatalf("unexpected stuck entries: %v", stuck)
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// reclassifiedSteps は再分類で必ずやり直すステップ（移動先・ファイル名は分類結果で決まる）
+var reclassifiedSteps = []model.StepName{
+	model.StepResolveDestination,
+	model.StepRename,
+	model.StepMove,
+}
+
+// ReclassifyProvisional はAI障害時に仮分類して仕分けたファイルをAIで解析し直す
+// AIがまだ使えないファイルは仮分類のまま残し、次回の実行で再度試す
+func (fs *FileSorter) ReclassifyProvisional(ctx context.Context) (map[string]model.ProcessResult, error) {
+	entries, err := fs.ledger.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]model.ProcessResult)
+	for _, entry := range entries {
+		if !entry.Provisional || entry.State != model.ProcessingStateCompleted {
+			continue
+		}
+		results[entry.FileID] = fs.Reclassify(ctx, entry.FileID)
+	}
+	return results, nil
+}
+
+// Reclassify は仮分類のファイルを1件AIで解析し直し、Inboxに戻して仕分けをやり直す
+// 仮分類では実行しなかった予定・タスク・医療費・家計のステップもAIの結果で実行する
+func (fs *FileSorter) Reclassify(ctx context.Context, fileID string) model.ProcessResult {
+	entry, err := fs.ledger.Get(ctx, fileID)
+	if err != nil {
+		log.Printf("処理台帳の参照失敗: %v", err)
+		return model.ProcessResultError
+	}
+	if entry == nil || !entry.Provisional || entry.State != model.ProcessingStateCompleted {
+		return model.ProcessResultSkipped
+	}
+
+	fileInfo, err := fs.driveClient.GetFile(ctx, fileID)
+	if err != nil {
+		log.Printf("ファイル情報取得失敗: %v", err)
+		return model.ProcessResultError
+	}
+	data, err := fs.driveClient.DownloadFile(ctx, fileID)
+	if err != nil {
+		log.Printf("ファイルダウンロード失敗: %v", err)
+		return model.ProcessResultError
+	}
+	result, bundle, err := fs.analyzeWithGemini(withUsageFileID(ctx, fileID), data, fileInfo)
+	if err != nil {
+		log.Printf("再分類失敗（仮分類のまま残します）: %s: %v", fileInfo.Name, err)
+		return model.ProcessResultError
+	}
+
+	// 台帳より先にInboxへ戻す（台帳の更新に失敗しても、仮分類のまま次回やり直せる）
+	sourceID := fs.household.Current().FolderIDs["SOURCE"]
+	if !contains(fileInfo.Parents, sourceID) {
+		if err := fs.driveClient.MoveFile(ctx, fileID, sourceID); err != nil {
+			log.Printf("Inboxへの移動失敗: %s: %v", fileInfo.Name, err)
+			return model.ProcessResultError
+		}
+	}
+	if _, err := fs.ledger.Update(ctx, fileID, func(e *model.LedgerEntry) {
+		applyReclassification(e, result, bundle)
+	}); err != nil {
+		log.Printf("処理台帳の更新失敗: %v", err)
+		return model.ProcessResultError
+	}
+
+	log.Printf("AIで再分類: %s (%s)", fileInfo.Name, result.Category)
+	return fs.ProcessFile(ctx, fileID)
+}
+
+// applyReclassification はAIの解析結果で台帳の仮分類を置き換え、仕分けをやり直す状態に戻す
+// 実行済みのアクション（Photosへのアップロード・NotebookLM同期）は重複させないよう残し、スキップしたものだけやり直す
+func applyReclassification(e *model.LedgerEntry, result *model.AnalysisResult, bundle *model.DocumentBundle) {
+	previous := ""
+	if e.Analysis != nil {
+		previous = e.Analysis.Category
+	}
+	e.Analysis = result
+	// 予定・タスクはAIで抽出し直す（OCR結果は統合解析で取得できた場合のみ置き換える）
+	e.EventsAndTasks = nil
+	if bundle != nil {
+		e.EventsAndTasks = bundle.EventsAndTasks
+		if bundle.OCRBundle != nil {
+			e.OCRBundle = bundle.OCRBundle
+		}
+	}
+	e.Provisional = false
+	e.DestinationFolderID = ""
+	e.NewFileName = ""
+	for _, name := range reclassifiedSteps {
+		delete(e.Steps, name)
+	}
+	for name, rec := range e.Steps {
+		if rec.Status == model.StepStatusSkipped && name != model.StepDedup && name != model.StepSplit {
+			delete(e.Steps, name)
+		}
+	}
+	e.Transition(model.ProcessingStateAnalyzed, fmt.Sprintf("AIで再分類: %s → %s", previous, result.Category))
+}
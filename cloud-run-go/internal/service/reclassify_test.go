@@ -0,0 +1,58 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+func TestApplyReclassification(t *testing.T) {
+	entry := &model.LedgerEntry{
+		FileID:              "f1",
+		State:               model.ProcessingStateCompleted,
+		Provisional:         true,
+		DestinationFolderID: "photo-other",
+		NewFileName:         "20250601_メモ.pdf",
+		Analysis:            &model.AnalysisResult{Category: "50_写真・その他"},
+		EventsAndTasks:      &model.EventsAndTasks{},
+		OCRBundle:           &model.OCRBundle{OCRText: "ローカルOCR"},
+		Steps: map[model.StepName]*model.StepRecord{
+			model.StepDedup:              {Status: model.StepStatusSucceeded},
+			model.StepSplit:              {Status: model.StepStatusSkipped},
+			model.StepAnalyze:            {Status: model.StepStatusSucceeded},
+			model.StepResolveDestination: {Status: model.StepStatusSucceeded},
+			model.StepRename:             {Status: model.StepStatusSucceeded},
+			model.StepMove:               {Status: model.StepStatusSucceeded},
+			model.StepPhotos:             {Status: model.StepStatusSucceeded},
+			model.StepCalendar:           {Status: model.StepStatusSkipped, Detail: "AI未使用の仮分類"},
+			model.StepTasks:              {Status: model.StepStatusSkipped, Detail: "AI未使用の仮分類"},
+			model.StepNotebookLM:         {Status: model.StepStatusSucceeded},
+		},
+	}
+	result := &model.AnalysisResult{Category: "40_子供・教育", ConfidenceScore: 0.9}
+
+	applyReclassification(entry, result, nil)
+
+	if entry.Provisional || entry.State != model.ProcessingStateAnalyzed || entry.Analysis != result {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+	if entry.DestinationFolderID != "" || entry.NewFileName != "" || entry.EventsAndTasks != nil {
+		t.Fatalf("destination, name and events should be cleared: %+v", entry)
+	}
+	if entry.OCRBundle == nil || entry.OCRBundle.OCRText != "ローカルOCR" {
+		t.Fatalf("OCR bundle should be kept without a combined analysis: %+v", entry.OCRBundle)
+	}
+
+	kept := []model.StepName{model.StepDedup, model.StepSplit, model.StepAnalyze, model.StepPhotos, model.StepNotebookLM}
+	rerun := []model.StepName{model.StepResolveDestination, model.StepRename, model.StepMove, model.StepCalendar, model.StepTasks}
+	for _, name := range kept {
+		if entry.Steps[name] == nil {
+			t.Errorf("step %s should be kept", name)
+		}
+	}
+	for _, name := range rerun {
+		if entry.Steps[name] != nil {
+			t.Errorf("step %s should be re-run", name)
+		}
+	}
+}
@@ -0,0 +1,536 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// SchemaType はレスポンススキーマの型
+type SchemaType string
+
+const (
+	SchemaObject  SchemaType = "object"
+	SchemaArray   SchemaType = "array"
+	SchemaString  SchemaType = "string"
+	SchemaNumber  SchemaType = "number"
+	SchemaBoolean SchemaType = "boolean"
+)
+
+// 文字列の書式（検証・補正に使う）
+const (
+	FormatCompactDate = "yyyymmdd"   // 20250401
+	FormatDate        = "yyyy-mm-dd" // 2025-04-01
+	FormatTime        = "hh:mm"      // 09:30
+)
+
+var formatPatterns = map[string]*regexp.Regexp{
+	FormatCompactDate: regexp.MustCompile(`^\d{8}$`),
+	FormatDate:        regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`),
+	FormatTime:        regexp.MustCompile(`^\d{2}:\d{2}$`),
+}
+
+// ResponseSchema はAIの出力形式（JSON Schemaのサブセット）
+// バックエンドごとの形式（Gemini の ResponseSchema / OpenAI の json_schema）に変換して送信し、
+// 応答の検証・補正にも同じ定義を使う
+type ResponseSchema struct {
+	Type        SchemaType
+	Description string
+	Properties  map[string]*ResponseSchema
+	Required    []string
+	Items       *ResponseSchema
+	Enum        []string
+	Format      string // FormatCompactDate など
+	Nullable    bool   // null（文字列の場合は空文字も）を許可
+	Minimum     *float64
+	Maximum     *float64
+}
+
+func schemaFloat(v float64) *float64 { return &v }
+
+// confidenceSchema は 0.0〜1.0 の信頼度
+func confidenceSchema(desc string) *ResponseSchema {
+	return &ResponseSchema{Type: SchemaNumber, Description: desc, Minimum: schemaFloat(0), Maximum: schemaFloat(1)}
+}
+
+func stringSchema(desc string) *ResponseSchema {
+	return &ResponseSchema{Type: SchemaString, Description: desc}
+}
+
+// AnalysisSchema は分類結果（model.AnalysisResult）のスキーマ
+// categories が空の場合はカテゴリを列挙しない
+func AnalysisSchema(categories []string) *ResponseSchema {
+	return &ResponseSchema{
+		Type: SchemaObject,
+		Properties: map[string]*ResponseSchema{
+			"category":           {Type: SchemaString, Description: "カテゴリ名", Enum: categories},
+			"child_name":         stringSchema("お子様の名前（名寄せ後の正規名。複数または不明時は空文字）"),
+			"target_adult":       stringSchema("大人の名前（名寄せ後の正規名。不明時は空文字）"),
+			"target_grade_class": stringSchema("対象となる学年やクラス名（例：小2、くるみ組）"),
+			"issuer":             stringSchema("発行元（学校・園・塾・クラブ名、先生の名前など。不明時は空文字）"),
+			"sub_category":       {Type: SchemaString, Description: "サブカテゴリ（40_子供・教育・60_ヘルス・医療の場合のみ）", Enum: append(append([]string{}, config.SubCategories...), config.MedicalSubCategories...), Nullable: true},
+			"is_photo":           {Type: SchemaBoolean},
+			"date":               {Type: SchemaString, Description: "書類の日付（YYYYMMDD）", Format: FormatCompactDate, Nullable: true},
+			"summary":            stringSchema("要約（15文字以内、ファイル名に使用）"),
+			"confidence_score":   confidenceSchema("解析結果の信頼度（0.0〜1.0）"),
+		},
+		Required: []string{"category", "child_name", "target_adult", "target_grade_class", "sub_category", "is_photo", "date", "summary", "confidence_score"},
+	}
+}
+
+// EventsAndTasksSchema は予定・タスク抽出結果（model.EventsAndTasks）のスキーマ
+func EventsAndTasksSchema() *ResponseSchema {
+	return &ResponseSchema{
+		Type: SchemaObject,
+		Properties: map[string]*ResponseSchema{
+			"events": {Type: SchemaArray, Items: &ResponseSchema{
+				Type: SchemaObject,
+				Properties: map[string]*ResponseSchema{
+					"title":       stringSchema("イベントタイトル"),
+					"date":        {Type: SchemaString, Description: "日付（YYYY-MM-DD）", Format: FormatDate},
+					"start_time":  {Type: SchemaString, Description: "開始時刻（HH:MM、不明な場合は null）", Format: FormatTime, Nullable: true},
+					"end_time":    {Type: SchemaString, Description: "終了時刻（HH:MM、不明な場合は null）", Format: FormatTime, Nullable: true},
+					"location":    {Type: SchemaString, Description: "場所（不明な場合は null）", Nullable: true},
+					"description": stringSchema("詳細説明"),
+				},
+				Required: []string{"title", "date"},
+			}},
+			"tasks": {Type: SchemaArray, Items: &ResponseSchema{
+				Type: SchemaObject,
+				Properties: map[string]*ResponseSchema{
+					"title":    stringSchema("タスクタイトル（例：○○の提出）"),
+					"due_date": {Type: SchemaString, Description: "期限（YYYY-MM-DD）", Format: FormatDate},
+					"notes":    stringSchema("備考"),
+				},
+				Required: []string{"title", "due_date"},
+			}},
+		},
+		Required: []string{"events", "tasks"},
+	}
+}
+
+// PageClassificationSchema はページ単位の判定結果（model.PageClassifications）のスキーマ
+func PageClassificationSchema() *ResponseSchema {
+	return &ResponseSchema{
+		Type: SchemaObject,
+		Properties: map[string]*ResponseSchema{
+			"pages": {Type: SchemaArray, Items: &ResponseSchema{
+				Type: SchemaObject,
+				Properties: map[string]*ResponseSchema{
+					"page":                {Type: SchemaNumber, Description: "ページ番号（1始まり）", Minimum: schemaFloat(1)},
+					"starts_new_document": {Type: SchemaBoolean, Description: "このページから別の書類が始まるか"},
+					"title":               stringSchema("このページが属する書類の件名"),
+				},
+				Required: []string{"page", "starts_new_document"},
+			}},
+		},
+		Required: []string{"pages"},
+	}
+}
+
+// MedicalExpenseSchema は医療費の領収書の読み取り結果のスキーマ
+func MedicalExpenseSchema() *ResponseSchema {
+	return &ResponseSchema{
+		Type: SchemaObject,
+		Properties: map[string]*ResponseSchema{
+			"date":     {Type: SchemaString, Description: "支払日（YYYY-MM-DD）", Format: FormatDate},
+			"patient":  stringSchema("医療を受けた人の氏名（不明な場合は空文字）"),
+			"provider": stringSchema("病院・薬局などの名称"),
+			"kind": {Type: SchemaString, Description: "医療費の区分", Enum: []string{
+				model.MedicalExpenseKindTreatment, model.MedicalExpenseKindMedicine, model.MedicalExpenseKindCare, model.MedicalExpenseKindOther,
+			}},
+			"amount":        {Type: SchemaNumber, Description: "支払った金額（円、整数）", Minimum: schemaFloat(0)},
+			"reimbursement": {Type: SchemaNumber, Description: "保険金・高額療養費などで補填される金額（円、整数。なければ0）", Minimum: schemaFloat(0)},
+		},
+		Required: []string{"date", "patient", "provider", "kind", "amount", "reimbursement"},
+	}
+}
+
+// FinanceSchema は家計の書類の読み取り結果（model.FinanceRecord）のスキーマ
+func FinanceSchema() *ResponseSchema {
+	return &ResponseSchema{
+		Type: SchemaObject,
+		Properties: map[string]*ResponseSchema{
+			"document_type": {Type: SchemaString, Description: "書類の種類", Enum: []string{
+				model.FinanceDocumentBill, model.FinanceDocumentStatement, model.FinanceDocumentTax, model.FinanceDocumentOther,
+			}},
+			"payee":        stringSchema("支払先・発行元の名称"),
+			"amount":       {Type: SchemaNumber, Description: "請求・支払金額（円、整数。なければ0）", Minimum: schemaFloat(0)},
+			"due_date":     {Type: SchemaString, Description: "支払期日・引き落とし日（YYYY-MM-DD、なければ null）", Format: FormatDate, Nullable: true},
+			"period_start": {Type: SchemaString, Description: "請求対象期間の開始日（YYYY-MM-DD、なければ null）", Format: FormatDate, Nullable: true},
+			"period_end":   {Type: SchemaString, Description: "請求対象期間の終了日（YYYY-MM-DD、なければ null）", Format: FormatDate, Nullable: true},
+			"account":      {Type: SchemaString, Description: "口座番号・カード番号の下4桁（なければ null）", Nullable: true},
+		},
+		Required: []string{"document_type", "payee", "amount", "due_date", "period_start", "period_end", "account"},
+	}
+}
+
+// OCRBundleSchema はOCR結果（model.OCRBundle）のスキーマ
+func OCRBundleSchema() *ResponseSchema {
+	return &ResponseSchema{
+		Type: SchemaObject,
+		Properties: map[string]*ResponseSchema{
+			"ocr_text":         stringSchema("画像/ドキュメントに含まれるすべてのテキスト"),
+			"facts":            {Type: SchemaArray, Items: stringSchema("本文から抽出した具体的な項目")},
+			"summary":          stringSchema("要約（1-2文、曖昧な場合は空文字）"),
+			"confidence_score": confidenceSchema("読み取り結果の信頼度（0.0〜1.0）"),
+			"quality": {
+				Type: SchemaObject,
+				Properties: map[string]*ResponseSchema{
+					"uncertain":        {Type: SchemaBoolean},
+					"needs_high_model": {Type: SchemaBoolean},
+					"notes":            stringSchema("読み取りに問題があれば記載"),
+				},
+				Required: []string{"uncertain", "needs_high_model", "notes"},
+			},
+		},
+		Required: []string{"ocr_text", "facts", "summary", "confidence_score", "quality"},
+	}
+}
+
+// DocumentBundleSchema は統合解析結果（model.DocumentBundle）のスキーマ
+func DocumentBundleSchema(analysis *ResponseSchema) *ResponseSchema {
+	if analysis == nil {
+		analysis = AnalysisSchema(nil)
+	}
+	return &ResponseSchema{
+		Type: SchemaObject,
+		Properties: map[string]*ResponseSchema{
+			"analysis":         analysis,
+			"events_and_tasks": EventsAndTasksSchema(),
+			"ocr_bundle":       OCRBundleSchema(),
+		},
+		Required: []string{"analysis", "events_and_tasks", "ocr_bundle"},
+	}
+}
+
+// JSONSchema は標準のJSON Schema（OpenAI互換APIの json_schema 用）に変換する
+func (s *ResponseSchema) JSONSchema() map[string]any {
+	out := map[string]any{"type": string(s.Type)}
+	if s.Nullable && len(s.Enum) == 0 {
+		out["type"] = []string{string(s.Type), "null"}
+	}
+	if s.Description != "" {
+		out["description"] = s.Description
+	}
+	if len(s.Enum) > 0 {
+		enum := make([]any, 0, len(s.Enum)+2)
+		for _, e := range s.Enum {
+			enum = append(enum, e)
+		}
+		if s.Nullable {
+			out["type"] = []string{string(s.Type), "null"}
+			enum = append(enum, "", nil)
+		}
+		out["enum"] = enum
+	}
+	if p, ok := formatPatterns[s.Format]; ok {
+		out["pattern"] = p.String()
+		if s.Nullable {
+			out["pattern"] = "^$|" + p.String()
+		}
+	}
+	if s.Minimum != nil {
+		out["minimum"] = *s.Minimum
+	}
+	if s.Maximum != nil {
+		out["maximum"] = *s.Maximum
+	}
+	if s.Items != nil {
+		out["items"] = s.Items.JSONSchema()
+	}
+	if s.Properties != nil {
+		props := make(map[string]any, len(s.Properties))
+		for name, p := range s.Properties {
+			props[name] = p.JSONSchema()
+		}
+		out["properties"] = props
+		out["required"] = s.Required
+	}
+	return out
+}
+
+// Validate はデコード済みのJSON値（map[string]any 等）をスキーマで検証し、違反内容を返す
+func (s *ResponseSchema) Validate(v any) []string {
+	var errs []string
+	s.validate("$", v, &errs)
+	return errs
+}
+
+func (s *ResponseSchema) validate(path string, v any, errs *[]string) {
+	if v == nil {
+		if !s.Nullable {
+			*errs = append(*errs, fmt.Sprintf("%s: null は使えません", path))
+		}
+		return
+	}
+
+	switch s.Type {
+	case SchemaObject:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			*errs = append(*errs, fmt.Sprintf("%s: オブジェクトである必要があります", path))
+			return
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				*errs = append(*errs, fmt.Sprintf("%s.%s: 必須項目がありません", path, name))
+			}
+		}
+		for _, name := range sortedKeys(s.Properties) {
+			if pv, ok := obj[name]; ok {
+				s.Properties[name].validate(path+"."+name, pv, errs)
+			}
+		}
+	case SchemaArray:
+		arr, ok := v.([]any)
+		if !ok {
+			*errs = append(*errs, fmt.Sprintf("%s: 配列である必要があります", path))
+			return
+		}
+		if s.Items != nil {
+			for i, item := range arr {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+			}
+		}
+	case SchemaString:
+		str, ok := v.(string)
+		if !ok {
+			*errs = append(*errs, fmt.Sprintf("%s: 文字列である必要があります", path))
+			return
+		}
+		if str == "" && s.Nullable {
+			return
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, str) {
+			*errs = append(*errs, fmt.Sprintf("%s: %q は %s のいずれかである必要があります", path, str, strings.Join(s.Enum, ", ")))
+		}
+		if p, ok := formatPatterns[s.Format]; ok {
+			if !p.MatchString(str) || !validFormat(s.Format, str) {
+				*errs = append(*errs, fmt.Sprintf("%s: %q は %s 形式である必要があります", path, str, strings.ToUpper(s.Format)))
+			}
+		}
+	case SchemaNumber:
+		n, ok := v.(float64)
+		if !ok {
+			*errs = append(*errs, fmt.Sprintf("%s: 数値である必要があります", path))
+			return
+		}
+		if (s.Minimum != nil && n < *s.Minimum) || (s.Maximum != nil && n > *s.Maximum) {
+			*errs = append(*errs, fmt.Sprintf("%s: %v は範囲外です", path, n))
+		}
+	case SchemaBoolean:
+		if _, ok := v.(bool); !ok {
+			*errs = append(*errs, fmt.Sprintf("%s: true/false である必要があります", path))
+		}
+	}
+}
+
+// Repair は機械的に直せる違反を補正した値を返す
+//   - 型の取り違え（"0.9" → 0.9、"true" → true）
+//   - 日付・時刻の書式（2025/4/1 → 20250401、9:00 → 09:00）
+//   - 列挙値の表記ゆれ（子供・教育 → 40_子供・教育。候補が1つに絞れる場合のみ）
+//   - 範囲外の数値（百分率は 0〜1 に換算、それ以外は丸める）
+//   - 不要な null・欠けている文字列/真偽値/配列は空値で補う
+//
+// 列挙値・数値・オブジェクトの欠落は補正せず、検証エラーのままにする
+func (s *ResponseSchema) Repair(v any) any {
+	switch s.Type {
+	case SchemaObject:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return v
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				if zero, ok := s.Properties[name].zeroValue(); ok {
+					obj[name] = zero
+				}
+			}
+		}
+		for name, p := range s.Properties {
+			if pv, ok := obj[name]; ok {
+				obj[name] = p.Repair(pv)
+			}
+		}
+		return obj
+	case SchemaArray:
+		if v == nil && !s.Nullable {
+			return []any{}
+		}
+		arr, ok := v.([]any)
+		if !ok || s.Items == nil {
+			return v
+		}
+		for i := range arr {
+			arr[i] = s.Items.Repair(arr[i])
+		}
+		return arr
+	case SchemaString:
+		if v == nil {
+			if s.Nullable || len(s.Enum) > 0 {
+				return v
+			}
+			return ""
+		}
+		str, ok := v.(string)
+		if !ok {
+			if n, isNum := v.(float64); isNum {
+				str = strconv.FormatFloat(n, 'f', -1, 64)
+			} else {
+				return v
+			}
+		}
+		str = strings.TrimSpace(str)
+		if str == "" {
+			return str
+		}
+		if len(s.Enum) > 0 {
+			return repairEnum(s.Enum, str)
+		}
+		if s.Format != "" {
+			if fixed, ok := normalizeFormat(s.Format, str); ok {
+				return fixed
+			}
+		}
+		return str
+	case SchemaNumber:
+		n, ok := v.(float64)
+		if str, isStr := v.(string); isStr {
+			parsed, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(str), "%"), 64)
+			if err != nil {
+				return v
+			}
+			n, ok = parsed, true
+		}
+		if !ok {
+			return v
+		}
+		if s.Maximum != nil && *s.Maximum == 1 && n > 1 && n <= 100 {
+			n /= 100
+		}
+		if s.Minimum != nil {
+			n = math.Max(n, *s.Minimum)
+		}
+		if s.Maximum != nil {
+			n = math.Min(n, *s.Maximum)
+		}
+		return n
+	case SchemaBoolean:
+		if v == nil {
+			return false
+		}
+		if str, ok := v.(string); ok {
+			if b, err := strconv.ParseBool(strings.TrimSpace(str)); err == nil {
+				return b
+			}
+		}
+		return v
+	}
+	return v
+}
+
+// zeroValue は欠けている項目を補う空値を返す（補えない型は false）
+func (s *ResponseSchema) zeroValue() (any, bool) {
+	if s == nil {
+		return nil, false
+	}
+	switch s.Type {
+	case SchemaString:
+		if len(s.Enum) > 0 && !s.Nullable {
+			return nil, false
+		}
+		return "", true
+	case SchemaBoolean:
+		return false, true
+	case SchemaArray:
+		return []any{}, true
+	}
+	return nil, false
+}
+
+// repairEnum は列挙値の表記ゆれを補正する（候補が1つに絞れない場合はそのまま返す）
+func repairEnum(enum []string, str string) string {
+	if contains(enum, str) {
+		return str
+	}
+	var candidates []string
+	for _, e := range enum {
+		if strings.EqualFold(e, str) || strings.Contains(e, str) || strings.Contains(str, e) {
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+	return str
+}
+
+var (
+	datePartsPattern = regexp.MustCompile(`^(\d{4})\D*?(\d{1,2})\D*?(\d{1,2})\D*$`)
+	timePartsPattern = regexp.MustCompile(`^(\d{1,2})\s*(?::|時)\s*(\d{2})?\s*分?$`)
+)
+
+// normalizeFormat は日付・時刻を指定の書式に揃える
+func normalizeFormat(format, str string) (string, bool) {
+	switch format {
+	case FormatCompactDate, FormatDate:
+		m := datePartsPattern.FindStringSubmatch(str)
+		if m == nil {
+			return "", false
+		}
+		y, _ := strconv.Atoi(m[1])
+		mo, _ := strconv.Atoi(m[2])
+		d, _ := strconv.Atoi(m[3])
+		t := time.Date(y, time.Month(mo), d, 0, 0, 0, 0, time.UTC)
+		if t.Month() != time.Month(mo) || t.Day() != d {
+			return "", false
+		}
+		if format == FormatCompactDate {
+			return t.Format("20060102"), true
+		}
+		return t.Format("2006-01-02"), true
+	case FormatTime:
+		m := timePartsPattern.FindStringSubmatch(str)
+		if m == nil {
+			return "", false
+		}
+		h, _ := strconv.Atoi(m[1])
+		min := 0
+		if m[2] != "" {
+			min, _ = strconv.Atoi(m[2])
+		}
+		if h > 23 || min > 59 {
+			return "", false
+		}
+		return fmt.Sprintf("%02d:%02d", h, min), true
+	}
+	return "", false
+}
+
+// validFormat は書式に合った文字列が実在する日付・時刻かを確認する
+func validFormat(format, str string) bool {
+	layouts := map[string]string{
+		FormatCompactDate: "20060102",
+		FormatDate:        "2006-01-02",
+		FormatTime:        "15:04",
+	}
+	_, err := time.Parse(layouts[format], str)
+	return err == nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
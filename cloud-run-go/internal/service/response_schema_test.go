@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testCategories = []string{"10_マネー・税務", "30_ライフ・行政", "40_子供・教育", "50_写真・その他"}
+
+func TestResponseSchema_RepairAndValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		schema    *ResponseSchema
+		input     string
+		wantValid bool
+		check     func(t *testing.T, v map[string]any)
+	}{
+		{
+			name:      "valid analysis",
+			schema:    AnalysisSchema(testCategories),
+			input:     `{"category":"40_子供・教育","child_name":"","target_adult":"","target_grade_class":"","sub_category":"01_お便り・スケジュール","is_photo":false,"date":"20250401","summary":"遠足","confidence_score":0.9}`,
+			wantValid: true,
+		},
+		{
+			name:      "repairs dates, enums, numbers and missing strings",
+			schema:    AnalysisSchema(testCategories),
+			input:     `{"category":"子供・教育","sub_category":null,"is_photo":"false","date":"2025年4月1日","summary":"遠足","confidence_score":"85"}`,
+			wantValid: true,
+			check: func(t *testing.T, v map[string]any) {
+				if v["category"] != "40_子供・教育" || v["date"] != "20250401" || v["confidence_score"] != 0.85 || v["is_photo"] != false || v["child_name"] != "" {
+					t.Fatalf("unexpected repair: %+v", v)
+				}
+			},
+		},
+		{
+			name:      "unknown category is a violation",
+			schema:    AnalysisSchema(testCategories),
+			input:     `{"category":"99_不明","child_name":"","target_adult":"","target_grade_class":"","sub_category":"","is_photo":false,"date":"","summary":"","confidence_score":0.5}`,
+			wantValid: false,
+		},
+		{
+			name:      "missing confidence is a violation",
+			schema:    AnalysisSchema(testCategories),
+			input:     `{"category":"30_ライフ・行政","date":"20250401"}`,
+			wantValid: false,
+		},
+		{
+			name:      "invalid calendar date is a violation",
+			schema:    AnalysisSchema(testCategories),
+			input:     `{"category":"30_ライフ・行政","date":"20250231","confidence_score":0.9}`,
+			wantValid: false,
+		},
+		{
+			name:      "repairs event times",
+			schema:    EventsAndTasksSchema(),
+			input:     `{"events":[{"title":"運動会","date":"2025/10/4","start_time":"9時","end_time":null}],"tasks":null}`,
+			wantValid: true,
+			check: func(t *testing.T, v map[string]any) {
+				ev := v["events"].([]any)[0].(map[string]any)
+				if ev["date"] != "2025-10-04" || ev["start_time"] != "09:00" || ev["end_time"] != nil {
+					t.Fatalf("unexpected event: %+v", ev)
+				}
+				if tasks, ok := v["tasks"].([]any); !ok || len(tasks) != 0 {
+					t.Fatalf("tasks = %#v, want empty array", v["tasks"])
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var raw any
+			if err := json.Unmarshal([]byte(tt.input), &raw); err != nil {
+				t.Fatal(err)
+			}
+			repaired := tt.schema.Repair(raw)
+			violations := tt.schema.Validate(repaired)
+			if (len(violations) == 0) != tt.wantValid {
+				t.Fatalf("violations = %v, wantValid %v", violations, tt.wantValid)
+			}
+			if tt.check != nil {
+				tt.check(t, repaired.(map[string]any))
+			}
+		})
+	}
+}
+
+func TestAIRouter_SchemaRetry(t *testing.T) {
+	valid := `{"category":"30_ライフ・行政","child_name":"","target_adult":"","target_grade_class":"","sub_category":"","is_photo":false,"date":"20250401","summary":"通知","confidence_score":0.9}`
+
+	t.Run("retries with violations in the prompt", func(t *testing.T) {
+		backend := NewFakeBackend()
+		backend.Respond = func(req GenerateRequest) (string, error) {
+			if strings.Contains(req.Prompt, "前回の出力の問題") {
+				return valid, nil
+			}
+			return `{"category":"99_不明"`, nil
+		}
+		router := NewAIRouterWithBackend(backend)
+
+		result, err := router.AnalyzeDocument(context.Background(), []byte("img"), "image/jpeg", "prompt", AnalysisSchema(testCategories), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		calls := backend.Calls()
+		if result.Category != "30_ライフ・行政" || len(calls) != 2 {
+			t.Fatalf("result = %+v, calls = %d", result, len(calls))
+		}
+		if calls[0].Schema == nil || calls[0].Schema.Properties["category"].Enum[0] != "10_マネー・税務" {
+			t.Fatal("schema with household categories should be sent to the backend")
+		}
+	})
+
+	t.Run("gives up after retries", func(t *testing.T) {
+		backend := NewFakeBackend()
+		backend.Respond = func(req GenerateRequest) (string, error) {
+			return `{"category":"99_不明","confidence_score":0.9}`, nil
+		}
+		router := NewAIRouterWithBackend(backend)
+
+		_, err := router.AnalyzeDocument(context.Background(), []byte("img"), "image/jpeg", "prompt", AnalysisSchema(testCategories), false)
+		if !errors.Is(err, ErrSchemaViolation) {
+			t.Fatalf("err = %v, want ErrSchemaViolation", err)
+		}
+	})
+
+	t.Run("OCR falls back to plain text", func(t *testing.T) {
+		backend := NewFakeBackend()
+		backend.Respond = func(req GenerateRequest) (string, error) {
+			return "読み取ったテキスト", nil
+		}
+		router := NewAIRouterWithBackend(backend)
+
+		bundle, err := router.ExtractOCRBundle(context.Background(), []byte("img"), "image/jpeg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bundle.OCRText != "読み取ったテキスト" || bundle.ConfidenceScore != 0.5 {
+			t.Fatalf("unexpected bundle: %+v", bundle)
+		}
+	})
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// RetentionOptions は保存期間の整理ジョブの実行条件
+type RetentionOptions struct {
+	Now    time.Time
+	DryRun bool // 移動・削除・予告の記録を行わず、対象だけを返す
+	// 削除の予告の送信先。nil の場合は法定の保存期間がある書類の予告・削除を行わない
+	Warner RetentionWarner
+}
+
+// RetentionWarner は法定の保存期間がある書類の削除を予告する
+// 送信できた書類だけを返し、途中で送信に失敗した場合はそれまでに送信できた書類とエラーを返す
+type RetentionWarner interface {
+	WarnRetention(items []model.RetentionItem) ([]model.RetentionItem, error)
+}
+
+// retentionCandidate は保存期限を過ぎた書類
+type retentionCandidate struct {
+	entry   *model.LedgerEntry
+	policy  *config.RetentionPolicy
+	expires time.Time
+}
+
+// ApplyRetention は処理台帳の仕分け済みの書類のうち、保存期限を過ぎたものをアーカイブ・削除する
+// 法定の保存期間がある書類は削除を予告し、Retention.DeleteGraceDays 日後の実行で削除する
+func (fs *FileSorter) ApplyRetention(ctx context.Context, opts RetentionOptions) (*model.RetentionResult, error) {
+	entries, err := fs.ledger.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	household := fs.household.Current()
+	candidates := fs.retentionCandidates(household, entries, opts.Now)
+
+	result := &model.RetentionResult{
+		DryRun:   opts.DryRun,
+		Archived: []model.RetentionItem{},
+		Trashed:  []model.RetentionItem{},
+		Warned:   []model.RetentionItem{},
+		Pending:  []model.RetentionItem{},
+		Failed:   []model.RetentionItem{},
+	}
+	grace := time.Duration(config.Retention.DeleteGraceDays) * 24 * time.Hour
+	processed := 0
+	var toWarn []retentionCandidate
+	for _, c := range candidates {
+		item := retentionItem(c)
+		state := c.entry.Retention
+		legal := c.policy.Action == config.RetentionActionTrash && c.policy.LegalYears > 0
+
+		// 予告済みで猶予期間中の書類は上限に数えない
+		if legal && state != nil && !state.WarnedAt.IsZero() && opts.Now.Before(state.WarnedAt.Add(grace)) {
+			item.DeleteAfter = state.WarnedAt.Add(grace).Format("2006-01-02")
+			result.Pending = append(result.Pending, item)
+			continue
+		}
+		if legal && opts.Warner == nil {
+			log.Printf("Warning: 削除の予告を通知できないため、法定の保存期間がある書類は削除しません: %s", item.FileName)
+			continue
+		}
+		if processed >= config.Retention.MaxPerRun {
+			result.Deferred++
+			continue
+		}
+		processed++
+
+		switch {
+		case legal && (state == nil || state.WarnedAt.IsZero()):
+			// 予告は送信できた書類だけを記録するため、まとめて後で送る
+			toWarn = append(toWarn, c)
+		case c.policy.Action == config.RetentionActionArchive:
+			if err := fs.archiveExpired(ctx, c, household.FolderIDs["ARCHIVE"], opts); err != nil {
+				item.Error = err.Error()
+				result.Failed = append(result.Failed, item)
+				continue
+			}
+			result.Archived = append(result.Archived, item)
+		default:
+			if err := fs.trashExpired(ctx, c, opts); err != nil {
+				item.Error = err.Error()
+				result.Failed = append(result.Failed, item)
+				continue
+			}
+			result.Trashed = append(result.Trashed, item)
+		}
+	}
+
+	fs.warnRetention(ctx, toWarn, opts, result)
+
+	log.Printf("保存期間の整理: archived=%d, trashed=%d, warned=%d, pending=%d, failed=%d, deferred=%d (dry_run=%v)",
+		len(result.Archived), len(result.Trashed), len(result.Warned), len(result.Pending), len(result.Failed), result.Deferred, opts.DryRun)
+	return result, nil
+}
+
+// warnRetention は削除を予告し、予告を送信できた書類だけに予告日を記録する
+// 送信できなかった書類は失敗として返し、次回の実行で改めて予告する
+func (fs *FileSorter) warnRetention(ctx context.Context, candidates []retentionCandidate, opts RetentionOptions, result *model.RetentionResult) {
+	if len(candidates) == 0 {
+		return
+	}
+	grace := time.Duration(config.Retention.DeleteGraceDays) * 24 * time.Hour
+	items := make([]model.RetentionItem, len(candidates))
+	for i, c := range candidates {
+		items[i] = retentionItem(c)
+		items[i].DeleteAfter = opts.Now.Add(grace).Format("2006-01-02")
+	}
+	if opts.DryRun {
+		result.Warned = append(result.Warned, items...)
+		return
+	}
+
+	delivered, sendErr := opts.Warner.WarnRetention(items)
+	if sendErr != nil {
+		log.Printf("Warning: 削除予告の送信失敗（%d/%d件送信済み）: %v", len(delivered), len(items), sendErr)
+	}
+	sent := make(map[string]bool, len(delivered))
+	for _, item := range delivered {
+		sent[item.FileID] = true
+	}
+	for i, c := range candidates {
+		item := items[i]
+		if !sent[item.FileID] {
+			item.Error = "削除予告を送信できませんでした"
+			if sendErr != nil {
+				item.Error += ": " + sendErr.Error()
+			}
+			result.Failed = append(result.Failed, item)
+			continue
+		}
+		if err := fs.markRetention(ctx, c, func(s *model.RetentionState) { s.WarnedAt = opts.Now }); err != nil {
+			item.Error = err.Error()
+			result.Failed = append(result.Failed, item)
+			continue
+		}
+		result.Warned = append(result.Warned, item)
+	}
+}
+
+// retentionCandidates は保存期限を過ぎ、まだ整理していない書類を期限の古い順に返す
+func (fs *FileSorter) retentionCandidates(household *config.Household, entries []*model.LedgerEntry, now time.Time) []retentionCandidate {
+	var candidates []retentionCandidate
+	for _, e := range entries {
+		if e.State != model.ProcessingStateCompleted && e.State != model.ProcessingStateMoved {
+			continue
+		}
+		if e.Analysis == nil || e.DestinationFolderID == "" {
+			continue
+		}
+		if e.Retention != nil && !e.Retention.DoneAt.IsZero() {
+			continue
+		}
+		policy := household.RetentionPolicyFor(e.Analysis.Category, e.Analysis.SubCategory)
+		if policy == nil {
+			continue
+		}
+		if policy.Action == config.RetentionActionArchive && e.DestinationFolderID == household.FolderIDs["ARCHIVE"] {
+			continue
+		}
+		var expires time.Time
+		if policy.LegalYears > 0 {
+			expires = legalRetentionExpiry(documentDate(e).Year(), policy.Years)
+		} else {
+			expires = retentionExpiry(fs.documentFiscalYear(e), policy.Years)
+		}
+		// 保存期限の日の終わりまでは保存する
+		if now.Before(expires.AddDate(0, 0, 1)) {
+			continue
+		}
+		candidates = append(candidates, retentionCandidate{entry: e, policy: policy, expires: expires})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if !candidates[i].expires.Equal(candidates[j].expires) {
+			return candidates[i].expires.Before(candidates[j].expires)
+		}
+		return candidates[i].entry.FileID < candidates[j].entry.FileID
+	})
+	return candidates
+}
+
+// documentFiscalYear は書類の年度を返す（書類の日付がない場合は処理台帳に登録した日）
+func (fs *FileSorter) documentFiscalYear(e *model.LedgerEntry) int {
+	if e.Analysis.FiscalYear > 0 {
+		return e.Analysis.FiscalYear
+	}
+	return fs.gradeManager.CalculateFiscalYear(documentDate(e).Format("20060102"))
+}
+
+// documentDate は書類の日付を返す（書類の日付がない場合は処理台帳に登録した日）
+func documentDate(e *model.LedgerEntry) time.Time {
+	if date, err := time.ParseInLocation("20060102", e.Analysis.Date, time.Local); err == nil {
+		return date
+	}
+	return e.CreatedAt
+}
+
+// retentionExpiry は保存期限（その年度末から years 年後の3月31日）を返す
+func retentionExpiry(fiscalYear, years int) time.Time {
+	return time.Date(fiscalYear+years+1, time.March, 31, 0, 0, 0, 0, time.Local)
+}
+
+// legalRetentionExpiry は法定の保存期限を返す
+// 税務書類の保存期間は年度ではなく、書類の日付の年（暦年）の確定申告期限（翌年3月15日）から数える
+func legalRetentionExpiry(year, years int) time.Time {
+	return time.Date(year+years+1, time.March, 15, 0, 0, 0, 0, time.Local)
+}
+
+func retentionItem(c retentionCandidate) model.RetentionItem {
+	name := c.entry.NewFileName
+	if name == "" {
+		name = c.entry.FileName
+	}
+	return model.RetentionItem{
+		FileID:      c.entry.FileID,
+		FileName:    name,
+		Category:    c.entry.Analysis.Category,
+		SubCategory: c.entry.Analysis.SubCategory,
+		ExpiresOn:   c.expires.Format("2006-01-02"),
+		LegalYears:  c.policy.LegalYears,
+	}
+}
+
+// archiveExpired は書類をアーカイブフォルダへ移動する
+func (fs *FileSorter) archiveExpired(ctx context.Context, c retentionCandidate, archiveFolderID string, opts RetentionOptions) error {
+	if opts.DryRun {
+		return nil
+	}
+	if err := fs.driveClient.MoveFile(ctx, c.entry.FileID, archiveFolderID); err != nil {
+		return fmt.Errorf("アーカイブへの移動失敗: %w", err)
+	}
+	log.Printf("保存期限切れの書類をアーカイブ: %s", c.entry.FileID)
+	return fs.markRetention(ctx, c, func(s *model.RetentionState) {
+		s.DoneAt = opts.Now
+	}, func(e *model.LedgerEntry) {
+		e.DestinationFolderID = archiveFolderID
+	})
+}
+
+// trashExpired は書類をゴミ箱へ移動する
+func (fs *FileSorter) trashExpired(ctx context.Context, c retentionCandidate, opts RetentionOptions) error {
+	if opts.DryRun {
+		return nil
+	}
+	if err := fs.driveClient.TrashFile(ctx, c.entry.FileID); err != nil {
+		return fmt.Errorf("ゴミ箱への移動失敗: %w", err)
+	}
+	log.Printf("保存期限切れの書類を削除: %s", c.entry.FileID)
+	return fs.markRetention(ctx, c, func(s *model.RetentionState) {
+		s.DoneAt = opts.Now
+	})
+}
+
+// markRetention は処理台帳のエントリに整理状況を記録する
+func (fs *FileSorter) markRetention(ctx context.Context, c retentionCandidate, fn func(*model.RetentionState), extra ...func(*model.LedgerEntry)) error {
+	_, err := fs.ledger.Update(ctx, c.entry.FileID, func(e *model.LedgerEntry) {
+		if e.Retention == nil {
+			e.Retention = &model.RetentionState{}
+		}
+		e.Retention.Action = c.policy.Action
+		e.Retention.ExpiresOn = c.expires.Format("2006-01-02")
+		fn(e.Retention)
+		for _, f := range extra {
+			f(e)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("処理台帳の更新失敗: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// fakeRetentionWarner は先頭から limit 件（0 は無制限）だけ送信できたことにする
+type fakeRetentionWarner struct {
+	limit int
+	sent  []model.RetentionItem
+}
+
+func (w *fakeRetentionWarner) WarnRetention(items []model.RetentionItem) ([]model.RetentionItem, error) {
+	if w.limit > 0 && len(items) > w.limit {
+		w.sent = append(w.sent, items[:w.limit]...)
+		return items[:w.limit], errors.New("webhook down")
+	}
+	w.sent = append(w.sent, items...)
+	return items, nil
+}
+
+func newRetentionTestSorter(t *testing.T, entries ...*model.LedgerEntry) *FileSorter {
+	t.Helper()
+	ledger, err := NewFileLedger(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if _, err := ledger.Update(context.Background(), e.FileID, func(entry *model.LedgerEntry) { *entry = *e }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	household := newTestHousehold(t)
+	return &FileSorter{ledger: ledger, household: household, gradeManager: NewGradeManager(household)}
+}
+
+func completedEntry(fileID, category, subCategory, date, folderID string) *model.LedgerEntry {
+	return &model.LedgerEntry{
+		FileID:              fileID,
+		NewFileName:         fileID + ".pdf",
+		State:               model.ProcessingStateCompleted,
+		DestinationFolderID: folderID,
+		Analysis:            &model.AnalysisResult{Category: category, SubCategory: subCategory, Date: date},
+	}
+}
+
+func TestApplyRetention_DryRun(t *testing.T) {
+	archiveID := newTestHousehold(t).Current().FolderIDs["ARCHIVE"]
+	fs := newRetentionTestSorter(t,
+		completedEntry("newsletter", "40_子供・教育", "01_お便り・スケジュール", "20240510", "edu"),
+		completedEntry("newsletter-current", "40_子供・教育", "01_お便り・スケジュール", "20250410", "edu"),
+		completedEntry("newsletter-archived", "40_子供・教育", "01_お便り・スケジュール", "20230510", archiveID),
+		completedEntry("submission", "40_子供・教育", "02_提出・手続き・重要", "20200510", "edu"),
+		completedEntry("tax", "10_マネー・税務", "", "20170601", "money"),
+		&model.LedgerEntry{FileID: "failed", State: model.ProcessingStateDeadLettered, Analysis: &model.AnalysisResult{Category: "10_マネー・税務", Date: "20100101"}},
+	)
+
+	now := time.Date(2025, 3, 31, 23, 0, 0, 0, time.Local)
+	result, err := fs.ApplyRetention(context.Background(), RetentionOptions{Now: now, DryRun: true, Warner: &fakeRetentionWarner{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Archived) != 0 {
+		t.Fatalf("nothing should be archived before the end of the expiry day: %+v", result.Archived)
+	}
+
+	now = now.Add(2 * time.Hour)
+	result, err = fs.ApplyRetention(context.Background(), RetentionOptions{Now: now, DryRun: true, Warner: &fakeRetentionWarner{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Archived) != 1 || result.Archived[0].FileID != "newsletter" || result.Archived[0].ExpiresOn != "2025-03-31" {
+		t.Fatalf("archived = %+v", result.Archived)
+	}
+	if len(result.Warned) != 1 || result.Warned[0].FileID != "tax" || result.Warned[0].LegalYears != 7 || result.Warned[0].ExpiresOn != "2025-03-15" {
+		t.Fatalf("warned = %+v", result.Warned)
+	}
+	if len(result.Trashed)+len(result.Failed) != 0 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	// ドライランでは予告を記録しない
+	entry, err := fs.ledger.Get(context.Background(), "tax")
+	if err != nil || entry.Retention != nil {
+		t.Fatalf("dry run should not record retention state: %+v, %v", entry, err)
+	}
+}
+
+func TestApplyRetention_LegalDeletionWaitsForWarning(t *testing.T) {
+	ctx := context.Background()
+	fs := newRetentionTestSorter(t, completedEntry("tax", "10_マネー・税務", "", "20170601", "money"))
+	now := time.Date(2025, 4, 10, 9, 0, 0, 0, time.Local)
+
+	// 通知できない場合は予告も削除もしない
+	result, err := fs.ApplyRetention(ctx, RetentionOptions{Now: now})
+	if err != nil || len(result.Warned)+len(result.Trashed)+len(result.Pending) != 0 {
+		t.Fatalf("without warning channel: %+v, %v", result, err)
+	}
+
+	result, err = fs.ApplyRetention(ctx, RetentionOptions{Now: now, Warner: &fakeRetentionWarner{}})
+	if err != nil || len(result.Warned) != 1 || result.Warned[0].DeleteAfter != "2025-05-10" {
+		t.Fatalf("first run: %+v, %v", result, err)
+	}
+	entry, err := fs.ledger.Get(ctx, "tax")
+	if err != nil || entry.Retention == nil || !entry.Retention.WarnedAt.Equal(now) || !entry.Retention.DoneAt.IsZero() {
+		t.Fatalf("warning not recorded: %+v, %v", entry.Retention, err)
+	}
+
+	result, err = fs.ApplyRetention(ctx, RetentionOptions{Now: now.AddDate(0, 0, 10), Warner: &fakeRetentionWarner{}})
+	if err != nil || len(result.Pending) != 1 || len(result.Warned)+len(result.Trashed) != 0 {
+		t.Fatalf("during grace period: %+v, %v", result, err)
+	}
+
+	result, err = fs.ApplyRetention(ctx, RetentionOptions{Now: now.AddDate(0, 0, 30), DryRun: true, Warner: &fakeRetentionWarner{}})
+	if err != nil || len(result.Trashed) != 1 || result.Trashed[0].FileID != "tax" {
+		t.Fatalf("after grace period: %+v, %v", result, err)
+	}
+}
+
+func TestApplyRetention_RecordsOnlyDeliveredWarnings(t *testing.T) {
+	ctx := context.Background()
+	fs := newRetentionTestSorter(t,
+		completedEntry("tax1", "10_マネー・税務", "", "20170601", "money"),
+		completedEntry("tax2", "10_マネー・税務", "", "20170701", "money"),
+	)
+	now := time.Date(2025, 4, 10, 9, 0, 0, 0, time.Local)
+
+	warner := &fakeRetentionWarner{limit: 1}
+	result, err := fs.ApplyRetention(ctx, RetentionOptions{Now: now, Warner: warner})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Warned) != 1 || result.Warned[0].FileID != "tax1" {
+		t.Fatalf("warned = %+v", result.Warned)
+	}
+	if len(result.Failed) != 1 || result.Failed[0].FileID != "tax2" || !strings.Contains(result.Failed[0].Error, "webhook down") {
+		t.Fatalf("failed = %+v", result.Failed)
+	}
+	if entry, _ := fs.ledger.Get(ctx, "tax2"); entry.Retention != nil {
+		t.Fatalf("undelivered warning should not be recorded: %+v", entry.Retention)
+	}
+
+	// 予告が届かなかった書類は次回改めて予告し、猶予期間が過ぎても削除しない
+	result, err = fs.ApplyRetention(ctx, RetentionOptions{Now: now.AddDate(0, 0, 31), DryRun: true, Warner: &fakeRetentionWarner{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Trashed) != 1 || result.Trashed[0].FileID != "tax1" || len(result.Warned) != 1 || result.Warned[0].FileID != "tax2" {
+		t.Fatalf("next run: trashed=%+v warned=%+v", result.Trashed, result.Warned)
+	}
+}
+
+func TestRetentionWarningBatches(t *testing.T) {
+	var items []model.RetentionItem
+	for i := 0; i < 100; i++ {
+		items = append(items, model.RetentionItem{FileID: string(rune('a' + i%26)), FileName: strings.Repeat("源泉徴収票", 10) + ".pdf", Category: "10_マネー・税務", LegalYears: 7, DeleteAfter: "2025-05-10"})
+	}
+	batches := retentionWarningBatches(items, retentionWarningMaxRunes)
+	if len(batches) < 2 {
+		t.Fatalf("long list should be split: %d batch(es)", len(batches))
+	}
+	total := 0
+	for _, batch := range batches {
+		size := 0
+		for _, item := range batch {
+			size += utf8.RuneCountInString(retentionWarningLine(item)) + 1
+		}
+		if size > retentionWarningMaxRunes {
+			t.Fatalf("batch exceeds limit: %d runes", size)
+		}
+		total += len(batch)
+	}
+	if total != len(items) {
+		t.Fatalf("batches cover %d items, want %d", total, len(items))
+	}
+}
+
+func TestDiscordWarnRetention_StopsAtFailedBatch(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests > 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var items []model.RetentionItem
+	for i := 0; i < 100; i++ {
+		items = append(items, model.RetentionItem{FileID: fmt.Sprintf("tax%d", i), FileName: strings.Repeat("源泉徴収票", 10) + ".pdf", LegalYears: 7})
+	}
+	delivered, err := NewDiscordNotifier(server.URL).WarnRetention(items)
+	if err == nil {
+		t.Fatal("expected error from failed batch")
+	}
+	if want := len(retentionWarningBatches(items, retentionWarningMaxRunes)[0]); len(delivered) != want || delivered[0].FileID != "tax0" {
+		t.Fatalf("delivered %d items, want the first batch (%d)", len(delivered), want)
+	}
+}
+
+func TestApplyRetention_LegalYearsCountFromFilingDeadline(t *testing.T) {
+	// 1〜3月の税務書類は前年度だが、保存期間はその年の確定申告期限（翌年3月15日）から数える
+	fs := newRetentionTestSorter(t, completedEntry("receipt", "10_マネー・税務", "", "20250210", "money"))
+
+	for _, tt := range []struct {
+		now  time.Time
+		want int
+	}{
+		{time.Date(2032, 4, 1, 9, 0, 0, 0, time.Local), 0},
+		{time.Date(2033, 3, 15, 23, 0, 0, 0, time.Local), 0},
+		{time.Date(2033, 3, 16, 9, 0, 0, 0, time.Local), 1},
+	} {
+		result, err := fs.ApplyRetention(context.Background(), RetentionOptions{Now: tt.now, DryRun: true, Warner: &fakeRetentionWarner{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Warned) != tt.want {
+			t.Fatalf("at %s: warned = %+v, want %d", tt.now.Format("2006-01-02"), result.Warned, tt.want)
+		}
+		if tt.want > 0 && result.Warned[0].ExpiresOn != "2033-03-15" {
+			t.Fatalf("expires on %s, want 2033-03-15", result.Warned[0].ExpiresOn)
+		}
+	}
+}
+
+func TestRetentionExpiry(t *testing.T) {
+	tests := []struct {
+		fiscalYear int
+		years      int
+		want       string
+	}{
+		{2024, 0, "2025-03-31"},
+		{2017, 7, "2025-03-31"},
+	}
+	for _, tt := range tests {
+		if got := retentionExpiry(tt.fiscalYear, tt.years).Format("2006-01-02"); got != tt.want {
+			t.Errorf("retentionExpiry(%d, %d) = %s, want %s", tt.fiscalYear, tt.years, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// ErrReviewNotPending はレビュー待ちでないファイルに回答した場合のエラー
+var ErrReviewNotPending = errors.New("review is not pending")
+
+// errAwaitingReview はパイプラインをレビュー待ちで中断したことを示す
+var errAwaitingReview = errors.New("awaiting review")
+
+// ReviewNotifier は低信頼度の分類結果を家族に通知する（LINE Botが実装）
+type ReviewNotifier interface {
+	NotifyReview(ctx context.Context, req *model.ReviewRequest) error
+}
+
+// SetReviewNotifier はレビュー通知先を設定（未設定時は信頼度に関わらず解析結果をそのまま適用）
+func (fs *FileSorter) SetReviewNotifier(notifier ReviewNotifier) {
+	fs.reviewNotifier = notifier
+}
+
+// needsReview はレビュー待ちにすべきかを判定（低信頼度、または見た目が似ている仕分け済みの書類がある場合）
+// 回答済み、リネーム済み（仕分けが始まっている）、AI障害時の仮分類のファイルは対象外
+func (fs *FileSorter) needsReview(r *pipelineRun) bool {
+	if fs.reviewNotifier == nil || r.entry.ReviewDecision != nil || r.entry.Provisional {
+		return false
+	}
+	if reached(r.entry, model.ProcessingStateRenamed) {
+		return false
+	}
+	return r.analysis.ConfidenceScore < config.AIRouter.ReviewThreshold || r.entry.SimilarTo != nil
+}
+
+// parkForReview はファイルをレビュー待ちにして家族に通知する
+func (fs *FileSorter) parkForReview(ctx context.Context, r *pipelineRun) error {
+	req := &model.ReviewRequest{
+		FileID:          r.fileInfo.ID,
+		FileName:        r.fileInfo.Name,
+		Category:        r.analysis.Category,
+		SubCategory:     r.analysis.SubCategory,
+		TargetChildren:  r.analysis.TargetChildren,
+		TargetAdult:     r.analysis.TargetAdult,
+		NewFileName:     fs.generateNewFilename(r.analysis, r.fileInfo.Name),
+		ConfidenceScore: r.analysis.ConfidenceScore,
+		RequestedAt:     time.Now(),
+		SimilarTo:       r.entry.SimilarTo,
+	}
+
+	// 通知に失敗した場合は通常のエラーとして再試行させる
+	if err := fs.reviewNotifier.NotifyReview(ctx, req); err != nil {
+		return fmt.Errorf("レビュー通知失敗: %w", err)
+	}
+
+	detail := fmt.Sprintf("confidence=%.2f", req.ConfidenceScore)
+	if req.SimilarTo != nil {
+		detail += fmt.Sprintf(", similar to %s (distance=%d)", req.SimilarTo.FileID, req.SimilarTo.Distance)
+	}
+	fs.updateRun(ctx, r, func(e *model.LedgerEntry) {
+		e.Review = req
+		e.Transition(model.ProcessingStateAwaitingReview, detail)
+	})
+	log.Printf("レビュー待ちに登録: %s (category=%s, confidence=%.2f)", r.fileInfo.Name, req.Category, req.ConfidenceScore)
+	return errAwaitingReview
+}
+
+// ReviewCategories はレビューで選択できるカテゴリ一覧を返す
+func (fs *FileSorter) ReviewCategories() []string {
+	return fs.household.Current().CategoryNames()
+}
+
+// ListPendingReviews はレビュー待ちのファイルを取得
+func (fs *FileSorter) ListPendingReviews(ctx context.Context) ([]*model.ReviewRequest, error) {
+	entries, err := fs.ledger.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []*model.ReviewRequest
+	for _, e := range entries {
+		if e.State == model.ProcessingStateAwaitingReview && e.Review != nil {
+			pending = append(pending, e.Review)
+		}
+	}
+	return pending, nil
+}
+
+// ResolveReview はレビューの回答を反映して仕分けを再開する
+// category が空、または提案と同じ場合は承認として扱う
+func (fs *FileSorter) ResolveReview(ctx context.Context, fileID, category, decidedBy string) (model.ProcessResult, error) {
+	if err := fs.RecordReview(ctx, fileID, category, decidedBy); err != nil {
+		return model.ProcessResultError, err
+	}
+	return fs.ProcessFile(ctx, fileID), nil
+}
+
+// RecordReview はレビューの回答を台帳に記録し、仕分けを再開できる状態（解析済み）に戻す
+// 仕分け自体は行わない（ProcessFile、または滞留ファイルの再開で続きを処理する）
+func (fs *FileSorter) RecordReview(ctx context.Context, fileID, category, decidedBy string) error {
+	if category != "" {
+		if _, ok := fs.household.Current().CategoryMap[category]; !ok {
+			return fmt.Errorf("unknown category: %s", category)
+		}
+	}
+
+	entry, err := fs.ledger.Get(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	if entry == nil || entry.State != model.ProcessingStateAwaitingReview || entry.Analysis == nil {
+		return ErrReviewNotPending
+	}
+
+	decision := &model.ReviewDecision{
+		Approved:  category == "" || category == entry.Analysis.Category,
+		DecidedBy: decidedBy,
+		DecidedAt: time.Now(),
+	}
+	if !decision.Approved {
+		decision.Category = category
+	}
+
+	if _, err := fs.ledger.Update(ctx, fileID, func(e *model.LedgerEntry) {
+		e.ReviewDecision = decision
+		if !decision.Approved {
+			e.Analysis.Category = category
+			// サブカテゴリは変更後のカテゴリで使えるものだけ残す
+			if !config.ValidSubCategory(category, e.Analysis.SubCategory) {
+				e.Analysis.SubCategory = ""
+			}
+		}
+		detail := "承認"
+		if !decision.Approved {
+			detail = "カテゴリ変更: " + category
+		}
+		e.Transition(model.ProcessingStateAnalyzed, fmt.Sprintf("レビュー%s (%s)", detail, decidedBy))
+	}); err != nil {
+		return fmt.Errorf("処理台帳の更新失敗: %w", err)
+	}
+
+	log.Printf("レビュー回答: %s (approved=%v, category=%s, by=%s)", fileID, decision.Approved, decision.Category, decidedBy)
+	return nil
+}
+
+// ConfirmDuplicate は見た目が似ている書類の重複であるとの回答を反映し、「重複」フォルダへ移動する
+func (fs *FileSorter) ConfirmDuplicate(ctx context.Context, fileID, decidedBy string) (model.ProcessResult, error) {
+	if err := fs.RecordDuplicate(ctx, fileID, decidedBy); err != nil {
+		return model.ProcessResultError, err
+	}
+	return fs.ProcessFile(ctx, fileID), nil
+}
+
+// RecordDuplicate は重複であるとの回答を台帳に記録する（移動は ProcessFile、または滞留ファイルの再開で行う）
+func (fs *FileSorter) RecordDuplicate(ctx context.Context, fileID, decidedBy string) error {
+	entry, err := fs.ledger.Get(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	if entry == nil || entry.State != model.ProcessingStateAwaitingReview || entry.SimilarTo == nil {
+		return ErrReviewNotPending
+	}
+
+	if _, err := fs.ledger.Update(ctx, fileID, func(e *model.LedgerEntry) {
+		e.ReviewDecision = &model.ReviewDecision{Duplicate: true, DecidedBy: decidedBy, DecidedAt: time.Now()}
+		e.DuplicateOf = e.SimilarTo
+		e.Transition(model.ProcessingStateAnalyzed, fmt.Sprintf("レビュー重複 (%s)", decidedBy))
+	}); err != nil {
+		return fmt.Errorf("処理台帳の更新失敗: %w", err)
+	}
+
+	log.Printf("レビュー回答: %s は %s の重複 (by=%s)", fileID, entry.SimilarTo.FileID, decidedBy)
+	return nil
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+type stubReviewNotifier struct {
+	requests []*model.ReviewRequest
+}
+
+func (s *stubReviewNotifier) NotifyReview(ctx context.Context, req *model.ReviewRequest) error {
+	s.requests = append(s.requests, req)
+	return nil
+}
+
+func TestParkForReview(t *testing.T) {
+	ctx := context.Background()
+	ledger, err := NewFileLedger(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifier := &stubReviewNotifier{}
+	household := newTestHousehold(t)
+	fs := &FileSorter{ledger: ledger, gradeManager: NewGradeManager(household), household: household}
+
+	r := &pipelineRun{
+		fileInfo: &model.FileInfo{ID: "file1", Name: "scan.pdf"},
+		entry:    &model.LedgerEntry{FileID: "file1", State: model.ProcessingStateAnalyzed},
+		analysis: &model.AnalysisResult{Category: "30_ライフ・行政", Date: "20250401", Summary: "通知", ConfidenceScore: config.AIRouter.ReviewThreshold - 0.1},
+	}
+
+	// 通知先が未設定ならレビューしない
+	if fs.needsReview(r) {
+		t.Fatal("review should be disabled without notifier")
+	}
+
+	fs.SetReviewNotifier(notifier)
+	if !fs.needsReview(r) {
+		t.Fatal("low confidence result should need review")
+	}
+	if err := fs.parkForReview(ctx, r); !errors.Is(err, errAwaitingReview) {
+		t.Fatalf("expected errAwaitingReview, got %v", err)
+	}
+	if len(notifier.requests) != 1 || notifier.requests[0].NewFileName != "20250401_通知.pdf" {
+		t.Fatalf("unexpected notification: %+v", notifier.requests)
+	}
+
+	pending, err := fs.ListPendingReviews(ctx)
+	if err != nil || len(pending) != 1 || pending[0].FileID != "file1" {
+		t.Fatalf("unexpected pending reviews: %+v err=%v", pending, err)
+	}
+
+	// 回答済みなら再度レビューしない
+	r.entry.ReviewDecision = &model.ReviewDecision{Approved: true}
+	if fs.needsReview(r) {
+		t.Fatal("decided review should not be requested again")
+	}
+}
+
+func TestNeedsReview_SimilarDocument(t *testing.T) {
+	ctx := context.Background()
+	ledger, err := NewFileLedger(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifier := &stubReviewNotifier{}
+	household := newTestHousehold(t)
+	fs := &FileSorter{ledger: ledger, gradeManager: NewGradeManager(household), household: household}
+	fs.SetReviewNotifier(notifier)
+
+	similar := &model.DedupEntry{FileID: "original", FileName: "20250401_学年だより.pdf", Distance: 3}
+	r := &pipelineRun{
+		fileInfo: &model.FileInfo{ID: "file2", Name: "scan.pdf"},
+		entry:    &model.LedgerEntry{FileID: "file2", State: model.ProcessingStateAnalyzed, SimilarTo: similar},
+		analysis: &model.AnalysisResult{Category: "40_子供・教育", Date: "20250501", Summary: "学年だより", ConfidenceScore: 0.99},
+	}
+	if !fs.needsReview(r) {
+		t.Fatal("document similar to a filed one should be reviewed even with high confidence")
+	}
+	if err := fs.parkForReview(ctx, r); !errors.Is(err, errAwaitingReview) {
+		t.Fatalf("expected errAwaitingReview, got %v", err)
+	}
+	if len(notifier.requests) != 1 || notifier.requests[0].SimilarTo == nil || notifier.requests[0].SimilarTo.FileID != "original" {
+		t.Fatalf("review request should point at the similar document: %+v", notifier.requests)
+	}
+
+	// 低信頼度でもなく似た書類もなければレビューしない
+	r.entry.SimilarTo = nil
+	if fs.needsReview(r) {
+		t.Fatal("confident unique document should not be reviewed")
+	}
+}
+
+func TestConfirmDuplicate_RequiresSimilarPendingReview(t *testing.T) {
+	ctx := context.Background()
+	ledger, err := NewFileLedger(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := &FileSorter{ledger: ledger, household: newTestHousehold(t)}
+
+	// 低信頼度のみのレビュー待ちは重複として移動できない
+	if _, err := ledger.Update(ctx, "file1", func(e *model.LedgerEntry) {
+		e.State = model.ProcessingStateAwaitingReview
+		e.Analysis = &model.AnalysisResult{Category: "30_ライフ・行政"}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.ConfirmDuplicate(ctx, "file1", "tester"); !errors.Is(err, ErrReviewNotPending) {
+		t.Fatalf("expected ErrReviewNotPending, got %v", err)
+	}
+	if _, err := fs.ConfirmDuplicate(ctx, "missing", "tester"); !errors.Is(err, ErrReviewNotPending) {
+		t.Fatalf("expected ErrReviewNotPending for unknown file, got %v", err)
+	}
+}
+
+func TestResolveReview_RejectsUnknownOrNotPending(t *testing.T) {
+	ctx := context.Background()
+	ledger, err := NewFileLedger(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := &FileSorter{ledger: ledger, household: newTestHousehold(t)}
+
+	if _, err := fs.ResolveReview(ctx, "file1", "", "tester"); !errors.Is(err, ErrReviewNotPending) {
+		t.Fatalf("expected ErrReviewNotPending, got %v", err)
+	}
+	if _, err := fs.ResolveReview(ctx, "file1", "00_存在しない", "tester"); err == nil {
+		t.Fatal("expected error for unknown category")
+	}
+}
+
+func TestRecordReview_ChangesCategoryWithoutProcessing(t *testing.T) {
+	ctx := context.Background()
+	ledger, err := NewFileLedger(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := &FileSorter{ledger: ledger, household: newTestHousehold(t)}
+
+	if _, err := ledger.Update(ctx, "file1", func(e *model.LedgerEntry) {
+		e.State = model.ProcessingStateAwaitingReview
+		e.Analysis = &model.AnalysisResult{Category: "30_ライフ・行政"}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.RecordReview(ctx, "file1", "40_子供・教育", "tester"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 仕分けは行わず、滞留ファイルの再開でも続きを処理できる状態で回答が残る
+	entry, err := ledger.Get(ctx, "file1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.State != model.ProcessingStateAnalyzed || entry.Analysis.Category != "40_子供・教育" {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+	if d := entry.ReviewDecision; d == nil || d.Approved || d.Category != "40_子供・教育" || d.DecidedBy != "tester" {
+		t.Fatalf("unexpected decision: %+v", d)
+	}
+
+	// 回答済みのファイルには再度回答できない
+	if err := fs.RecordReview(ctx, "file1", "", "tester"); !errors.Is(err, ErrReviewNotPending) {
+		t.Fatalf("expected ErrReviewNotPending, got %v", err)
+	}
+}
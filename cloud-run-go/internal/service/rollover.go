@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// PlanRollover は指定年度の子供の学年・クラスと、共有グループが引き続き当てはまるかをまとめる
+func (gm *GradeManager) PlanRollover(fiscalYear int) *model.RolloverReport {
+	grades := gm.grades()
+	report := &model.RolloverReport{
+		FiscalYear:   fiscalYear,
+		Children:     []model.RolloverChild{},
+		SharedGroups: []model.RolloverGroup{},
+	}
+
+	for _, name := range sortedKeys(grades.ChildrenBaseGrades) {
+		grade := gm.GetChildGrade(name, fiscalYear)
+		label, emoji := gm.GetGradeInfo(grade)
+		report.Children = append(report.Children, model.RolloverChild{
+			Name:          name,
+			PreviousGrade: gm.GetChildGrade(name, fiscalYear-1),
+			Grade:         grade,
+			Label:         label,
+			Emoji:         emoji,
+			Graduated:     gm.IsGraduated(name, fiscalYear),
+		})
+	}
+
+	for _, className := range sortedKeys(grades.SharedGroups) {
+		group := grades.SharedGroups[className]
+		reasons := gm.sharedGroupIssues(className, group, fiscalYear)
+		report.SharedGroups = append(report.SharedGroups, model.RolloverGroup{
+			ClassName:  className,
+			FolderName: group.FolderName,
+			Children:   group.Children,
+			Applies:    len(reasons) == 0,
+			Reasons:    reasons,
+		})
+	}
+	return report
+}
+
+// sharedGroupIssues は共有グループが指定年度に当てはまらない理由を返す
+// （卒業した子供がいる、学年が分かれた、クラス名が学年と一致しない）
+func (gm *GradeManager) sharedGroupIssues(className string, group config.SharedGroup, fiscalYear int) []string {
+	var reasons []string
+	var labels []string
+	grades := map[int]bool{}
+	for _, child := range group.Children {
+		if gm.IsGraduated(child, fiscalYear) {
+			reasons = append(reasons, fmt.Sprintf("%sは卒業済み", child))
+		}
+		grade := gm.GetChildGrade(child, fiscalYear)
+		grades[grade] = true
+		label, _ := gm.GetGradeInfo(grade)
+		labels = append(labels, fmt.Sprintf("%s: %s", child, label))
+	}
+	if len(grades) > 1 {
+		reasons = append(reasons, fmt.Sprintf("学年が分かれる（%s）", strings.Join(labels, "、")))
+	}
+	if len(grades) == 1 && len(group.Children) > 0 {
+		grade := gm.GetChildGrade(group.Children[0], fiscalYear)
+		if !gm.matchesGrade(className, grade) {
+			label, _ := gm.GetGradeInfo(grade)
+			reasons = append(reasons, fmt.Sprintf("クラス名「%s」が%d年度の学年（%s）と一致しない", className, fiscalYear, label))
+		}
+	}
+	return reasons
+}
+
+// Rollover は指定年度の子供・教育のフォルダ（子供・共有グループごとのサブカテゴリ）を事前に作成し、学年の一覧を返す
+// フォルダの階層は子供・教育のパステンプレート（folderTemplate）に従い、日付は年度の初日（4月1日）として展開する
+func (fs *FileSorter) Rollover(ctx context.Context, fiscalYear int, dryRun bool) (*model.RolloverReport, error) {
+	category := "40_子供・教育"
+	if _, ok := fs.household.Current().CategoryMap[category]; !ok {
+		return nil, fmt.Errorf("categories に %s がありません", category)
+	}
+
+	report := fs.gradeManager.PlanRollover(fiscalYear)
+	report.DryRun = dryRun
+	for i := range report.Children {
+		child := &report.Children[i]
+		if child.Graduated {
+			continue
+		}
+		child.Folders = fs.prepareEducationFolders(ctx, report, &model.AnalysisResult{
+			ChildName:          child.Name,
+			TargetChildren:     []string{child.Name},
+			ResolvedFolderName: child.Name,
+			ResolvedLabel:      child.Name,
+		}, dryRun)
+	}
+	for i := range report.SharedGroups {
+		group := &report.SharedGroups[i]
+		if !group.Applies {
+			continue
+		}
+		label := fs.household.Current().Grades.SharedGroups[group.ClassName].Label
+		group.Folders = fs.prepareEducationFolders(ctx, report, &model.AnalysisResult{
+			TargetChildren:     group.Children,
+			ResolvedFolderName: group.FolderName,
+			ResolvedLabel:      label,
+			ResolvedEmoji:      label,
+		}, dryRun)
+	}
+
+	log.Printf("年度替わりの準備: %d年度 (children=%d, shared_groups=%d, errors=%d, dry_run=%v)",
+		fiscalYear, len(report.Children), len(report.SharedGroups), len(report.Errors), dryRun)
+	return report, nil
+}
+
+// prepareEducationFolders はサブカテゴリごとのフォルダを作成し、作成したパスを返す（失敗は report.Errors に記録）
+func (fs *FileSorter) prepareEducationFolders(ctx context.Context, report *model.RolloverReport, base *model.AnalysisResult, dryRun bool) []string {
+	var folders []string
+	for _, sub := range config.SubCategories {
+		result := *base
+		result.Category = "40_子供・教育"
+		result.SubCategory = sub
+		result.FiscalYear = report.FiscalYear
+		result.Date = fmt.Sprintf("%d0401", report.FiscalYear)
+
+		_, segments := fs.destinationPath(&result)
+		path := strings.Join(segments, "/")
+		if !dryRun {
+			if _, err := fs.getDestinationFolder(ctx, &result); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", path, err))
+				continue
+			}
+		}
+		folders = append(folders, path)
+	}
+	return folders
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+func TestPlanRollover(t *testing.T) {
+	household := newTestHousehold(t)
+	household.Current().Grades.SharedGroups = map[string]config.SharedGroup{
+		"小4":   {Children: []string{"太郎", "花子"}, FolderName: "太郎・花子", Label: "👦👧"},
+		"きりん組": {Children: []string{"花子"}, FolderName: "花子", Label: "🦒"},
+		"うさぎ組": {Children: []string{"花子"}, FolderName: "花子", Label: "🐰"},
+	}
+	gm := NewGradeManager(household)
+
+	report := gm.PlanRollover(2025)
+	wantChildren := []model.RolloverChild{
+		{Name: "太郎", PreviousGrade: 3, Grade: 4, Label: "小4"},
+		{Name: "花子", PreviousGrade: -2, Grade: -1, Label: "きりん組", Emoji: "🦒"},
+	}
+	if !reflect.DeepEqual(report.Children, wantChildren) {
+		t.Fatalf("children = %+v", report.Children)
+	}
+
+	applies := map[string]bool{}
+	for _, g := range report.SharedGroups {
+		applies[g.ClassName] = g.Applies
+		if !g.Applies && len(g.Reasons) == 0 {
+			t.Fatalf("%s: missing reasons", g.ClassName)
+		}
+	}
+	want := map[string]bool{"小4": false, "きりん組": true, "うさぎ組": false}
+	if !reflect.DeepEqual(applies, want) {
+		t.Fatalf("shared groups = %+v", report.SharedGroups)
+	}
+
+	if report := gm.PlanRollover(2034); !report.Children[0].Graduated {
+		t.Fatalf("太郎 should have graduated in 2034: %+v", report.Children[0])
+	}
+}
+
+func TestRollover_DryRunFolders(t *testing.T) {
+	household := newTestHousehold(t)
+	fs := &FileSorter{household: household, gradeManager: NewGradeManager(household)}
+
+	report, err := fs.Rollover(context.Background(), 2025, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"太郎/2025年度/01_お便り・スケジュール",
+		"太郎/2025年度/02_提出・手続き・重要",
+		"太郎/2025年度/03_記録・作品・成績",
+	}
+	if !report.DryRun || !reflect.DeepEqual(report.Children[0].Folders, want) {
+		t.Fatalf("folders = %+v", report.Children[0].Folders)
+	}
+	if len(report.Errors) != 0 {
+		t.Fatalf("errors = %v", report.Errors)
+	}
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+var (
+	// 2025年4月1日 / 2025/4/1 / 2025-04-01 / 2025.4.1
+	westernDatePattern = regexp.MustCompile(`(20\d{2})\s*[年/\-.]\s*(\d{1,2})\s*[月/\-.]\s*(\d{1,2})`)
+	// 令和7年4月1日 / 令和元年5月1日
+	reiwaDatePattern = regexp.MustCompile(`令和\s*(\d{1,2}|元)\s*年\s*(\d{1,2})\s*月\s*(\d{1,2})\s*日`)
+	// 1,234円 / ￥1,234
+	amountPattern = regexp.MustCompile(`(?:[¥￥]\s*[\d,]+|[\d,]+\s*円)`)
+
+	fullWidthDigits = strings.NewReplacer(
+		"０", "0", "１", "1", "２", "2", "３", "3", "４", "4",
+		"５", "5", "６", "6", "７", "7", "８", "8", "９", "9",
+		"，", ",", "／", "/", "－", "-", "．", ".",
+	)
+)
+
+// ruleFactsLimit は抽出する項目数の上限（OCRBundleのプロンプトと同じ）
+const ruleFactsLimit = 10
+
+// ClassifyByRules はOCRテキストをキーワードルールで仮分類する（AIが使えない場合のフォールバック）
+// 一致したキーワードが最も多いルールのカテゴリを採用し、世帯にないカテゴリのルールは使わない
+func ClassifyByRules(text string, household *config.Household, rules []*config.ClassificationRule, now time.Time) *model.AnalysisResult {
+	normalized := fullWidthDigits.Replace(text)
+
+	result := &model.AnalysisResult{
+		Category:        config.FallbackCategory,
+		Date:            extractDocumentDate(normalized, now),
+		Summary:         summarizeText(text),
+		ConfidenceScore: config.LocalOCR.Confidence,
+	}
+
+	best := 0
+	for _, rule := range rules {
+		if _, ok := household.CategoryMap[rule.Category]; !ok {
+			continue
+		}
+		hits := 0
+		for _, kw := range rule.Keywords {
+			if strings.Contains(normalized, kw) {
+				hits++
+			}
+		}
+		if hits > best {
+			best = hits
+			result.Category = rule.Category
+			result.SubCategory = rule.SubCategory
+		}
+	}
+
+	result.ChildName = matchAlias(normalized, household.ChildAliases)
+	result.TargetAdult = matchAlias(normalized, household.AdultAliases)
+	// 子供の名前があれば子供・教育として扱う（AIの判断基準と同じ。医療書類は受診者として残す）
+	if result.ChildName != "" && result.Category != "40_子供・教育" && result.Category != "60_ヘルス・医療" {
+		if _, ok := household.CategoryMap["40_子供・教育"]; ok {
+			result.Category = "40_子供・教育"
+			result.SubCategory = "01_お便り・スケジュール"
+		}
+	}
+	if !config.ValidSubCategory(result.Category, result.SubCategory) {
+		result.SubCategory = ""
+	}
+	return result
+}
+
+// matchAlias は本文に名前（正規名または別名）が含まれる人を返す（該当なし・複数該当の場合は空文字）
+func matchAlias(text string, aliases map[string][]string) string {
+	found := ""
+	for name, names := range aliases {
+		for _, n := range append([]string{name}, names...) {
+			if n == "" || !strings.Contains(text, n) {
+				continue
+			}
+			if found != "" && found != name {
+				return ""
+			}
+			found = name
+			break
+		}
+	}
+	return found
+}
+
+// extractDocumentDate は本文の最初の有効な日付を YYYYMMDD で返す（見つからない場合は now）
+func extractDocumentDate(text string, now time.Time) string {
+	type candidate struct {
+		pos  int
+		date time.Time
+	}
+	var first *candidate
+
+	for _, m := range westernDatePattern.FindAllStringSubmatchIndex(text, -1) {
+		year, _ := strconv.Atoi(text[m[2]:m[3]])
+		if d, ok := validDate(year, text[m[4]:m[5]], text[m[6]:m[7]]); ok {
+			if first == nil || m[0] < first.pos {
+				first = &candidate{m[0], d}
+			}
+			break
+		}
+	}
+	for _, m := range reiwaDatePattern.FindAllStringSubmatchIndex(text, -1) {
+		era := text[m[2]:m[3]]
+		n := 1
+		if era != "元" {
+			n, _ = strconv.Atoi(era)
+		}
+		if d, ok := validDate(2018+n, text[m[4]:m[5]], text[m[6]:m[7]]); ok {
+			if first == nil || m[0] < first.pos {
+				first = &candidate{m[0], d}
+			}
+			break
+		}
+	}
+
+	if first == nil {
+		return now.Format("20060102")
+	}
+	return first.date.Format("20060102")
+}
+
+// validDate は実在する日付かを確認して返す
+func validDate(year int, month, day string) (time.Time, bool) {
+	m, err1 := strconv.Atoi(month)
+	d, err2 := strconv.Atoi(day)
+	if err1 != nil || err2 != nil {
+		return time.Time{}, false
+	}
+	t := time.Date(year, time.Month(m), d, 0, 0, 0, 0, time.Local)
+	if t.Year() != year || int(t.Month()) != m || t.Day() != d {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// extractFacts は本文から日付・金額を抜き出す（推測はしない）
+func extractFacts(text string) []string {
+	normalized := fullWidthDigits.Replace(text)
+	facts := []string{}
+	seen := map[string]bool{}
+	add := func(s string) {
+		s = strings.TrimSpace(s)
+		if s == "" || seen[s] || len(facts) >= ruleFactsLimit {
+			return
+		}
+		seen[s] = true
+		facts = append(facts, s)
+	}
+	for _, p := range []*regexp.Regexp{westernDatePattern, reiwaDatePattern, amountPattern} {
+		for _, m := range p.FindAllString(normalized, -1) {
+			add(m)
+		}
+	}
+	return facts
+}
+
+// summarizeText は本文の最初の意味のある行を要約として返す（ファイル名に使うため15文字まで）
+func summarizeText(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if utf8.RuneCountInString(line) < 4 || !containsLetter(line) {
+			continue
+		}
+		runes := []rune(line)
+		if len(runes) > 15 {
+			runes = runes[:15]
+		}
+		return string(runes)
+	}
+	return ""
+}
+
+// containsLetter は数字・記号以外の文字を含むかを返す
+func containsLetter(s string) bool {
+	for _, r := range s {
+		if r >= 0x3000 || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') {
+			return true
+		}
+	}
+	return false
+}
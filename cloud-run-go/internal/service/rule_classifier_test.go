@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+func TestClassifyByRules(t *testing.T) {
+	household := newTestHousehold(t).Current()
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name string
+		text string
+		want model.AnalysisResult
+	}{
+		{
+			name: "school letter with child name",
+			text: "６月の学年だより\n令和７年５月３０日\n太郎さんの保護者様\n遠足の持ち物について",
+			want: model.AnalysisResult{Category: "40_子供・教育", SubCategory: "01_お便り・スケジュール", ChildName: "太郎", Date: "20250530", Summary: "６月の学年だより"},
+		},
+		{
+			name: "bill for an adult",
+			text: "ご請求金額のお知らせ\nパパ 様\nお振込期限 2025/06/20\n口座振替 3,300円",
+			want: model.AnalysisResult{Category: "10_マネー・税務", TargetAdult: "父", Date: "20250620", Summary: "ご請求金額のお知らせ"},
+		},
+		{
+			name: "vaccination keeps child as patient",
+			text: "予防接種済証\n太郎 様\n接種日 令和7年5月12日\n○○クリニック",
+			want: model.AnalysisResult{Category: "60_ヘルス・医療", SubCategory: "03_予防接種・健診", ChildName: "太郎", Date: "20250512", Summary: "予防接種済証"},
+		},
+		{
+			name: "no keywords",
+			text: "12345\nメモ書きです",
+			want: model.AnalysisResult{Category: config.FallbackCategory, Date: "20250601", Summary: "メモ書きです"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifyByRules(tt.text, household, household.FallbackRules, now)
+			tt.want.ConfidenceScore = config.LocalOCR.Confidence
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Fatalf("ClassifyByRules() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractDocumentDate(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"発行日 2025年4月1日", "20250401"},
+		{"令和元年5月1日 / 2025-04-01", "20190501"},
+		{"2025/2/30 は無効、2025/3/1 が有効", "20250301"},
+		{"日付なし", "20250601"},
+	}
+	for _, tt := range tests {
+		if got := extractDocumentDate(tt.text, now); got != tt.want {
+			t.Errorf("extractDocumentDate(%q) = %s, want %s", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeOCRText(t *testing.T) {
+	got := normalizeOCRText("  学 年 だ よ り \nPage 1 of 2\n")
+	if got != "学年だより\nPage 1 of 2" {
+		t.Fatalf("normalizeOCRText() = %q", got)
+	}
+}
+
+func TestStepAnalyze_LocalOCRFallback(t *testing.T) {
+	ctx := context.Background()
+	origRetries := config.API.MaxRetries
+	config.API.MaxRetries = 1
+	defer func() { config.API.MaxRetries = origRetries }()
+
+	// tesseract の代わりに固定のテキストを出力するスクリプト
+	script := filepath.Join(t.TempDir(), "tesseract")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncat >/dev/null\necho '水道料金のお知らせ'\necho '2025年5月20日'\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	ledger, err := NewFileLedger(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := NewFakeBackend()
+	backend.Respond = func(req GenerateRequest) (string, error) {
+		return "", errors.New("quota exceeded")
+	}
+	household := newTestHousehold(t)
+	pdfProcessor := NewPDFProcessor()
+	fs := &FileSorter{
+		aiRouter:     NewAIRouterWithBackend(backend),
+		pdfProcessor: pdfProcessor,
+		ledger:       ledger,
+		household:    household,
+		localOCR:     &LocalOCR{command: script, languages: "jpn+eng", pdfProcessor: pdfProcessor},
+	}
+	r := &pipelineRun{
+		fileInfo: &model.FileInfo{ID: "file1", Name: "scan.jpg", MimeType: "image/jpeg"},
+		entry:    &model.LedgerEntry{FileID: "file1"},
+		data:     []byte("jpeg"),
+	}
+
+	category, err := fs.stepAnalyze(ctx, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if category != "30_ライフ・行政" || !r.entry.Provisional {
+		t.Fatalf("category = %s, provisional = %v", category, r.entry.Provisional)
+	}
+	if r.entry.Analysis.Date != "20250520" || r.entry.OCRBundle == nil || r.entry.OCRBundle.OCRText == "" {
+		t.Fatalf("unexpected entry: analysis=%+v ocr=%+v", r.entry.Analysis, r.entry.OCRBundle)
+	}
+	if r.entry.EventsAndTasks == nil || len(r.entry.EventsAndTasks.Events) != 0 {
+		t.Fatalf("events should be empty: %+v", r.entry.EventsAndTasks)
+	}
+}
@@ -1,8 +1,12 @@
 package service
 
+import "github.com/leo-sagawa/homedocmanager/internal/config"
+
 // Services は全サービスをまとめた構造体
 type Services struct {
-	AIRouter        *AIRouter
+	Household       *config.HouseholdStore
+	AIRouter        DocumentAnalyzer
+	AIUsage         *UsageTracker // 全テナント共通（未設定の場合は nil）
 	PDFProcessor    *PDFProcessor
 	DriveClient     *DriveClient
 	PhotosClient    *PhotosClient
@@ -12,4 +16,7 @@ type Services struct {
 	GradeManager    *GradeManager
 	FileSorter      *FileSorter
 	DiscordNotifier *DiscordNotifier
+	ConfigReloader  *ConfigReloader
+	MedicalExpenses MedicalExpenseLedger // 未設定の場合は nil
+	Finance         FinanceLedger        // 未設定の場合は nil
 }
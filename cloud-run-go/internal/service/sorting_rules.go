@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// sortingRuleMatch は仕分けルールに一致した結果
+type sortingRuleMatch struct {
+	rule     *config.SortingRule
+	analysis *model.AnalysisResult
+	// 本文の抽出にローカルOCRを使った場合のOCR結果（NotebookLM同期に使う）
+	ocrBundle *model.OCRBundle
+}
+
+// matchSortingRule は世帯設定の仕分けルールを上から順に評価し、最初に一致したルールの分類結果を返す
+// 本文の条件を持つルールがある場合のみ、pdftotext（テキストがなければローカルOCR）で本文を抽出する
+func (fs *FileSorter) matchSortingRule(ctx context.Context, data []byte, fileInfo *model.FileInfo) *sortingRuleMatch {
+	rules := fs.household.Current().SortingRules
+	if len(rules) == 0 {
+		return nil
+	}
+
+	var text string
+	var ocrBundle *model.OCRBundle
+	for _, rule := range rules {
+		if rule.NeedsText() {
+			text, ocrBundle = fs.extractRuleText(ctx, data, fileInfo)
+			break
+		}
+	}
+
+	for _, rule := range rules {
+		if !rule.Matches(fileInfo.Name, fileInfo.Uploader, text) {
+			continue
+		}
+		log.Printf("仕分けルールに一致: %s → %s", fileInfo.Name, rule.Name)
+		return &sortingRuleMatch{
+			rule:      rule,
+			analysis:  ruleAnalysis(rule, text, fileInfo.Name, time.Now()),
+			ocrBundle: ocrBundle,
+		}
+	}
+	return nil
+}
+
+// extractRuleText はルール照合用の本文を抽出する（失敗した場合は空文字とし、本文の条件を持つルールは一致しない）
+func (fs *FileSorter) extractRuleText(ctx context.Context, data []byte, fileInfo *model.FileInfo) (string, *model.OCRBundle) {
+	if fs.pdfProcessor.IsPDF(fileInfo.MimeType) {
+		text, err := fs.pdfProcessor.ExtractText(data)
+		if err != nil {
+			log.Printf("Warning: 仕分けルール用のテキスト抽出失敗 (%s): %v", fileInfo.Name, err)
+		}
+		if text != "" {
+			return text, nil
+		}
+	}
+	// スキャン画像のみのPDF・画像はローカルOCRで読み取る
+	if fs.localOCR == nil {
+		return "", nil
+	}
+	bundle, err := fs.localOCR.ExtractOCRBundle(ctx, data, fileInfo.MimeType)
+	if err != nil {
+		log.Printf("Warning: 仕分けルール用のローカルOCR失敗 (%s): %v", fileInfo.Name, err)
+		return "", nil
+	}
+	return bundle.OCRText, bundle
+}
+
+// ruleAnalysis はルールの設定から分類結果を作る（日付・要約は本文から、なければ現在日時・ファイル名から補う）
+func ruleAnalysis(rule *config.SortingRule, text, fileName string, now time.Time) *model.AnalysisResult {
+	summary := rule.Summary
+	if summary == "" {
+		summary = summarizeText(text)
+	}
+	if summary == "" {
+		summary = strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	}
+	return &model.AnalysisResult{
+		Category:         rule.Category,
+		SubCategory:      rule.SubCategory,
+		ChildName:        rule.Child,
+		TargetAdult:      rule.Adult,
+		Date:             extractDocumentDate(fullWidthDigits.Replace(text), now),
+		Summary:          summary,
+		ConfidenceScore:  1.0,
+		FilenameTemplate: rule.FilenameTemplate,
+	}
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// errSplit はパイプラインをPDFの分割で中断したことを示す
+var errSplit = errors.New("split into documents")
+
+// stepSplit は複数の書類をまとめてスキャンしたPDFを書類ごとに分割し、Inboxにアップロードする
+// 分割したファイルはそれぞれ別のファイルとして通常どおり処理され、元のPDFは分類しない
+func (fs *FileSorter) stepSplit(ctx context.Context, r *pipelineRun) (string, error) {
+	if !config.PDFSplit.Enabled {
+		return "", skipStep("分割無効")
+	}
+	if !fs.pdfProcessor.IsPDF(r.fileInfo.MimeType) {
+		return "", skipStep("PDF以外")
+	}
+	if r.entry.Analysis != nil {
+		return "", skipStep("解析済み")
+	}
+
+	data, err := fs.fileData(ctx, r)
+	if err != nil {
+		return "", err
+	}
+
+	// 書類の区切りは台帳にキャッシュし、アップロード途中の再試行ではGeminiを呼ばない
+	split := r.entry.Split
+	if split == nil {
+		ranges, err := fs.splitRanges(ctx, data, r.fileInfo)
+		if err != nil {
+			return "", err
+		}
+		if len(ranges) < 2 {
+			return "1 document", nil
+		}
+		split = &model.SplitResult{Ranges: ranges}
+		fs.updateRun(ctx, r, func(e *model.LedgerEntry) { e.Split = split })
+	}
+
+	parts, err := fs.pdfProcessor.SplitPDF(data, split.Ranges)
+	if err != nil {
+		return "", fmt.Errorf("PDF分割失敗: %w", err)
+	}
+
+	sourceID := fs.household.Current().FolderIDs["SOURCE"]
+	for i := len(split.PartFileIDs); i < len(split.Ranges); i++ {
+		name := splitPartName(r.fileInfo.Name, split.Ranges[i])
+		partID, err := fs.uploadSplitPart(ctx, sourceID, name, parts[i])
+		if err != nil {
+			return "", err
+		}
+		fs.registerSplitPart(ctx, partID, name, r.fileInfo.ID)
+		fs.updateRun(ctx, r, func(e *model.LedgerEntry) {
+			e.Split.PartFileIDs = append(e.Split.PartFileIDs, partID)
+			e.Step(model.StepSplit).Progress = len(e.Split.PartFileIDs)
+		})
+		split = r.entry.Split
+	}
+	return fmt.Sprintf("%d documents", len(split.Ranges)), nil
+}
+
+// splitRanges はPDFの書類ごとのページ範囲をGeminiで判定する（分割の対象外のページ数の場合は skipStep を返す）
+func (fs *FileSorter) splitRanges(ctx context.Context, data []byte, fileInfo *model.FileInfo) ([]model.PageRange, error) {
+	pageCount, err := fs.pdfProcessor.PageCount(data)
+	if err != nil {
+		return nil, fmt.Errorf("ページ数取得失敗: %w", err)
+	}
+	if pageCount < 2 {
+		return nil, skipStep("1ページのみ")
+	}
+	if pageCount > config.PDFSplit.MaxPages {
+		return nil, skipStep(fmt.Sprintf("%dページ（上限%dページ）", pageCount, config.PDFSplit.MaxPages))
+	}
+
+	pages, err := fs.aiRouter.ClassifyPages(ctx, data, fileInfo.MimeType, fileInfo.Name, pageCount)
+	if err != nil {
+		return nil, fmt.Errorf("ページ判定失敗: %w", err)
+	}
+	return documentRanges(pages, pageCount), nil
+}
+
+// uploadSplitPart は分割ファイルをInboxにアップロードする
+// アップロード後、台帳に記録する前に中断した場合に備え、Inboxに同名のファイルがあればそれを使う
+func (fs *FileSorter) uploadSplitPart(ctx context.Context, sourceID, name string, data []byte) (string, error) {
+	ids, err := fs.driveClient.FindFileIDsByName(ctx, sourceID, name)
+	if err != nil {
+		return "", fmt.Errorf("分割ファイルの確認失敗 (%s): %w", name, err)
+	}
+	if len(ids) > 0 {
+		log.Printf("アップロード済みの分割ファイルを使用: %s (%s)", name, ids[0])
+		return ids[0], nil
+	}
+
+	partID, err := fs.driveClient.UploadFile(ctx, name, "application/pdf", sourceID, data)
+	if err != nil {
+		return "", fmt.Errorf("分割ファイルのアップロード失敗 (%s): %w", name, err)
+	}
+	return partID, nil
+}
+
+// registerSplitPart は分割ファイルを台帳に登録する
+// 分割ファイルが再度分割されないようにし、変更通知を取りこぼしても滞留ファイルとして再開されるようにする
+// 中断前にアップロードした分割ファイルが変更通知で処理済みの場合は、その状態・ステップを巻き戻さない
+func (fs *FileSorter) registerSplitPart(ctx context.Context, partID, name, sourceFileID string) {
+	fs.updateEntry(ctx, partID, func(e *model.LedgerEntry) {
+		if e.State == "" {
+			e.FileName = name
+			e.Transition(model.ProcessingStateReceived, "分割元: "+sourceFileID)
+		}
+		if rec := e.Step(model.StepSplit); !rec.Done() {
+			rec.Status = model.StepStatusSkipped
+			rec.Detail = "分割済みの書類"
+			rec.UpdatedAt = time.Now()
+		}
+	})
+}
+
+// archiveSplitSource は分割元のPDFを「分割済み」フォルダへ移動し、説明欄に分割ファイルへのリンクを残す
+func (fs *FileSorter) archiveSplitSource(ctx context.Context, r *pipelineRun) error {
+	split := r.entry.Split
+
+	folderID, err := fs.driveClient.GetOrCreateFolder(ctx, config.PDFSplit.ArchiveFolder, fs.household.Current().FolderIDs["SOURCE"])
+	if err != nil {
+		return fmt.Errorf("分割済みフォルダの取得失敗: %w", err)
+	}
+	if !contains(r.fileInfo.Parents, folderID) {
+		if err := fs.driveClient.MoveFile(ctx, r.fileInfo.ID, folderID); err != nil {
+			return fmt.Errorf("分割済みフォルダへの移動失敗: %w", err)
+		}
+	}
+
+	lines := []string{fmt.Sprintf("%d件の書類に分割しました", len(split.PartFileIDs))}
+	for i, id := range split.PartFileIDs {
+		rg := split.Ranges[i]
+		lines = append(lines, fmt.Sprintf("p%d-%d %s: https://drive.google.com/file/d/%s/view", rg.Start, rg.End, rg.Title, id))
+	}
+	if err := fs.driveClient.SetDescription(ctx, r.fileInfo.ID, strings.Join(lines, "\n")); err != nil {
+		log.Printf("Warning: 分割元ファイルの説明設定失敗 (%s): %v", r.fileInfo.Name, err)
+	}
+
+	fs.updateRun(ctx, r, func(e *model.LedgerEntry) {
+		e.Transition(model.ProcessingStateSplit, fmt.Sprintf("%d documents", len(split.PartFileIDs)))
+	})
+	log.Printf("PDFを分割: %s → %d件", r.fileInfo.Name, len(split.PartFileIDs))
+	return errSplit
+}
+
+// documentRanges はページ単位の判定結果から書類ごとのページ範囲を求める
+// 1ページ目は常に書類の先頭とし、範囲外のページ番号は無視する
+func documentRanges(pages []model.PageClassification, pageCount int) []model.PageRange {
+	titles := make(map[int]string, len(pages))
+	starts := map[int]bool{1: true}
+	for _, p := range pages {
+		if p.Page < 1 || p.Page > pageCount {
+			continue
+		}
+		titles[p.Page] = p.Title
+		if p.StartsNewDocument {
+			starts[p.Page] = true
+		}
+	}
+
+	startPages := make([]int, 0, len(starts))
+	for page := range starts {
+		startPages = append(startPages, page)
+	}
+	sort.Ints(startPages)
+
+	ranges := make([]model.PageRange, 0, len(startPages))
+	for i, start := range startPages {
+		end := pageCount
+		if i+1 < len(startPages) {
+			end = startPages[i+1] - 1
+		}
+		ranges = append(ranges, model.PageRange{Start: start, End: end, Title: titles[start]})
+	}
+	return ranges
+}
+
+// splitPartName は分割ファイルの名前を生成（例: scan.pdf → scan_p1-2.pdf）
+func splitPartName(sourceName string, rg model.PageRange) string {
+	base := strings.TrimSuffix(sourceName, filepath.Ext(sourceName))
+	return fmt.Sprintf("%s_p%d-%d.pdf", base, rg.Start, rg.End)
+}
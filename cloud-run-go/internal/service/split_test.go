@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+func TestDocumentRanges(t *testing.T) {
+	tests := []struct {
+		name      string
+		pages     []model.PageClassification
+		pageCount int
+		want      []model.PageRange
+	}{
+		{
+			name: "three letters",
+			pages: []model.PageClassification{
+				{Page: 1, StartsNewDocument: true, Title: "遠足のお知らせ"},
+				{Page: 2, StartsNewDocument: false, Title: "遠足のお知らせ"},
+				{Page: 3, StartsNewDocument: true, Title: "水道料金"},
+				{Page: 4, StartsNewDocument: true, Title: "健診案内"},
+			},
+			pageCount: 4,
+			want: []model.PageRange{
+				{Start: 1, End: 2, Title: "遠足のお知らせ"},
+				{Start: 3, End: 3, Title: "水道料金"},
+				{Start: 4, End: 4, Title: "健診案内"},
+			},
+		},
+		{
+			name:      "single document",
+			pages:     []model.PageClassification{{Page: 1, StartsNewDocument: true}, {Page: 2}, {Page: 3}},
+			pageCount: 3,
+			want:      []model.PageRange{{Start: 1, End: 3}},
+		},
+		{
+			name: "first page is always a start and out of range pages are ignored",
+			pages: []model.PageClassification{
+				{Page: 1, StartsNewDocument: false, Title: "通知"},
+				{Page: 3, StartsNewDocument: true, Title: "請求書"},
+				{Page: 9, StartsNewDocument: true, Title: "存在しない"},
+			},
+			pageCount: 4,
+			want: []model.PageRange{
+				{Start: 1, End: 2, Title: "通知"},
+				{Start: 3, End: 4, Title: "請求書"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := documentRanges(tt.pages, tt.pageCount)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("documentRanges() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitPartName(t *testing.T) {
+	got := splitPartName("scan_20250401.pdf", model.PageRange{Start: 3, End: 5})
+	if got != "scan_20250401_p3-5.pdf" {
+		t.Fatalf("splitPartName() = %s", got)
+	}
+}
+
+func TestAIRouter_ClassifyPages(t *testing.T) {
+	backend := NewFakeBackend()
+	backend.Pages = []model.PageClassification{
+		{Page: 1, StartsNewDocument: true, Title: "お便り"},
+		{Page: 2, StartsNewDocument: true, Title: "請求書"},
+	}
+	router := NewAIRouterWithBackend(backend)
+
+	pages, err := router.ClassifyPages(context.Background(), []byte("%PDF"), "application/pdf", "scan.pdf", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pages, backend.Pages) {
+		t.Fatalf("pages = %+v", pages)
+	}
+	calls := backend.Calls()
+	if len(calls) != 1 || calls[0].Kind != GenerateKindPages || calls[0].Schema == nil {
+		t.Fatalf("unexpected calls: %+v", calls)
+	}
+}
+
+func TestRegisterSplitPart(t *testing.T) {
+	ctx := context.Background()
+	ledger, err := NewFileLedger(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := &FileSorter{ledger: ledger}
+
+	fs.registerSplitPart(ctx, "part1", "scan_p1-2.pdf", "src")
+	entry, err := ledger.Get(ctx, "part1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.State != model.ProcessingStateReceived || entry.FileName != "scan_p1-2.pdf" || entry.Steps[model.StepSplit].Status != model.StepStatusSkipped {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+
+	// 中断前にアップロードし、変更通知で処理が進んだ分割ファイルを登録し直しても巻き戻さない
+	if _, err := ledger.Update(ctx, "part2", func(e *model.LedgerEntry) {
+		e.FileName = "20250601_遠足のお知らせ.pdf"
+		e.Transition(model.ProcessingStateMoved, "")
+		e.Step(model.StepSplit).Status = model.StepStatusSucceeded
+	}); err != nil {
+		t.Fatal(err)
+	}
+	fs.registerSplitPart(ctx, "part2", "scan_p3-3.pdf", "src")
+	entry, err = ledger.Get(ctx, "part2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.State != model.ProcessingStateMoved || entry.FileName != "20250601_遠足のお知らせ.pdf" || entry.Steps[model.StepSplit].Status != model.StepStatusSucceeded {
+		t.Fatalf("registered part should not be rewound: %+v", entry)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/leo-sagawa/homedocmanager/internal/config"
 	"github.com/leo-sagawa/homedocmanager/internal/model"
 )
 
@@ -18,8 +19,8 @@ type TasksClient struct {
 }
 
 // NewTasksClient は新しいTasksClientを作成
-func NewTasksClient(ctx context.Context) (*TasksClient, error) {
-	creds, err := GetOAuthCredentials(ctx)
+func NewTasksClient(ctx context.Context, tenant *config.Tenant) (*TasksClient, error) {
+	creds, err := GetOAuthCredentials(ctx, tenant)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get OAuth credentials for Tasks: %w", err)
 	}
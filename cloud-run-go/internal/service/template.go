@@ -0,0 +1,107 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// expandTemplate はテンプレートのプレースホルダーを分類結果の値で置き換える（値が空で既定値があれば既定値）
+// extra で個別のプレースホルダー（ファイル名の {seq} など）の値を与えられる
+func (fs *FileSorter) expandTemplate(tmpl string, result *model.AnalysisResult, extra func(name, format string) (string, bool)) string {
+	return config.TemplatePlaceholderPattern.ReplaceAllStringFunc(tmpl, func(placeholder string) string {
+		m := config.TemplatePlaceholderPattern.FindStringSubmatch(placeholder)
+		name, format, fallback := m[1], m[2], m[3]
+
+		value, ok := "", false
+		if extra != nil {
+			value, ok = extra(name, format)
+		}
+		if !ok {
+			value = fs.placeholderValue(result, name, format)
+		}
+		if value == "" {
+			return fallback
+		}
+		return value
+	})
+}
+
+// placeholderValue は分類結果からプレースホルダーの値を求める
+func (fs *FileSorter) placeholderValue(result *model.AnalysisResult, name, format string) string {
+	switch name {
+	case "date":
+		date, err := time.ParseInLocation("20060102", result.Date, time.Local)
+		if err != nil {
+			date = time.Now()
+		}
+		return date.Format(config.DateLayout(format))
+	case "fiscal_year":
+		fiscalYear := result.FiscalYear
+		if fiscalYear == 0 {
+			fiscalYear = fs.gradeManager.CalculateFiscalYear(result.Date)
+		}
+		return strconv.Itoa(fiscalYear)
+	case "child":
+		if result.ResolvedFolderName != "" {
+			return result.ResolvedFolderName
+		}
+		if len(result.TargetChildren) > 0 {
+			return strings.Join(result.TargetChildren, "・")
+		}
+		return result.ChildName
+	case "grade_label":
+		return fs.gradeLabel(result)
+	case "adult":
+		return result.TargetAdult
+	case "person":
+		if result.TargetAdult != "" {
+			return result.TargetAdult
+		}
+		return fs.placeholderValue(result, "child", "")
+	case "summary":
+		return result.Summary
+	case "category":
+		return result.Category
+	case "sub_category":
+		return result.SubCategory
+	}
+	return ""
+}
+
+// folderTemplate はカテゴリの移動先フォルダのパステンプレートを返す（世帯設定 → 既定の順）
+func (fs *FileSorter) folderTemplate(category string) string {
+	if tmpl, ok := fs.household.Current().FolderTemplates[category]; ok {
+		return tmpl
+	}
+	return config.DefaultFolderTemplates[category]
+}
+
+// folderSegments はパステンプレートを「/」で区切った階層ごとに展開する（値が空になった階層は作らない）
+func (fs *FileSorter) folderSegments(result *model.AnalysisResult, tmpl string) []string {
+	var segments []string
+	for _, part := range strings.Split(tmpl, "/") {
+		name := fs.expandTemplate(part, result, nil)
+		name = strings.TrimSpace(illegalFilenameChars.ReplaceAllString(name, "_"))
+		if name != "" {
+			segments = append(segments, name)
+		}
+	}
+	return segments
+}
+
+// gradeLabel は対象の子供の学年ラベルを返す（複数の子供の共有フォルダの場合はそのラベル）
+func (fs *FileSorter) gradeLabel(result *model.AnalysisResult) string {
+	if len(result.TargetChildren) > 1 {
+		return result.ResolvedLabel
+	}
+	if len(result.TargetChildren) == 0 || result.FiscalYear == 0 {
+		return ""
+	}
+	grade := fs.gradeManager.GetChildGrade(result.TargetChildren[0], result.FiscalYear)
+	label, _ := fs.gradeManager.GetGradeInfo(grade)
+	return label
+}
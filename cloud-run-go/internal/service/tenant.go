@@ -0,0 +1,88 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+)
+
+// Tenant は1世帯分のサービス一式
+type Tenant struct {
+	Config       *config.Tenant
+	Services     *Services
+	WatchManager *WatchManager
+}
+
+// TenantRegistry はテナントの一覧を保持し、通知元からテナントを特定する
+type TenantRegistry struct {
+	tenants []*Tenant
+	byID    map[string]*Tenant
+}
+
+// NewTenantRegistry は新しいTenantRegistryを作成
+func NewTenantRegistry() *TenantRegistry {
+	return &TenantRegistry{byID: make(map[string]*Tenant)}
+}
+
+// Add はテナントを登録する（最初に登録したテナントが既定のテナントになる）
+func (r *TenantRegistry) Add(t *Tenant) error {
+	if _, ok := r.byID[t.Config.ID]; ok {
+		return fmt.Errorf("tenant %s is already registered", t.Config.ID)
+	}
+	r.tenants = append(r.tenants, t)
+	r.byID[t.Config.ID] = t
+	return nil
+}
+
+// Get はIDからテナントを取得する
+func (r *TenantRegistry) Get(id string) (*Tenant, bool) {
+	t, ok := r.byID[id]
+	return t, ok
+}
+
+// Default は既定のテナントを返す（テナント指定のないリクエスト用）
+func (r *TenantRegistry) Default() *Tenant {
+	if len(r.tenants) == 0 {
+		return nil
+	}
+	return r.tenants[0]
+}
+
+// List は登録順にテナントを返す
+func (r *TenantRegistry) List() []*Tenant {
+	return r.tenants
+}
+
+// ForDriveChannel はDrive Watch通知のチャンネルID・トークンからテナントを特定する
+// トークン → 現在のWatchチャンネル → チャンネルIDの接頭辞 の順に照合する
+func (r *TenantRegistry) ForDriveChannel(channelID, token string) (*Tenant, bool) {
+	if token != "" {
+		for _, t := range r.tenants {
+			if t.Config.DriveWebhookToken != "" && t.Config.DriveWebhookToken == token {
+				return t, true
+			}
+		}
+	}
+
+	for _, t := range r.tenants {
+		if t.WatchManager != nil && channelID != "" && t.WatchManager.ChannelID() == channelID {
+			return t, true
+		}
+	}
+
+	// homedocmanager-{テナントID}-{連番}（テナントIDにも - を含み得るため末尾から切り出す）
+	if rest, ok := strings.CutPrefix(channelID, WatchChannelPrefix); ok {
+		if i := strings.LastIndex(rest, "-"); i > 0 {
+			if t, ok := r.byID[rest[:i]]; ok {
+				return t, true
+			}
+		}
+	}
+
+	// 単一テナント運用では従来どおり既定のテナントで処理する
+	if len(r.tenants) == 1 {
+		return r.tenants[0], true
+	}
+	return nil, false
+}
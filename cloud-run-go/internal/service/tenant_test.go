@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+)
+
+func TestTenantRegistry_ForDriveChannel(t *testing.T) {
+	r := NewTenantRegistry()
+	a := &Tenant{Config: &config.Tenant{ID: "a", DriveWebhookToken: "token-a"}}
+	b := &Tenant{Config: &config.Tenant{ID: "b"}}
+	ab := &Tenant{Config: &config.Tenant{ID: "a-b"}}
+	for _, tn := range []*Tenant{a, b, ab} {
+		if err := r.Add(tn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Add(&Tenant{Config: &config.Tenant{ID: "a"}}); err == nil {
+		t.Fatal("duplicate tenant should be rejected")
+	}
+
+	tests := []struct {
+		name      string
+		channelID string
+		token     string
+		want      *Tenant
+	}{
+		{"token", "unknown", "token-a", a},
+		{"channel prefix", WatchChannelPrefix + "b-123", "", b},
+		{"hyphenated id", WatchChannelPrefix + "a-b-123", "", ab},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := r.ForDriveChannel(tt.channelID, tt.token)
+			if !ok || got != tt.want {
+				t.Fatalf("got %v, want %s", got, tt.want.Config.ID)
+			}
+		})
+	}
+
+	if _, ok := r.ForDriveChannel("other-channel", ""); ok {
+		t.Fatal("unknown channel should not match when multiple tenants exist")
+	}
+	if r.Default() != a {
+		t.Fatal("first tenant should be the default")
+	}
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+// usageTopFiles は月次集計に含めるファイル数の上限
+const usageTopFiles = 20
+
+// UsageTracker はAI呼び出しのトークン使用量・費用を記録し、月次予算を管理する
+// 記録は月ごと・インスタンスごとのJSON Lines（usage-YYYY-MM.<instance>.jsonl）に追記する
+// 予算の判定は全インスタンスの記録を読み直して行い、インスタンス数にかかわらず月の上限を守る
+type UsageTracker struct {
+	dir       string
+	budgetUSD float64
+	prices    map[string]config.ModelPrice
+	now       func() time.Time
+	instance  string
+
+	mu           sync.Mutex
+	alertedMonth string // 予算超過を通知済みの月
+	onExceeded   func(*model.AIUsageSummary)
+}
+
+// NewUsageTracker は新しいUsageTrackerを作成（budgetUSD が0の場合は予算なし）
+func NewUsageTracker(dir string, budgetUSD float64, prices map[string]config.ModelPrice) (*UsageTracker, error) {
+	return newUsageTracker(dir, budgetUSD, prices, time.Now)
+}
+
+func newUsageTracker(dir string, budgetUSD float64, prices map[string]config.ModelPrice, now func() time.Time) (*UsageTracker, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create usage dir: %w", err)
+	}
+	compactInstanceLogs(dir, instanceID, now())
+	t := &UsageTracker{dir: dir, budgetUSD: budgetUSD, prices: prices, now: now, instance: instanceID}
+	// 再起動のたびに通知しないよう、起動時点で超過済みなら通知済みとして扱う
+	month := now().Format("2006-01")
+	exceeded, err := t.exceeded(month)
+	if err != nil {
+		return nil, err
+	}
+	if exceeded {
+		t.alertedMonth = month
+	}
+	return t, nil
+}
+
+// SetBudgetAlert は予算超過時の通知先を設定する（月に1回だけ呼ばれる）
+func (t *UsageTracker) SetBudgetAlert(fn func(*model.AIUsageSummary)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onExceeded = fn
+}
+
+// Record は1回分の使用量を記録する。費用は料金表から算出する
+func (t *UsageTracker) Record(rec model.AIUsageRecord) error {
+	t.mu.Lock()
+	if rec.Time.IsZero() {
+		rec.Time = t.now()
+	}
+	rec.CostUSD = t.cost(rec.Model, rec.PromptTokens, rec.OutputTokens)
+
+	if err := t.log(rec.Time.Format("2006-01")).append(rec); err != nil {
+		t.mu.Unlock()
+		return fmt.Errorf("failed to write usage record: %w", err)
+	}
+
+	var alert func(*model.AIUsageSummary)
+	month := t.now().Format("2006-01")
+	if t.alertedMonth != month {
+		exceeded, err := t.exceeded(month)
+		if err != nil {
+			t.mu.Unlock()
+			return err
+		}
+		if exceeded {
+			t.alertedMonth = month
+			alert = t.onExceeded
+		}
+	}
+	t.mu.Unlock()
+
+	if alert != nil {
+		log.Printf("AI usage budget exceeded for %s: Pro escalation paused", month)
+		summary, err := t.Summary(month)
+		if err != nil {
+			return err
+		}
+		alert(summary)
+	}
+	return nil
+}
+
+// EscalationAllowed は当月の費用が予算内かどうかを返す
+// 他のインスタンスの記録も含めるため、判定のたびに当月の記録を読み直す（読めない場合は予算超過とみなす）
+func (t *UsageTracker) EscalationAllowed() bool {
+	exceeded, err := t.exceeded(t.now().Format("2006-01"))
+	if err != nil {
+		log.Printf("Warning: failed to load AI usage, pausing escalation: %v", err)
+		return false
+	}
+	return !exceeded
+}
+
+// Summary は指定月（YYYY-MM、空の場合は当月）の使用量を集計する
+func (t *UsageTracker) Summary(month string) (*model.AIUsageSummary, error) {
+	if month == "" {
+		month = t.now().Format("2006-01")
+	}
+	records, err := t.Records(month, "")
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &model.AIUsageSummary{
+		Month:     month,
+		ByModel:   make(map[string]*model.AIUsageTotals),
+		TopFiles:  []*model.AIUsageFileTotals{},
+		BudgetUSD: t.budgetUSD,
+	}
+	byFile := make(map[string]*model.AIUsageFileTotals)
+	for _, r := range records {
+		summary.Add(r)
+		m, ok := summary.ByModel[r.Model]
+		if !ok {
+			m = &model.AIUsageTotals{}
+			summary.ByModel[r.Model] = m
+		}
+		m.Add(r)
+		if r.FileID == "" {
+			continue
+		}
+		f, ok := byFile[r.FileID]
+		if !ok {
+			f = &model.AIUsageFileTotals{FileID: r.FileID}
+			byFile[r.FileID] = f
+			summary.TopFiles = append(summary.TopFiles, f)
+		}
+		f.Add(r)
+	}
+
+	sort.SliceStable(summary.TopFiles, func(i, j int) bool {
+		return summary.TopFiles[i].CostUSD > summary.TopFiles[j].CostUSD
+	})
+	if len(summary.TopFiles) > usageTopFiles {
+		summary.TopFiles = summary.TopFiles[:usageTopFiles]
+	}
+
+	summary.BudgetExceeded = t.budgetUSD > 0 && summary.CostUSD >= t.budgetUSD
+	summary.EscalationPaused = summary.BudgetExceeded && month == t.now().Format("2006-01")
+	return summary, nil
+}
+
+// Records は指定月の記録を返す（fileID を指定した場合はそのファイルのみ）
+func (t *UsageTracker) Records(month, fileID string) ([]model.AIUsageRecord, error) {
+	if _, err := time.Parse("2006-01", month); err != nil {
+		return nil, fmt.Errorf("invalid month %q (YYYY-MM): %w", month, err)
+	}
+
+	records := []model.AIUsageRecord{}
+	err := t.log(month).scan(func(line []byte) bool {
+		var r model.AIUsageRecord
+		if err := json.Unmarshal(line, &r); err != nil {
+			// 書き込み途中の行などは読み飛ばす
+			return true
+		}
+		if fileID == "" || r.FileID == fileID {
+			records = append(records, r)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage file: %w", err)
+	}
+	// インスタンスごとのファイルをまとめるため、時刻順に並べ直す
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	return records, nil
+}
+
+// exceeded はその月の全インスタンスの費用が予算に達しているかを返す
+func (t *UsageTracker) exceeded(month string) (bool, error) {
+	if t.budgetUSD <= 0 {
+		return false, nil
+	}
+	records, err := t.Records(month, "")
+	if err != nil {
+		return false, err
+	}
+	var cost float64
+	for _, r := range records {
+		cost += r.CostUSD
+	}
+	return cost >= t.budgetUSD, nil
+}
+
+// cost は料金表からUSDを算出する
+func (t *UsageTracker) cost(modelName string, promptTokens, outputTokens int) float64 {
+	price, ok := t.prices[modelName]
+	if !ok {
+		return 0
+	}
+	return (float64(promptTokens)*price.InputPerMTok + float64(outputTokens)*price.OutputPerMTok) / 1_000_000
+}
+
+// log はその月の記録のファイル群を返す
+func (t *UsageTracker) log(month string) instanceLog {
+	return instanceLog{dir: t.dir, name: "usage-" + month, instance: t.instance}
+}
+
+type usageFileIDKey struct{}
+
+// withUsageFileID は使用量の記録に使うファイルIDをコンテキストに設定する
+func withUsageFileID(ctx context.Context, fileID string) context.Context {
+	return context.WithValue(ctx, usageFileIDKey{}, fileID)
+}
+
+// usageFileID はコンテキストからファイルIDを取得する
+func usageFileID(ctx context.Context) string {
+	id, _ := ctx.Value(usageFileIDKey{}).(string)
+	return id
+}
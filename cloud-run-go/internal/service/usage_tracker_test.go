@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/leo-sagawa/homedocmanager/internal/config"
+	"github.com/leo-sagawa/homedocmanager/internal/model"
+)
+
+var testPrices = map[string]config.ModelPrice{
+	"fake-fast": {InputPerMTok: 1, OutputPerMTok: 2},
+	"fake-high": {InputPerMTok: 10, OutputPerMTok: 20},
+}
+
+func newTestUsageTracker(t *testing.T, dir string, budget float64, now time.Time) *UsageTracker {
+	t.Helper()
+	u, err := newUsageTracker(dir, budget, testPrices, func() time.Time { return now })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestUsageTracker_SummaryAndBudget(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	u := newTestUsageTracker(t, dir, 1.0, now)
+
+	var alerts []*model.AIUsageSummary
+	u.SetBudgetAlert(func(s *model.AIUsageSummary) { alerts = append(alerts, s) })
+
+	// 0.3 USD
+	if err := u.Record(model.AIUsageRecord{Model: "fake-fast", FileID: "f1", PromptTokens: 100_000, OutputTokens: 100_000}); err != nil {
+		t.Fatal(err)
+	}
+	if !u.EscalationAllowed() || len(alerts) != 0 {
+		t.Fatal("budget should not be exceeded yet")
+	}
+	// 1.0 USD（累計 1.3 USD）
+	if err := u.Record(model.AIUsageRecord{Model: "fake-high", FileID: "f2", Escalation: true, PromptTokens: 100_000}); err != nil {
+		t.Fatal(err)
+	}
+	// 価格未登録のモデルは0円
+	if err := u.Record(model.AIUsageRecord{Model: "local", FileID: "f1", TotalTokens: 500}); err != nil {
+		t.Fatal(err)
+	}
+
+	if u.EscalationAllowed() {
+		t.Fatal("escalation should be paused after exceeding the budget")
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("alerts = %d, want 1", len(alerts))
+	}
+
+	s, err := u.Summary("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Month != "2026-10" || s.Calls != 3 || s.Escalations != 1 || !s.BudgetExceeded || !s.EscalationPaused {
+		t.Fatalf("unexpected summary: %+v", s)
+	}
+	if diff := s.CostUSD - 1.3; diff > 1e-9 || diff < -1e-9 {
+		t.Fatalf("cost = %f, want 1.3", s.CostUSD)
+	}
+	if len(s.ByModel) != 3 || s.ByModel["fake-high"].Escalations != 1 {
+		t.Fatalf("unexpected by_model: %+v", s.ByModel)
+	}
+	if len(s.TopFiles) != 2 || s.TopFiles[0].FileID != "f2" || s.TopFiles[1].Calls != 2 {
+		t.Fatalf("unexpected top_files: %+v", s.TopFiles)
+	}
+
+	records, err := u.Records("2026-10", "f1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("records = %d, want 2", len(records))
+	}
+
+	// 再起動しても同じ月には再通知しない
+	restarted := newTestUsageTracker(t, dir, 1.0, now)
+	restarted.SetBudgetAlert(func(s *model.AIUsageSummary) { alerts = append(alerts, s) })
+	if restarted.EscalationAllowed() {
+		t.Fatal("restarted tracker should load this month's cost")
+	}
+	if err := restarted.Record(model.AIUsageRecord{Model: "fake-fast", PromptTokens: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("alerts after restart = %d, want 1", len(alerts))
+	}
+
+	// 翌月は予算がリセットされる
+	u.now = func() time.Time { return now.AddDate(0, 1, 0) }
+	if !u.EscalationAllowed() {
+		t.Fatal("budget should reset in the next month")
+	}
+}
+
+func TestUsageTracker_BudgetSharedAcrossInstances(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	a := newTestUsageTracker(t, dir, 1.0, now)
+	a.instance = "a"
+	b := newTestUsageTracker(t, dir, 1.0, now)
+	b.instance = "b"
+
+	// 0.6 USD ずつ（各インスタンスでは予算内だが、合計では超過）
+	if err := a.Record(model.AIUsageRecord{Model: "fake-high", PromptTokens: 60_000}); err != nil {
+		t.Fatal(err)
+	}
+	if !b.EscalationAllowed() {
+		t.Fatal("budget should not be exceeded yet")
+	}
+	if err := b.Record(model.AIUsageRecord{Model: "fake-high", PromptTokens: 60_000}); err != nil {
+		t.Fatal(err)
+	}
+	if a.EscalationAllowed() || b.EscalationAllowed() {
+		t.Fatal("escalation should be paused once the instances together exceed the budget")
+	}
+
+	// 記録はインスタンスごとのファイルに分かれ、集計ではまとめて読む
+	for _, name := range []string{"usage-2026-10.a.jsonl", "usage-2026-10.b.jsonl"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+	s, err := a.Summary("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Calls != 2 || !s.BudgetExceeded {
+		t.Fatalf("unexpected summary: %+v", s)
+	}
+}
+
+func TestAIRouter_BudgetPausesEscalation(t *testing.T) {
+	now := time.Now()
+	usage := newTestUsageTracker(t, t.TempDir(), 1.0, now)
+	backend := NewFakeBackend()
+	backend.Respond = func(req GenerateRequest) (string, error) {
+		b, err := json.Marshal(model.AnalysisResult{Category: "30_ライフ・行政", ConfidenceScore: 0.1})
+		return string(b), err
+	}
+	router := NewAIRouterWithBackend(backend)
+	router.SetUsageTracker(usage)
+	ctx := withUsageFileID(context.Background(), "file-1")
+
+	// 予算内: Flash → Pro にエスカレーションし、呼び出しごとに記録する
+	if _, err := router.AnalyzeDocument(ctx, []byte("img"), "image/jpeg", "prompt", nil, true); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(backend.Calls()); n != 2 {
+		t.Fatalf("calls = %d, want 2", n)
+	}
+	records, err := usage.Records(now.Format("2006-01"), "file-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Escalation || !records[1].Escalation || records[1].Model != "fake-high" || records[0].TotalTokens == 0 {
+		t.Fatalf("unexpected records: %+v", records)
+	}
+
+	// 予算超過後: Flashの結果をそのまま使う
+	if err := usage.Record(model.AIUsageRecord{Model: "fake-high", PromptTokens: 100_000}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := router.AnalyzeDocument(ctx, []byte("img"), "image/jpeg", "prompt", nil, true); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(backend.Calls()); n != 3 {
+		t.Fatalf("calls = %d, want 3 (no escalation)", n)
+	}
+}
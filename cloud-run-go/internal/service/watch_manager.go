@@ -160,3 +160,14 @@ func (wm *WatchManager) ValidateNotification(channelID, resourceID string) (bool
 	}
 	return true, ""
 }
+
+// ChannelID は現在のWatchチャンネルIDを返す（Watchがない場合は空文字）
+func (wm *WatchManager) ChannelID() string {
+	wm.mu.RLock()
+	defer wm.mu.RUnlock()
+
+	if wm.currentWatch == nil {
+		return ""
+	}
+	return wm.currentWatch.ChannelID
+}